@@ -0,0 +1,95 @@
+package governor
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Action is what a rule does when it matches. Allow and deny rules decide;
+// the obligation actions, warn, redact and transform, let the request
+// through with an Obligation attached and never decide it themselves.
+type Action string
+
+const (
+	ActionAllow Action = "allow"
+	ActionDeny  Action = "deny"
+	// ActionWarn asks the caller to flag the request, e.g. in its logs.
+	ActionWarn Action = "warn"
+	// ActionRedact asks the caller to redact the fields the rule tested.
+	ActionRedact Action = "redact"
+	// ActionTransform asks the caller to rewrite the fields the rule
+	// tested, as its Params describe.
+	ActionTransform Action = "transform"
+)
+
+// Valid reports whether a is a known action or empty.
+func (a Action) Valid() bool {
+	switch a {
+	case "", ActionAllow, ActionDeny, ActionWarn, ActionRedact, ActionTransform:
+		return true
+	}
+	return false
+}
+
+// obligation reports whether a attaches an obligation instead of deciding.
+func (a Action) obligation() bool {
+	return a == ActionWarn || a == ActionRedact || a == ActionTransform
+}
+
+// Effect returns the action of d: Action when set, and otherwise allow or
+// deny according to Allow.
+func (d RuleDefinition) Effect() Action {
+	switch {
+	case d.Action != "":
+		return d.Action
+	case d.Allow:
+		return ActionAllow
+	default:
+		return ActionDeny
+	}
+}
+
+// Obligation asks the caller of an allowed decision to act on the request.
+// It is triggered by a matching warn, redact or transform rule; Fields are
+// the selectors of the values the rule tested and Params are the rule's.
+type Obligation struct {
+	RuleID string            `json:"rule_id" yaml:"rule_id"`
+	Action Action            `json:"action" yaml:"action"`
+	Fields []string          `json:"fields,omitempty" yaml:"fields,omitempty"`
+	Reason string            `json:"reason,omitempty" yaml:"reason,omitempty"`
+	Params map[string]string `json:"params,omitempty" yaml:"params,omitempty"`
+}
+
+// newObligation returns the obligation def triggers, or nil for rules that
+// decide.
+func newObligation(def RuleDefinition) *Obligation {
+	if !def.Effect().obligation() {
+		return nil
+	}
+	var fields []string
+	seen := map[string]bool{}
+	for _, leaf := range def.Leaves() {
+		field := leaf.Field
+		if field == "" {
+			field = def.Selector()
+		}
+		if !seen[field] {
+			seen[field] = true
+			fields = append(fields, field)
+		}
+	}
+	return &Obligation{RuleID: def.ID, Action: def.Action, Fields: fields, Reason: def.Description, Params: def.Params}
+}
+
+// validateAction checks def's action and that its other settings agree.
+func validateAction(def RuleDefinition) error {
+	switch {
+	case !def.Action.Valid():
+		return fmt.Errorf("unknown action %q", def.Action)
+	case def.Allow && def.Action != "" && def.Action != ActionAllow:
+		return fmt.Errorf("allow contradicts action %s", def.Action)
+	case len(def.Params) > 0 && !def.Effect().obligation():
+		return errors.New("params apply only to warn, redact and transform rules")
+	}
+	return nil
+}
@@ -0,0 +1,346 @@
+package aisentinelhttp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+
+	governor "github.com/mfifth/aisentinel-go-sdk"
+)
+
+// GatewayOptions configures a Gateway.
+type GatewayOptions struct {
+	// Upstream is the model endpoint requests are proxied to. It is required.
+	Upstream *url.URL
+	// Transport performs upstream round trips; nil uses
+	// http.DefaultTransport.
+	Transport http.RoundTripper
+	// RulepackID is evaluated against request bodies; empty selects
+	// Config.DefaultRulepackID.
+	RulepackID string
+	// ResponseRulepackID is evaluated against response bodies; empty uses
+	// RulepackID.
+	ResponseRulepackID string
+	// SkipResponse disables response evaluation.
+	SkipResponse bool
+	// OnDeny is the action for denied bodies; the default is ActionBlock.
+	OnDeny Action
+	// Redact rewrites a denied body for ActionRedact. The default replaces
+	// PII with placeholders.
+	Redact func(body []byte) []byte
+	// MaxBodyBytes bounds buffered bodies; zero uses DefaultMaxBodyBytes.
+	MaxBodyBytes int64
+	// FailOpen lets traffic through when the Governor cannot decide.
+	FailOpen bool
+}
+
+// Gateway is a governed reverse proxy in front of a model endpoint. Request
+// bodies are evaluated before they are forwarded. Responses are evaluated as
+// they stream: server-sent events one event at a time against the text of the
+// stream so far, so tokens keep flowing, and other responses as a whole. A
+// denied request is answered with 403; a denied event ends the stream with an
+// "error" event.
+type Gateway struct {
+	proxy     *httputil.ReverseProxy
+	transport *Transport
+	opts      GatewayOptions
+}
+
+// NewGateway returns a Gateway for gov.
+func NewGateway(gov *governor.Governor, opts GatewayOptions) (*Gateway, error) {
+	if gov == nil {
+		return nil, errors.New("aisentinelhttp: gateway needs a Governor")
+	}
+	if opts.Upstream == nil || opts.Upstream.Scheme == "" || opts.Upstream.Host == "" {
+		return nil, errors.New("aisentinelhttp: gateway needs an absolute upstream URL")
+	}
+	if opts.ResponseRulepackID == "" {
+		opts.ResponseRulepackID = opts.RulepackID
+	}
+	g := &Gateway{
+		opts: opts,
+		transport: &Transport{
+			Base:         opts.Transport,
+			Governor:     gov,
+			RulepackID:   opts.RulepackID,
+			SkipResponse: true,
+			OnDeny:       opts.OnDeny,
+			Redact:       opts.Redact,
+			MaxBodyBytes: opts.MaxBodyBytes,
+			FailOpen:     opts.FailOpen,
+		},
+	}
+	upstream := opts.Upstream
+	g.proxy = &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetURL(upstream)
+			pr.SetXForwarded()
+			// Responses are evaluated, so they must not arrive in an
+			// encoding the client chose and the gateway may not decode.
+			pr.Out.Header.Del("Accept-Encoding")
+		},
+		Transport:      g.transport,
+		FlushInterval:  -1,
+		ModifyResponse: g.modifyResponse,
+		ErrorHandler:   g.errorHandler,
+	}
+	return g, nil
+}
+
+// ServeHTTP implements http.Handler.
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	g.proxy.ServeHTTP(w, r)
+}
+
+func (g *Gateway) modifyResponse(resp *http.Response) error {
+	if g.opts.SkipResponse || resp.Body == nil || resp.Body == http.NoBody {
+		return nil
+	}
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType == "text/event-stream" {
+		src, err := decodeBody(resp.Body, resp.Header.Get("Content-Encoding"))
+		if err != nil {
+			return err
+		}
+		stream := &eventStream{
+			src:      bufio.NewReader(src),
+			closer:   resp.Body,
+			ctx:      resp.Request.Context(),
+			gov:      g.transport.Governor,
+			rulepack: g.opts.ResponseRulepackID,
+			failOpen: g.opts.FailOpen,
+		}
+		if g.opts.OnDeny == ActionRedact {
+			stream.redact = g.opts.Redact
+			if stream.redact == nil {
+				stream.redact = redactPII
+			}
+		}
+		stream.eval = stream.newEvaluator()
+		resp.Body = stream
+		// Governed events may differ in length from the upstream ones.
+		resp.ContentLength = -1
+		resp.Header.Del("Content-Length")
+		resp.Header.Del("Content-Encoding")
+		return nil
+	}
+	body, err := g.transport.readBody(resp.Body, resp.Header.Get("Content-Encoding"))
+	if err != nil {
+		return err
+	}
+	if body, err = g.transport.govern(resp.Request, "response", g.opts.ResponseRulepackID, body); err != nil {
+		return err
+	}
+	setBody(resp, body)
+	return nil
+}
+
+func (g *Gateway) errorHandler(w http.ResponseWriter, _ *http.Request, err error) {
+	var (
+		denied  *DeniedError
+		evalErr *evaluationError
+	)
+	switch {
+	case errors.As(err, &denied):
+		writeJSON(w, http.StatusForbidden, denyResponse{Error: denied.Phase + " denied by policy", Reason: denied.Result.Reason, RuleID: denied.Result.RuleID})
+	case errors.Is(err, ErrBodyTooLarge):
+		writeError(w, http.StatusRequestEntityTooLarge, err.Error())
+	case errors.Is(err, ErrUnsupportedEncoding):
+		writeError(w, http.StatusUnsupportedMediaType, err.Error())
+	case errors.As(err, &evalErr):
+		writeError(w, http.StatusServiceUnavailable, "policy evaluation failed")
+	default:
+		writeError(w, http.StatusBadGateway, "upstream unavailable")
+	}
+}
+
+// eventStream governs a server-sent event stream one event at a time. The
+// text of the events, as extracted by eventText, is accumulated and the text
+// so far is evaluated under "completion" for every event, so patterns split
+// across events still match. Allowed events are passed on, a denied event is
+// redacted when that makes the text acceptable, and otherwise the stream ends
+// with an "error" event.
+type eventStream struct {
+	src      *bufio.Reader
+	closer   io.Closer
+	ctx      context.Context
+	gov      *governor.Governor
+	rulepack string
+	redact   func(body []byte) []byte // nil blocks denied events
+	failOpen bool
+	eval     *governor.StreamEvaluator
+
+	out    bytes.Buffer
+	err    error
+	done   bool
+	denied bool
+}
+
+func (s *eventStream) newEvaluator() *governor.StreamEvaluator {
+	return s.gov.NewStreamEvaluator(s.rulepack, "completion", nil)
+}
+
+func (s *eventStream) Read(p []byte) (int, error) {
+	for s.out.Len() == 0 && !s.done {
+		s.next()
+	}
+	if s.out.Len() > 0 {
+		return s.out.Read(p)
+	}
+	if s.err != nil {
+		return 0, s.err
+	}
+	return 0, io.EOF
+}
+
+func (s *eventStream) Close() error { return s.closer.Close() }
+
+// next reads one event and queues what should be forwarded for it. At the end
+// of the stream the complete text is evaluated once more to be audited.
+func (s *eventStream) next() {
+	defer s.finish()
+	var lines []string
+	for {
+		line, err := s.src.ReadString('\n')
+		if line != "" {
+			lines = append(lines, line)
+		}
+		if err != nil {
+			s.done = true
+			if !errors.Is(err, io.EOF) {
+				s.err = err
+			}
+			break
+		}
+		if strings.TrimRight(line, "\r\n") == "" {
+			break
+		}
+	}
+	if len(lines) == 0 {
+		return
+	}
+
+	var data []string
+	for _, line := range lines {
+		if v, ok := strings.CutPrefix(strings.TrimRight(line, "\r\n"), "data:"); ok {
+			data = append(data, strings.TrimPrefix(v, " "))
+		}
+	}
+	joined := []byte(strings.Join(data, "\n"))
+	if len(data) == 0 || string(joined) == "[DONE]" {
+		for _, line := range lines {
+			s.out.WriteString(line)
+		}
+		return
+	}
+
+	if text := eventText(joined); text != "" {
+		var ok bool
+		if joined, ok = s.govern(joined, text); !ok {
+			return
+		}
+	}
+
+	// Forward the event with its data replaced by the governed copy.
+	wroteData := false
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "data:") {
+			s.out.WriteString(line)
+			continue
+		}
+		if !wroteData {
+			for _, d := range strings.Split(string(joined), "\n") {
+				fmt.Fprintf(&s.out, "data: %s\n", d)
+			}
+			wroteData = true
+		}
+	}
+}
+
+// govern evaluates the text of an event with data and returns the data to
+// forward, or false when the stream has ended.
+func (s *eventStream) govern(data []byte, text string) ([]byte, bool) {
+	prior := s.eval.Text()
+	result, err := s.eval.Write(s.ctx, text)
+	if err == nil && !result.Allowed && s.redact != nil {
+		// The denial is audited; the redacted text is checked by an
+		// evaluator of its own, which replaces the denied one if allowed.
+		redacted := s.redact(data)
+		eval := s.newEvaluator()
+		if result, err = eval.Write(s.ctx, prior+eventText(redacted)); err == nil && result.Allowed {
+			s.eval, data = eval, redacted
+		}
+	}
+	switch {
+	case err != nil && s.failOpen:
+		return data, true
+	case err != nil:
+		s.err, s.done = &evaluationError{err}, true
+		return nil, false
+	case !result.Allowed:
+		s.deny(result)
+		return nil, false
+	}
+	return data, true
+}
+
+// finish audits the complete text once the stream has ended without a
+// denial or an error.
+func (s *eventStream) finish() {
+	if !s.done || s.denied || s.err != nil {
+		return
+	}
+	if result, err := s.eval.Finish(s.ctx); err == nil && !result.Allowed {
+		s.deny(result)
+	}
+}
+
+// deny ends the stream with an "error" event for result.
+func (s *eventStream) deny(result governor.DecisionResult) {
+	msg, _ := json.Marshal(denyResponse{Error: "response denied by policy", Reason: result.Reason, RuleID: result.RuleID})
+	fmt.Fprintf(&s.out, "event: error\ndata: %s\n\n", msg)
+	s.done, s.denied = true, true
+}
+
+// eventText returns the generated text in the data of a server-sent event:
+// the delta of OpenAI chat and completion chunks and of Anthropic and OpenAI
+// Responses events, or the data itself for other formats.
+func eventText(data []byte) string {
+	var event struct {
+		Type    string `json:"type"`
+		Choices []struct {
+			Text  string `json:"text"`
+			Delta struct {
+				Content string `json:"content"`
+			} `json:"delta"`
+		} `json:"choices"`
+		Delta json.RawMessage `json:"delta"`
+	}
+	if json.Unmarshal(data, &event) != nil || (event.Type == "" && event.Choices == nil && event.Delta == nil) {
+		return string(data)
+	}
+	var text strings.Builder
+	for _, choice := range event.Choices {
+		text.WriteString(choice.Text)
+		text.WriteString(choice.Delta.Content)
+	}
+	var delta struct {
+		Text string `json:"text"`
+	}
+	var s string
+	switch {
+	case json.Unmarshal(event.Delta, &s) == nil:
+		text.WriteString(s)
+	case json.Unmarshal(event.Delta, &delta) == nil:
+		text.WriteString(delta.Text)
+	}
+	return text.String()
+}
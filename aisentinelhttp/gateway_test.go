@@ -0,0 +1,95 @@
+package aisentinelhttp
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	governor "github.com/mfifth/aisentinel-go-sdk"
+)
+
+func TestGateway(t *testing.T) {
+	gov := newGovernor(t,
+		governor.Rulepack{ID: "egress", Rules: []governor.RuleDefinition{{ID: "prompt", Pattern: "^[^@]*$", Allow: true}}},
+		governor.Rulepack{ID: "ingress", DefaultDecision: governor.DefaultAllow, Rules: []governor.RuleDefinition{
+			{ID: "dollar", Field: "completion", Description: "no secrets", Pattern: `\$`},
+			{ID: "secret", Field: "completion", Description: "no secrets", Pattern: "secret"},
+		}},
+	)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat" {
+			http.NotFound(w, r)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		switch {
+		case strings.Contains(string(body), "split"):
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = io.WriteString(w, "data: {\"choices\":[{\"delta\":{\"content\":\"the sec\"}}]}\n\ndata: {\"choices\":[{\"delta\":{\"content\":\"ret\"}}]}\n\ndata: [DONE]\n\n")
+		case strings.Contains(string(body), "stream"):
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = io.WriteString(w, "data: {\"delta\":\"hel\"}\n\ndata: {\"delta\":\"lo\"}\n\ndata: {\"delta\":\"$ecret\"}\n\ndata: {\"delta\":\"never sent\"}\n\n")
+		case strings.Contains(string(body), "leak") && strings.Contains(r.Header.Get("Accept-Encoding"), "gzip"):
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Content-Encoding", "gzip")
+			_, _ = w.Write(gzipped(t, `{"completion":"the key is $ecret"}`))
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = io.WriteString(w, `{"completion":"hello"}`)
+		}
+	}))
+	t.Cleanup(upstream.Close)
+	target, _ := url.Parse(upstream.URL)
+
+	gateway, err := NewGateway(gov, GatewayOptions{Upstream: target, RulepackID: "egress", ResponseRulepackID: "ingress"})
+	if err != nil {
+		t.Fatalf("expected gateway: %v", err)
+	}
+	srv := httptest.NewServer(gateway)
+	t.Cleanup(srv.Close)
+
+	post := func(body string) (*http.Response, string) {
+		t.Helper()
+		resp, err := http.Post(srv.URL+"/v1/chat", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("post: %v", err)
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		return resp, string(data)
+	}
+
+	if resp, body := post(`{"prompt":"hi"}`); resp.StatusCode != http.StatusOK || body != `{"completion":"hello"}` {
+		t.Fatalf("expected the upstream response, got %d %q", resp.StatusCode, body)
+	}
+	if resp, body := post(`{"prompt":"bob@example.com"}`); resp.StatusCode != http.StatusForbidden || !strings.Contains(body, "request denied by policy") {
+		t.Fatalf("expected 403, got %d %q", resp.StatusCode, body)
+	}
+
+	resp, body := post(`{"prompt":"stream please"}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected the stream to start, got %d", resp.StatusCode)
+	}
+	if !strings.HasPrefix(body, "data: {\"delta\":\"hel\"}\n\ndata: {\"delta\":\"lo\"}\n\nevent: error\n") || strings.Contains(body, "never sent") {
+		t.Fatalf("expected the stream to be cut at the denied event, got %q", body)
+	}
+
+	resp, body = post(`{"prompt":"split stream"}`)
+	if !strings.Contains(body, "the sec") || !strings.Contains(body, "event: error\n") || !strings.Contains(body, `"rule_id":"secret"`) || strings.Contains(body, "[DONE]") {
+		t.Fatalf("expected a match split across events to end the stream, got %q", body)
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/v1/chat", strings.NewReader(`{"prompt":"leak"}`))
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("post: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected the response to be evaluated whatever encoding the client accepts, got %d", resp.StatusCode)
+	}
+}
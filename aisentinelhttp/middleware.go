@@ -0,0 +1,204 @@
+// Package aisentinelhttp guards net/http handlers with a Governor: each
+// request is evaluated before it reaches the wrapped handler and denied
+// requests are rejected with 403 and the policy reason.
+package aisentinelhttp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	governor "github.com/mfifth/aisentinel-go-sdk"
+)
+
+// DefaultMaxBodyBytes bounds the request bodies JSONBody reads.
+const DefaultMaxBodyBytes = 1 << 20 // 1 MiB
+
+// Extractor builds the decision request for an incoming HTTP request. An
+// empty RulepackID is filled from Options.RulepackID.
+type Extractor func(r *http.Request) (governor.DecisionRequest, error)
+
+// Options configures Middleware. The zero value evaluates the JSON request
+// body against the Governor's default rulepack and fails closed.
+type Options struct {
+	// RulepackID is the rulepack to evaluate; empty selects
+	// Config.DefaultRulepackID.
+	RulepackID string
+	// Extract builds the decision request. Defaults to
+	// JSONBody(DefaultMaxBodyBytes).
+	Extract Extractor
+	// Skip lets matching requests, such as health checks, through without
+	// evaluation.
+	Skip func(r *http.Request) bool
+	// OnDeny writes the response for a denied request. The default writes
+	// 403 with a JSON body carrying the reason and rule ID.
+	OnDeny func(w http.ResponseWriter, r *http.Request, result governor.DecisionResult)
+	// FailOpen passes requests through when the Governor cannot decide,
+	// instead of rejecting them with 503.
+	FailOpen bool
+}
+
+// ErrBodyTooLarge is returned by JSONBody for bodies over its limit.
+var ErrBodyTooLarge = errors.New("aisentinelhttp: request body too large")
+
+type decisionKey struct{}
+
+// DecisionFromContext returns the decision that admitted the request, for use
+// by the wrapped handler.
+func DecisionFromContext(ctx context.Context) (governor.DecisionResult, bool) {
+	result, ok := ctx.Value(decisionKey{}).(governor.DecisionResult)
+	return result, ok
+}
+
+// Middleware returns a wrapper that evaluates every request with gov before
+// calling the next handler. Requests that cannot be extracted are rejected
+// with 400, and payloads over Config.MaxPayloadBytes with 413.
+func Middleware(gov *governor.Governor, opts Options) func(http.Handler) http.Handler {
+	if opts.Extract == nil {
+		opts.Extract = JSONBody(DefaultMaxBodyBytes)
+	}
+	if opts.OnDeny == nil {
+		opts.OnDeny = denyJSON
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if opts.Skip != nil && opts.Skip(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			req, err := opts.Extract(r)
+			if err != nil {
+				code := http.StatusBadRequest
+				if errors.Is(err, ErrBodyTooLarge) {
+					code = http.StatusRequestEntityTooLarge
+				}
+				writeError(w, code, err.Error())
+				return
+			}
+			if req.RulepackID == "" {
+				req.RulepackID = opts.RulepackID
+			}
+			result, err := gov.Evaluate(r.Context(), req)
+			switch {
+			case errors.Is(err, governor.ErrPayloadTooLarge):
+				writeError(w, http.StatusRequestEntityTooLarge, "payload too large")
+			case err != nil && opts.FailOpen:
+				next.ServeHTTP(w, r)
+			case err != nil:
+				writeError(w, http.StatusServiceUnavailable, "policy evaluation failed")
+			case !result.Allowed:
+				opts.OnDeny(w, r, result)
+			default:
+				next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), decisionKey{}, result)))
+			}
+		})
+	}
+}
+
+// RequireAllow is the minimal form of Middleware: requests are admitted only
+// when extract's decision request is allowed. nil extract evaluates the JSON
+// body. The result composes with chi's Use and With, or wraps handlers
+// registered on a plain http.ServeMux.
+func RequireAllow(gov *governor.Governor, extract Extractor) func(http.Handler) http.Handler {
+	return Middleware(gov, Options{Extract: extract})
+}
+
+// ForRulepack returns an Extractor evaluating extract's payload against the
+// given rulepack, so one route can be held to a different policy:
+//
+//	r.With(aisentinelhttp.RequireAllow(gov, aisentinelhttp.ForRulepack("billing", nil))).Post("/refund", refund)
+//
+// nil extract evaluates the JSON body.
+func ForRulepack(id string, extract Extractor) Extractor {
+	if extract == nil {
+		extract = JSONBody(DefaultMaxBodyBytes)
+	}
+	return func(r *http.Request) (governor.DecisionRequest, error) {
+		req, err := extract(r)
+		req.RulepackID = id
+		return req, err
+	}
+}
+
+// JSONBody uses the request body as the payload, restoring it for the next
+// handler. Bodies that are not JSON are wrapped as {"body": "<text>"} and an
+// empty body becomes {}.
+func JSONBody(maxBytes int64) Extractor {
+	return func(r *http.Request) (governor.DecisionRequest, error) {
+		var req governor.DecisionRequest
+		if r.Body == nil || r.Body == http.NoBody {
+			req.Payload = json.RawMessage("{}")
+			return req, nil
+		}
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxBytes+1))
+		r.Body.Close()
+		if err != nil {
+			return req, fmt.Errorf("read request body: %w", err)
+		}
+		if int64(len(body)) > maxBytes {
+			return req, ErrBodyTooLarge
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		req.Payload, err = bodyPayload(body)
+		return req, err
+	}
+}
+
+// bodyPayload turns a body into a payload: JSON is used as is, other text is
+// wrapped as {"body": "<text>"} and an empty body becomes {}.
+func bodyPayload(body []byte) (json.RawMessage, error) {
+	switch {
+	case len(bytes.TrimSpace(body)) == 0:
+		return json.RawMessage("{}"), nil
+	case json.Valid(body):
+		return body, nil
+	default:
+		return json.Marshal(map[string]string{"body": string(body)})
+	}
+}
+
+// QueryParams builds the payload from URL query parameters, one string field
+// per name, for guarding GET endpoints. Without names every parameter is
+// used. Repeated parameters keep their first value.
+func QueryParams(names ...string) Extractor {
+	return func(r *http.Request) (governor.DecisionRequest, error) {
+		query := r.URL.Query()
+		fields := make(map[string]string, len(query))
+		if len(names) == 0 {
+			for name := range query {
+				fields[name] = query.Get(name)
+			}
+		}
+		for _, name := range names {
+			if query.Has(name) {
+				fields[name] = query.Get(name)
+			}
+		}
+		payload, err := json.Marshal(fields)
+		return governor.DecisionRequest{Payload: payload}, err
+	}
+}
+
+type denyResponse struct {
+	Error  string `json:"error"`
+	Reason string `json:"reason,omitempty"`
+	RuleID string `json:"rule_id,omitempty"`
+}
+
+func denyJSON(w http.ResponseWriter, _ *http.Request, result governor.DecisionResult) {
+	writeJSON(w, http.StatusForbidden, denyResponse{Error: "request denied by policy", Reason: result.Reason, RuleID: result.RuleID})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, denyResponse{Error: msg})
+}
@@ -0,0 +1,135 @@
+package aisentinelhttp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	governor "github.com/mfifth/aisentinel-go-sdk"
+)
+
+// newGovernor returns a Governor whose control plane serves packs by ID, or
+// a single "remote" pack allowing prompts that start with "ok".
+func newGovernor(t *testing.T, packs ...governor.Rulepack) *governor.Governor {
+	t.Helper()
+	if len(packs) == 0 {
+		packs = []governor.Rulepack{{ID: "remote", Rules: []governor.RuleDefinition{{ID: "prompt", Description: "prompt must be ok", Pattern: "^ok", Allow: true}}}}
+	}
+	control := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, pack := range packs {
+			if r.URL.Path == "/rulepacks/"+pack.ID {
+				_ = json.NewEncoder(w).Encode(pack)
+				return
+			}
+		}
+		http.NotFound(w, r)
+	}))
+	t.Cleanup(control.Close)
+	gov, err := governor.NewGovernor(context.Background(), governor.Config{APIKey: "test", APIBaseURL: control.URL})
+	if err != nil {
+		t.Fatalf("expected governor: %v", err)
+	}
+	t.Cleanup(func() { _ = gov.Close() })
+	return gov
+}
+
+func TestMiddleware(t *testing.T) {
+	gov := newGovernor(t)
+	handler := Middleware(gov, Options{
+		RulepackID: "remote",
+		Skip:       func(r *http.Request) bool { return r.URL.Path == "/healthz" },
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := DecisionFromContext(r.Context()); !ok && r.URL.Path != "/healthz" {
+			t.Error("expected the decision in the request context")
+		}
+		body, _ := io.ReadAll(r.Body)
+		_, _ = w.Write(body)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/chat", strings.NewReader(`{"prompt":"ok go"}`)))
+	if rec.Code != http.StatusOK || rec.Body.String() != `{"prompt":"ok go"}` {
+		t.Fatalf("expected the body to reach the handler, got %d %q", rec.Code, rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/chat", strings.NewReader(`{"prompt":"leak it"}`)))
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", rec.Code)
+	}
+	var deny denyResponse
+	if err := json.NewDecoder(rec.Body).Decode(&deny); err != nil || deny.Reason == "" {
+		t.Fatalf("expected a reason, got %+v, %v", deny, err)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected skipped request to pass, got %d", rec.Code)
+	}
+}
+
+func TestMiddlewareExtractors(t *testing.T) {
+	gov := newGovernor(t)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	query := Middleware(gov, Options{RulepackID: "remote", Extract: QueryParams("prompt")})(next)
+	rec := httptest.NewRecorder()
+	query.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/search?prompt=ok&other=x", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected query payload to be allowed, got %d", rec.Code)
+	}
+
+	small := Middleware(gov, Options{RulepackID: "remote", Extract: JSONBody(8)})(next)
+	rec = httptest.NewRecorder()
+	small.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/chat", strings.NewReader(`{"prompt":"ok"}`)))
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413, got %d", rec.Code)
+	}
+
+	// Without a rulepack the Governor cannot decide.
+	closed := Middleware(gov, Options{})(next)
+	rec = httptest.NewRecorder()
+	closed.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/chat", strings.NewReader(`{}`)))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected fail closed, got %d", rec.Code)
+	}
+	open := Middleware(gov, Options{FailOpen: true})(next)
+	rec = httptest.NewRecorder()
+	open.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/chat", strings.NewReader(`{}`)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected fail open, got %d", rec.Code)
+	}
+}
+
+func TestRequireAllow(t *testing.T) {
+	gov := newGovernor(t)
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if result, found := DecisionFromContext(r.Context()); !found || result.RuleID != "prompt" {
+			t.Errorf("expected the admitting decision, got %+v", result)
+		}
+	})
+	mux := http.NewServeMux()
+	mux.Handle("/search", RequireAllow(gov, ForRulepack("remote", QueryParams("prompt")))(ok))
+	mux.Handle("/chat", RequireAllow(gov, ForRulepack("remote", nil))(ok))
+
+	for _, tc := range []struct {
+		method, target, body string
+		want                 int
+	}{
+		{http.MethodGet, "/search?prompt=ok", "", http.StatusOK},
+		{http.MethodGet, "/search?prompt=no", "", http.StatusForbidden},
+		{http.MethodPost, "/chat", `{"prompt":"ok"}`, http.StatusOK},
+		{http.MethodPost, "/chat", `{"prompt":"no"}`, http.StatusForbidden},
+	} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body)))
+		if rec.Code != tc.want {
+			t.Errorf("%s %s: expected %d, got %d", tc.method, tc.target, tc.want, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package aisentinelhttp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	governor "github.com/mfifth/aisentinel-go-sdk"
+	"github.com/mfifth/aisentinel-go-sdk/pii"
+)
+
+// Action selects what Transport does with a denied body.
+type Action string
+
+const (
+	// ActionBlock fails the round trip with a *DeniedError.
+	ActionBlock Action = "block"
+	// ActionRedact redacts the body and evaluates it again, blocking only
+	// when the redacted body is still denied.
+	ActionRedact Action = "redact"
+)
+
+// ErrDenied is matched by every *DeniedError.
+var ErrDenied = errors.New("aisentinelhttp: denied by policy")
+
+// DeniedError reports a request or response body blocked by Transport.
+type DeniedError struct {
+	// Phase is "request" or "response".
+	Phase  string
+	Result governor.DecisionResult
+}
+
+func (e *DeniedError) Error() string {
+	return fmt.Sprintf("aisentinelhttp: %s denied by policy (rule %s): %s", e.Phase, e.Result.RuleID, e.Result.Reason)
+}
+
+func (e *DeniedError) Is(target error) bool { return target == ErrDenied }
+
+// ErrUnsupportedEncoding is returned for bodies whose Content-Encoding
+// Transport cannot decode, since their content cannot be evaluated.
+var ErrUnsupportedEncoding = errors.New("aisentinelhttp: unsupported content encoding")
+
+// Transport is an http.RoundTripper that governs outbound calls to model
+// providers: the request body is evaluated before it is sent and the response
+// body after it is received. Both are buffered in full, so streamed responses
+// are only returned once complete. Gzip and deflate bodies are decoded before
+// they are evaluated and passed on decoded; other encodings fail the round
+// trip with ErrUnsupportedEncoding.
+//
+//	client := &http.Client{Transport: &aisentinelhttp.Transport{Governor: gov, RulepackID: "llm-egress"}}
+type Transport struct {
+	// Base performs the round trip; nil uses http.DefaultTransport.
+	Base http.RoundTripper
+	// Governor makes the decisions. It is required.
+	Governor *governor.Governor
+	// RulepackID is evaluated against request bodies; empty selects
+	// Config.DefaultRulepackID.
+	RulepackID string
+	// ResponseRulepackID is evaluated against response bodies; empty uses
+	// RulepackID.
+	ResponseRulepackID string
+	// SkipResponse disables response evaluation.
+	SkipResponse bool
+	// OnDeny is the action for denied bodies; the default is ActionBlock.
+	OnDeny Action
+	// Redact rewrites a denied body for ActionRedact. The default replaces
+	// PII with placeholders such as [EMAIL].
+	Redact func(body []byte) []byte
+	// MaxBodyBytes bounds the bodies buffered for evaluation; zero uses
+	// DefaultMaxBodyBytes. Larger bodies fail the round trip.
+	MaxBodyBytes int64
+	// FailOpen lets bodies through when the Governor cannot decide instead of
+	// failing the round trip.
+	FailOpen bool
+}
+
+var defaultDetector = pii.New()
+
+func redactPII(body []byte) []byte {
+	redacted, _ := defaultDetector.Redact(string(body), pii.StrategyPlaceholder)
+	return []byte(redacted)
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.Governor == nil {
+		return nil, errors.New("aisentinelhttp: Transport.Governor is nil")
+	}
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := t.readBody(req.Body, req.Header.Get("Content-Encoding"))
+		if err != nil {
+			return nil, err
+		}
+		if body, err = t.govern(req, "request", t.RulepackID, body); err != nil {
+			return nil, err
+		}
+		req = req.Clone(req.Context())
+		req.Header.Del("Content-Encoding")
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		req.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(body)), nil }
+		req.ContentLength = int64(len(body))
+		if req.Header.Get("Content-Length") != "" {
+			req.Header.Set("Content-Length", strconv.Itoa(len(body)))
+		}
+	}
+
+	resp, err := base.RoundTrip(req)
+	if err != nil || t.SkipResponse || resp.Body == nil || resp.Body == http.NoBody {
+		return resp, err
+	}
+	body, err := t.readBody(resp.Body, resp.Header.Get("Content-Encoding"))
+	if err != nil {
+		return nil, err
+	}
+	rulepack := t.ResponseRulepackID
+	if rulepack == "" {
+		rulepack = t.RulepackID
+	}
+	if body, err = t.govern(req, "response", rulepack, body); err != nil {
+		return nil, err
+	}
+	setBody(resp, body)
+	return resp, nil
+}
+
+// setBody replaces the body of resp with the decoded body.
+func setBody(resp *http.Response, body []byte) {
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+	resp.Header.Del("Content-Length")
+	if resp.Header.Get("Content-Encoding") != "" {
+		resp.Header.Del("Content-Encoding")
+		resp.Uncompressed = true
+	}
+}
+
+// readBody reads rc decoded according to encoding. The limit applies to the
+// decoded body.
+func (t *Transport) readBody(rc io.ReadCloser, encoding string) ([]byte, error) {
+	defer rc.Close()
+	limit := t.MaxBodyBytes
+	if limit <= 0 {
+		limit = DefaultMaxBodyBytes
+	}
+	r, err := decodeBody(rc, encoding)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > limit {
+		return nil, ErrBodyTooLarge
+	}
+	return body, nil
+}
+
+// decodeBody returns a reader of body without its Content-Encoding.
+func decodeBody(body io.Reader, encoding string) (io.Reader, error) {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "", "identity":
+		return body, nil
+	case "gzip", "x-gzip":
+		return gzip.NewReader(body)
+	case "deflate":
+		return zlib.NewReader(body)
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnsupportedEncoding, encoding)
+	}
+}
+
+// govern evaluates body and returns what may be sent on: the body itself, a
+// redacted copy, or an error when it is blocked.
+func (t *Transport) govern(req *http.Request, phase, rulepack string, body []byte) ([]byte, error) {
+	result, err := t.evaluate(req, rulepack, body)
+	if err != nil || result.Allowed {
+		return body, err
+	}
+	if t.OnDeny == ActionRedact {
+		redact := t.Redact
+		if redact == nil {
+			redact = redactPII
+		}
+		redacted := redact(body)
+		if result, err = t.evaluate(req, rulepack, redacted); err != nil || result.Allowed {
+			return redacted, err
+		}
+	}
+	return nil, &DeniedError{Phase: phase, Result: result}
+}
+
+func (t *Transport) evaluate(req *http.Request, rulepack string, body []byte) (governor.DecisionResult, error) {
+	payload, err := bodyPayload(body)
+	if err != nil {
+		return governor.DecisionResult{}, err
+	}
+	result, err := t.Governor.Evaluate(req.Context(), governor.DecisionRequest{RulepackID: rulepack, Payload: payload})
+	if err != nil && t.FailOpen {
+		return governor.DecisionResult{Allowed: true}, nil
+	}
+	if err != nil {
+		return result, &evaluationError{err}
+	}
+	return result, nil
+}
+
+// evaluationError marks failures of the Governor itself, as opposed to the
+// round trip, so the gateway can answer 503 rather than 502.
+type evaluationError struct{ err error }
+
+func (e *evaluationError) Error() string {
+	return "aisentinelhttp: policy evaluation: " + e.err.Error()
+}
+
+func (e *evaluationError) Unwrap() error { return e.err }
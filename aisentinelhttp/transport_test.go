@@ -0,0 +1,143 @@
+package aisentinelhttp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	governor "github.com/mfifth/aisentinel-go-sdk"
+)
+
+func TestTransport(t *testing.T) {
+	gov := newGovernor(t,
+		governor.Rulepack{ID: "egress", Rules: []governor.RuleDefinition{{ID: "prompt", Pattern: "^[^@]*$", Allow: true}}},
+		governor.Rulepack{ID: "ingress", Rules: []governor.RuleDefinition{{ID: "answer", Description: "no secrets", Pattern: "^[^$]*$", Allow: true}}},
+	)
+	var sent string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		sent = string(body)
+		if strings.Contains(sent, "leak") {
+			_, _ = w.Write([]byte(`{"answer":"the key is $ecret"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"answer":"hello"}`))
+	}))
+	t.Cleanup(upstream.Close)
+
+	transport := &Transport{Governor: gov, RulepackID: "egress", ResponseRulepackID: "ingress"}
+	client := &http.Client{Transport: transport}
+
+	resp, err := client.Post(upstream.URL, "application/json", strings.NewReader(`{"prompt":"hi"}`))
+	if err != nil {
+		t.Fatalf("expected allowed round trip: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != `{"answer":"hello"}` {
+		t.Fatalf("unexpected response %q", body)
+	}
+
+	sent = ""
+	_, err = client.Post(upstream.URL, "application/json", strings.NewReader(`{"prompt":"mail bob@example.com"}`))
+	var denied *DeniedError
+	if !errors.As(err, &denied) || denied.Phase != "request" || !errors.Is(err, ErrDenied) {
+		t.Fatalf("expected the request to be blocked, got %v", err)
+	}
+	if sent != "" {
+		t.Fatalf("expected nothing to be sent, got %q", sent)
+	}
+
+	transport.OnDeny = ActionRedact
+	resp, err = client.Post(upstream.URL, "application/json", strings.NewReader(`{"prompt":"mail bob@example.com"}`))
+	if err != nil {
+		t.Fatalf("expected the redacted request to be sent: %v", err)
+	}
+	resp.Body.Close()
+	if strings.Contains(sent, "bob@example.com") || !strings.Contains(sent, "[EMAIL]") {
+		t.Fatalf("expected the email to be redacted, sent %q", sent)
+	}
+
+	_, err = client.Post(upstream.URL, "application/json", strings.NewReader(`{"prompt":"leak"}`))
+	if !errors.As(err, &denied) || denied.Phase != "response" {
+		t.Fatalf("expected the response to be blocked, got %v", err)
+	}
+}
+
+func TestTransportContentEncoding(t *testing.T) {
+	gov := newGovernor(t,
+		governor.Rulepack{ID: "egress", Rules: []governor.RuleDefinition{{ID: "prompt", Pattern: "^[^@]*$", Allow: true}}},
+		governor.Rulepack{ID: "ingress", Rules: []governor.RuleDefinition{{ID: "answer", Description: "no secrets", Pattern: "^[^$]*$", Allow: true}}},
+	)
+	var sent string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		sent = string(body)
+		if strings.Contains(sent, "brotli") {
+			w.Header().Set("Content-Encoding", "br")
+			_, _ = w.Write([]byte("\x0b\x02\x80"))
+			return
+		}
+		answer := `{"answer":"hello"}`
+		if strings.Contains(sent, "leak") {
+			answer = `{"answer":"the key is $ecret"}`
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		_, _ = w.Write(gzipped(t, answer))
+	}))
+	t.Cleanup(upstream.Close)
+
+	client := &http.Client{Transport: &Transport{Governor: gov, RulepackID: "egress", ResponseRulepackID: "ingress"}}
+	post := func(body []byte, encoding string) (*http.Response, error) {
+		req, _ := http.NewRequest(http.MethodPost, upstream.URL, bytes.NewReader(body))
+		// Asking for gzip explicitly turns off the decoding of http.Transport.
+		req.Header.Set("Accept-Encoding", "gzip")
+		if encoding != "" {
+			req.Header.Set("Content-Encoding", encoding)
+		}
+		return client.Do(req)
+	}
+
+	resp, err := post([]byte(`{"prompt":"hi"}`), "")
+	if err != nil {
+		t.Fatalf("expected allowed round trip: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != `{"answer":"hello"}` || resp.Header.Get("Content-Encoding") != "" || !resp.Uncompressed {
+		t.Fatalf("expected the decoded response, got %q %v", body, resp.Header)
+	}
+
+	var denied *DeniedError
+	if _, err := post([]byte(`{"prompt":"leak"}`), ""); !errors.As(err, &denied) || denied.Phase != "response" {
+		t.Fatalf("expected the gzipped response to be evaluated and blocked, got %v", err)
+	}
+	sent = ""
+	if _, err := post(gzipped(t, `{"prompt":"mail bob@example.com"}`), "gzip"); !errors.As(err, &denied) || denied.Phase != "request" || sent != "" {
+		t.Fatalf("expected the gzipped request to be evaluated and blocked, got %v", err)
+	}
+	if _, err := post([]byte(`{"prompt":"brotli"}`), ""); !errors.Is(err, ErrUnsupportedEncoding) {
+		t.Fatalf("expected a response that cannot be decoded to fail, got %v", err)
+	}
+	if _, err := post([]byte(`{"prompt":"hi"}`), "br"); !errors.Is(err, ErrUnsupportedEncoding) {
+		t.Fatalf("expected a request that cannot be decoded to fail, got %v", err)
+	}
+}
+
+func gzipped(t *testing.T, s string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := io.WriteString(zw, s); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
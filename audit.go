@@ -0,0 +1,239 @@
+package governor
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mfifth/aisentinel-go-sdk/storage"
+)
+
+// AuditRecord is a persisted decision.
+type AuditRecord struct {
+	RulepackID      string          `json:"rulepack_id"`
+	RulepackVersion string          `json:"rulepack_version,omitempty"`
+	Payload         json.RawMessage `json:"payload"`
+	Allowed         bool            `json:"allowed"`
+	Reason          string          `json:"reason"`
+	Code            ReasonCode      `json:"code,omitempty"`
+	RuleID          string          `json:"rule_id,omitempty"`
+	MatchedRuleIDs  []string        `json:"matched_rule_ids,omitempty"`
+	// Obligations are those of the decision; see DecisionResult.
+	Obligations []Obligation `json:"obligations,omitempty"`
+	LatencyMS   int64        `json:"latency_ms"`
+	Time        time.Time    `json:"time"`
+	// Explanation is the rule-by-rule trace of decisions requested with
+	// DecisionRequest.Explain, kept as evidence of how they were reached.
+	Explanation *Explanation `json:"explanation,omitempty"`
+}
+
+// AuditFilter selects audit records. Zero fields match everything.
+type AuditFilter struct {
+	RulepackID string
+	// Since and Until bound the decision times: records after Since and
+	// before Until match.
+	Since time.Time
+	Until time.Time
+	// Allowed, when set, keeps only allowed or only denied decisions.
+	Allowed *bool
+	// DeniedOnly keeps only denied decisions, as Allowed set to false does.
+	DeniedOnly bool
+	// Limit keeps only the most recent records when positive.
+	Limit int
+}
+
+// AuditQuery is the former name of AuditFilter.
+//
+// Deprecated: Use AuditFilter.
+type AuditQuery = AuditFilter
+
+func (f AuditFilter) matches(r AuditRecord) bool {
+	return (f.Since.IsZero() || r.Time.After(f.Since)) &&
+		(f.Until.IsZero() || r.Time.Before(f.Until)) &&
+		(f.RulepackID == "" || r.RulepackID == f.RulepackID) &&
+		(f.Allowed == nil || r.Allowed == *f.Allowed) &&
+		(!f.DeniedOnly || !r.Allowed)
+}
+
+// auditKey returns the key of an audit record, "<rulepack>:<unix
+// nanos>-<seq>". The sequence number keeps records taken at the same instant
+// apart, and its fixed width keeps them in the order they were taken.
+func auditKey(rulepackID string, t time.Time, seq uint64) string {
+	return fmt.Sprintf("%s:%d-%016x", rulepackID, t.UnixNano(), seq)
+}
+
+// keyRange returns the range of audit record keys, see auditKey, that can
+// match f: those of its rulepack, from Since until Until. Without a rulepack
+// every record is scanned.
+func (f AuditFilter) keyRange() (start, end string) {
+	if f.RulepackID == "" {
+		return "", ""
+	}
+	prefix := f.RulepackID + ":"
+	start, end = prefix, storage.PrefixEnd(prefix)
+	// Nanosecond timestamps since 2001 have 19 digits, so they sort like
+	// the times they stand for.
+	if nanos, ok := keyNanos(f.Since); ok {
+		start += nanos
+	}
+	if nanos, ok := keyNanos(f.Until); ok {
+		end = prefix + nanos
+	}
+	return start, end
+}
+
+// keyNanos formats t as in audit record keys, reporting whether it sorts
+// with them.
+func keyNanos(t time.Time) (string, bool) {
+	nanos := strconv.FormatInt(t.UnixNano(), 10)
+	return nanos, !t.IsZero() && len(nanos) == 19
+}
+
+// ExportFormat selects the encoding used by ExportAudit.
+type ExportFormat string
+
+const (
+	// ExportJSONL writes an AuditRecord as JSON per line.
+	ExportJSONL ExportFormat = "jsonl"
+	// ExportCSV writes a header row and a row per record with the columns
+	// time, rulepack_id, allowed, reason, latency_ms, payload,
+	// rulepack_version, code, rule_id and matched_rule_ids, the last
+	// joined with "|". Columns are only ever appended, so readers of older
+	// exports keep working.
+	ExportCSV ExportFormat = "csv"
+)
+
+// AuditFormat is the former name of ExportFormat.
+//
+// Deprecated: Use ExportFormat.
+type AuditFormat = ExportFormat
+
+// Deprecated: Use ExportJSONL and ExportCSV.
+const (
+	AuditJSONL = ExportJSONL
+	AuditCSV   = ExportCSV
+)
+
+// auditCSVHeader names the ExportCSV columns.
+var auditCSVHeader = []string{
+	"time", "rulepack_id", "allowed", "reason", "latency_ms", "payload",
+	"rulepack_version", "code", "rule_id", "matched_rule_ids",
+}
+
+// QueryAudit returns the audit records matching f, oldest first.
+func (g *Governor) QueryAudit(ctx context.Context, f AuditFilter) ([]AuditRecord, error) {
+	if g.storage == nil {
+		return nil, nil
+	}
+	var records []AuditRecord
+	start, end := f.keyRange()
+	err := storage.IterRange(ctx, g.storage, start, end, func(rec storage.Record) error {
+		if strings.HasPrefix(rec.Key, usageKeyPrefix) {
+			return nil
+		}
+		record, err := decodeAuditRecord(rec)
+		if err != nil {
+			return err
+		}
+		if f.matches(record) {
+			records = append(records, record)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(records, func(i, j int) bool { return records[i].Time.Before(records[j].Time) })
+	if f.Limit > 0 && len(records) > f.Limit {
+		records = records[len(records)-f.Limit:]
+	}
+	return records, nil
+}
+
+// decodeAuditRecord parses a stored decision. Records written before the time
+// field existed take their timestamp from the "<rulepack>:<unix nanos>" key.
+func decodeAuditRecord(rec storage.Record) (AuditRecord, error) {
+	var record AuditRecord
+	if err := json.Unmarshal(rec.Value, &record); err != nil {
+		return record, fmt.Errorf("decode audit record %s: %w", rec.Key, err)
+	}
+	if record.Time.IsZero() {
+		if i := strings.LastIndexByte(rec.Key, ':'); i >= 0 {
+			nanos, _, _ := strings.Cut(rec.Key[i+1:], "-")
+			if nanos, err := strconv.ParseInt(nanos, 10, 64); err == nil {
+				record.Time = time.Unix(0, nanos)
+			}
+		}
+	}
+	return record, nil
+}
+
+// ExportAudit writes the audit records matching f to w in format, oldest
+// first, for handing audit trails to reviewers or other systems.
+func (g *Governor) ExportAudit(ctx context.Context, w io.Writer, format ExportFormat, f AuditFilter) error {
+	if err := format.validate(); err != nil {
+		return err
+	}
+	records, err := g.QueryAudit(ctx, f)
+	if err != nil {
+		return err
+	}
+	return WriteAuditRecords(w, format, records, true)
+}
+
+func (f ExportFormat) validate() error {
+	switch f {
+	case ExportJSONL, ExportCSV, "":
+		return nil
+	}
+	return fmt.Errorf("unknown audit format %q", f)
+}
+
+// WriteAuditRecords encodes records to w; an empty format is JSONL. header
+// controls whether the CSV header row is written, so callers streaming
+// batches can emit it once.
+func WriteAuditRecords(w io.Writer, format ExportFormat, records []AuditRecord, header bool) error {
+	if err := format.validate(); err != nil {
+		return err
+	}
+	if format != ExportCSV {
+		enc := json.NewEncoder(w)
+		for _, record := range records {
+			if err := enc.Encode(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	cw := csv.NewWriter(w)
+	if header {
+		if err := cw.Write(auditCSVHeader); err != nil {
+			return err
+		}
+	}
+	for _, r := range records {
+		row := []string{
+			r.Time.UTC().Format(time.RFC3339Nano),
+			r.RulepackID,
+			strconv.FormatBool(r.Allowed),
+			r.Reason,
+			strconv.FormatInt(r.LatencyMS, 10),
+			string(r.Payload),
+			r.RulepackVersion,
+			string(r.Code),
+			r.RuleID,
+			strings.Join(r.MatchedRuleIDs, "|"),
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
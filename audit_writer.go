@@ -0,0 +1,197 @@
+package governor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mfifth/aisentinel-go-sdk/storage"
+)
+
+// ErrAuditBufferFull reports an audit record dropped because
+// Config.AuditBufferSize records were already waiting to be written.
+var ErrAuditBufferFull = errors.New("governor: audit buffer full")
+
+// errAuditWriterClosed reports an audit record queued after Close.
+var errAuditWriterClosed = errors.New("governor: audit writer closed")
+
+// EventAuditDropped is emitted for every audit record dropped by
+// BackpressureDrop.
+const EventAuditDropped EventType = "audit_dropped"
+
+// BackpressurePolicy decides what Evaluate does with an audit record when the
+// asynchronous audit buffer is full; see Config.AuditAsync.
+type BackpressurePolicy string
+
+const (
+	// BackpressureDrop drops the record, counting it in
+	// CounterAuditDropped, so storage slowdowns never delay decisions. This
+	// is the default.
+	BackpressureDrop BackpressurePolicy = "drop"
+	// BackpressureBlock makes Evaluate wait for room, up to its context's
+	// deadline, so records are only lost when the wait is cut short.
+	BackpressureBlock BackpressurePolicy = "block"
+)
+
+// Valid reports whether p is a known policy.
+func (p BackpressurePolicy) Valid() bool {
+	return p == BackpressureDrop || p == BackpressureBlock
+}
+
+// AuditErrorHandler receives the audit records that could not be written,
+// for example to keep them elsewhere, with the error. The records are the
+// handler's to keep. It is called from Evaluate or from the asynchronous
+// writer and must not block.
+type AuditErrorHandler func(err error, records []storage.Record)
+
+// WithAuditErrorHandler registers fn for audit records that are dropped or
+// fail to be written. Failures are also counted in CounterAuditFailures and
+// emitted as events either way.
+func WithAuditErrorHandler(fn AuditErrorHandler) Option {
+	return func(g *Governor) error {
+		if fn == nil {
+			return fmt.Errorf("audit error handler cannot be nil")
+		}
+		g.auditErrors = fn
+		return nil
+	}
+}
+
+// reportAuditFailure counts, emits and hands over audit records that were
+// not written. rulepackID is empty for batches.
+func (g *Governor) reportAuditFailure(rulepackID string, err error, records []storage.Record) {
+	g.metrics.Add(CounterAuditFailures, uint64(max(len(records), 1)))
+	g.events.emit(Event{Type: EventAuditFailure, Level: LevelError, Message: "audit record not persisted", RulepackID: rulepackID, Err: err, Fields: map[string]any{"records": len(records)}})
+	if g.auditErrors != nil {
+		g.auditErrors(err, records)
+	}
+}
+
+// auditWriter writes queued audit records to storage in batches, off the
+// decision path.
+type auditWriter struct {
+	g       *Governor
+	records chan storage.Record
+	block   bool
+	timeout time.Duration
+
+	// mu is held for reading by enqueue and for writing by close while it
+	// sets closed, so no record is queued once the final drain starts.
+	mu     sync.RWMutex
+	closed bool
+	// stop releases the enqueues waiting for room, drain starts the final
+	// drain once they are gone and done reports it finished.
+	stop     chan struct{}
+	drain    chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// startAuditWriter starts writing the records queued by g until close.
+func startAuditWriter(g *Governor, cfg Config) *auditWriter {
+	w := &auditWriter{
+		g:       g,
+		records: make(chan storage.Record, cfg.AuditBufferSize),
+		block:   cfg.AuditBackpressure == BackpressureBlock,
+		timeout: cfg.operationTimeout(cfg.AuditFlushTimeout),
+		stop:    make(chan struct{}),
+		drain:   make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go w.run(cfg.AuditBatchSize, cfg.AuditFlushInterval)
+	return w
+}
+
+// enqueue queues record, which must not be modified afterwards, applying
+// the backpressure policy when the buffer is full.
+func (w *auditWriter) enqueue(ctx context.Context, rulepackID string, record storage.Record) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		w.g.reportAuditFailure(rulepackID, errAuditWriterClosed, []storage.Record{record})
+		return
+	}
+	select {
+	case w.records <- record:
+		return
+	default:
+	}
+	if !w.block {
+		w.g.metrics.Inc(CounterAuditDropped)
+		w.g.events.emit(Event{Type: EventAuditDropped, Level: LevelWarn, Message: "audit buffer full; record dropped", RulepackID: rulepackID, Fields: map[string]any{"capacity": cap(w.records)}})
+		if w.g.auditErrors != nil {
+			w.g.auditErrors(ErrAuditBufferFull, []storage.Record{record})
+		}
+		return
+	}
+	select {
+	case w.records <- record:
+	case <-ctx.Done():
+		w.g.reportAuditFailure(rulepackID, fmt.Errorf("%w: %w", ErrAuditBufferFull, ctx.Err()), []storage.Record{record})
+	case <-w.stop:
+		w.g.reportAuditFailure(rulepackID, errAuditWriterClosed, []storage.Record{record})
+	}
+}
+
+// run writes a batch whenever batchSize records are queued and at least
+// every interval, until close. The records still queued are written before
+// it returns.
+func (w *auditWriter) run(batchSize int, interval time.Duration) {
+	defer close(w.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	batch := make([]storage.Record, 0, batchSize)
+	for {
+		select {
+		case record := <-w.records:
+			if batch = append(batch, record); len(batch) >= batchSize {
+				batch = w.flush(batch)
+			}
+		case <-ticker.C:
+			batch = w.flush(batch)
+		case <-w.drain:
+			for {
+				select {
+				case record := <-w.records:
+					if batch = append(batch, record); len(batch) >= batchSize {
+						batch = w.flush(batch)
+					}
+				default:
+					w.flush(batch)
+					return
+				}
+			}
+		}
+	}
+}
+
+// flush writes batch and returns an empty batch to fill next.
+func (w *auditWriter) flush(batch []storage.Record) []storage.Record {
+	if len(batch) == 0 {
+		return batch
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), w.timeout)
+	defer cancel()
+	if err := storage.PutBatch(ctx, w.g.storage, batch); err != nil {
+		w.g.reportAuditFailure("", err, batch)
+		// The handler may keep the failed batch.
+		return make([]storage.Record, 0, cap(batch))
+	}
+	clear(batch)
+	return batch[:0]
+}
+
+// close stops the writer once the queued records are written. Enqueues
+// waiting for room fail, and the others finish before the final drain.
+func (w *auditWriter) close() {
+	w.stopOnce.Do(func() {
+		close(w.stop)
+		w.mu.Lock()
+		w.closed = true
+		w.mu.Unlock()
+		close(w.drain)
+	})
+	<-w.done
+}
@@ -0,0 +1,146 @@
+package governor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+)
+
+// EventRulepackImported is emitted for each rulepack ImportBundle caches.
+const EventRulepackImported EventType = "rulepack_imported"
+
+// bundleFormat is the version of the bundle layout written by ExportBundle.
+const bundleFormat = 1
+
+// ErrBundleChecksum indicates a bundled rulepack whose bytes do not match
+// the checksum recorded for them.
+var ErrBundleChecksum = errors.New("governor: bundle checksum mismatch")
+
+// bundle is the document ExportBundle writes: every rulepack the Governor
+// holds, as the exact bytes it was decoded from.
+type bundle struct {
+	Format    int           `json:"format"`
+	CreatedAt time.Time     `json:"created_at"`
+	Rulepacks []bundleEntry `json:"rulepacks"`
+}
+
+// bundleEntry is one rulepack of a bundle, stored under the reference it is
+// cached as, "governance" or a pinned "governance@v12".
+type bundleEntry struct {
+	Ref     string `json:"ref"`
+	Version string `json:"version"`
+	// Checksum is the hex encoded SHA-256 of Rulepack.
+	Checksum string `json:"checksum"`
+	Rulepack []byte `json:"rulepack"`
+	// Signature is the RulepackSignatureHeader value the control plane
+	// served Rulepack with, if it was verified.
+	Signature string `json:"signature,omitempty"`
+}
+
+// ExportBundle writes every rulepack the Governor holds, preloaded or
+// cached and unexpired, to w as a single JSON document for ImportBundle,
+// typically into an air-gapped deployment. Each rulepack is recorded with
+// its version and SHA-256 checksum, and with its signature when it was
+// verified against Config.RulepackPublicKeys.
+func (g *Governor) ExportBundle(w io.Writer) error {
+	out := bundle{Format: bundleFormat, CreatedAt: g.now().UTC()}
+	packs := g.cache.snapshot()
+	for id, pack := range g.pinned {
+		packs[id] = pack
+	}
+	for ref, pack := range packs {
+		data, signature := pack.body, pack.signature
+		if data == nil {
+			var err error
+			if data, err = json.Marshal(pack); err != nil {
+				return fmt.Errorf("export rulepack %s: %w", ref, err)
+			}
+		}
+		sum := sha256.Sum256(data)
+		out.Rulepacks = append(out.Rulepacks, bundleEntry{
+			Ref:       ref,
+			Version:   pack.Version,
+			Checksum:  hex.EncodeToString(sum[:]),
+			Rulepack:  data,
+			Signature: signature,
+		})
+	}
+	slices.SortFunc(out.Rulepacks, func(a, b bundleEntry) int { return strings.Compare(a.Ref, b.Ref) })
+	return json.NewEncoder(w).Encode(out)
+}
+
+// ImportBundle reads a bundle written by ExportBundle and caches its
+// rulepacks without an expiry, so they are evaluated without contacting the
+// control plane, even in offline mode. Rulepacks are checked against their
+// checksums, and their signatures when Config.RulepackPublicKeys is set, and
+// validated before any is imported; a bundle is imported whole or not at all.
+// Preloaded rulepack IDs are skipped.
+func (g *Governor) ImportBundle(r io.Reader) error {
+	var in bundle
+	if err := json.NewDecoder(r).Decode(&in); err != nil {
+		return fmt.Errorf("decode bundle: %w", err)
+	}
+	if in.Format != bundleFormat {
+		return fmt.Errorf("unsupported bundle format %d", in.Format)
+	}
+	packs := make([]*Rulepack, 0, len(in.Rulepacks))
+	for _, entry := range in.Rulepacks {
+		pack, err := g.importBundleEntry(entry)
+		if err != nil {
+			return fmt.Errorf("bundle rulepack %s: %w", entry.Ref, err)
+		}
+		if _, ok := g.pinned[entry.Ref]; !ok {
+			packs = append(packs, pack)
+		}
+	}
+	for _, pack := range packs {
+		if err := g.evaluator.Preload(pack.key(), pack.Rules); err != nil {
+			return fmt.Errorf("bundle rulepack %s: %w", pack.key(), err)
+		}
+	}
+	for _, pack := range packs {
+		g.cache.keep(pack.key(), pack)
+		g.events.emit(Event{Type: EventRulepackImported, Level: LevelInfo, Message: "rulepack imported", RulepackID: pack.ID, Fields: map[string]any{"version": pack.Version}})
+	}
+	return nil
+}
+
+// importBundleEntry verifies and decodes one rulepack of a bundle.
+func (g *Governor) importBundleEntry(entry bundleEntry) (*Rulepack, error) {
+	sum := sha256.Sum256(entry.Rulepack)
+	if hex.EncodeToString(sum[:]) != entry.Checksum {
+		return nil, ErrBundleChecksum
+	}
+	if len(g.publicKeys) > 0 {
+		if err := verifyRulepack(g.publicKeys, entry.Rulepack, entry.Signature); err != nil {
+			return nil, err
+		}
+	}
+	var pack Rulepack
+	if err := json.Unmarshal(entry.Rulepack, &pack); err != nil {
+		return nil, err
+	}
+	if err := pack.Validate(); err != nil {
+		return nil, err
+	}
+	id, version := splitRulepackRef(entry.Ref)
+	if pack.ID != id || pack.Version != entry.Version {
+		return nil, fmt.Errorf("holds rulepack %s version %q", pack.ID, pack.Version)
+	}
+	if version != "" {
+		if version != pack.Version {
+			return nil, fmt.Errorf("holds version %q", pack.Version)
+		}
+		pack.ref = entry.Ref
+	}
+	if len(g.publicKeys) > 0 {
+		pack.body, pack.signature = entry.Rulepack, entry.Signature
+	}
+	return &pack, nil
+}
@@ -53,16 +53,23 @@ func (c *RuleCache[T]) Get(key string) (T, bool) {
 
 // Set stores a value with an optional per-value TTL.
 func (c *RuleCache[T]) Set(key string, value T, ttlOverride ...time.Duration) {
+	c.mu.Lock()
 	ttl := c.ttl
 	if len(ttlOverride) > 0 {
 		ttl = ttlOverride[0]
 	}
-	entry := cacheEntry[T]{
+	c.entries[key] = cacheEntry[T]{
 		value:     value,
 		expiresAt: c.clock().Add(ttl),
 	}
+	c.mu.Unlock()
+}
+
+// SetTTL changes the default TTL for entries stored from now on. Existing
+// entries keep their expiry.
+func (c *RuleCache[T]) SetTTL(ttl time.Duration) {
 	c.mu.Lock()
-	c.entries[key] = entry
+	c.ttl = ttl
 	c.mu.Unlock()
 }
 
@@ -83,3 +90,34 @@ func (c *RuleCache[T]) Len() int {
 	defer c.mu.RUnlock()
 	return len(c.entries)
 }
+
+// purgeExpired removes every expired entry.
+func (c *RuleCache[T]) purgeExpired() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, entry := range c.entries {
+		if c.expired(entry) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// keep stores a value that never expires.
+func (c *RuleCache[T]) keep(key string, value T) {
+	c.mu.Lock()
+	c.entries[key] = cacheEntry[T]{value: value}
+	c.mu.Unlock()
+}
+
+// snapshot returns the unexpired entries by key.
+func (c *RuleCache[T]) snapshot() map[string]T {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	values := make(map[string]T, len(c.entries))
+	for key, entry := range c.entries {
+		if !c.expired(entry) {
+			values[key] = entry.value
+		}
+	}
+	return values
+}
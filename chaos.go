@@ -0,0 +1,97 @@
+package governor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// ErrChaos is returned by rulepack fetches failed by ChaosConfig.
+var ErrChaos = errors.New("governor: injected chaos failure")
+
+// ChaosConfig injects faults into a Governor so timeout and failure policy
+// settings can be rehearsed before a real outage tests them. It is meant for
+// tests and staging environments only.
+type ChaosConfig struct {
+	// FetchLatency delays every rulepack fetch attempt, plus up to
+	// FetchJitter more at random. The delay counts against FetchTimeout.
+	FetchLatency time.Duration
+	FetchJitter  time.Duration
+	// FetchFailureRate is the probability, from 0 to 1, that a fetch attempt
+	// fails with ErrChaos. Failures are retried like server errors.
+	FetchFailureRate float64
+	// EvictionRate is the probability, from 0 to 1, that a cached rulepack is
+	// evicted when it is looked up, forcing a fetch. Rulepacks preloaded with
+	// WithRulepacks are never evicted.
+	EvictionRate float64
+	// Seed seeds the random source, so a run can be reproduced.
+	Seed int64
+}
+
+// chaos draws the faults described by a ChaosConfig.
+type chaos struct {
+	cfg ChaosConfig
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+// WithChaos enables fault injection; see ChaosConfig. The Governor emits a
+// warning event on construction so the setting is not left on by accident.
+func WithChaos(cfg ChaosConfig) Option {
+	return func(g *Governor) error {
+		switch {
+		case cfg.FetchLatency < 0 || cfg.FetchJitter < 0:
+			return fmt.Errorf("chaos: latencies must not be negative")
+		case cfg.FetchFailureRate < 0 || cfg.FetchFailureRate > 1:
+			return fmt.Errorf("chaos: fetch failure rate %v is outside [0, 1]", cfg.FetchFailureRate)
+		case cfg.EvictionRate < 0 || cfg.EvictionRate > 1:
+			return fmt.Errorf("chaos: eviction rate %v is outside [0, 1]", cfg.EvictionRate)
+		}
+		g.chaos = &chaos{cfg: cfg, rng: rand.New(rand.NewSource(cfg.Seed))} // #nosec G404 -- fault injection
+		return nil
+	}
+}
+
+// chance reports whether an event of probability p happens.
+func (c *chaos) chance(p float64) bool {
+	if c == nil || p <= 0 {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.rng.Float64() < p
+}
+
+// evict reports whether a cache hit should be treated as a miss.
+func (c *chaos) evict() bool {
+	return c != nil && c.chance(c.cfg.EvictionRate)
+}
+
+// fetch delays a fetch attempt and decides whether it fails.
+func (c *chaos) fetch(ctx context.Context) error {
+	if c == nil {
+		return nil
+	}
+	delay := c.cfg.FetchLatency
+	if c.cfg.FetchJitter > 0 {
+		c.mu.Lock()
+		delay += time.Duration(c.rng.Int63n(int64(c.cfg.FetchJitter)))
+		c.mu.Unlock()
+	}
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if c.chance(c.cfg.FetchFailureRate) {
+		return ErrChaos
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	aisentinel "github.com/mfifth/aisentinel-go-sdk"
+	"gopkg.in/yaml.v3"
+)
+
+const auditUsage = `usage: aisentinel-go-sdk audit <command> [flags]
+
+commands:
+  export  write audit records as jsonl (default), json, yaml, csv or a table
+  tail    print the most recent records; -f follows new ones
+  query   print records filtered by --since, --until, --rulepack and --denied-only`
+
+// runAudit implements the audit subcommands against the configured storage
+// backend.
+func runAudit(args []string) error {
+	if len(args) == 0 {
+		return errors.New(auditUsage)
+	}
+	cmd, args := args[0], args[1:]
+
+	fs := flag.NewFlagSet("audit "+cmd, flag.ContinueOnError)
+	configFile := fs.String("config", "", configFlagUsage)
+	profile := fs.String("profile", "", "Config file profile to apply")
+	format := outputTable
+	if cmd == "export" {
+		format = outputJSONL
+	}
+	fs.StringVar(&format, "format", format, "Output format: table, json, jsonl, yaml or csv")
+	fs.StringVar(&format, "output", format, "Alias of --format")
+	since := fs.Duration("since", 0, "Only records newer than this, e.g. 1h")
+	until := fs.Duration("until", 0, "Only records older than this, e.g. 24h")
+	rulepack := fs.String("rulepack", "", "Only records for this rulepack")
+	deniedOnly := fs.Bool("denied-only", false, "Only denied decisions")
+	var follow *bool
+	var lines *int
+	var poll *time.Duration
+	limit := new(int)
+	switch cmd {
+	case "export":
+	case "query":
+		limit = fs.Int("limit", 0, "Only the most recent N records")
+	case "tail":
+		follow = fs.Bool("f", false, "Follow new records")
+		lines = fs.Int("n", 10, "Number of records to print")
+		poll = fs.Duration("interval", time.Second, "Polling interval with -f")
+	default:
+		return fmt.Errorf("unknown audit command %q\n%s", cmd, auditUsage)
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+
+	if format != auditCSV {
+		if err := validateOutput(format); err != nil {
+			return err
+		}
+	}
+	if cmd == "tail" && *follow && format == outputJSON {
+		return errors.New("-f cannot stream a JSON array; use --output jsonl")
+	}
+
+	cfg, err := loadConfig(*configFile, *profile)
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	governor, err := aisentinel.NewGovernor(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("initialise governor: %w", err)
+	}
+	defer governor.Close()
+
+	q := aisentinel.AuditFilter{RulepackID: *rulepack, DeniedOnly: *deniedOnly, Limit: *limit}
+	if *since > 0 {
+		q.Since = time.Now().Add(-*since)
+	}
+	if *until > 0 {
+		q.Until = time.Now().Add(-*until)
+	}
+	if cmd == "tail" {
+		q.Limit = *lines
+	}
+	records, err := governor.QueryAudit(ctx, q)
+	if err != nil {
+		return err
+	}
+	if err := writeAudit(os.Stdout, format, records, true); err != nil {
+		return err
+	}
+	if cmd != "tail" || !*follow {
+		return nil
+	}
+
+	q.Limit = 0
+	ticker := time.NewTicker(*poll)
+	defer ticker.Stop()
+	for {
+		if len(records) > 0 {
+			q.Since = records[len(records)-1].Time
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		next, err := governor.QueryAudit(ctx, q)
+		if err != nil {
+			return err
+		}
+		if len(next) == 0 {
+			continue
+		}
+		records = next
+		if err := writeAudit(os.Stdout, format, records, false); err != nil {
+			return err
+		}
+	}
+}
+
+// auditCSV is accepted by the audit commands in addition to outputFormats.
+const auditCSV = string(aisentinel.ExportCSV)
+
+func writeAudit(w io.Writer, format string, records []aisentinel.AuditRecord, header bool) error {
+	switch format {
+	case outputJSONL, auditCSV:
+		return aisentinel.WriteAuditRecords(w, aisentinel.ExportFormat(format), records, header)
+	case outputJSON:
+		if records == nil {
+			records = []aisentinel.AuditRecord{}
+		}
+		return writeIndentedJSON(w, records)
+	case outputYAML:
+		for _, r := range records {
+			doc := map[string]any{
+				"time":        r.Time,
+				"rulepack_id": r.RulepackID,
+				"allowed":     r.Allowed,
+				"rule_id":     r.RuleID,
+				"reason":      r.Reason,
+				"latency_ms":  r.LatencyMS,
+				"payload":     string(r.Payload),
+			}
+			if _, err := io.WriteString(w, "---\n"); err != nil {
+				return err
+			}
+			if err := yaml.NewEncoder(w).Encode(doc); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, r := range records {
+		decision := "allow"
+		if !r.Allowed {
+			decision = "deny"
+		}
+		rule := r.RuleID
+		if rule == "" {
+			rule = "-"
+		}
+		if _, err := fmt.Fprintf(w, "%s  %-5s  %-20s  %-20s  %5dms  %s\n", r.Time.Format(time.RFC3339), decision, r.RulepackID, rule, r.LatencyMS, r.Reason); err != nil {
+			return err
+		}
+	}
+	return nil
+}
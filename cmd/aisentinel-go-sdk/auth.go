@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mfifth/aisentinel-go-sdk/secrets"
+)
+
+const authUsage = `usage: aisentinel-go-sdk auth <command> [flags]
+
+commands:
+  set-key     store the API key in the OS keyring (read from stdin or --key-file)
+  show        show where the API key comes from, masked
+  delete-key  remove the API key from the OS keyring`
+
+// keyringService is the keyring service name; the account is the profile.
+const keyringService = "aisentinel"
+
+func keyringFor(profile string) secrets.Keyring {
+	if profile == "" {
+		profile = "default"
+	}
+	return secrets.Keyring{Service: keyringService, Account: profile}
+}
+
+// runAuth implements the auth subcommands.
+func runAuth(args []string) error {
+	if len(args) == 0 {
+		return errors.New(authUsage)
+	}
+	cmd, args := args[0], args[1:]
+
+	fs := flag.NewFlagSet("auth "+cmd, flag.ContinueOnError)
+	profile := fs.String("profile", "", "Profile the key belongs to (default \"default\")")
+	var keyFile, configFile *string
+	switch cmd {
+	case "set-key":
+		keyFile = fs.String("key-file", "-", "Read the key from this file instead of stdin")
+	case "show":
+		configFile = fs.String("config", "", configFlagUsage)
+	case "delete-key":
+	default:
+		return fmt.Errorf("unknown auth command %q\n%s", cmd, authUsage)
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	ctx := context.Background()
+	ring := keyringFor(*profile)
+
+	switch cmd {
+	case "set-key":
+		key, err := readKey(*keyFile)
+		if err != nil {
+			return err
+		}
+		if err := ring.Set(ctx, key); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "stored API key for profile %q in the OS keyring\n", ring.Account)
+		return nil
+
+	case "delete-key":
+		if err := ring.Delete(ctx); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "removed API key for profile %q from the OS keyring\n", ring.Account)
+		return nil
+
+	default: // show
+		cfg, source, err := resolveConfig(*configFile, *profile)
+		if err != nil {
+			return err
+		}
+		if cfg.APIKey == "" && cfg.APIKeyFile != "" {
+			key, err := (secrets.File{Path: cfg.APIKeyFile}).FetchSecret(ctx)
+			if err != nil {
+				return err
+			}
+			cfg.APIKey = key
+		}
+		if cfg.APIKey == "" {
+			return errors.New("no API key configured")
+		}
+		fmt.Printf("source: %s\nkey:    %s\n", source, maskKey(cfg.APIKey))
+		return nil
+	}
+}
+
+// readKey reads a single line key from path, or from stdin for "-",
+// prompting when stdin is a terminal.
+func readKey(path string) (string, error) {
+	var in io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path) // #nosec G304 -- operator supplied key file
+		if err != nil {
+			return "", err
+		}
+		defer f.Close()
+		in = f
+	} else if info, err := os.Stdin.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+		fmt.Fprint(os.Stderr, "API key: ")
+	}
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	key := strings.TrimSpace(line)
+	if key == "" {
+		return "", errors.New("empty API key")
+	}
+	return key, nil
+}
+
+// maskKey keeps the first and last four characters of long keys.
+func maskKey(key string) string {
+	if len(key) <= 12 {
+		return strings.Repeat("*", len(key))
+	}
+	return key[:4] + strings.Repeat("*", len(key)-8) + key[len(key)-4:]
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	aisentinel "github.com/mfifth/aisentinel-go-sdk"
+	"github.com/mfifth/aisentinel-go-sdk/internal/latency"
+	"gopkg.in/yaml.v3"
+)
+
+// benchReport summarises a bench run. Latencies are in milliseconds.
+type benchReport struct {
+	RulepackID    string             `json:"rulepack_id" yaml:"rulepack_id"`
+	Concurrency   int                `json:"concurrency" yaml:"concurrency"`
+	Duration      float64            `json:"duration_seconds" yaml:"duration_seconds"`
+	Requests      int                `json:"requests" yaml:"requests"`
+	Allowed       int                `json:"allowed" yaml:"allowed"`
+	Denied        int                `json:"denied" yaml:"denied"`
+	Errors        int                `json:"errors" yaml:"errors"`
+	Throughput    float64            `json:"throughput_rps" yaml:"throughput_rps"`
+	LatencyMS     map[string]float64 `json:"latency_ms" yaml:"latency_ms"`
+	FirstErrorMsg string             `json:"first_error,omitempty" yaml:"first_error,omitempty"`
+}
+
+// runBench drives the Governor with a fixed payload from concurrent workers
+// and reports throughput and latency percentiles.
+func runBench(args []string) error {
+	fs := flag.NewFlagSet("bench", flag.ContinueOnError)
+	configFile := fs.String("config", "", configFlagUsage)
+	profile := fs.String("profile", "", "Config file profile to apply")
+	rulepack := fs.String("rulepack", "", "Rulepack identifier to evaluate (default: the config default_rulepack_id, else \"default\")")
+	payloadInline := fs.String("payload", "", "Inline JSON payload to evaluate")
+	payloadFile := fs.String("payload-file", "", "Path to a file containing JSON payload")
+	concurrency := fs.Int("concurrency", 32, "Concurrent workers")
+	duration := fs.Duration("duration", 30*time.Second, "How long to run")
+	maxRequests := fs.Int("requests", 0, "Stop after this many evaluations (0 for no limit)")
+	offline := fs.Bool("offline", false, "Enable offline evaluation mode")
+	timeout := fs.Duration("timeout", 15*time.Second, "Timeout for each evaluation")
+	output := fs.String("output", outputTable, "Output format: table, json or yaml")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	switch *output {
+	case outputTable, outputJSON, outputYAML:
+	default:
+		return fmt.Errorf("unknown output format %q (want table, json or yaml)", *output)
+	}
+	if *concurrency < 1 {
+		return errors.New("--concurrency must be at least 1")
+	}
+	if *duration <= 0 && *maxRequests <= 0 {
+		return errors.New("one of --duration or --requests must be positive")
+	}
+	if *payloadInline != "" && *payloadFile != "" {
+		return errors.New("only one of --payload or --payload-file may be provided")
+	}
+	payload, err := resolvePayload(*payloadInline, *payloadFile, fs.Args(), defaultPayloadLimits)
+	if err != nil {
+		return fmt.Errorf("resolve payload: %w", err)
+	}
+
+	cfg, err := loadConfig(*configFile, *profile)
+	if err != nil {
+		return err
+	}
+	if *offline {
+		cfg.OfflineMode = true
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	governor, err := aisentinel.NewGovernor(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("initialise governor: %w", err)
+	}
+	defer func() {
+		if cerr := governor.Close(); cerr != nil {
+			log.Printf("close governor: %v", cerr)
+		}
+	}()
+	if *rulepack == "" {
+		if *rulepack, err = resolveRulepack(ctx, governor); err != nil {
+			return err
+		}
+	}
+
+	// Warm the rulepack cache so the first fetch is not measured.
+	req := aisentinel.DecisionRequest{RulepackID: *rulepack, Payload: payload} // nolint:exhaustruct
+	warmCtx, cancel := context.WithTimeout(ctx, *timeout)
+	_, err = governor.Evaluate(warmCtx, req)
+	cancel()
+	if err != nil {
+		return fmt.Errorf("warm up: %w", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "benchmarking %s with %d workers...\n", *rulepack, *concurrency)
+	report := bench(ctx, governor, req, *concurrency, *duration, *maxRequests, *timeout)
+	return writeBenchReport(os.Stdout, *output, report)
+}
+
+// benchWorker holds one worker's results so the hot loop is lock free.
+type benchWorker struct {
+	latencies       []time.Duration
+	allowed, denied int
+	errors          int
+	firstErr        error
+}
+
+func bench(ctx context.Context, gov *aisentinel.Governor, req aisentinel.DecisionRequest, concurrency int, duration time.Duration, maxRequests int, timeout time.Duration) benchReport {
+	if duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, duration)
+		defer cancel()
+	}
+	var remaining atomic.Int64
+	remaining.Store(int64(maxRequests))
+	// take reserves one evaluation against --requests.
+	take := func() bool {
+		return maxRequests <= 0 || remaining.Add(-1) >= 0
+	}
+
+	var wg sync.WaitGroup
+	workers := make([]benchWorker, concurrency)
+	start := time.Now()
+	for i := range workers {
+		wg.Add(1)
+		go func(w *benchWorker) {
+			defer wg.Done()
+			for ctx.Err() == nil && take() {
+				evalCtx, cancel := context.WithTimeout(ctx, timeout)
+				began := time.Now()
+				result, err := gov.Evaluate(evalCtx, req)
+				elapsed := time.Since(began)
+				cancel()
+				switch {
+				case err != nil && ctx.Err() != nil:
+					// Cut off by the end of the run; not a failure.
+					return
+				case err != nil:
+					w.errors++
+					if w.firstErr == nil {
+						w.firstErr = err
+					}
+					continue
+				case result.Allowed:
+					w.allowed++
+				default:
+					w.denied++
+				}
+				w.latencies = append(w.latencies, elapsed)
+			}
+		}(&workers[i])
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	report := benchReport{
+		RulepackID:  req.RulepackID,
+		Concurrency: concurrency,
+		Duration:    elapsed.Seconds(),
+	}
+	var latencies []time.Duration
+	for _, w := range workers {
+		latencies = append(latencies, w.latencies...)
+		report.Allowed += w.allowed
+		report.Denied += w.denied
+		report.Errors += w.errors
+		if w.firstErr != nil && report.FirstErrorMsg == "" {
+			report.FirstErrorMsg = w.firstErr.Error()
+		}
+	}
+	report.Requests = report.Allowed + report.Denied + report.Errors
+	if elapsed > 0 {
+		report.Throughput = float64(report.Requests) / elapsed.Seconds()
+	}
+	report.LatencyMS = latency.Summarize(latencies, latency.Milliseconds)
+	return report
+}
+
+func writeBenchReport(w io.Writer, format string, r benchReport) error {
+	switch format {
+	case outputJSON:
+		return writeIndentedJSON(w, r)
+	case outputYAML:
+		return yaml.NewEncoder(w).Encode(r)
+	}
+	fmt.Fprintf(w, "rulepack:     %s\n", r.RulepackID)
+	fmt.Fprintf(w, "concurrency:  %d\n", r.Concurrency)
+	fmt.Fprintf(w, "duration:     %.2fs\n", r.Duration)
+	fmt.Fprintf(w, "requests:     %d (allowed %d, denied %d, errors %d)\n", r.Requests, r.Allowed, r.Denied, r.Errors)
+	fmt.Fprintf(w, "throughput:   %.1f req/s\n", r.Throughput)
+	if r.FirstErrorMsg != "" {
+		fmt.Fprintf(w, "first error:  %s\n", r.FirstErrorMsg)
+	}
+	if len(r.LatencyMS) == 0 {
+		return nil
+	}
+	fmt.Fprintln(w, "latency:")
+	for _, name := range latency.Names {
+		if _, err := fmt.Fprintf(w, "  %-6s %10.3fms\n", name, r.LatencyMS[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
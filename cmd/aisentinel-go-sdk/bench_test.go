@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	aisentinel "github.com/mfifth/aisentinel-go-sdk"
+)
+
+func TestBench(t *testing.T) {
+	pack := &aisentinel.Rulepack{ID: "dev", Rules: []aisentinel.RuleDefinition{{ID: "prompt", Pattern: "ok", Allow: true}}}
+	gov, err := aisentinel.NewGovernor(context.Background(), aisentinel.Config{APIKey: "test", OfflineMode: true}, aisentinel.WithRulepacks(pack))
+	if err != nil {
+		t.Fatalf("expected governor: %v", err)
+	}
+	t.Cleanup(func() { _ = gov.Close() })
+
+	req := aisentinel.DecisionRequest{RulepackID: "dev", Payload: json.RawMessage(`{"prompt":"ok"}`)}
+	report := bench(context.Background(), gov, req, 4, 0, 50, time.Second)
+	if report.Requests != 50 || report.Allowed != 50 || report.Errors != 0 || report.Concurrency != 4 {
+		t.Fatalf("expected 50 allowed evaluations, got %+v", report)
+	}
+	if report.LatencyMS["min"] > report.LatencyMS["p50"] || report.LatencyMS["p50"] > report.LatencyMS["max"] {
+		t.Fatalf("expected ordered latencies, got %v", report.LatencyMS)
+	}
+
+	tests := []struct {
+		format string
+		want   []string
+	}{
+		{outputTable, []string{"rulepack:     dev\n", "requests:     50 (allowed 50, denied 0, errors 0)\n", "  p999 "}},
+		{outputJSON, []string{`"rulepack_id": "dev"`, `"requests": 50`, `"p99":`}},
+		{outputYAML, []string{"rulepack_id: dev\n", "requests: 50\n", "p99:"}},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		if err := writeBenchReport(&out, tt.format, report); err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(out.String(), want) {
+				t.Errorf("%s: expected %q in\n%s", tt.format, want, out.String())
+			}
+		}
+	}
+
+	if err := runBench([]string{"--output", "csv"}); err == nil {
+		t.Fatal("expected an unknown output format to be rejected")
+	}
+	if err := runBench([]string{"--duration", "0"}); err == nil {
+		t.Fatal("expected a run without a duration or request limit to be rejected")
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	aisentinel "github.com/mfifth/aisentinel-go-sdk"
+)
+
+// completeTimeout bounds the control plane lookup behind a tab press.
+const completeTimeout = 3 * time.Second
+
+// completion and __complete read the subcommands map, so they are registered
+// here rather than in its initializer to avoid an initialization cycle.
+func init() {
+	subcommands["completion"] = runCompletion
+	subcommands["__complete"] = runComplete
+}
+
+const bashCompletion = `# aisentinel-go-sdk bash completion
+_aisentinel_go_sdk() {
+    local cur prev words
+    cur="${COMP_WORDS[COMP_CWORD]}"
+    prev="${COMP_WORDS[COMP_CWORD-1]}"
+    if [[ $COMP_CWORD -eq 1 ]]; then
+        COMPREPLY=($(compgen -W "%s" -- "$cur"))
+        return
+    fi
+    case "$prev" in
+        --rulepack|-rulepack)
+            words=$(aisentinel-go-sdk __complete rulepacks "${COMP_WORDS[@]:1}" 2>/dev/null)
+            COMPREPLY=($(compgen -W "$words" -- "$cur"))
+            return
+            ;;
+    esac
+    COMPREPLY=($(compgen -f -- "$cur"))
+}
+complete -o default -F _aisentinel_go_sdk aisentinel-go-sdk
+`
+
+const fishCompletion = `# aisentinel-go-sdk fish completion
+complete -c aisentinel-go-sdk -n __fish_use_subcommand -f -a "%s"
+complete -c aisentinel-go-sdk -l rulepack -x -a "(aisentinel-go-sdk __complete rulepacks (commandline -opc)[2..-1] 2>/dev/null)"
+`
+
+// runCompletion prints a shell completion script. Subcommands complete from
+// the built-in list and --rulepack values from the control plane.
+func runCompletion(args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: aisentinel-go-sdk completion bash|zsh|fish")
+	}
+	names := commandNames()
+	switch args[0] {
+	case "bash":
+		fmt.Printf(bashCompletion, strings.Join(names, " "))
+	case "zsh":
+		fmt.Println("autoload -U +X bashcompinit && bashcompinit")
+		fmt.Printf(bashCompletion, strings.Join(names, " "))
+	case "fish":
+		fmt.Printf(fishCompletion, strings.Join(names, " "))
+	default:
+		return fmt.Errorf("unsupported shell %q (want bash, zsh or fish)", args[0])
+	}
+	return nil
+}
+
+// commandNames lists the public subcommands in alphabetical order.
+func commandNames() []string {
+	names := make([]string, 0, len(subcommands))
+	for name := range subcommands {
+		if !strings.HasPrefix(name, "__") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// runComplete serves completion scripts. `__complete rulepacks [words...]`
+// prints one rulepack ID per line, honouring any --config and --profile among
+// the words of the command line being completed. Failures print nothing.
+func runComplete(args []string) error {
+	if len(args) == 0 || args[0] != "rulepacks" {
+		return errors.New("usage: aisentinel-go-sdk __complete rulepacks [words...]")
+	}
+	configFile, profile := completionFlag(args[1:], "config"), completionFlag(args[1:], "profile")
+	cfg, err := loadConfig(configFile, profile)
+	if err != nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), completeTimeout)
+	defer cancel()
+	cfg.FetchRetries = aisentinel.NoFetchRetries
+	governor, err := aisentinel.NewGovernor(ctx, cfg)
+	if err != nil {
+		return nil
+	}
+	defer governor.Close()
+	packs, err := governor.ListRulepacks(ctx)
+	if err != nil {
+		return nil
+	}
+	for _, p := range packs {
+		fmt.Fprintln(os.Stdout, p.ID)
+	}
+	return nil
+}
+
+// completionFlag returns the value of --name or -name in words, in either the
+// "--name value" or "--name=value" form.
+func completionFlag(words []string, name string) string {
+	for i, w := range words {
+		trimmed := strings.TrimLeft(w, "-")
+		if trimmed == w {
+			continue
+		}
+		if v, ok := strings.CutPrefix(trimmed, name+"="); ok {
+			return v
+		}
+		if trimmed == name && i+1 < len(words) {
+			return words[i+1]
+		}
+	}
+	return ""
+}
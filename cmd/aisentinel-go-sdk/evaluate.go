@@ -0,0 +1,364 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"runtime"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	aisentinel "github.com/mfifth/aisentinel-go-sdk"
+)
+
+// runEvaluate evaluates a single payload, or with --stdin/--input every
+// newline-delimited JSON payload of a stream.
+// With --fail-on-deny the exit status is 0 when every decision allows, 1 when
+// any denies and 2 on errors.
+func runEvaluate(args []string) (err error) {
+	fs := flag.NewFlagSet("evaluate", flag.ContinueOnError)
+	apiKey := fs.String("api-key", os.Getenv("AISENTINEL_API_KEY"), "AISentinel API key (or set AISENTINEL_API_KEY)")
+	apiBaseURL := fs.String("api-base-url", "", "Override the AISentinel API base URL")
+	configFile := fs.String("config", "", configFlagUsage)
+	profile := fs.String("profile", "", "Config file profile to apply")
+	rulepack := fs.String("rulepack", "", "Rulepack identifier to evaluate (default: the config default_rulepack_id, else \"default\")")
+	payloadInline := fs.String("payload", "", "Inline JSON payload to evaluate")
+	var payloadFiles stringsFlag
+	fs.Var(&payloadFiles, "payload-file", "Path to a file containing JSON payload, - for stdin; repeat to evaluate several")
+	maxPayloadBytes := fs.Int64("max-payload-bytes", maxPayloadFileBytes, "Largest payload file accepted, 0 for no limit")
+	allowAnyPath := fs.Bool("allow-any-path", false, "Read payload files outside the working directory")
+	template := fs.String("template", "", "Scaffold the payload from a template ("+strings.Join(templateNames(), " or ")+"); arguments become the prompt")
+	rulepackFile := fs.String("rulepack-file", "", "Evaluate against a local rulepack file instead of the control plane")
+	watch := fs.Bool("watch", false, "Re-evaluate whenever --payload-file or --rulepack-file changes")
+	fromStdin := fs.Bool("stdin", false, "Evaluate newline-delimited JSON payloads read from stdin")
+	inputFile := fs.String("input", "", "Evaluate newline-delimited JSON payloads read from this file")
+	workers := fs.Int("workers", runtime.NumCPU(), "Concurrent evaluations for --stdin and --input")
+	offline := fs.Bool("offline", false, "Enable offline evaluation mode")
+	timeout := fs.Duration("timeout", 15*time.Second, "Timeout for each evaluation")
+	output := fs.String("output", "", "Output format: json, jsonl, yaml or table (default json, jsonl for batches)")
+	failOnDeny := fs.Bool("fail-on-deny", false, "Exit 1 when a decision is deny and 2 on errors")
+	showVersion := fs.Bool("version", false, "Print version information and exit")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+
+	defer func() {
+		var exit *exitError
+		if *failOnDeny && err != nil && !errors.As(err, &exit) {
+			err = &exitError{code: 2, err: err}
+		}
+	}()
+
+	if *showVersion {
+		fmt.Printf("aisentinel-go-sdk %s (commit %s, built %s)\n", buildVersion, buildCommit, buildDate)
+		return nil
+	}
+
+	batch := *fromStdin || *inputFile != ""
+	multi := len(payloadFiles) > 1
+	payloadFile := new(string)
+	if len(payloadFiles) == 1 {
+		*payloadFile = payloadFiles[0]
+	}
+	limits := payloadLimits{maxBytes: *maxPayloadBytes, anyPath: *allowAnyPath}
+	if *fromStdin && *inputFile != "" {
+		return errors.New("only one of --stdin or --input may be provided")
+	}
+	if batch && (*payloadInline != "" || len(payloadFiles) > 0) {
+		return errors.New("--payload and --payload-file cannot be combined with --stdin or --input")
+	}
+	if *payloadInline != "" && len(payloadFiles) > 0 {
+		return errors.New("only one of --payload or --payload-file may be provided")
+	}
+	if batch && *template != "" {
+		return errors.New("--template cannot be combined with --stdin or --input")
+	}
+	if err := applyTemplate(*template, payloadInline, payloadFiles.String(), fs.Args()); err != nil {
+		return err
+	}
+	if *watch && batch {
+		return errors.New("--watch cannot be combined with --stdin or --input")
+	}
+	if *watch && (multi || *payloadFile == "-") {
+		return errors.New("--watch needs a single --payload-file that is not stdin")
+	}
+	stdinFiles := 0
+	for _, path := range payloadFiles {
+		if path == "-" {
+			stdinFiles++
+		}
+	}
+	if stdinFiles > 1 {
+		return errors.New("--payload-file - can only be given once")
+	}
+	if *watch && *payloadFile == "" && *rulepackFile == "" {
+		return errors.New("--watch needs --payload-file or --rulepack-file")
+	}
+	if *output == "" {
+		*output = outputJSON
+		if batch {
+			*output = outputJSONL
+		}
+	}
+	if err := validateOutput(*output); err != nil {
+		return err
+	}
+	out := &decisionWriter{w: os.Stdout, format: *output, batch: batch || multi}
+
+	ctx := context.Background()
+	var decider decider
+	var local *localRulepack
+	if *rulepackFile != "" {
+		if local, err = loadLocalRulepack(*rulepackFile); err != nil {
+			return err
+		}
+		if *rulepack != "" && *rulepack != local.pack.ID {
+			return fmt.Errorf("%s defines rulepack %q, not %q", *rulepackFile, local.pack.ID, *rulepack)
+		}
+		*rulepack, decider = local.pack.ID, local
+	} else {
+		governor, err := newEvaluateGovernor(ctx, *configFile, *profile, *apiKey, *apiBaseURL, *offline, *timeout)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if cerr := governor.Close(); cerr != nil {
+				log.Printf("close governor: %v", cerr)
+			}
+		}()
+		if *rulepack == "" {
+			if *rulepack, err = resolveRulepack(ctx, governor); err != nil {
+				return err
+			}
+		}
+		decider = governor
+	}
+
+	if batch {
+		in := io.Reader(os.Stdin)
+		if *inputFile != "" {
+			f, err := os.Open(*inputFile) // #nosec G304 -- operator supplied dataset
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			in = f
+		}
+		if err := evaluateStream(ctx, decider, *rulepack, *timeout, *workers, in, out); err != nil {
+			return err
+		}
+		return decisionStatus(out, *failOnDeny)
+	}
+
+	if *watch {
+		ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		return watchEvaluate(ctx, decider, local, *rulepack, *payloadInline, *payloadFile, fs.Args(), limits, *timeout, *output)
+	}
+
+	if multi {
+		if err := evaluateFiles(ctx, decider, *rulepack, payloadFiles, limits, *timeout, out); err != nil {
+			return err
+		}
+		return decisionStatus(out, *failOnDeny)
+	}
+
+	payload, err := resolvePayload(*payloadInline, *payloadFile, fs.Args(), limits)
+	if err != nil {
+		return fmt.Errorf("resolve payload: %w", err)
+	}
+	evalCtx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+	result, err := decider.Evaluate(evalCtx, aisentinel.DecisionRequest{ // nolint:exhaustruct
+		RulepackID: *rulepack,
+		Payload:    payload,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := out.write(decisionOutput{Allowed: result.Allowed, RuleID: result.RuleID, Reason: result.Reason, LatencyMS: result.Latency.Milliseconds()}); err != nil {
+		return err
+	}
+	if err := out.flush(); err != nil {
+		return err
+	}
+	return decisionStatus(out, *failOnDeny)
+}
+
+// evaluateFiles evaluates each payload file in turn, writing one decision per
+// file. Unreadable files are reported as errors rather than stopping the run.
+func evaluateFiles(ctx context.Context, d decider, rulepack string, paths []string, limits payloadLimits, timeout time.Duration, out *decisionWriter) error {
+	for _, path := range paths {
+		out.sourceWidth = max(out.sourceWidth, len(path))
+	}
+	for _, path := range paths {
+		r := decisionOutput{Source: path}
+		if path == "-" {
+			r.Source = "stdin"
+		}
+		payload, err := resolvePayload("", path, nil, limits)
+		if err == nil {
+			evalCtx, cancel := context.WithTimeout(ctx, timeout)
+			var result aisentinel.DecisionResult
+			result, err = d.Evaluate(evalCtx, aisentinel.DecisionRequest{RulepackID: rulepack, Payload: payload}) // nolint:exhaustruct
+			cancel()
+			r.Allowed, r.RuleID, r.Reason, r.LatencyMS = result.Allowed, result.RuleID, result.Reason, result.Latency.Milliseconds()
+		}
+		if err != nil {
+			r.Error = err.Error()
+		}
+		if err := out.write(r); err != nil {
+			return err
+		}
+	}
+	return out.flush()
+}
+
+// newEvaluateGovernor builds the Governor for evaluate from the config file
+// and the flags that override it.
+func newEvaluateGovernor(ctx context.Context, configFile, profile, apiKey, apiBaseURL string, offline bool, timeout time.Duration) (*aisentinel.Governor, error) {
+	cfg, err := loadConfig(configFile, profile)
+	if err != nil {
+		return nil, err
+	}
+	if apiKey != "" {
+		cfg.APIKey = apiKey
+	}
+	if offline {
+		cfg.OfflineMode = true
+	}
+	if apiBaseURL != "" {
+		cfg.APIBaseURL = apiBaseURL
+	}
+	if timeout > 0 {
+		cfg.HTTPTimeout = timeout
+	}
+	if cfg.APIKey == "" && cfg.APIKeyFile == "" {
+		return nil, errors.New("API key is required (set --api-key or AISENTINEL_API_KEY)")
+	}
+	governor, err := aisentinel.NewGovernor(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("initialise governor: %w", err)
+	}
+	return governor, nil
+}
+
+// decider evaluates requests; it is implemented by the Governor and by
+// localRulepack.
+type decider interface {
+	Evaluate(ctx context.Context, req aisentinel.DecisionRequest) (aisentinel.DecisionResult, error)
+}
+
+// decisionStatus maps the written decisions to the --fail-on-deny exit code.
+func decisionStatus(out *decisionWriter, failOnDeny bool) error {
+	switch {
+	case !failOnDeny:
+		return nil
+	case out.failed > 0:
+		return &exitError{code: 2, err: fmt.Errorf("%d evaluations failed", out.failed)}
+	case out.denied > 0:
+		return &exitError{code: 1}
+	default:
+		return nil
+	}
+}
+
+// evaluateStream evaluates every non-empty line of in with the given number of
+// workers and writes one result per line to out, in input order.
+// Invalid lines produce an error result rather than aborting the run.
+func evaluateStream(ctx context.Context, decider decider, rulepack string, timeout time.Duration, workers int, in io.Reader, out *decisionWriter) error {
+	if workers < 1 {
+		workers = 1
+	}
+	// seq numbers the non-empty lines so results can be re-ordered.
+	type job struct {
+		seq, line int
+		payload   []byte
+	}
+	type sequenced struct {
+		seq int
+		decisionOutput
+	}
+	jobs := make(chan job, workers)
+	results := make(chan sequenced, workers)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				res := sequenced{seq: j.seq, decisionOutput: decisionOutput{Line: j.line}}
+				if !json.Valid(j.payload) {
+					res.Error = "payload must be valid JSON"
+					results <- res
+					continue
+				}
+				evalCtx, cancel := context.WithTimeout(ctx, timeout)
+				result, err := decider.Evaluate(evalCtx, aisentinel.DecisionRequest{RulepackID: rulepack, Payload: j.payload}) // nolint:exhaustruct
+				cancel()
+				if err != nil {
+					res.Error = err.Error()
+				} else {
+					res.Allowed, res.RuleID, res.Reason, res.LatencyMS = result.Allowed, result.RuleID, result.Reason, result.Latency.Milliseconds()
+				}
+				results <- res
+			}
+		}()
+	}
+
+	scanErr := make(chan error, 1)
+	go func() {
+		defer close(jobs)
+		scanner := bufio.NewScanner(in)
+		scanner.Buffer(make([]byte, 64*1024), int(maxPayloadFileBytes))
+		line, seq := 0, 0
+		for scanner.Scan() {
+			line++
+			if len(scanner.Bytes()) == 0 {
+				continue
+			}
+			jobs <- job{seq: seq, line: line, payload: append([]byte(nil), scanner.Bytes()...)}
+			seq++
+		}
+		scanErr <- scanner.Err()
+	}()
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// Workers finish out of order; buffer results until the next one in
+	// sequence is available so output lines up with the input.
+	pending := map[int]decisionOutput{}
+	next := 0
+	var writeErr error
+	for res := range results {
+		pending[res.seq] = res.decisionOutput
+		for r, ok := pending[next]; ok && writeErr == nil; r, ok = pending[next] {
+			delete(pending, next)
+			next++
+			writeErr = out.write(r)
+		}
+	}
+	if writeErr != nil {
+		return writeErr
+	}
+	if err := <-scanErr; err != nil {
+		return err
+	}
+	return out.flush()
+}
@@ -0,0 +1,486 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	aisentinel "github.com/mfifth/aisentinel-go-sdk"
+)
+
+func TestEvaluateStreamPreservesOrder(t *testing.T) {
+	pack := aisentinel.Rulepack{ID: "default", Rules: []aisentinel.RuleDefinition{{ID: "prompt", Pattern: "ok", Allow: true}}}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(pack)
+	}))
+	t.Cleanup(srv.Close)
+
+	ctx := context.Background()
+	gov, err := aisentinel.NewGovernor(ctx, aisentinel.Config{APIKey: "test", APIBaseURL: srv.URL})
+	if err != nil {
+		t.Fatalf("expected governor: %v", err)
+	}
+	t.Cleanup(func() { _ = gov.Close() })
+
+	var in strings.Builder
+	for i := 0; i < 50; i++ {
+		if i%2 == 0 {
+			in.WriteString(`{"prompt":"ok"}` + "\n\n")
+		} else {
+			in.WriteString(`{"prompt":"no"}` + "\n")
+		}
+	}
+	in.WriteString("not json\n")
+
+	var out bytes.Buffer
+	if err := evaluateStream(ctx, gov, "default", time.Second, 8, strings.NewReader(in.String()), &decisionWriter{w: &out, format: outputJSONL, batch: true}); err != nil {
+		t.Fatalf("evaluate stream: %v", err)
+	}
+
+	dec := json.NewDecoder(&out)
+	var results []decisionOutput
+	for dec.More() {
+		var r decisionOutput
+		if err := dec.Decode(&r); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		results = append(results, r)
+	}
+	if len(results) != 51 {
+		t.Fatalf("expected 51 results, got %d", len(results))
+	}
+	for i, r := range results[:50] {
+		if r.Allowed != (i%2 == 0) || r.Error != "" {
+			t.Fatalf("result %d out of order or failed: %+v", i, r)
+		}
+	}
+	if last := results[50]; last.Error == "" || last.Line != 76 {
+		t.Fatalf("expected error for line 76, got %+v", last)
+	}
+}
+
+func TestLocalRulepackReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pack.json")
+	write := func(allow bool) {
+		pack := aisentinel.Rulepack{ID: "dev", Rules: []aisentinel.RuleDefinition{{ID: "prompt", Pattern: "ok", Allow: allow}}}
+		data, _ := json.Marshal(pack)
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(true)
+	local, err := loadLocalRulepack(path)
+	if err != nil {
+		t.Fatalf("expected rulepack: %v", err)
+	}
+
+	req := aisentinel.DecisionRequest{Payload: json.RawMessage(`{"prompt":"ok"}`)}
+	ctx := context.Background()
+	if res, err := local.Evaluate(ctx, req); err != nil || !res.Allowed || res.RuleID != "prompt" {
+		t.Fatalf("expected allow by prompt, got %+v (%v)", res, err)
+	}
+
+	write(false)
+	if err := local.reload(); err != nil {
+		t.Fatalf("expected reload: %v", err)
+	}
+	if res, err := local.Evaluate(ctx, req); err != nil || res.Allowed {
+		t.Fatalf("expected deny after reload, got %+v (%v)", res, err)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"id":"dev","rules":[{"ID":"prompt","Pattern":"("}]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := local.reload(); err == nil {
+		t.Fatal("expected invalid pattern to fail reload")
+	}
+	if res, err := local.Evaluate(ctx, req); err != nil || res.Allowed {
+		t.Fatalf("expected previous rulepack to stay in use, got %+v (%v)", res, err)
+	}
+}
+
+func TestREPLSession(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pack.json")
+	pack := aisentinel.Rulepack{ID: "dev", Rules: []aisentinel.RuleDefinition{{ID: "prompt", Pattern: "secret", Description: "no secrets"}, {ID: "title", Pattern: "^hi", Allow: true}}}
+	data, _ := json.Marshal(pack)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	local, err := loadLocalRulepack(path)
+	if err != nil {
+		t.Fatalf("expected rulepack: %v", err)
+	}
+
+	in := "a secret\n{\"title\":\n\"hi there\"}\n:explain\n:rulepack other\n:quit\nignored\n"
+	var out bytes.Buffer
+	r := &repl{in: strings.NewReader(in), out: &out, decider: local, explainer: local, local: local, rulepack: "dev", field: "prompt", timeout: time.Second}
+	if err := r.run(context.Background()); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if !strings.HasPrefix(lines[0], "DENY   rule=prompt  no secrets") {
+		t.Fatalf("expected plain text to be evaluated as the prompt, got %q", lines[0])
+	}
+	if !strings.HasPrefix(lines[1], "ALLOW  rule=title") {
+		t.Fatalf("expected multi-line JSON to be evaluated, got %q", lines[1])
+	}
+	if !strings.HasPrefix(lines[2], "dev ALLOW (decided by title)") {
+		t.Fatalf("expected :explain of the last payload, got %q", lines[2])
+	}
+	if last := lines[len(lines)-1]; last != "error: the rulepack is fixed by --rulepack-file" {
+		t.Fatalf("expected :rulepack to be refused, got %q", last)
+	}
+}
+
+func TestDeviceLoginFlow(t *testing.T) {
+	polls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("parse form: %v", err)
+		}
+		switch r.URL.Path {
+		case "/oauth/device/code":
+			if r.Form.Get("client_id") != defaultClientID {
+				t.Errorf("unexpected client_id %q", r.Form.Get("client_id"))
+			}
+			_, _ = w.Write([]byte(`{"device_code":"dev-123","user_code":"ABCD-EFGH","verification_uri":"https://example.test/device","expires_in":60,"interval":1}`))
+		case "/oauth/token":
+			if r.Form.Get("grant_type") != deviceGrantType || r.Form.Get("device_code") != "dev-123" {
+				t.Errorf("unexpected token request %v", r.Form)
+			}
+			polls++
+			if polls == 1 {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"error":"authorization_pending"}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"access_token":"tok-1","refresh_token":"ref-1","expires_in":3600}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	auth, err := requestDeviceCode(ctx, srv.URL+"/oauth/device/code", defaultClientID, "")
+	if err != nil {
+		t.Fatalf("device code: %v", err)
+	}
+	if auth.UserCode != "ABCD-EFGH" {
+		t.Fatalf("unexpected user code %q", auth.UserCode)
+	}
+	auth.Interval = 0
+	token, err := pollDeviceToken(ctx, srv.URL+"/oauth/token", defaultClientID, auth)
+	if err != nil {
+		t.Fatalf("poll: %v", err)
+	}
+	if polls != 2 || token.AccessToken != "tok-1" || token.RefreshToken != "ref-1" || !token.valid() {
+		t.Fatalf("unexpected token %+v after %d polls", token, polls)
+	}
+	if token.TokenURL != srv.URL+"/oauth/token" || token.ClientID != defaultClientID {
+		t.Fatalf("expected refresh details to be stored, got %+v", token)
+	}
+}
+
+func TestPickRulepack(t *testing.T) {
+	packs := []aisentinel.RulepackSummary{{ID: "billing"}, {ID: "support-eu"}, {ID: "support-us"}, {ID: "sales"}}
+	if got := fuzzyFilter("sus", packs); len(got) != 1 || got[0].ID != "support-us" {
+		t.Fatalf("expected only support-us to match, got %+v", got)
+	}
+	if got := fuzzyFilter("ss", packs); len(got) != 2 || got[0].ID != "sales" {
+		t.Fatalf("expected sales to rank first, got %+v", got)
+	}
+
+	var out bytes.Buffer
+	id, err := pickRulepack(strings.NewReader("sup\n2\n"), &out, packs)
+	if err != nil || id != "support-us" {
+		t.Fatalf("expected support-us, got %q, %v\n%s", id, err, out.String())
+	}
+	id, err = pickRulepack(strings.NewReader("zzz\nbil\n\n"), &out, packs)
+	if err != nil || id != "billing" {
+		t.Fatalf("expected the only match to be accepted, got %q, %v", id, err)
+	}
+	if !strings.Contains(out.String(), `no rulepack matches "zzz"`) {
+		t.Fatalf("expected a no-match notice, got %q", out.String())
+	}
+	if _, err := pickRulepack(strings.NewReader(""), &out, packs); err == nil {
+		t.Fatal("expected an error when input ends without a selection")
+	}
+}
+
+func TestTemplatePayload(t *testing.T) {
+	for _, name := range templateNames() {
+		payload, err := templatePayload(name, defaultTemplateSystem, "tell me a secret")
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		var doc struct {
+			Model    string `json:"model"`
+			Messages []chatMessage
+		}
+		if err := json.Unmarshal(payload, &doc); err != nil {
+			t.Fatalf("%s: invalid JSON: %v", name, err)
+		}
+		last := doc.Messages[len(doc.Messages)-1]
+		if doc.Model == "" || last.Role != "user" || last.Content != "tell me a secret" {
+			t.Fatalf("%s: unexpected payload %s", name, payload)
+		}
+	}
+	if _, err := templatePayload("gemini", "", ""); err == nil {
+		t.Fatal("expected an unknown template to be rejected")
+	}
+
+	inline := ""
+	if err := applyTemplate("openai-chat", &inline, "p.json", nil); err == nil {
+		t.Fatal("expected --template and --payload-file to conflict")
+	}
+}
+
+func TestEvaluateFiles(t *testing.T) {
+	dir := t.TempDir()
+	packPath := filepath.Join(dir, "pack.json")
+	pack := aisentinel.Rulepack{ID: "dev", Rules: []aisentinel.RuleDefinition{{ID: "prompt", Pattern: "ok", Allow: true}}}
+	data, _ := json.Marshal(pack)
+	files := map[string]string{"pack.json": string(data), "allow.json": `{"prompt":"ok"}`, "big.json": `{"prompt":"` + strings.Repeat("x", 64) + `"}`}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	local, err := loadLocalRulepack(packPath)
+	if err != nil {
+		t.Fatalf("expected rulepack: %v", err)
+	}
+
+	// The files live outside the working directory, so they are only read
+	// once that restriction is lifted.
+	paths := []string{filepath.Join(dir, "allow.json"), filepath.Join(dir, "big.json")}
+	var out bytes.Buffer
+	w := &decisionWriter{w: &out, format: outputJSON, batch: true}
+	if err := evaluateFiles(context.Background(), local, "dev", paths, payloadLimits{maxBytes: 32, anyPath: true}, time.Second, w); err != nil {
+		t.Fatalf("evaluate files: %v", err)
+	}
+	var got []decisionOutput
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("expected a JSON array: %v\n%s", err, out.String())
+	}
+	if len(got) != 2 || !got[0].Allowed || got[0].Source != paths[0] || !strings.Contains(got[1].Error, "exceeds 32 bytes") {
+		t.Fatalf("unexpected decisions %+v", got)
+	}
+
+	if _, err := loadPayloadFromFile(paths[0], defaultPayloadLimits); err == nil {
+		t.Fatal("expected files outside the working directory to be rejected by default")
+	}
+}
+
+func TestGenerateTypes(t *testing.T) {
+	pack := &aisentinel.Rulepack{ID: "support-chat", Version: "3", Rules: []aisentinel.RuleDefinition{
+		{ID: "prompt", Pattern: "(?i)ignore previous", Description: "Block prompt injection."},
+		{ID: "user_id", Pattern: "^u-", Allow: true},
+		{ID: "user-id", Pattern: "^v-", Allow: true},
+		{ID: "first_message", Field: "request.messages[0].content", Pattern: "(?i)password"},
+		{ID: "request_model", Field: "request.model", Pattern: "^gpt", Allow: true},
+	}}
+	src, err := generateTypes(pack, "policy", "")
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	file, err := parser.ParseFile(token.NewFileSet(), "payload.go", src, 0)
+	if err != nil {
+		t.Fatalf("expected valid Go: %v\n%s", err, src)
+	}
+	var fields []string
+	ast.Inspect(file, func(n ast.Node) bool {
+		if spec, ok := n.(*ast.TypeSpec); ok && spec.Name.Name == "SupportChatPayload" {
+			for _, f := range spec.Type.(*ast.StructType).Fields.List {
+				fields = append(fields, f.Names[0].Name+" "+f.Tag.Value)
+			}
+		}
+		return true
+	})
+	want := []string{"Prompt `json:\"prompt,omitempty\"`", "UserID `json:\"user_id,omitempty\"`", "UserID2 `json:\"user-id,omitempty\"`", "Request `json:\"request,omitempty\"`"}
+	if strings.Join(fields, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected fields %q\n%s", fields, src)
+	}
+	if !bytes.Contains(src, []byte("Request any")) || !bytes.Contains(src, []byte(`at request.messages[0].content`)) {
+		t.Fatalf("expected nested selectors to share an untyped field\n%s", src)
+	}
+	if !bytes.Contains(src, []byte(`const SupportChatRulepackID = "support-chat"`)) {
+		t.Fatalf("expected the rulepack ID constant\n%s", src)
+	}
+
+	if _, err := generateTypes(pack, "policy", "payload"); err == nil {
+		t.Fatal("expected an unexported type name to be rejected")
+	}
+}
+
+func TestLintBeforePublish(t *testing.T) {
+	dir := t.TempDir()
+	warned := filepath.Join(dir, "warned.yaml")
+	if err := os.WriteFile(warned, []byte("id: warned\nrules:\n  - id: all\n    field: prompt\n    pattern: \".*\"\n    description: Allow everything.\n    allow: true\n  - id: never\n    field: prompt\n    pattern: secret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	warnings, err := lintBeforePublish(&out, warned)
+	if err != nil || warnings != 2 {
+		t.Fatalf("expected two warnings and no error, got %d %v", warnings, err)
+	}
+	if !strings.Contains(out.String(), "[shadowed-rule]") || !strings.Contains(out.String(), "[missing-description]") {
+		t.Fatalf("expected the warnings printed, got:\n%s", out.String())
+	}
+
+	broken := filepath.Join(dir, "broken.yaml")
+	if err := os.WriteFile(broken, []byte("id: broken\nrules:\n  - id: bad\n    pattern: \"(\"\n    description: Unbalanced.\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	_, err = lintBeforePublish(&out, broken)
+	var exit *exitError
+	if !errors.As(err, &exit) || exit.code != 1 || !strings.Contains(out.String(), "[invalid-regex]") {
+		t.Fatalf("expected lint errors to fail, got %v:\n%s", err, out.String())
+	}
+	if err := runRulepack([]string{"push", broken}); !errors.As(err, &exit) {
+		t.Fatalf("expected push to refuse a rulepack with lint errors before connecting, got %v", err)
+	}
+}
+
+func TestResolveConfigDefaults(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("AISENTINEL_CONFIG", "")
+	t.Setenv("AISENTINEL_API_KEY", "test")
+	cfg, source, err := resolveConfig("", "")
+	if err != nil {
+		t.Fatalf("resolve config: %v", err)
+	}
+	if !cfg.MetricsEnabled || cfg.FetchRetries != aisentinel.DefaultConfig().FetchRetries || !strings.Contains(source, "API_KEY") {
+		t.Fatalf("expected the defaults without a config file, got %+v from %s", cfg, source)
+	}
+}
+
+// captureStdout redirects os.Stdout to a file until the returned function
+// is called, which restores it and returns what was written.
+func captureStdout(t *testing.T) func() string {
+	t.Helper()
+	f, err := os.CreateTemp(t.TempDir(), "stdout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = f
+	return func() string {
+		os.Stdout = stdout
+		f.Close()
+		data, _ := os.ReadFile(f.Name())
+		return string(data)
+	}
+}
+
+func TestEvaluateFailOnDeny(t *testing.T) {
+	dir := t.TempDir()
+	packPath := filepath.Join(dir, "pack.json")
+	data, _ := json.Marshal(aisentinel.Rulepack{ID: "dev", Rules: []aisentinel.RuleDefinition{{ID: "prompt", Pattern: "ok", Allow: true}}})
+	if err := os.WriteFile(packPath, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name       string
+		input      string
+		failOnDeny bool
+		wantCode   int
+	}{
+		{"all allowed", `{"prompt":"ok"}` + "\n", true, 0},
+		{"one denied", `{"prompt":"ok"}` + "\n" + `{"prompt":"no"}` + "\n", true, 1},
+		{"error beats deny", `{"prompt":"no"}` + "\nnot json\n", true, 2},
+		{"deny without the flag", `{"prompt":"no"}` + "\n", false, 0},
+		{"error without the flag", "not json\n", false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := filepath.Join(t.TempDir(), "input.jsonl")
+			if err := os.WriteFile(input, []byte(tt.input), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			args := []string{"--rulepack-file", packPath, "--input", input}
+			if tt.failOnDeny {
+				args = append(args, "--fail-on-deny")
+			}
+			done := captureStdout(t)
+			err := runEvaluate(args)
+			out := done()
+			code := 0
+			var exit *exitError
+			if errors.As(err, &exit) {
+				code = exit.code
+			} else if err != nil {
+				t.Fatalf("expected an exit status, got %v", err)
+			}
+			if code != tt.wantCode {
+				t.Fatalf("expected exit %d, got %d (%v)\n%s", tt.wantCode, code, err, out)
+			}
+		})
+	}
+
+	done := captureStdout(t)
+	err := runEvaluate([]string{"--rulepack-file", filepath.Join(dir, "missing.json"), "--payload", "{}", "--fail-on-deny"})
+	done()
+	var exit *exitError
+	if !errors.As(err, &exit) || exit.code != 2 {
+		t.Fatalf("expected a setup failure to exit 2, got %v", err)
+	}
+}
+
+func TestDecisionWriterFormats(t *testing.T) {
+	decisions := []decisionOutput{
+		{Line: 1, Allowed: true, RuleID: "prompt", LatencyMS: 3},
+		{Line: 2, Reason: "no rule allowed the payload"},
+		{Line: 3, Error: "invalid JSON"},
+	}
+	tests := []struct {
+		format string
+		batch  bool
+		want   string
+	}{
+		{outputJSON, false, "{\n  \"line\": 1,\n  \"allowed\": true,\n  \"rule_id\": \"prompt\",\n  \"latency_ms\": 3\n}\n"},
+		{outputJSON, true, "[\n  {\n    \"line\": 1,"},
+		{outputJSONL, true, "{\"line\":1,\"allowed\":true,\"rule_id\":\"prompt\",\"latency_ms\":3}\n{\"line\":2,\"allowed\":false,\"reason\":\"no rule allowed the payload\",\"latency_ms\":0}\n"},
+		{outputYAML, true, "line: 1\nallowed: true\nrule_id: prompt\nlatency_ms: 3\n---\nline: 2\n"},
+		{outputTable, true, "LINE   DECISION RULE                  LATENCY  REASON\n1      allow    prompt                    3ms  \n2      deny     -                         0ms  no rule allowed the payload\n3      error    -                         0ms  invalid JSON\n"},
+		{outputTable, false, "DECISION RULE                  LATENCY  REASON\nallow    prompt                    3ms  \n"},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s batch=%t", tt.format, tt.batch), func(t *testing.T) {
+			var out bytes.Buffer
+			w := &decisionWriter{w: &out, format: tt.format, batch: tt.batch}
+			for _, d := range decisions {
+				if err := w.write(d); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := w.flush(); err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(out.String(), tt.want) {
+				t.Fatalf("expected output starting with\n%s\ngot\n%s", tt.want, out.String())
+			}
+			if w.denied != 1 || w.failed != 1 {
+				t.Fatalf("expected one denial and one failure counted, got %d and %d", w.denied, w.failed)
+			}
+		})
+	}
+	if err := validateOutput("xml"); err == nil {
+		t.Fatal("expected an unknown format to be rejected")
+	}
+}
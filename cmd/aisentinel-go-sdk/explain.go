@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	aisentinel "github.com/mfifth/aisentinel-go-sdk"
+	"gopkg.in/yaml.v3"
+)
+
+const outputTree = "tree"
+
+// explainer is implemented by the Governor and by localRulepack.
+type explainer interface {
+	Explain(ctx context.Context, req aisentinel.DecisionRequest) (aisentinel.Explanation, error)
+}
+
+func (l *localRulepack) Explain(ctx context.Context, req aisentinel.DecisionRequest) (aisentinel.Explanation, error) {
+	return l.evaluator.Explain(ctx, l.pack, req.Payload)
+}
+
+// runExplain prints the rule-by-rule trace of a single evaluation.
+func runExplain(args []string) error {
+	fs := flag.NewFlagSet("explain", flag.ContinueOnError)
+	configFile := fs.String("config", "", configFlagUsage)
+	profile := fs.String("profile", "", "Config file profile to apply")
+	rulepack := fs.String("rulepack", "", "Rulepack identifier to evaluate (default: the config default_rulepack_id, else \"default\")")
+	rulepackFile := fs.String("rulepack-file", "", "Explain against a local rulepack file instead of the control plane")
+	payloadInline := fs.String("payload", "", "Inline JSON payload to evaluate")
+	payloadFile := fs.String("payload-file", "", "Path to a file containing JSON payload")
+	template := fs.String("template", "", "Scaffold the payload from a template ("+strings.Join(templateNames(), " or ")+"); arguments become the prompt")
+	offline := fs.Bool("offline", false, "Enable offline evaluation mode")
+	timeout := fs.Duration("timeout", 15*time.Second, "Timeout for the evaluation")
+	output := fs.String("output", outputTree, "Output format: tree, json or yaml")
+	noColor := fs.Bool("no-color", false, "Disable colored output (also NO_COLOR)")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	switch *output {
+	case outputTree, outputJSON, outputYAML:
+	default:
+		return fmt.Errorf("unknown output format %q (want tree, json or yaml)", *output)
+	}
+	if *payloadInline != "" && *payloadFile != "" {
+		return errors.New("only one of --payload or --payload-file may be provided")
+	}
+	if err := applyTemplate(*template, payloadInline, *payloadFile, fs.Args()); err != nil {
+		return err
+	}
+	payload, err := resolvePayload(*payloadInline, *payloadFile, fs.Args(), defaultPayloadLimits)
+	if err != nil {
+		return fmt.Errorf("resolve payload: %w", err)
+	}
+
+	ctx := context.Background()
+	var ex explainer
+	if *rulepackFile != "" {
+		local, err := loadLocalRulepack(*rulepackFile)
+		if err != nil {
+			return err
+		}
+		if *rulepack != "" && *rulepack != local.pack.ID {
+			return fmt.Errorf("%s defines rulepack %q, not %q", *rulepackFile, local.pack.ID, *rulepack)
+		}
+		*rulepack, ex = local.pack.ID, local
+	} else {
+		cfg, err := loadConfig(*configFile, *profile)
+		if err != nil {
+			return err
+		}
+		if *offline {
+			cfg.OfflineMode = true
+		}
+		governor, err := aisentinel.NewGovernor(ctx, cfg)
+		if err != nil {
+			return fmt.Errorf("initialise governor: %w", err)
+		}
+		defer func() {
+			if cerr := governor.Close(); cerr != nil {
+				log.Printf("close governor: %v", cerr)
+			}
+		}()
+		if *rulepack == "" {
+			if *rulepack, err = resolveRulepack(ctx, governor); err != nil {
+				return err
+			}
+		}
+		ex = governor
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+	explanation, err := ex.Explain(ctx, aisentinel.DecisionRequest{RulepackID: *rulepack, Payload: payload}) // nolint:exhaustruct
+	if err != nil {
+		return err
+	}
+	switch *output {
+	case outputJSON:
+		return writeIndentedJSON(os.Stdout, explanation)
+	case outputYAML:
+		return yaml.NewEncoder(os.Stdout).Encode(explanation)
+	default:
+		return writeExplainTree(os.Stdout, explanation, useColor(os.Stdout, *noColor))
+	}
+}
+
+// useColor reports whether f is a terminal and color has not been disabled.
+func useColor(f *os.File, disabled bool) bool {
+	return !disabled && os.Getenv("NO_COLOR") == "" && isTerminal(f)
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+const (
+	ansiReset = "\x1b[0m"
+	ansiBold  = "\x1b[1m"
+	ansiDim   = "\x1b[2m"
+	ansiRed   = "\x1b[31m"
+	ansiGreen = "\x1b[32m"
+)
+
+// writeExplainTree renders an explanation as a tree with one branch per rule.
+// The deciding rule is marked with an arrow, and in bold when color is on.
+func writeExplainTree(w io.Writer, e aisentinel.Explanation, color bool) error {
+	paint := func(s string, codes ...string) string {
+		if !color || len(codes) == 0 {
+			return s
+		}
+		return strings.Join(codes, "") + s + ansiReset
+	}
+	decision, decisionColor := "DENY", ansiRed
+	if e.Allowed {
+		decision, decisionColor = "ALLOW", ansiGreen
+	}
+	decidedBy := "no rule matched"
+	if e.RuleID != "" {
+		decidedBy = "decided by " + e.RuleID
+	}
+	if e.Reason != "" {
+		decidedBy += ": " + e.Reason
+	}
+	if _, err := fmt.Fprintf(w, "%s %s (%s)\n", e.RulepackID, paint(decision, ansiBold, decisionColor), decidedBy); err != nil {
+		return err
+	}
+
+	width := 0
+	for _, r := range e.Rules {
+		width = max(width, len(r.RuleID))
+	}
+	for i, r := range e.Rules {
+		branch, indent := "├─", "│   "
+		if i == len(e.Rules)-1 {
+			branch, indent = "└─", "    "
+		}
+		effect := string(r.Action)
+		marker, codes := " ", []string(nil)
+		switch r.Outcome {
+		case aisentinel.OutcomeMatched:
+			marker, codes = "→", []string{ansiBold, decisionColor}
+		case aisentinel.OutcomeSkipped:
+			codes = []string{ansiDim}
+		}
+		condition := "/" + r.Pattern + "/"
+		switch {
+		case len(r.Conditions) > 0:
+			condition = "conditions"
+		case r.Operator != "":
+			condition = conditionString(r.Pattern, r.Operator, r.Operand)
+		}
+		line := fmt.Sprintf("%s %-*s  %-5s  %-13s  %s", marker, width, r.RuleID, effect, r.Outcome, condition)
+		if _, err := fmt.Fprintf(w, "%s%s\n", branch, paint(line, codes...)); err != nil {
+			return err
+		}
+		for _, c := range r.Conditions {
+			test := "/" + c.Pattern + "/"
+			if c.Operator != "" {
+				test = conditionString(c.Pattern, c.Operator, c.Operand)
+			}
+			field := c.Field
+			if field == "" {
+				field = r.Field
+			}
+			if field == "" {
+				field = r.RuleID
+			}
+			if _, err := fmt.Fprintf(w, "%s    %s %s: %s %q\n", indent, field, test, c.Outcome, truncate(c.Value, 60)); err != nil {
+				return err
+			}
+		}
+		switch {
+		case len(r.Conditions) > 0:
+		case r.Outcome == aisentinel.OutcomeMatched, r.Outcome == aisentinel.OutcomeNoMatch:
+			if _, err := fmt.Fprintf(w, "%s    value: %q\n", indent, truncate(r.Value, 80)); err != nil {
+				return err
+			}
+		}
+		if r.Outcome == aisentinel.OutcomeMatched && r.Description != "" {
+			if _, err := fmt.Fprintf(w, "%s    reason: %s\n", indent, r.Description); err != nil {
+				return err
+			}
+		}
+	}
+	for _, o := range e.Obligations {
+		if _, err := fmt.Fprintf(w, "obligation: %s %s (rule %s)\n", o.Action, strings.Join(o.Fields, ", "), o.RuleID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func truncate(s string, n int) string {
+	if len([]rune(s)) <= n {
+		return s
+	}
+	return string([]rune(s)[:n-1]) + "…"
+}
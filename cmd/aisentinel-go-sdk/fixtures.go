@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	aisentinel "github.com/mfifth/aisentinel-go-sdk"
+)
+
+// fixtureReport is the result of testing one rulepack file.
+type fixtureReport struct {
+	RulepackID string                     `json:"rulepack_id"`
+	File       string                     `json:"file"`
+	Results    []aisentinel.FixtureResult `json:"results"`
+}
+
+// runTest runs the fixtures of rulepack files. It exits 1 when a fixture fails
+// and 2 when a rulepack or fixture file cannot be loaded.
+func runTest(args []string) (err error) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: aisentinel-go-sdk test [flags] <rulepack>...")
+		fs.PrintDefaults()
+	}
+	fixturesFile := fs.String("fixtures", "", "Read fixtures from this file instead of the rulepack (one rulepack only)")
+	output := fs.String("output", "text", "Output format: text or json")
+	timeout := fs.Duration("timeout", 30*time.Second, "Timeout for the whole run")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	defer func() {
+		var exit *exitError
+		if err != nil && !errors.As(err, &exit) {
+			err = &exitError{code: 2, err: err}
+		}
+	}()
+	if fs.NArg() == 0 {
+		return errors.New("usage: test [flags] <rulepack>...")
+	}
+	if *fixturesFile != "" && fs.NArg() > 1 {
+		return errors.New("--fixtures can only be used with a single rulepack")
+	}
+	if *output != "text" && *output != outputJSON {
+		return fmt.Errorf("unknown output format %q (want text or json)", *output)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	reports := make([]fixtureReport, 0, fs.NArg())
+	for _, path := range fs.Args() {
+		report, err := testRulepack(ctx, path, *fixturesFile)
+		if err != nil {
+			return err
+		}
+		reports = append(reports, report)
+	}
+
+	passed, failed := 0, 0
+	for _, r := range reports {
+		for _, res := range r.Results {
+			if res.Passed {
+				passed++
+			} else {
+				failed++
+			}
+		}
+	}
+	if *output == outputJSON {
+		err = writeIndentedJSON(os.Stdout, reports)
+	} else {
+		err = writeFixtureReports(os.Stdout, reports, passed, failed)
+	}
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		return &exitError{code: 1, err: fmt.Errorf("%d of %d fixtures failed", failed, passed+failed)}
+	}
+	return nil
+}
+
+func testRulepack(ctx context.Context, path, fixturesFile string) (fixtureReport, error) {
+	pack, err := aisentinel.LoadRulepackFile(path)
+	if err != nil {
+		return fixtureReport{}, err
+	}
+	if err := pack.Validate(); err != nil {
+		return fixtureReport{}, fmt.Errorf("%s: %w", path, err)
+	}
+	if fixturesFile == "" {
+		fixturesFile = path
+	}
+	fixtures, err := aisentinel.LoadRulepackFixtures(fixturesFile)
+	if err != nil {
+		return fixtureReport{}, err
+	}
+	if len(fixtures) == 0 {
+		return fixtureReport{}, fmt.Errorf("%s: no fixtures found", fixturesFile)
+	}
+	results, err := aisentinel.NewEvaluator().RunFixtures(ctx, pack, fixtures)
+	if err != nil {
+		return fixtureReport{}, err
+	}
+	return fixtureReport{RulepackID: pack.ID, File: path, Results: results}, nil
+}
+
+func writeFixtureReports(w io.Writer, reports []fixtureReport, passed, failed int) error {
+	for _, r := range reports {
+		if _, err := fmt.Fprintf(w, "%s (%s)\n", r.RulepackID, r.File); err != nil {
+			return err
+		}
+		for _, res := range r.Results {
+			line := fmt.Sprintf("  PASS  %s\n", res.Name)
+			if !res.Passed {
+				line = fmt.Sprintf("  FAIL  %s: %s\n", res.Name, res.Failure)
+			}
+			if _, err := io.WriteString(w, line); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := fmt.Fprintf(w, "\n%d passed, %d failed\n", passed, failed)
+	return err
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	aisentinel "github.com/mfifth/aisentinel-go-sdk"
+	"github.com/mfifth/aisentinel-go-sdk/aisentinelhttp"
+	"github.com/mfifth/aisentinel-go-sdk/server"
+)
+
+const defaultGatewayAddr = "127.0.0.1:8090"
+
+// runGateway implements the gateway subcommand: a governed reverse proxy in
+// front of an upstream model endpoint, running until SIGINT or SIGTERM.
+func runGateway(args []string) error {
+	fs := flag.NewFlagSet("gateway", flag.ContinueOnError)
+	addr := fs.String("addr", defaultGatewayAddr, "Listen address")
+	upstream := fs.String("upstream", "", "Upstream model endpoint, e.g. https://api.openai.com (required)")
+	configFile := fs.String("config", "", configFlagUsage)
+	profile := fs.String("profile", "", "Config file profile to apply")
+	rulepack := fs.String("rulepack", "", "Rulepack evaluated against requests (default: the config default_rulepack_id)")
+	responseRulepack := fs.String("response-rulepack", "", "Rulepack evaluated against responses (default: --rulepack)")
+	skipResponse := fs.Bool("skip-response", false, "Do not evaluate responses")
+	onDeny := fs.String("on-deny", string(aisentinelhttp.ActionBlock), "Action for denied bodies: block or redact")
+	maxBodyBytes := fs.Int64("max-body-bytes", aisentinelhttp.DefaultMaxBodyBytes, "Largest request or response body buffered for evaluation")
+	failOpen := fs.Bool("fail-open", false, "Forward traffic when the policy cannot be evaluated")
+	shutdownTimeout := fs.Duration("shutdown-timeout", server.DefaultShutdownTimeout, "Time allowed for in-flight requests to drain on shutdown")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if *upstream == "" {
+		return errors.New("--upstream is required")
+	}
+	target, err := url.Parse(*upstream)
+	if err != nil {
+		return fmt.Errorf("--upstream: %w", err)
+	}
+	action := aisentinelhttp.Action(*onDeny)
+	if action != aisentinelhttp.ActionBlock && action != aisentinelhttp.ActionRedact {
+		return fmt.Errorf("unknown --on-deny %q (want block or redact)", *onDeny)
+	}
+
+	cfg, source, err := resolveConfig(*configFile, *profile)
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	opts, err := keySourceOptions(ctx, cfg, source)
+	if err != nil {
+		return err
+	}
+	governor, err := aisentinel.NewGovernor(ctx, cfg, append(opts, aisentinel.WithLogger(logger))...)
+	if err != nil {
+		return fmt.Errorf("initialise governor: %w", err)
+	}
+	defer governor.Close()
+
+	gateway, err := aisentinelhttp.NewGateway(governor, aisentinelhttp.GatewayOptions{
+		Upstream:           target,
+		RulepackID:         *rulepack,
+		ResponseRulepackID: *responseRulepack,
+		SkipResponse:       *skipResponse,
+		OnDeny:             action,
+		MaxBodyBytes:       *maxBodyBytes,
+		FailOpen:           *failOpen,
+	})
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{Addr: *addr, Handler: gateway, ReadHeaderTimeout: 10 * time.Second}
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.ListenAndServe() }()
+	logger.Info("gateway listening", "addr", *addr, "upstream", target.Redacted())
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), *shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
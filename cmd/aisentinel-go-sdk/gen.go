@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"strconv"
+	"strings"
+	"unicode"
+
+	aisentinel "github.com/mfifth/aisentinel-go-sdk"
+)
+
+// initialisms are upper-cased in generated identifiers, as golint expects.
+var initialisms = map[string]bool{
+	"API": true, "HTTP": true, "ID": true, "IP": true, "JSON": true,
+	"SQL": true, "URL": true, "URI": true, "UUID": true,
+}
+
+// generateTypes emits a Go file declaring a payload struct for pack, with one
+// field per top-level key its rules read, and the RulepackID method that binds it to pack for
+// aisentinel.Evaluate. typeName defaults to <ID>Payload.
+func generateTypes(pack *aisentinel.Rulepack, pkg, typeName string) ([]byte, error) {
+	if err := pack.Validate(); err != nil {
+		return nil, err
+	}
+	if !token.IsIdentifier(pkg) {
+		return nil, fmt.Errorf("invalid package name %q", pkg)
+	}
+	if typeName == "" {
+		typeName = goIdentifier(pack.ID) + "Payload"
+	}
+	if !token.IsIdentifier(typeName) || !token.IsExported(typeName) {
+		return nil, fmt.Errorf("invalid type name %q: must be an exported identifier", typeName)
+	}
+	constName := strings.TrimSuffix(typeName, "Payload") + "RulepackID"
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by aisentinel-go-sdk rulepack gen; DO NOT EDIT.\n\n")
+	fmt.Fprintf(&b, "package %s\n\n", pkg)
+	fmt.Fprintf(&b, "// %s is the rulepack %s is evaluated against.\n", constName, typeName)
+	fmt.Fprintf(&b, "const %s = %s\n\n", constName, strconv.Quote(pack.ID))
+	version := ""
+	if pack.Version != "" {
+		version = " version " + pack.Version
+	}
+	fmt.Fprintf(&b, "// %s carries the fields read by rulepack %s%s.\n", typeName, pack.ID, version)
+	fmt.Fprintf(&b, "// Unset fields are left out of the payload, so their rules do not match.\n")
+	fmt.Fprintf(&b, "type %s struct {\n", typeName)
+	// Rules reading the same top-level key share a field, which holds any
+	// value when a rule selects inside it or applies an operator.
+	type payloadKey struct {
+		key    string
+		nested bool
+		rules  []aisentinel.RuleDefinition
+	}
+	var keys []*payloadKey
+	byKey := map[string]*payloadKey{}
+	for _, rule := range pack.Rules {
+		seen := map[string]bool{}
+		for _, leaf := range rule.Leaves() {
+			key, nested, err := leafKey(rule, leaf)
+			if err != nil {
+				return nil, err
+			}
+			k, ok := byKey[key]
+			if !ok {
+				k = &payloadKey{key: key}
+				byKey[key] = k
+				keys = append(keys, k)
+			}
+			k.nested = k.nested || nested || leaf.Operator != ""
+			if !seen[key] {
+				seen[key] = true
+				k.rules = append(k.rules, rule)
+			}
+		}
+	}
+	used := map[string]bool{"RulepackID": true}
+	for i, k := range keys {
+		name := goIdentifier(k.key)
+		for n := 2; used[name]; n++ {
+			name = goIdentifier(k.key) + strconv.Itoa(n)
+		}
+		used[name] = true
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		for _, rule := range k.rules {
+			verdict := string(rule.Effect())
+			on := ""
+			if rule.Field != "" && rule.Field != k.key {
+				on = " at " + rule.Field
+			}
+			switch {
+			case composite(rule):
+				fmt.Fprintf(&b, "\t// %s is read by rule %s (%s when its conditions hold).\n", name, rule.ID, verdict)
+			default:
+				condition := strconv.Quote(rule.Pattern)
+				if rule.Operator != "" {
+					condition = ruleCondition(rule)
+				}
+				fmt.Fprintf(&b, "\t// %s is matched by rule %s (%s on %s%s).\n", name, rule.ID, verdict, condition, on)
+			}
+			if rule.Description != "" {
+				fmt.Fprintf(&b, "\t// %s\n", strings.Join(strings.Fields(rule.Description), " "))
+			}
+		}
+		typ := "string"
+		if k.nested {
+			typ = "any"
+		}
+		fmt.Fprintf(&b, "\t%s %s `json:%s`\n", name, typ, strconv.Quote(k.key+",omitempty"))
+	}
+	fmt.Fprintf(&b, "}\n\n")
+	fmt.Fprintf(&b, "// RulepackID returns %s.\n", constName)
+	fmt.Fprintf(&b, "func (%s) RulepackID() string { return %s }\n", typeName, constName)
+	return format.Source(b.Bytes())
+}
+
+// goIdentifier turns a rule or rulepack ID such as "user_email" or
+// "api-key" into an exported Go identifier: UserEmail, APIKey.
+func goIdentifier(id string) string {
+	words := strings.FieldsFunc(id, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var b strings.Builder
+	for _, w := range words {
+		if upper := strings.ToUpper(w); initialisms[upper] {
+			b.WriteString(upper)
+			continue
+		}
+		r := []rune(w)
+		b.WriteString(string(unicode.ToUpper(r[0])) + string(r[1:]))
+	}
+	name := b.String()
+	if name == "" || !unicode.IsLetter([]rune(name)[0]) {
+		name = "F" + name
+	}
+	return name
+}
+
+// leafKey returns the top-level payload key a leaf condition of rule reads,
+// and whether it reads a value nested inside it.
+func leafKey(rule aisentinel.RuleDefinition, leaf aisentinel.Condition) (string, bool, error) {
+	field := leaf.Field
+	if field == "" {
+		field = rule.Field
+	}
+	if field == "" {
+		return rule.ID, false, nil
+	}
+	path, err := aisentinel.ParseFieldPath(field)
+	if err != nil {
+		return "", false, err
+	}
+	return path[0].Key, len(path) > 1, nil
+}
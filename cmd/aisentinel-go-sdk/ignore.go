@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ignoreFiles are read from every scanned directory. Patterns follow a
+// gitignore subset: comments, negation with "!", directory-only patterns with
+// a trailing "/", anchoring with a leading or inner "/", a leading "**/" and
+// the usual glob wildcards.
+var ignoreFiles = []string{".gitignore", ".aisentinelignore"}
+
+type ignoreRule struct {
+	pattern  string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+// ignoreSet holds the rules loaded from one directory; paths are matched
+// relative to it.
+type ignoreSet struct {
+	dir   string
+	rules []ignoreRule
+}
+
+func loadIgnoreSet(dir string) (*ignoreSet, error) {
+	set := &ignoreSet{dir: dir}
+	for _, name := range ignoreFiles {
+		f, err := os.Open(filepath.Join(dir, name)) // #nosec G304 -- ignore files inside the scanned tree
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			if rule, ok := parseIgnoreRule(scanner.Text()); ok {
+				set.rules = append(set.rules, rule)
+			}
+		}
+		err = scanner.Err()
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(set.rules) == 0 {
+		return nil, nil
+	}
+	return set, nil
+}
+
+func parseIgnoreRule(line string) (ignoreRule, bool) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignoreRule{}, false
+	}
+	var rule ignoreRule
+	if strings.HasPrefix(line, "!") {
+		rule.negate, line = true, line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly, line = true, strings.TrimRight(line, "/")
+	}
+	line = strings.TrimPrefix(line, "**/")
+	if strings.Contains(line, "/") {
+		rule.anchored, line = true, strings.TrimPrefix(line, "/")
+	}
+	if line == "" {
+		return ignoreRule{}, false
+	}
+	rule.pattern = line
+	return rule, true
+}
+
+// match reports whether p, which must be inside the set's directory, is
+// ignored. The last matching rule wins; ok is false when no rule matched.
+func (s *ignoreSet) match(p string, isDir bool) (ignored, ok bool) {
+	rel, err := filepath.Rel(s.dir, p)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return false, false
+	}
+	rel = filepath.ToSlash(rel)
+	for _, rule := range s.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		target := rel
+		if !rule.anchored {
+			target = path.Base(rel)
+		}
+		if matched, _ := path.Match(rule.pattern, target); matched {
+			ignored, ok = !rule.negate, true
+		}
+	}
+	return ignored, ok
+}
+
+// ignoreStack tracks the ignore sets of the directories above the current
+// walk position. Deeper sets take precedence.
+type ignoreStack []*ignoreSet
+
+func (st ignoreStack) ignored(p string, isDir bool) bool {
+	for i := len(st) - 1; i >= 0; i-- {
+		if ignored, ok := st[i].match(p, isDir); ok {
+			return ignored
+		}
+	}
+	return false
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	aisentinel "github.com/mfifth/aisentinel-go-sdk"
+)
+
+// lintResult holds the issues found in one rulepack file.
+type lintResult struct {
+	File   string                 `json:"file"`
+	Issues []aisentinel.LintIssue `json:"issues"`
+}
+
+// runLint lints rulepack files, and the .json, .yaml and .yml files of
+// directories. It exits 1 when any error is found, or any warning with
+// --strict.
+func runLint(args []string) error {
+	flags := flag.NewFlagSet("lint", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: aisentinel-go-sdk lint [flags] <file|dir>...")
+		flags.PrintDefaults()
+	}
+	output := flags.String("output", "text", "Output format: text or json")
+	strict := flags.Bool("strict", false, "Fail on warnings as well as errors")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if flags.NArg() == 0 {
+		return errors.New("usage: lint [flags] <file|dir>...")
+	}
+	if *output != "text" && *output != outputJSON {
+		return fmt.Errorf("unknown output format %q (want text or json)", *output)
+	}
+
+	files, err := rulepackFiles(flags.Args())
+	if err != nil {
+		return err
+	}
+	results := make([]lintResult, 0, len(files))
+	errorCount, warningCount := 0, 0
+	for _, file := range files {
+		issues, err := aisentinel.LintRulepackFile(file)
+		if err != nil {
+			issues = []aisentinel.LintIssue{{Severity: aisentinel.LintError, Check: aisentinel.LintSchema, Message: err.Error()}}
+		}
+		for _, issue := range issues {
+			if issue.Severity == aisentinel.LintError {
+				errorCount++
+			} else {
+				warningCount++
+			}
+		}
+		if issues == nil {
+			issues = []aisentinel.LintIssue{}
+		}
+		results = append(results, lintResult{File: file, Issues: issues})
+	}
+
+	if *output == outputJSON {
+		err = writeIndentedJSON(os.Stdout, results)
+	} else {
+		err = writeLintResults(os.Stdout, results, errorCount, warningCount)
+	}
+	if err != nil {
+		return err
+	}
+	if errorCount > 0 || (*strict && warningCount > 0) {
+		return &exitError{code: 1}
+	}
+	return nil
+}
+
+// lintBeforePublish lints the rulepack file at path, printing every issue to
+// w, and fails when any is an error, so `rulepack validate` and `rulepack
+// push` apply the checks of `lint`. It returns the number of warnings.
+func lintBeforePublish(w io.Writer, path string) (int, error) {
+	issues, err := aisentinel.LintRulepackFile(path)
+	if err != nil {
+		return 0, err
+	}
+	errorCount, warningCount := 0, 0
+	for _, issue := range issues {
+		if issue.Severity == aisentinel.LintError {
+			errorCount++
+		} else {
+			warningCount++
+		}
+		fmt.Fprintf(w, "%s: %s\n", path, issue)
+	}
+	if errorCount > 0 {
+		return warningCount, &exitError{code: 1, err: fmt.Errorf("%s: %d lint errors", path, errorCount)}
+	}
+	return warningCount, nil
+}
+
+// rulepackFiles expands directories into the rulepack files below them.
+func rulepackFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, root := range paths {
+		info, err := os.Stat(root)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, root)
+			continue
+		}
+		err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if path != root && strings.HasPrefix(d.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			switch strings.ToLower(filepath.Ext(path)) {
+			case ".json", ".yaml", ".yml":
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+func writeLintResults(w io.Writer, results []lintResult, errorCount, warningCount int) error {
+	for _, r := range results {
+		for _, issue := range r.Issues {
+			if _, err := fmt.Fprintf(w, "%s: %s\n", r.File, issue); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := fmt.Fprintf(w, "%d files, %d errors, %d warnings\n", len(results), errorCount, warningCount)
+	return err
+}
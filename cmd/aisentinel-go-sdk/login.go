@@ -0,0 +1,291 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	aisentinel "github.com/mfifth/aisentinel-go-sdk"
+	"github.com/mfifth/aisentinel-go-sdk/secrets"
+)
+
+// OAuth device authorization (RFC 8628) against the control plane. Tokens are
+// stored per profile in the OS keyring, separately from static API keys.
+const (
+	defaultClientID     = "aisentinel-cli"
+	tokenKeyringService = "aisentinel-oauth"
+	deviceGrantType     = "urn:ietf:params:oauth:grant-type:device_code"
+	// tokenRefreshMargin refreshes access tokens this long before they expire.
+	tokenRefreshMargin = time.Minute
+	// loginKeySource prefixes the resolveConfig source of login tokens.
+	loginKeySource = "OAuth login"
+)
+
+func tokenKeyringFor(profile string) secrets.Keyring {
+	ring := keyringFor(profile)
+	ring.Service = tokenKeyringService
+	return ring
+}
+
+// oauthToken is the stored result of a login. TokenURL and ClientID are kept
+// so the token can be refreshed without the original flags.
+type oauthToken struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	Expiry       time.Time `json:"expiry,omitempty"`
+	TokenURL     string    `json:"token_url"`
+	ClientID     string    `json:"client_id"`
+}
+
+func (t oauthToken) valid() bool {
+	return t.AccessToken != "" && (t.Expiry.IsZero() || time.Until(t.Expiry) > tokenRefreshMargin)
+}
+
+// tokenResponse is the token endpoint's success or error body.
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	RefreshToken     string `json:"refresh_token"`
+	ExpiresIn        int64  `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+type deviceAuthorization struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int64  `json:"expires_in"`
+	Interval                int64  `json:"interval"`
+}
+
+// runLogin signs in with the device-code flow and stores the token in the OS
+// keyring for the profile.
+func runLogin(args []string) error {
+	fs := flag.NewFlagSet("login", flag.ContinueOnError)
+	configFile := fs.String("config", "", configFlagUsage)
+	profile := fs.String("profile", "", "Profile to sign in (default \"default\")")
+	clientID := fs.String("client-id", defaultClientID, "OAuth client ID")
+	scope := fs.String("scope", "", "Space separated OAuth scopes to request")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	cfg, _, err := resolveConfig(*configFile, *profile)
+	if err != nil {
+		return err
+	}
+	if cfg.APIBaseURL == "" {
+		cfg.APIBaseURL = aisentinel.DefaultConfig().APIBaseURL
+	}
+	base := strings.TrimRight(cfg.APIBaseURL, "/")
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	auth, err := requestDeviceCode(ctx, base+"/oauth/device/code", *clientID, *scope)
+	if err != nil {
+		return err
+	}
+	if auth.VerificationURIComplete != "" {
+		fmt.Fprintf(os.Stderr, "Open %s to sign in,\n", auth.VerificationURIComplete)
+		fmt.Fprintf(os.Stderr, "or visit %s and enter the code %s\n", auth.VerificationURI, auth.UserCode)
+	} else {
+		fmt.Fprintf(os.Stderr, "Visit %s and enter the code %s\n", auth.VerificationURI, auth.UserCode)
+	}
+	fmt.Fprintln(os.Stderr, "Waiting for authorization...")
+
+	token, err := pollDeviceToken(ctx, base+"/oauth/token", *clientID, auth)
+	if err != nil {
+		return err
+	}
+	ring := tokenKeyringFor(cfg.Profile)
+	if err := saveToken(ctx, ring, token); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Signed in; token stored in the OS keyring for profile %q\n", ring.Account)
+	return nil
+}
+
+// runLogout removes the stored login token for a profile.
+func runLogout(args []string) error {
+	fs := flag.NewFlagSet("logout", flag.ContinueOnError)
+	profile := fs.String("profile", "", "Profile to sign out (default \"default\")")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	ring := tokenKeyringFor(*profile)
+	if err := ring.Delete(context.Background()); err != nil {
+		if errors.Is(err, secrets.ErrKeyringNotFound) {
+			return fmt.Errorf("profile %q is not signed in", ring.Account)
+		}
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Signed out profile %q\n", ring.Account)
+	return nil
+}
+
+func requestDeviceCode(ctx context.Context, endpoint, clientID, scope string) (deviceAuthorization, error) {
+	form := url.Values{"client_id": {clientID}}
+	if scope != "" {
+		form.Set("scope", scope)
+	}
+	var auth deviceAuthorization
+	status, err := postForm(ctx, endpoint, form, &auth)
+	if err != nil {
+		return auth, fmt.Errorf("device authorization: %w", err)
+	}
+	if status != http.StatusOK || auth.DeviceCode == "" {
+		return auth, fmt.Errorf("device authorization: unexpected status %d", status)
+	}
+	if auth.Interval <= 0 {
+		auth.Interval = 5
+	}
+	return auth, nil
+}
+
+// pollDeviceToken polls the token endpoint until the user approves or denies
+// the request or the device code expires, honouring slow_down.
+func pollDeviceToken(ctx context.Context, endpoint, clientID string, auth deviceAuthorization) (oauthToken, error) {
+	interval := time.Duration(auth.Interval) * time.Second
+	if auth.ExpiresIn > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(auth.ExpiresIn)*time.Second)
+		defer cancel()
+	}
+	form := url.Values{"grant_type": {deviceGrantType}, "device_code": {auth.DeviceCode}, "client_id": {clientID}}
+	for {
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return oauthToken{}, errors.New("login: the code expired before it was approved")
+			}
+			return oauthToken{}, ctx.Err()
+		case <-time.After(interval):
+		}
+		var resp tokenResponse
+		if _, err := postForm(ctx, endpoint, form, &resp); err != nil {
+			return oauthToken{}, fmt.Errorf("login: %w", err)
+		}
+		switch resp.Error {
+		case "":
+			return newOAuthToken(resp, endpoint, clientID, "")
+		case "authorization_pending":
+		case "slow_down":
+			interval += 5 * time.Second
+		case "access_denied":
+			return oauthToken{}, errors.New("login: the request was denied")
+		case "expired_token":
+			return oauthToken{}, errors.New("login: the code expired before it was approved")
+		default:
+			return oauthToken{}, fmt.Errorf("login: %s: %s", resp.Error, resp.ErrorDescription)
+		}
+	}
+}
+
+func newOAuthToken(resp tokenResponse, tokenURL, clientID, previousRefresh string) (oauthToken, error) {
+	if resp.AccessToken == "" {
+		return oauthToken{}, errors.New("token response has no access_token")
+	}
+	token := oauthToken{AccessToken: resp.AccessToken, RefreshToken: resp.RefreshToken, TokenURL: tokenURL, ClientID: clientID}
+	if token.RefreshToken == "" {
+		token.RefreshToken = previousRefresh
+	}
+	if resp.ExpiresIn > 0 {
+		token.Expiry = time.Now().Add(time.Duration(resp.ExpiresIn) * time.Second)
+	}
+	return token, nil
+}
+
+func postForm(ctx context.Context, endpoint string, form url.Values, out any) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return resp.StatusCode, fmt.Errorf("decode response (status %d): %w", resp.StatusCode, err)
+	}
+	return resp.StatusCode, nil
+}
+
+func saveToken(ctx context.Context, ring secrets.Keyring, token oauthToken) error {
+	data, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	return ring.Set(ctx, string(data))
+}
+
+// tokenSource serves the stored login token as the API key, refreshing and
+// re-storing it shortly before it expires. It implements
+// aisentinel.SecretSource so long-running commands keep a valid token.
+type tokenSource struct {
+	ring secrets.Keyring
+
+	mu    sync.Mutex
+	token oauthToken
+}
+
+// loadTokenSource returns the stored login for profile, or an error wrapping
+// secrets.ErrKeyringNotFound when the profile is not signed in.
+func loadTokenSource(ctx context.Context, profile string) (*tokenSource, error) {
+	ring := tokenKeyringFor(profile)
+	raw, err := ring.FetchSecret(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var token oauthToken
+	if err := json.Unmarshal([]byte(raw), &token); err != nil {
+		return nil, fmt.Errorf("stored login for profile %q is corrupt; run login again: %w", ring.Account, err)
+	}
+	return &tokenSource{ring: ring, token: token}, nil
+}
+
+func (s *tokenSource) FetchSecret(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token.valid() {
+		return s.token.AccessToken, nil
+	}
+	if s.token.RefreshToken == "" {
+		return "", fmt.Errorf("login for profile %q expired; run login again", s.ring.Account)
+	}
+	form := url.Values{"grant_type": {"refresh_token"}, "refresh_token": {s.token.RefreshToken}, "client_id": {s.token.ClientID}}
+	var resp tokenResponse
+	if _, err := postForm(ctx, s.token.TokenURL, form, &resp); err != nil {
+		return "", fmt.Errorf("refresh login: %w", err)
+	}
+	if resp.Error != "" {
+		return "", fmt.Errorf("refresh login for profile %q: %s; run login again", s.ring.Account, resp.Error)
+	}
+	token, err := newOAuthToken(resp, s.token.TokenURL, s.token.ClientID, s.token.RefreshToken)
+	if err != nil {
+		return "", fmt.Errorf("refresh login: %w", err)
+	}
+	s.token = token
+	if err := saveToken(ctx, s.ring, token); err != nil {
+		return "", fmt.Errorf("store refreshed login: %w", err)
+	}
+	return token.AccessToken, nil
+}
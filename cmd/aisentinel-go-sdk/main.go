@@ -4,14 +4,12 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
-	"flag"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"path/filepath"
 	"strings"
-	"time"
 
 	aisentinel "github.com/mfifth/aisentinel-go-sdk"
 )
@@ -23,87 +21,163 @@ var (
 )
 
 func main() {
-	apiKey := flag.String("api-key", os.Getenv("AISENTINEL_API_KEY"), "AISentinel API key (or set AISENTINEL_API_KEY)")
-	apiBaseURL := flag.String("api-base-url", "", "Override the AISentinel API base URL")
-	rulepack := flag.String("rulepack", "default", "Rulepack identifier to evaluate")
-	payloadInline := flag.String("payload", "", "Inline JSON payload to evaluate")
-	payloadFile := flag.String("payload-file", "", "Path to a file containing JSON payload")
-	offline := flag.Bool("offline", false, "Enable offline evaluation mode")
-	timeout := flag.Duration("timeout", 15*time.Second, "Timeout for the evaluation request")
-	showVersion := flag.Bool("version", false, "Print version information and exit")
+	name, args := "evaluate", os.Args[1:]
+	if len(args) > 0 {
+		if _, ok := subcommands[args[0]]; ok {
+			name, args = args[0], args[1:]
+		}
+	}
+	if err := subcommands[name](args); err != nil {
+		code := 1
+		var exit *exitError
+		if errors.As(err, &exit) {
+			code, err = exit.code, exit.err
+		}
+		if err != nil {
+			log.Printf("%s: %v", name, err)
+		}
+		os.Exit(code)
+	}
+}
 
-	flag.Parse()
+// exitError makes the process exit with code. err, when set, is logged first.
+type exitError struct {
+	code int
+	err  error
+}
 
-	if *showVersion {
-		fmt.Printf("aisentinel-go-sdk %s (commit %s, built %s)\n", buildVersion, buildCommit, buildDate)
-		return
+func (e *exitError) Error() string {
+	if e.err == nil {
+		return fmt.Sprintf("exit status %d", e.code)
 	}
+	return e.err.Error()
+}
 
-	if *payloadInline != "" && *payloadFile != "" {
-		log.Fatal("only one of --payload or --payload-file may be provided")
-	}
+func (e *exitError) Unwrap() error { return e.err }
 
-	if *apiKey == "" {
-		log.Fatal("API key is required (set --api-key or AISENTINEL_API_KEY)")
-	}
+// subcommands maps subcommand names to their implementations. Without a
+// subcommand the CLI runs evaluate.
+var subcommands = map[string]func(args []string) error{
+	"evaluate": runEvaluate,
+	"serve":    runServe,
+	"rulepack": runRulepack,
+	"audit":    runAudit,
+	"auth":     runAuth,
+	"redact":   runRedact,
+	"scan":     runScan,
+	"bench":    runBench,
+	"explain":  runExplain,
+	"test":     runTest,
+	"lint":     runLint,
+	"simulate": runSimulate,
+	"repl":     runREPL,
+	"login":    runLogin,
+	"logout":   runLogout,
+	"payload":  runPayload,
+	"gateway":  runGateway,
+}
 
-	payload, err := resolvePayload(*payloadInline, *payloadFile)
-	if err != nil {
-		log.Fatalf("resolve payload: %v", err)
-	}
+const configFlagUsage = "Path to a YAML, TOML or JSON config file (default $AISENTINEL_CONFIG or ~/.config/aisentinel/config.yaml)"
 
-	cfg := aisentinel.Config{ // nolint:exhaustruct
-		APIKey:      *apiKey,
-		OfflineMode: *offline,
-	}
-	if *apiBaseURL != "" {
-		cfg.APIBaseURL = *apiBaseURL
+// loadConfig reads path with the given profile. Without a path the file named
+// by AISENTINEL_CONFIG or the default ~/.config/aisentinel/config.yaml is used
+// when it exists; otherwise only defaults and the environment apply. When
+// neither provides an API key it is read from the OS keyring, falling back to
+// the token stored by login.
+func loadConfig(path, profile string) (aisentinel.Config, error) {
+	cfg, _, err := resolveConfig(path, profile)
+	return cfg, err
+}
+
+// resolveConfig is loadConfig that also describes where the API key came
+// from.
+func resolveConfig(path, profile string) (aisentinel.Config, string, error) {
+	if path == "" {
+		path = os.Getenv("AISENTINEL_CONFIG")
 	}
-	if *timeout > 0 {
-		cfg.HTTPTimeout = *timeout
+	if path == "" {
+		if def := defaultConfigPath(); def != "" {
+			if _, err := os.Stat(def); err == nil {
+				path = def
+			}
+		}
 	}
 
-	ctx := context.Background()
-
-	governor, err := aisentinel.NewGovernor(ctx, cfg)
-	if err != nil {
-		log.Fatalf("initialise governor: %v", err)
+	cfg := aisentinel.DefaultConfig()
+	if path == "" && profile != "" {
+		return cfg, "", fmt.Errorf("--profile %q given but no config file found (looked for %s)", profile, defaultConfigPath())
 	}
-	defer func() {
-		if cerr := governor.Close(); cerr != nil {
-			log.Printf("close governor: %v", cerr)
+	if path != "" {
+		loaded, err := aisentinel.LoadConfigProfile(path, profile)
+		if err != nil {
+			return cfg, "", fmt.Errorf("load config: %w", err)
 		}
-	}()
-
-	evalCtx, cancel := context.WithTimeout(ctx, *timeout)
-	defer cancel()
-
-	result, err := governor.Evaluate(evalCtx, aisentinel.DecisionRequest{ // nolint:exhaustruct
-		RulepackID: *rulepack,
-		Payload:    payload,
-	})
-	if err != nil {
-		log.Fatalf("evaluate: %v", err)
+		cfg = loaded
+		profile = cfg.Profile
+	} else if err := cfg.ApplyEnv(); err != nil {
+		return cfg, "", err
 	}
 
-	output := map[string]any{
-		"allowed":    result.Allowed,
-		"reason":     result.Reason,
-		"latency_ms": result.Latency.Milliseconds(),
+	prefix := cfg.EnvironmentPrefix
+	if prefix == "" {
+		prefix = "AISENTINEL_"
+	}
+	switch {
+	case os.Getenv(prefix+"API_KEY") != "":
+		return cfg, "environment (" + prefix + "API_KEY)", nil
+	case os.Getenv(prefix+"API_KEY_FILE") != "":
+		return cfg, "environment (" + prefix + "API_KEY_FILE)", nil
+	case cfg.APIKey != "":
+		return cfg, "config file " + path, nil
+	case cfg.APIKeyFile != "":
+		return cfg, "key file " + cfg.APIKeyFile, nil
 	}
+	if key, err := keyringFor(profile).FetchSecret(context.Background()); err == nil {
+		cfg.APIKey = key
+		return cfg, fmt.Sprintf("OS keyring (profile %q)", keyringFor(profile).Account), nil
+	}
+	if src, err := loadTokenSource(context.Background(), profile); err == nil {
+		key, err := src.FetchSecret(context.Background())
+		if err != nil {
+			return cfg, "", err
+		}
+		cfg.APIKey = key
+		return cfg, fmt.Sprintf("%s (profile %q)", loginKeySource, src.ring.Account), nil
+	}
+	return cfg, "", nil
+}
 
-	encoded, err := json.MarshalIndent(output, "", "  ")
-	if err != nil {
-		log.Fatalf("encode result: %v", err)
+// defaultConfigPath returns $XDG_CONFIG_HOME/aisentinel/config.yaml, falling
+// back to ~/.config/aisentinel/config.yaml on every platform so the location
+// is the same on macOS and Linux.
+func defaultConfigPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
 	}
-	fmt.Println(string(encoded))
+	return filepath.Join(dir, "aisentinel", "config.yaml")
 }
 
 const maxPayloadFileBytes int64 = 1 << 20 // 1 MiB
 
-func resolvePayload(inline, path string) (json.RawMessage, error) {
+// payloadLimits bound the payload files the CLI reads. The defaults keep them
+// small and inside the working directory; trusted local use can lift both.
+type payloadLimits struct {
+	maxBytes int64 // zero for no limit
+	anyPath  bool
+}
+
+var defaultPayloadLimits = payloadLimits{maxBytes: maxPayloadFileBytes}
+
+// resolvePayload returns the payload from path ("-" for stdin), else inline,
+// else the first argument, else an empty object.
+func resolvePayload(inline, path string, args []string, limits payloadLimits) (json.RawMessage, error) {
 	if path != "" {
-		data, err := loadPayloadFromFile(path)
+		data, err := loadPayloadFromFile(path, limits)
 		if err != nil {
 			return nil, err
 		}
@@ -114,8 +188,8 @@ func resolvePayload(inline, path string) (json.RawMessage, error) {
 	}
 
 	if inline == "" {
-		if flag.NArg() > 0 {
-			inline = flag.Arg(0)
+		if len(args) > 0 {
+			inline = args[0]
 		} else {
 			inline = "{}"
 		}
@@ -128,21 +202,77 @@ func resolvePayload(inline, path string) (json.RawMessage, error) {
 	return json.RawMessage(data), nil
 }
 
-func loadPayloadFromFile(path string) ([]byte, error) {
+func loadPayloadFromFile(path string, limits payloadLimits) ([]byte, error) {
 	if path == "" {
 		return nil, errors.New("payload file path is required")
 	}
+	if path == "-" {
+		return readPayload(os.Stdin, limits.maxBytes)
+	}
+
+	var resolved string
+	var err error
+	if limits.anyPath {
+		resolved, err = filepath.EvalSymlinks(filepath.Clean(path))
+	} else {
+		resolved, err = confineToWorkingDir(path)
+	}
+	if err != nil {
+		return nil, err
+	}
 
+	file, err := os.Open(resolved) // #nosec G304 -- confined to the working directory unless the operator lifted it
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if cerr := file.Close(); cerr != nil {
+			log.Printf("close payload file: %v", cerr)
+		}
+	}()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return nil, errors.New("payload file must be a regular file")
+	}
+	if limits.maxBytes > 0 && info.Size() > limits.maxBytes {
+		return nil, fmt.Errorf("payload file exceeds %d bytes", limits.maxBytes)
+	}
+	return readPayload(file, limits.maxBytes)
+}
+
+// readPayload reads r, failing when it holds more than maxBytes (zero for no
+// limit).
+func readPayload(r io.Reader, maxBytes int64) ([]byte, error) {
+	if maxBytes <= 0 {
+		return io.ReadAll(r)
+	}
+	data, err := io.ReadAll(io.LimitReader(r, maxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, fmt.Errorf("payload exceeds %d bytes", maxBytes)
+	}
+	return data, nil
+}
+
+// confineToWorkingDir resolves path and rejects it when it lies outside the
+// working directory.
+func confineToWorkingDir(path string) (string, error) {
 	cleaned := filepath.Clean(path)
 
 	cwd, err := os.Getwd()
 	if err != nil {
-		return nil, fmt.Errorf("determine working directory: %w", err)
+		return "", fmt.Errorf("determine working directory: %w", err)
 	}
 
 	base, err := filepath.EvalSymlinks(cwd)
 	if err != nil {
-		return nil, fmt.Errorf("resolve working directory: %w", err)
+		return "", fmt.Errorf("resolve working directory: %w", err)
 	}
 
 	var candidate string
@@ -154,41 +284,26 @@ func loadPayloadFromFile(path string) ([]byte, error) {
 
 	resolved, err := filepath.EvalSymlinks(candidate)
 	if err != nil {
-		return nil, fmt.Errorf("resolve payload file: %w", err)
+		return "", fmt.Errorf("resolve payload file: %w", err)
 	}
 
 	rel, err := filepath.Rel(base, resolved)
 	if err != nil {
-		return nil, fmt.Errorf("resolve payload file: %w", err)
+		return "", fmt.Errorf("resolve payload file: %w", err)
 	}
 	if rel == ".." || strings.HasPrefix(rel, ".."+string(os.PathSeparator)) {
-		return nil, errors.New("payload file must be within the working directory")
+		return "", errors.New("payload file must be within the working directory")
 	}
+	return resolved, nil
+}
 
-	file, err := os.Open(resolved) // #nosec G304 -- path sanitisation above confines access to the working directory
-	if err != nil {
-		return nil, err
-	}
-	defer func() {
-		if cerr := file.Close(); cerr != nil {
-			log.Printf("close payload file: %v", cerr)
-		}
-	}()
+// stringsFlag is a flag.Value collecting every occurrence of a repeatable
+// flag.
+type stringsFlag []string
 
-	info, err := file.Stat()
-	if err != nil {
-		return nil, err
-	}
-	if !info.Mode().IsRegular() {
-		return nil, errors.New("payload file must be a regular file")
-	}
-	if info.Size() > maxPayloadFileBytes {
-		return nil, fmt.Errorf("payload file exceeds %d bytes", maxPayloadFileBytes)
-	}
+func (s *stringsFlag) String() string { return strings.Join(*s, ",") }
 
-	data, err := io.ReadAll(io.LimitReader(file, maxPayloadFileBytes))
-	if err != nil {
-		return nil, err
-	}
-	return data, nil
+func (s *stringsFlag) Set(v string) error {
+	*s = append(*s, v)
+	return nil
 }
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats accepted by --output.
+const (
+	outputJSON  = "json"
+	outputJSONL = "jsonl"
+	outputYAML  = "yaml"
+	outputTable = "table"
+)
+
+var outputFormats = []string{outputJSON, outputJSONL, outputYAML, outputTable}
+
+func validateOutput(format string) error {
+	for _, f := range outputFormats {
+		if format == f {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown output format %q (want %s)", format, strings.Join(outputFormats, ", "))
+}
+
+// decisionOutput is the rendered form of a decision. Line is the 1-based input
+// line number in batch mode and zero otherwise; Source names the payload file
+// when several are evaluated.
+type decisionOutput struct {
+	Line      int    `json:"line,omitempty" yaml:"line,omitempty"`
+	Source    string `json:"source,omitempty" yaml:"source,omitempty"`
+	Allowed   bool   `json:"allowed" yaml:"allowed"`
+	RuleID    string `json:"rule_id,omitempty" yaml:"rule_id,omitempty"`
+	Reason    string `json:"reason,omitempty" yaml:"reason,omitempty"`
+	LatencyMS int64  `json:"latency_ms" yaml:"latency_ms"`
+	Error     string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// decisionWriter renders decisions one at a time so batch results can be
+// streamed. JSON output is the exception: batches are buffered and written
+// as a single array by flush.
+type decisionWriter struct {
+	w       io.Writer
+	format  string
+	batch   bool
+	written int
+	pending []decisionOutput
+
+	// sourceWidth, when set, replaces the table's line column with a source
+	// column this wide.
+	sourceWidth int
+
+	// denied and failed count the decisions written, for --fail-on-deny.
+	denied, failed int
+}
+
+func (d *decisionWriter) write(r decisionOutput) error {
+	defer func() { d.written++ }()
+	switch {
+	case r.Error != "":
+		d.failed++
+	case !r.Allowed:
+		d.denied++
+	}
+	switch d.format {
+	case outputJSON:
+		if d.batch {
+			d.pending = append(d.pending, r)
+			return nil
+		}
+		return writeIndentedJSON(d.w, r)
+	case outputYAML:
+		if d.written > 0 {
+			if _, err := io.WriteString(d.w, "---\n"); err != nil {
+				return err
+			}
+		}
+		return yaml.NewEncoder(d.w).Encode(r)
+	case outputTable:
+		if d.written == 0 {
+			first := "LINE"
+			if d.sourceWidth > 0 {
+				first = "SOURCE"
+			}
+			if err := d.row(first, "DECISION", "RULE", "LATENCY", "REASON"); err != nil {
+				return err
+			}
+		}
+		decision, reason := "deny", r.Reason
+		if r.Allowed {
+			decision = "allow"
+		}
+		if r.Error != "" {
+			decision, reason = "error", r.Error
+		}
+		rule := r.RuleID
+		if rule == "" {
+			rule = "-"
+		}
+		first := fmt.Sprint(r.Line)
+		if d.sourceWidth > 0 {
+			first = r.Source
+		}
+		return d.row(first, decision, rule, fmt.Sprintf("%dms", r.LatencyMS), reason)
+	default:
+		return json.NewEncoder(d.w).Encode(r)
+	}
+}
+
+// row writes a fixed-width table row; the line or source column is only
+// shown for batches.
+func (d *decisionWriter) row(line, decision, rule, latency, reason string) error {
+	var err error
+	if d.batch {
+		_, err = fmt.Fprintf(d.w, "%-*s %-8s %-20s %8s  %s\n", max(6, d.sourceWidth), line, decision, rule, latency, reason)
+	} else {
+		_, err = fmt.Fprintf(d.w, "%-8s %-20s %8s  %s\n", decision, rule, latency, reason)
+	}
+	return err
+}
+
+func (d *decisionWriter) flush() error {
+	if d.format != outputJSON || !d.batch {
+		return nil
+	}
+	if d.pending == nil {
+		d.pending = []decisionOutput{}
+	}
+	return writeIndentedJSON(d.w, d.pending)
+}
+
+func writeIndentedJSON(w io.Writer, v any) error {
+	encoded, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(encoded))
+	return err
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+const payloadUsage = `usage: aisentinel-go-sdk payload <command> [flags]
+
+commands:
+  init     write a payload scaffolded from a template`
+
+const (
+	defaultTemplatePrompt = "Summarise the attached customer email and draft a reply."
+	defaultTemplateSystem = "You are a helpful assistant."
+)
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type openAIChatRequest struct {
+	Model       string        `json:"model"`
+	Messages    []chatMessage `json:"messages"`
+	Temperature float64       `json:"temperature"`
+	MaxTokens   int           `json:"max_tokens"`
+	User        string        `json:"user"`
+}
+
+type anthropicMessagesRequest struct {
+	Model     string        `json:"model"`
+	MaxTokens int           `json:"max_tokens"`
+	System    string        `json:"system,omitempty"`
+	Messages  []chatMessage `json:"messages"`
+	Metadata  struct {
+		UserID string `json:"user_id"`
+	} `json:"metadata"`
+}
+
+// payloadTemplates build realistic request bodies of common LLM APIs around a
+// system and user prompt, so rules can be tested against real shapes.
+var payloadTemplates = map[string]func(system, prompt string) any{
+	"openai-chat": func(system, prompt string) any {
+		req := openAIChatRequest{Model: "gpt-4o", Temperature: 0.7, MaxTokens: 512, User: "user-1234"}
+		if system != "" {
+			req.Messages = append(req.Messages, chatMessage{Role: "system", Content: system})
+		}
+		req.Messages = append(req.Messages, chatMessage{Role: "user", Content: prompt})
+		return req
+	},
+	"anthropic-messages": func(system, prompt string) any {
+		req := anthropicMessagesRequest{Model: "claude-sonnet-4-5", MaxTokens: 1024, System: system}
+		req.Messages = []chatMessage{{Role: "user", Content: prompt}}
+		req.Metadata.UserID = "user-1234"
+		return req
+	},
+}
+
+func templateNames() []string {
+	names := make([]string, 0, len(payloadTemplates))
+	for name := range payloadTemplates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// templatePayload renders the named template. An empty prompt uses a sample.
+func templatePayload(name, system, prompt string) (json.RawMessage, error) {
+	build, ok := payloadTemplates[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown template %q (want %s)", name, strings.Join(templateNames(), ", "))
+	}
+	if prompt == "" {
+		prompt = defaultTemplatePrompt
+	}
+	return json.MarshalIndent(build(system, prompt), "", "  ")
+}
+
+// applyTemplate replaces the payload flags with the rendered template when
+// --template is set, taking the prompt from the positional arguments.
+func applyTemplate(template string, payloadInline *string, payloadFile string, args []string) error {
+	if template == "" {
+		return nil
+	}
+	if *payloadInline != "" || payloadFile != "" {
+		return errors.New("--template cannot be combined with --payload or --payload-file")
+	}
+	payload, err := templatePayload(template, defaultTemplateSystem, strings.Join(args, " "))
+	if err != nil {
+		return err
+	}
+	*payloadInline = string(payload)
+	return nil
+}
+
+// runPayload implements the payload subcommands.
+func runPayload(args []string) error {
+	if len(args) == 0 {
+		return errors.New(payloadUsage)
+	}
+	cmd, args := args[0], args[1:]
+	if cmd != "init" {
+		return fmt.Errorf("unknown payload command %q\n%s", cmd, payloadUsage)
+	}
+
+	fs := flag.NewFlagSet("payload init", flag.ContinueOnError)
+	template := fs.String("template", "openai-chat", "Payload shape: "+strings.Join(templateNames(), " or "))
+	prompt := fs.String("prompt", "", "User prompt to embed (default: a sample prompt; also the positional arguments)")
+	system := fs.String("system", defaultTemplateSystem, "System prompt to embed (empty to omit)")
+	output := fs.String("o", "-", "Output file (- for stdout)")
+	force := fs.Bool("force", false, "Overwrite an existing output file")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if *prompt == "" {
+		*prompt = strings.Join(fs.Args(), " ")
+	}
+	payload, err := templatePayload(*template, *system, *prompt)
+	if err != nil {
+		return err
+	}
+	payload = append(payload, '\n')
+	if *output == "-" {
+		_, err := os.Stdout.Write(payload)
+		return err
+	}
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if !*force {
+		flags |= os.O_EXCL
+	}
+	f, err := os.OpenFile(*output, flags, 0o600) // #nosec G304 -- operator supplied output path
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return fmt.Errorf("%s already exists; use --force to overwrite", *output)
+		}
+		return err
+	}
+	if _, err := f.Write(payload); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "wrote %s payload to %s\n", *template, *output)
+	return nil
+}
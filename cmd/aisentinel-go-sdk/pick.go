@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	aisentinel "github.com/mfifth/aisentinel-go-sdk"
+)
+
+// pickerRows is how many candidates the rulepack picker lists at once.
+const pickerRows = 10
+
+// resolveRulepack returns the rulepack to evaluate when --rulepack is
+// omitted: the config default_rulepack_id, else one picked interactively
+// from the control plane when stdin and stderr are terminals, else
+// "default".
+func resolveRulepack(ctx context.Context, governor *aisentinel.Governor) (string, error) {
+	if id := governor.Config().DefaultRulepackID; id != "" {
+		return id, nil
+	}
+	if governor.Config().OfflineMode || !isTerminal(os.Stdin) || !isTerminal(os.Stderr) {
+		return "default", nil
+	}
+	packs, err := governor.ListRulepacks(ctx)
+	if err != nil || len(packs) == 0 {
+		return "default", nil
+	}
+	return pickRulepack(os.Stdin, os.Stderr, packs)
+}
+
+// pickRulepack asks the user to choose a rulepack. Typing text narrows the
+// list with a fuzzy match, a number selects that row, and Enter accepts the
+// only remaining candidate.
+func pickRulepack(in io.Reader, out io.Writer, packs []aisentinel.RulepackSummary) (string, error) {
+	scanner := bufio.NewScanner(in)
+	matches := packs
+	for {
+		shown := min(len(matches), pickerRows)
+		for i, p := range matches[:shown] {
+			fmt.Fprintf(out, "%3d) %s\n", i+1, p.ID)
+		}
+		if rest := len(matches) - shown; rest > 0 {
+			fmt.Fprintf(out, "     ... %d more; type to filter\n", rest)
+		}
+		fmt.Fprint(out, "rulepack> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+			if err := scanner.Err(); err != nil {
+				return "", err
+			}
+			return "", errors.New("no rulepack selected")
+		}
+		input := strings.TrimSpace(scanner.Text())
+		if input == "" && len(matches) == 1 {
+			return matches[0].ID, nil
+		}
+		if n, err := strconv.Atoi(input); err == nil && n >= 1 && n <= shown {
+			return matches[n-1].ID, nil
+		}
+		if input == "" {
+			continue
+		}
+		if filtered := fuzzyFilter(input, packs); len(filtered) > 0 {
+			matches = filtered
+		} else {
+			fmt.Fprintf(out, "no rulepack matches %q\n", input)
+			matches = packs
+		}
+	}
+}
+
+// fuzzyFilter returns the packs whose ID contains query as a subsequence,
+// best match first.
+func fuzzyFilter(query string, packs []aisentinel.RulepackSummary) []aisentinel.RulepackSummary {
+	type scored struct {
+		pack  aisentinel.RulepackSummary
+		score int
+	}
+	var hits []scored
+	for _, p := range packs {
+		if score, ok := fuzzyScore(query, p.ID); ok {
+			hits = append(hits, scored{p, score})
+		}
+	}
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].score != hits[j].score {
+			return hits[i].score > hits[j].score
+		}
+		return len(hits[i].pack.ID) < len(hits[j].pack.ID)
+	})
+	out := make([]aisentinel.RulepackSummary, len(hits))
+	for i, h := range hits {
+		out[i] = h.pack
+	}
+	return out
+}
+
+// fuzzyScore reports whether pattern is a case-insensitive subsequence of s.
+// Matches at the start, after a separator or right after the previous match
+// score higher.
+func fuzzyScore(pattern, s string) (int, bool) {
+	pattern, s = strings.ToLower(pattern), strings.ToLower(s)
+	score, last, pi := 0, -2, 0
+	for i := 0; i < len(s) && pi < len(pattern); i++ {
+		if s[i] != pattern[pi] {
+			continue
+		}
+		switch {
+		case i == 0:
+			score += 3
+		case last == i-1, strings.IndexByte("-_./ ", s[i-1]) >= 0:
+			score += 2
+		default:
+			score++
+		}
+		last, pi = i, pi+1
+	}
+	return score, pi == len(pattern)
+}
+
+func writeRulepackList(w io.Writer, packs []aisentinel.RulepackSummary, format string) error {
+	switch format {
+	case outputJSON:
+		return writeIndentedJSON(w, packs)
+	case outputTable:
+	default:
+		return fmt.Errorf("unknown output format %q (want table or json)", format)
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tVERSION\tUPDATED")
+	for _, p := range packs {
+		updated := "-"
+		if !p.UpdatedAt.IsZero() {
+			updated = p.UpdatedAt.Local().Format("2006-01-02 15:04")
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", p.ID, ruleOrDash(p.Version), updated)
+	}
+	return tw.Flush()
+}
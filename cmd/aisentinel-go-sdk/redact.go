@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mfifth/aisentinel-go-sdk/pii"
+)
+
+// Input formats accepted by redact --format.
+const (
+	redactAuto  = "auto"
+	redactText  = "text"
+	redactJSON  = "json"
+	redactJSONL = "jsonl"
+)
+
+// runRedact strips PII from text, JSON or JSONL input. JSON string values are
+// redacted in place so the document structure survives, which keeps datasets
+// usable for fine-tuning.
+func runRedact(args []string) error {
+	fs := flag.NewFlagSet("redact", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: aisentinel-go-sdk redact [flags] [file|-]")
+		fs.PrintDefaults()
+	}
+	strategyName := fs.String("strategy", string(pii.StrategyPlaceholder), "Replacement strategy: placeholder, mask, hash or remove")
+	format := fs.String("format", redactAuto, "Input format: auto, text, json or jsonl")
+	output := fs.String("o", "-", "Output file (- for stdout)")
+	report := fs.Bool("report", false, "Print a summary of findings by type to stderr")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if fs.NArg() > 1 {
+		return errors.New("usage: redact [flags] [file|-]")
+	}
+	strategy, err := pii.ParseStrategy(*strategyName)
+	if err != nil {
+		return err
+	}
+	switch *format {
+	case redactAuto, redactText, redactJSON, redactJSONL:
+	default:
+		return fmt.Errorf("unknown input format %q (want auto, text, json or jsonl)", *format)
+	}
+
+	path := fs.Arg(0)
+	var in io.Reader = os.Stdin
+	if path != "" && path != "-" {
+		f, err := os.Open(path) // #nosec G304 -- operator supplied input file
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+		if *format == redactAuto {
+			switch strings.ToLower(filepath.Ext(path)) {
+			case ".jsonl", ".ndjson":
+				*format = redactJSONL
+			}
+		}
+	}
+
+	var out io.Writer = os.Stdout
+	if *output != "-" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	bw := bufio.NewWriter(out)
+
+	r := &redactor{detector: pii.New(), strategy: strategy, counts: map[pii.Kind]int{}}
+	if err := r.run(in, bw, *format); err != nil {
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	if *report {
+		r.writeReport(os.Stderr)
+	}
+	return nil
+}
+
+// redactor applies a strategy and tallies findings by kind.
+type redactor struct {
+	detector *pii.Detector
+	strategy pii.Strategy
+	counts   map[pii.Kind]int
+}
+
+func (r *redactor) run(in io.Reader, out io.Writer, format string) error {
+	if format == redactJSONL {
+		return r.jsonLines(in, out)
+	}
+	data, err := io.ReadAll(in)
+	if err != nil {
+		return err
+	}
+	if format == redactAuto {
+		format = detectRedactFormat(data)
+	}
+	switch format {
+	case redactJSON:
+		return r.jsonDocument(data, out)
+	case redactJSONL:
+		return r.jsonLines(bytes.NewReader(data), out)
+	default:
+		_, err := io.WriteString(out, r.text(string(data)))
+		return err
+	}
+}
+
+// detectRedactFormat picks json for a single valid document, jsonl when every
+// non-blank line is valid JSON, and text otherwise.
+func detectRedactFormat(data []byte) string {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 || (trimmed[0] != '{' && trimmed[0] != '[') {
+		return redactText
+	}
+	if json.Valid(trimmed) {
+		return redactJSON
+	}
+	for _, line := range bytes.Split(trimmed, []byte("\n")) {
+		if line = bytes.TrimSpace(line); len(line) > 0 && !json.Valid(line) {
+			return redactText
+		}
+	}
+	return redactJSONL
+}
+
+func (r *redactor) text(s string) string {
+	redacted, findings := r.detector.Redact(s, r.strategy)
+	for _, f := range findings {
+		r.counts[f.Kind]++
+	}
+	return redacted
+}
+
+func (r *redactor) jsonDocument(data []byte, out io.Writer) error {
+	redacted, err := r.jsonValue(data)
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(redacted)
+	return err
+}
+
+// jsonLines redacts one JSON document per line. Blank lines are kept so line
+// numbers still match the input.
+func (r *redactor) jsonLines(in io.Reader, out io.Writer) error {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			if _, err := io.WriteString(out, "\n"); err != nil {
+				return err
+			}
+			continue
+		}
+		redacted, err := r.jsonValue(raw)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		if _, err := out.Write(redacted); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// jsonValue redacts every string in a JSON document and re-encodes it on a
+// single line. Numbers are preserved verbatim.
+func (r *redactor) jsonValue(data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("decode json: %w", err)
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(r.walk(v)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (r *redactor) walk(v any) any {
+	switch v := v.(type) {
+	case string:
+		return r.text(v)
+	case []any:
+		for i := range v {
+			v[i] = r.walk(v[i])
+		}
+	case map[string]any:
+		for k := range v {
+			v[k] = r.walk(v[k])
+		}
+	}
+	return v
+}
+
+// writeReport prints finding counts by type, most frequent first.
+func (r *redactor) writeReport(w io.Writer) {
+	kinds := make([]pii.Kind, 0, len(r.counts))
+	total := 0
+	for kind, n := range r.counts {
+		kinds = append(kinds, kind)
+		total += n
+	}
+	sort.Slice(kinds, func(i, j int) bool {
+		if r.counts[kinds[i]] != r.counts[kinds[j]] {
+			return r.counts[kinds[i]] > r.counts[kinds[j]]
+		}
+		return kinds[i] < kinds[j]
+	})
+	fmt.Fprintf(w, "%-12s %8s\n", "TYPE", "COUNT")
+	for _, kind := range kinds {
+		fmt.Fprintf(w, "%-12s %8d\n", kind, r.counts[kind])
+	}
+	fmt.Fprintf(w, "%-12s %8d\n", "total", total)
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	aisentinel "github.com/mfifth/aisentinel-go-sdk"
+)
+
+const replHelp = `Enter a JSON object to evaluate it, or plain text to evaluate it as the
+--field value. JSON may span several lines.
+
+commands:
+  :explain [payload]  show the rule-by-rule trace (default: the last payload)
+  :rulepack [id]      show or switch the rulepack (control plane only)
+  :field [name]       show or change the field plain text is assigned to
+  :reload             re-read --rulepack-file
+  :help               show this help
+  :quit               leave (also Ctrl-D)`
+
+// runREPL starts an interactive evaluation session.
+func runREPL(args []string) error {
+	fs := flag.NewFlagSet("repl", flag.ContinueOnError)
+	configFile := fs.String("config", "", configFlagUsage)
+	profile := fs.String("profile", "", "Config file profile to apply")
+	rulepack := fs.String("rulepack", "", "Rulepack identifier to evaluate (default: the config default_rulepack_id, else \"default\")")
+	rulepackFile := fs.String("rulepack-file", "", "Evaluate against a local rulepack file instead of the control plane")
+	field := fs.String("field", "prompt", "Payload field plain text lines are assigned to")
+	offline := fs.Bool("offline", false, "Enable offline evaluation mode")
+	timeout := fs.Duration("timeout", 15*time.Second, "Timeout for each evaluation")
+	noColor := fs.Bool("no-color", false, "Disable colored output (also NO_COLOR)")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+
+	r := &repl{
+		in:      os.Stdin,
+		out:     os.Stdout,
+		field:   *field,
+		timeout: *timeout,
+		color:   useColor(os.Stdout, *noColor),
+		prompt:  isTerminal(os.Stdin),
+	}
+	ctx := context.Background()
+	if *rulepackFile != "" {
+		local, err := loadLocalRulepack(*rulepackFile)
+		if err != nil {
+			return err
+		}
+		if *rulepack != "" && *rulepack != local.pack.ID {
+			return fmt.Errorf("%s defines rulepack %q, not %q", *rulepackFile, local.pack.ID, *rulepack)
+		}
+		r.local, r.rulepack, r.decider, r.explainer = local, local.pack.ID, local, local
+	} else {
+		cfg, err := loadConfig(*configFile, *profile)
+		if err != nil {
+			return err
+		}
+		if *offline {
+			cfg.OfflineMode = true
+		}
+		governor, err := aisentinel.NewGovernor(ctx, cfg)
+		if err != nil {
+			return fmt.Errorf("initialise governor: %w", err)
+		}
+		defer func() {
+			if cerr := governor.Close(); cerr != nil {
+				log.Printf("close governor: %v", cerr)
+			}
+		}()
+		if *rulepack == "" {
+			if *rulepack, err = resolveRulepack(ctx, governor); err != nil {
+				return err
+			}
+		}
+		r.rulepack, r.decider, r.explainer = *rulepack, governor, governor
+	}
+	if r.prompt {
+		fmt.Fprintf(r.out, "aisentinel %s repl on rulepack %s; :help for commands\n", buildVersion, r.rulepack)
+	}
+	return r.run(ctx)
+}
+
+// repl is an interactive session. Prompts are only written when prompt is
+// set, so piped sessions produce clean output.
+type repl struct {
+	in        io.Reader
+	out       io.Writer
+	decider   decider
+	explainer explainer
+	local     *localRulepack
+	rulepack  string
+	field     string
+	timeout   time.Duration
+	color     bool
+	prompt    bool
+
+	last json.RawMessage
+}
+
+func (r *repl) run(ctx context.Context) error {
+	scanner := bufio.NewScanner(r.in)
+	scanner.Buffer(make([]byte, 64*1024), int(maxPayloadFileBytes))
+	var pending strings.Builder
+	for {
+		if r.prompt {
+			if pending.Len() > 0 {
+				fmt.Fprint(r.out, "... ")
+			} else {
+				fmt.Fprintf(r.out, "%s> ", r.rulepack)
+			}
+		}
+		if !scanner.Scan() {
+			if r.prompt {
+				fmt.Fprintln(r.out)
+			}
+			return scanner.Err()
+		}
+		line := scanner.Text()
+
+		// Continue a multi-line JSON document until it parses; a blank line
+		// abandons it.
+		if pending.Len() > 0 {
+			if strings.TrimSpace(line) == "" {
+				r.errorf("incomplete JSON discarded")
+				pending.Reset()
+				continue
+			}
+			pending.WriteString("\n" + line)
+			if !json.Valid([]byte(pending.String())) {
+				continue
+			}
+			line = pending.String()
+			pending.Reset()
+		}
+
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			continue
+		case strings.HasPrefix(trimmed, ":"):
+			if quit := r.command(ctx, trimmed); quit {
+				return nil
+			}
+			continue
+		case strings.HasPrefix(trimmed, "{") && !json.Valid([]byte(trimmed)):
+			pending.WriteString(trimmed)
+			continue
+		}
+
+		payload, err := r.payload(trimmed)
+		if err != nil {
+			r.errorf("%v", err)
+			continue
+		}
+		r.last = payload
+		r.evaluate(ctx, payload)
+	}
+}
+
+// payload turns input into a JSON payload: objects are used as is and
+// anything else becomes the value of the configured field.
+func (r *repl) payload(input string) (json.RawMessage, error) {
+	if strings.HasPrefix(input, "{") {
+		var doc map[string]any
+		if err := json.Unmarshal([]byte(input), &doc); err != nil {
+			return nil, fmt.Errorf("payload must be a JSON object: %w", err)
+		}
+		return json.RawMessage(input), nil
+	}
+	return json.Marshal(map[string]string{r.field: input})
+}
+
+func (r *repl) evaluate(ctx context.Context, payload json.RawMessage) {
+	evalCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	result, err := r.decider.Evaluate(evalCtx, aisentinel.DecisionRequest{RulepackID: r.rulepack, Payload: payload}) // nolint:exhaustruct
+	if err != nil {
+		r.errorf("%v", err)
+		return
+	}
+	decision, color := "DENY ", ansiRed
+	if result.Allowed {
+		decision, color = "ALLOW", ansiGreen
+	}
+	if r.color {
+		decision = ansiBold + color + decision + ansiReset
+	}
+	reason := ""
+	if result.Reason != "" {
+		reason = "  " + result.Reason
+	}
+	fmt.Fprintf(r.out, "%s  rule=%s%s  (%s)\n", decision, ruleOrDash(result.RuleID), reason, result.Latency.Round(time.Microsecond))
+}
+
+// command runs a ":" command and reports whether the session should end.
+func (r *repl) command(ctx context.Context, input string) (quit bool) {
+	name, arg, _ := strings.Cut(input, " ")
+	arg = strings.TrimSpace(arg)
+	switch name {
+	case ":quit", ":q", ":exit":
+		return true
+	case ":help", ":h":
+		fmt.Fprintln(r.out, replHelp)
+	case ":explain", ":e":
+		payload := r.last
+		if arg != "" {
+			var err error
+			if payload, err = r.payload(arg); err != nil {
+				r.errorf("%v", err)
+				return false
+			}
+		}
+		if payload == nil {
+			r.errorf("nothing to explain yet; enter a payload first")
+			return false
+		}
+		explainCtx, cancel := context.WithTimeout(ctx, r.timeout)
+		defer cancel()
+		explanation, err := r.explainer.Explain(explainCtx, aisentinel.DecisionRequest{RulepackID: r.rulepack, Payload: payload}) // nolint:exhaustruct
+		if err != nil {
+			r.errorf("%v", err)
+			return false
+		}
+		if err := writeExplainTree(r.out, explanation, r.color); err != nil {
+			r.errorf("%v", err)
+		}
+	case ":rulepack":
+		switch {
+		case arg == "":
+			fmt.Fprintln(r.out, r.rulepack)
+		case r.local != nil:
+			r.errorf("the rulepack is fixed by --rulepack-file")
+		default:
+			r.rulepack = arg
+		}
+	case ":field":
+		if arg == "" {
+			fmt.Fprintln(r.out, r.field)
+		} else {
+			r.field = arg
+		}
+	case ":reload":
+		if r.local == nil {
+			r.errorf(":reload needs --rulepack-file")
+			return false
+		}
+		if err := r.local.reload(); err != nil {
+			r.errorf("%v", err)
+			return false
+		}
+		fmt.Fprintf(r.out, "reloaded %s (%d rules)\n", r.local.path, len(r.local.pack.Rules))
+	default:
+		r.errorf("unknown command %s; :help lists commands", name)
+	}
+	return false
+}
+
+func (r *repl) errorf(format string, args ...any) {
+	msg := "error: " + fmt.Sprintf(format, args...)
+	if r.color {
+		msg = ansiRed + msg + ansiReset
+	}
+	fmt.Fprintln(r.out, msg)
+}
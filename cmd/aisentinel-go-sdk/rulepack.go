@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"time"
+
+	aisentinel "github.com/mfifth/aisentinel-go-sdk"
+)
+
+const rulepackUsage = `usage: aisentinel-go-sdk rulepack <command> [flags]
+
+commands:
+  list             list the rulepacks available on the control plane
+  pull <id>        download a rulepack and save it to disk
+  push <file>      lint a rulepack file and upload it
+  validate <file>  lint a rulepack file without uploading it
+  diff <a> <b>     show rule changes between two rulepack files
+  gen <file>       generate a typed Go payload struct for a rulepack file`
+
+// errDiffFound makes `rulepack diff` exit non-zero when the packs differ, so
+// CI pipelines can gate on it.
+var errDiffFound = errors.New("rulepacks differ")
+
+// runRulepack implements the rulepack subcommands.
+func runRulepack(args []string) error {
+	if len(args) == 0 {
+		return errors.New(rulepackUsage)
+	}
+	cmd, args := args[0], args[1:]
+
+	fs := flag.NewFlagSet("rulepack "+cmd, flag.ContinueOnError)
+	configFile := fs.String("config", "", configFlagUsage)
+	profile := fs.String("profile", "", "Config file profile to apply")
+	timeout := fs.Duration("timeout", 30*time.Second, "Timeout for control plane requests")
+	var output *string
+	switch cmd {
+	case "pull":
+		output = fs.String("o", "", "Output file (default <id>.json, - for stdout)")
+	case "list":
+		output = fs.String("output", outputTable, "Output format: table or json")
+	case "gen":
+		output = fs.String("o", "-", "Output file (- for stdout)")
+	}
+	pkg := fs.String("package", "policy", "Package name of the generated file (gen)")
+	typeName := fs.String("type", "", "Name of the generated struct (gen, default <ID>Payload)")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+
+	switch cmd {
+	case "validate":
+		if fs.NArg() != 1 {
+			return errors.New("usage: rulepack validate <file>")
+		}
+		warnings, err := lintBeforePublish(os.Stdout, fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		pack, err := aisentinel.LoadRulepackFile(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		if warnings > 0 {
+			fmt.Printf("%s: ok (%d rules, %d warnings)\n", fs.Arg(0), len(pack.Rules), warnings)
+			return nil
+		}
+		fmt.Printf("%s: ok (%d rules)\n", fs.Arg(0), len(pack.Rules))
+		return nil
+
+	case "diff":
+		if fs.NArg() != 2 {
+			return errors.New("usage: rulepack diff <a> <b>")
+		}
+		a, err := aisentinel.LoadRulepackFile(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		b, err := aisentinel.LoadRulepackFile(fs.Arg(1))
+		if err != nil {
+			return err
+		}
+		changes := aisentinel.DiffRulepacks(a, b)
+		printDiff(os.Stdout, changes)
+		if len(changes) > 0 {
+			return errDiffFound
+		}
+		return nil
+
+	case "gen":
+		if fs.NArg() != 1 {
+			return errors.New("usage: rulepack gen [--package name] [--type name] [-o file] <file>")
+		}
+		pack, err := aisentinel.LoadRulepackFile(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		src, err := generateTypes(pack, *pkg, *typeName)
+		if err != nil {
+			return err
+		}
+		if *output == "-" {
+			_, err = os.Stdout.Write(src)
+			return err
+		}
+		return os.WriteFile(*output, src, 0o644) // #nosec G306 -- generated source
+
+	case "list", "pull", "push":
+		if cmd == "list" && fs.NArg() != 0 {
+			return errors.New("usage: rulepack list")
+		}
+		if cmd != "list" && fs.NArg() != 1 {
+			return fmt.Errorf("usage: rulepack %s <%s>", cmd, map[string]string{"pull": "id", "push": "file"}[cmd])
+		}
+		if cmd == "push" {
+			if _, err := lintBeforePublish(os.Stderr, fs.Arg(0)); err != nil {
+				return err
+			}
+		}
+		cfg, err := loadConfig(*configFile, *profile)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+		defer cancel()
+		governor, err := aisentinel.NewGovernor(ctx, cfg)
+		if err != nil {
+			return fmt.Errorf("initialise governor: %w", err)
+		}
+		defer governor.Close()
+
+		if cmd == "list" {
+			packs, err := governor.ListRulepacks(ctx)
+			if err != nil {
+				return err
+			}
+			return writeRulepackList(os.Stdout, packs, *output)
+		}
+		if cmd == "push" {
+			pack, err := aisentinel.LoadRulepackFile(fs.Arg(0))
+			if err != nil {
+				return err
+			}
+			if err := governor.PushRulepack(ctx, pack); err != nil {
+				return err
+			}
+			fmt.Printf("pushed %s (%d rules)\n", pack.ID, len(pack.Rules))
+			return nil
+		}
+		pack, err := governor.FetchRulepack(ctx, fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		return writeRulepack(pack, *output)
+
+	default:
+		return fmt.Errorf("unknown rulepack command %q\n%s", cmd, rulepackUsage)
+	}
+}
+
+func writeRulepack(pack *aisentinel.Rulepack, path string) error {
+	data, err := json.MarshalIndent(pack, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	switch path {
+	case "-":
+		_, err = os.Stdout.Write(data)
+		return err
+	case "":
+		path = pack.ID + ".json"
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return err
+	}
+	fmt.Printf("saved %s to %s\n", pack.ID, path)
+	return nil
+}
+
+func printDiff(w io.Writer, changes []aisentinel.RuleChange) {
+	for _, c := range changes {
+		switch c.Kind {
+		case aisentinel.ChangeAdded:
+			fmt.Fprintf(w, "+ %s %s %s\n", c.RuleID, ruleCondition(*c.After), ruleEffect(*c.After))
+		case aisentinel.ChangeRemoved:
+			fmt.Fprintf(w, "- %s %s %s\n", c.RuleID, ruleCondition(*c.Before), ruleEffect(*c.Before))
+		case aisentinel.ChangeModified:
+			fmt.Fprintf(w, "~ %s\n", c.RuleID)
+			if c.Before.Operator == "" && c.After.Operator == "" && !composite(*c.Before) && !composite(*c.After) {
+				if c.Before.Pattern != c.After.Pattern {
+					fmt.Fprintf(w, "    pattern: %q -> %q\n", c.Before.Pattern, c.After.Pattern)
+				}
+			} else if before, after := ruleCondition(*c.Before), ruleCondition(*c.After); before != after {
+				fmt.Fprintf(w, "    %s -> %s\n", before, after)
+			}
+			if c.Before.Field != c.After.Field {
+				fmt.Fprintf(w, "    field: %q -> %q\n", c.Before.Field, c.After.Field)
+			}
+			if c.Before.Action == "" && c.After.Action == "" {
+				if c.Before.Allow != c.After.Allow {
+					fmt.Fprintf(w, "    allow: %t -> %t\n", c.Before.Allow, c.After.Allow)
+				}
+			} else if c.Before.Effect() != c.After.Effect() {
+				fmt.Fprintf(w, "    action: %s -> %s\n", c.Before.Effect(), c.After.Effect())
+			}
+			if !maps.Equal(c.Before.Params, c.After.Params) {
+				fmt.Fprintf(w, "    params: %v -> %v\n", c.Before.Params, c.After.Params)
+			}
+			if c.Before.Priority != c.After.Priority {
+				fmt.Fprintf(w, "    priority: %d -> %d\n", c.Before.Priority, c.After.Priority)
+			}
+			if c.Before.Description != c.After.Description {
+				fmt.Fprintf(w, "    description: %q -> %q\n", c.Before.Description, c.After.Description)
+			}
+		}
+	}
+}
+
+// ruleCondition describes what a rule matches: pattern="..." for patterns,
+// the operator and its JSON value, or the conditions as JSON.
+func ruleCondition(rule aisentinel.RuleDefinition) string {
+	if composite(rule) {
+		data, err := json.Marshal(aisentinel.Condition{All: rule.All, Any: rule.Any, Not: rule.Not})
+		if err != nil {
+			return "conditions=?"
+		}
+		return "conditions=" + string(data)
+	}
+	return conditionString(rule.Pattern, rule.Operator, rule.Value)
+}
+
+// ruleEffect describes what a rule does: allow=true or allow=false, or the
+// action it sets.
+func ruleEffect(rule aisentinel.RuleDefinition) string {
+	if rule.Action == "" {
+		return fmt.Sprintf("allow=%t", rule.Allow)
+	}
+	return "action=" + string(rule.Action)
+}
+
+// composite reports whether rule matches through conditions.
+func composite(rule aisentinel.RuleDefinition) bool {
+	return len(rule.All) > 0 || len(rule.Any) > 0 || rule.Not != nil
+}
+
+func conditionString(pattern string, op aisentinel.Operator, value any) string {
+	if op == "" {
+		return fmt.Sprintf("pattern=%q", pattern)
+	}
+	if value == nil {
+		return "operator=" + string(op)
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("operator=%s value=%v", op, value)
+	}
+	return fmt.Sprintf("operator=%s value=%s", op, data)
+}
@@ -0,0 +1,337 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mfifth/aisentinel-go-sdk/pii"
+)
+
+// Finding categories reported by scan.
+const (
+	categoryPII    = "pii"
+	categorySecret = "secret"
+)
+
+const outputSARIF = "sarif"
+
+// scanFinding is a PII or secret match in a file. Columns are 1-based and
+// count characters; Match is masked so reports do not leak what they found.
+type scanFinding struct {
+	Path      string   `json:"path"`
+	Line      int      `json:"line"`
+	Column    int      `json:"column"`
+	EndColumn int      `json:"end_column"`
+	Kind      pii.Kind `json:"kind"`
+	Category  string   `json:"category"`
+	Match     string   `json:"match"`
+}
+
+// runScan walks files and directories for PII and secrets. It exits 1 when
+// the number of findings exceeds --threshold and 2 when the scan fails, so it
+// can gate CI.
+func runScan(args []string) (err error) {
+	fs := flag.NewFlagSet("scan", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: aisentinel-go-sdk scan [flags] <path>...")
+		fs.PrintDefaults()
+	}
+	format := fs.String("format", outputTable, "Output format: table, json or sarif")
+	output := fs.String("o", "-", "Output file (- for stdout)")
+	threshold := fs.Int("threshold", 0, "Exit 1 when there are more findings than this")
+	scanPII := fs.Bool("pii", true, "Report PII such as emails and card numbers")
+	scanSecrets := fs.Bool("secrets", true, "Report secrets such as access keys and tokens")
+	maxSize := fs.Int64("max-size", 1<<20, "Skip files larger than this many bytes")
+	noIgnore := fs.Bool("no-ignore", false, "Do not read .gitignore and .aisentinelignore files")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	defer func() {
+		var exit *exitError
+		if err != nil && !errors.As(err, &exit) {
+			err = &exitError{code: 2, err: err}
+		}
+	}()
+	if fs.NArg() == 0 {
+		return errors.New("usage: scan [flags] <path>...")
+	}
+	switch *format {
+	case outputTable, outputJSON, outputSARIF:
+	default:
+		return fmt.Errorf("unknown output format %q (want table, json or sarif)", *format)
+	}
+	if !*scanPII && !*scanSecrets {
+		return errors.New("nothing to scan: both --pii and --secrets are disabled")
+	}
+
+	s := &scanner{
+		detector: pii.New(),
+		pii:      *scanPII,
+		secrets:  *scanSecrets,
+		maxSize:  *maxSize,
+		ignore:   !*noIgnore,
+	}
+	for _, root := range fs.Args() {
+		if err := s.scanPath(root); err != nil {
+			return err
+		}
+	}
+	sort.SliceStable(s.findings, func(i, j int) bool {
+		a, b := s.findings[i], s.findings[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+
+	var out io.Writer = os.Stdout
+	if *output != "-" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	switch *format {
+	case outputJSON:
+		if s.findings == nil {
+			s.findings = []scanFinding{}
+		}
+		err = writeIndentedJSON(out, s.findings)
+	case outputSARIF:
+		err = writeIndentedJSON(out, sarifReport(s.findings))
+	default:
+		err = writeScanTable(out, s.findings)
+	}
+	if err != nil {
+		return err
+	}
+	if len(s.findings) > *threshold {
+		return &exitError{code: 1, err: fmt.Errorf("%d findings exceed threshold %d", len(s.findings), *threshold)}
+	}
+	return nil
+}
+
+type scanner struct {
+	detector     *pii.Detector
+	pii, secrets bool
+	maxSize      int64
+	ignore       bool
+	findings     []scanFinding
+}
+
+// scanPath scans root, which is scanned even when an ignore file would
+// exclude it.
+func (s *scanner) scanPath(root string) error {
+	info, err := os.Stat(root)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return s.scanFile(root, info)
+	}
+	return s.scanDir(root, nil)
+}
+
+func (s *scanner) scanDir(dir string, stack ignoreStack) error {
+	if s.ignore {
+		set, err := loadIgnoreSet(dir)
+		if err != nil {
+			return err
+		}
+		if set != nil {
+			stack = append(stack[:len(stack):len(stack)], set)
+		}
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		p := filepath.Join(dir, entry.Name())
+		if entry.Name() == ".git" || stack.ignored(p, entry.IsDir()) {
+			continue
+		}
+		if entry.IsDir() {
+			if err := s.scanDir(p, stack); err != nil {
+				return err
+			}
+			continue
+		}
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		if err := s.scanFile(p, info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scanFile records the findings in one file. Oversized and binary files are
+// skipped.
+func (s *scanner) scanFile(p string, info os.FileInfo) error {
+	if info.Size() > s.maxSize {
+		return nil
+	}
+	data, err := os.ReadFile(p) // #nosec G304 -- operator supplied scan path
+	if err != nil {
+		return err
+	}
+	head := data
+	if len(head) > 8000 {
+		head = head[:8000]
+	}
+	if bytes.IndexByte(head, 0) >= 0 {
+		return nil
+	}
+	lines := bufio.NewScanner(bytes.NewReader(data))
+	lines.Buffer(make([]byte, 64*1024), int(s.maxSize)+1)
+	for n := 1; lines.Scan(); n++ {
+		line := lines.Text()
+		var secrets []pii.Finding
+		if s.secrets {
+			secrets = s.detector.FindSecrets(line)
+			s.record(p, n, line, categorySecret, secrets)
+		}
+		if s.pii {
+			// A token that looks like a phone number is reported once, as a
+			// secret.
+			var found []pii.Finding
+			for _, f := range s.detector.Find(line) {
+				if !overlapsAny(secrets, f) {
+					found = append(found, f)
+				}
+			}
+			s.record(p, n, line, categoryPII, found)
+		}
+	}
+	return lines.Err()
+}
+
+func overlapsAny(findings []pii.Finding, f pii.Finding) bool {
+	for _, other := range findings {
+		if f.Start < other.End && other.Start < f.End {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *scanner) record(p string, n int, line, category string, findings []pii.Finding) {
+	for _, f := range findings {
+		s.findings = append(s.findings, scanFinding{
+			Path:      filepath.ToSlash(p),
+			Line:      n,
+			Column:    utf8.RuneCountInString(line[:f.Start]) + 1,
+			EndColumn: utf8.RuneCountInString(line[:f.End]) + 1,
+			Kind:      f.Kind,
+			Category:  category,
+			Match:     maskKey(f.Value),
+		})
+	}
+}
+
+func writeScanTable(w io.Writer, findings []scanFinding) error {
+	if len(findings) == 0 {
+		_, err := fmt.Fprintln(w, "no findings")
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "%-40s %-8s %-16s %s\n", "LOCATION", "CATEGORY", "KIND", "MATCH"); err != nil {
+		return err
+	}
+	for _, f := range findings {
+		location := fmt.Sprintf("%s:%d:%d", f.Path, f.Line, f.Column)
+		if _, err := fmt.Fprintf(w, "%-40s %-8s %-16s %s\n", location, f.Category, f.Kind, f.Match); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sarifReport renders findings as a SARIF 2.1.0 log for code scanning
+// dashboards. Secrets are errors and PII warnings.
+func sarifReport(findings []scanFinding) map[string]any {
+	ruleIndex := map[pii.Kind]int{}
+	rules := []map[string]any{}
+	results := []map[string]any{}
+	for _, f := range findings {
+		idx, ok := ruleIndex[f.Kind]
+		if !ok {
+			idx = len(rules)
+			ruleIndex[f.Kind] = idx
+			rules = append(rules, map[string]any{
+				"id":               string(f.Kind),
+				"name":             sarifRuleName(f.Kind),
+				"shortDescription": map[string]any{"text": fmt.Sprintf("Possible %s (%s)", strings.ReplaceAll(string(f.Kind), "_", " "), f.Category)},
+			})
+		}
+		level := "warning"
+		if f.Category == categorySecret {
+			level = "error"
+		}
+		results = append(results, map[string]any{
+			"ruleId":    string(f.Kind),
+			"ruleIndex": idx,
+			"level":     level,
+			"message":   map[string]any{"text": fmt.Sprintf("%s found: %s", strings.ReplaceAll(string(f.Kind), "_", " "), f.Match)},
+			"locations": []map[string]any{{
+				"physicalLocation": map[string]any{
+					"artifactLocation": map[string]any{"uri": f.Path},
+					"region": map[string]any{
+						"startLine":   f.Line,
+						"startColumn": f.Column,
+						"endColumn":   f.EndColumn,
+					},
+				},
+			}},
+		})
+	}
+	return map[string]any{
+		"$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+		"version": "2.1.0",
+		"runs": []map[string]any{{
+			"tool": map[string]any{
+				"driver": map[string]any{
+					"name":           "aisentinel-go-sdk",
+					"version":        buildVersion,
+					"informationUri": "https://github.com/mfifth/aisentinel-go-sdk",
+					"rules":          rules,
+				},
+			},
+			"columnKind": "unicodeCodePoints",
+			"results":    results,
+		}},
+	}
+}
+
+// sarifRuleName turns a kind such as "aws_access_key" into "AwsAccessKey".
+func sarifRuleName(kind pii.Kind) string {
+	var b strings.Builder
+	for _, part := range strings.Split(string(kind), "_") {
+		if part != "" {
+			b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+		}
+	}
+	return b.String()
+}
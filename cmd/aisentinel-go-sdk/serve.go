@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	aisentinel "github.com/mfifth/aisentinel-go-sdk"
+	"github.com/mfifth/aisentinel-go-sdk/server"
+)
+
+// runServe implements the serve subcommand: it runs the HTTP decision sidecar
+// until SIGINT or SIGTERM, then drains in-flight requests.
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	addr := fs.String("addr", server.DefaultAddr, "Listen address")
+	configFile := fs.String("config", "", configFlagUsage)
+	profile := fs.String("profile", "", "Config file profile to apply")
+	maxConcurrent := fs.Int("max-concurrent", server.DefaultMaxConcurrent, "Maximum in-flight evaluations")
+	shutdownTimeout := fs.Duration("shutdown-timeout", server.DefaultShutdownTimeout, "Time allowed for in-flight requests to drain on shutdown")
+	authzenRulepack := fs.String("authzen-rulepack", "", "Rulepack for AuthZEN access evaluations (default: default_rulepack_id)")
+	metrics := fs.Bool("metrics", true, "Serve metrics on /metrics, overriding metrics_enabled")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+
+	cfg, source, err := resolveConfig(*configFile, *profile)
+	if err != nil {
+		return err
+	}
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "metrics" {
+			cfg.MetricsEnabled = *metrics
+		}
+	})
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	opts, err := keySourceOptions(ctx, cfg, source)
+	if err != nil {
+		return err
+	}
+	governor, err := aisentinel.NewGovernor(ctx, cfg, append(opts, aisentinel.WithLogger(logger))...)
+	if err != nil {
+		return fmt.Errorf("initialise governor: %w", err)
+	}
+	defer governor.Close()
+
+	srv := server.New(governor, server.Options{
+		Addr:              *addr,
+		MaxConcurrent:     *maxConcurrent,
+		ShutdownTimeout:   *shutdownTimeout,
+		AuthZENRulepackID: *authzenRulepack,
+	})
+	logger.Info("decision sidecar listening", "addr", *addr)
+	return srv.ListenAndServe(ctx)
+}
+
+// keySourceOptions keeps login tokens fresh in long-running commands: when
+// the API key came from login, the Governor re-reads it every minute.
+func keySourceOptions(ctx context.Context, cfg aisentinel.Config, source string) ([]aisentinel.Option, error) {
+	if !strings.HasPrefix(source, loginKeySource) {
+		return nil, nil
+	}
+	src, err := loadTokenSource(ctx, cfg.Profile)
+	if err != nil {
+		return nil, err
+	}
+	return []aisentinel.Option{aisentinel.WithAPIKeySource(src, time.Minute)}, nil
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	aisentinel "github.com/mfifth/aisentinel-go-sdk"
+)
+
+// runSimulate replays audited payloads against a candidate rulepack file and
+// reports the decisions that would change.
+func runSimulate(args []string) (err error) {
+	fs := flag.NewFlagSet("simulate", flag.ContinueOnError)
+	configFile := fs.String("config", "", configFlagUsage)
+	profile := fs.String("profile", "", "Config file profile to apply")
+	rulepackFile := fs.String("rulepack-file", "", "Candidate rulepack file (required)")
+	rulepack := fs.String("rulepack", "", "Replay decisions recorded for this rulepack (default: the candidate's id)")
+	since := fs.String("since", "7d", "Replay decisions newer than this, e.g. 24h or 7d")
+	output := fs.String("output", "text", "Output format: text, json or jsonl")
+	show := fs.Int("show", 20, "Changed decisions to list in text output (0 for all)")
+	failOnChange := fs.Bool("fail-on-change", false, "Exit 1 when any decision flips")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	defer func() {
+		var exit *exitError
+		if *failOnChange && err != nil && !errors.As(err, &exit) {
+			err = &exitError{code: 2, err: err}
+		}
+	}()
+	if *rulepackFile == "" {
+		return errors.New("--rulepack-file is required")
+	}
+	switch *output {
+	case "text", outputJSON, outputJSONL:
+	default:
+		return fmt.Errorf("unknown output format %q (want text, json or jsonl)", *output)
+	}
+	lookback, err := parseLookback(*since)
+	if err != nil {
+		return fmt.Errorf("--since: %w", err)
+	}
+	candidate, err := aisentinel.LoadRulepackFile(*rulepackFile)
+	if err != nil {
+		return err
+	}
+	if *rulepack == "" {
+		*rulepack = candidate.ID
+	}
+
+	cfg, err := loadConfig(*configFile, *profile)
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	governor, err := aisentinel.NewGovernor(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("initialise governor: %w", err)
+	}
+	defer func() {
+		if cerr := governor.Close(); cerr != nil {
+			log.Printf("close governor: %v", cerr)
+		}
+	}()
+
+	q := aisentinel.AuditFilter{RulepackID: *rulepack}
+	if lookback > 0 {
+		q.Since = time.Now().Add(-lookback)
+	}
+	report, err := governor.Replay(ctx, candidate, q)
+	if err != nil {
+		return err
+	}
+
+	switch *output {
+	case outputJSON:
+		err = writeIndentedJSON(os.Stdout, report)
+	case outputJSONL:
+		err = writeChangesJSONL(os.Stdout, report.Changes)
+	default:
+		err = writeReplayReport(os.Stdout, report, *show)
+	}
+	if err != nil {
+		return err
+	}
+	if *failOnChange && report.NewlyAllowed+report.NewlyDenied > 0 {
+		return &exitError{code: 1}
+	}
+	return nil
+}
+
+// parseLookback parses a Go duration, additionally accepting a number of days
+// such as "7d".
+func parseLookback(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.ParseFloat(days, 64)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(n * float64(24*time.Hour)), nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d, nil
+}
+
+func writeChangesJSONL(w io.Writer, changes []aisentinel.ReplayChange) error {
+	enc := json.NewEncoder(w)
+	for _, c := range changes {
+		if err := enc.Encode(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeReplayReport(w io.Writer, r aisentinel.ReplayReport, show int) error {
+	fmt.Fprintf(w, "replayed %d decisions: %d unchanged, %d newly allowed, %d newly denied, %d decided by a different rule, %d errors\n",
+		r.Total, r.Unchanged, r.NewlyAllowed, r.NewlyDenied, r.RuleChanged, r.Errors)
+	if len(r.Changes) == 0 {
+		return nil
+	}
+	fmt.Fprintf(w, "\n%-20s %-15s %-30s %s\n", "TIME", "DECISION", "RULE", "PAYLOAD")
+	for i, c := range r.Changes {
+		if show > 0 && i == show {
+			_, err := fmt.Fprintf(w, "... %d more (use --show 0 or --output json)\n", len(r.Changes)-show)
+			return err
+		}
+		decision := decisionWord(c.Before.Allowed) + " -> " + decisionWord(c.After.Allowed)
+		if !c.DecisionChanged() {
+			decision = decisionWord(c.After.Allowed)
+		}
+		rule := ruleOrDash(c.Before.RuleID) + " -> " + ruleOrDash(c.After.RuleID)
+		if _, err := fmt.Fprintf(w, "%-20s %-15s %-30s %s\n", c.Time.Local().Format("2006-01-02 15:04:05"), decision, rule, truncate(string(c.Payload), 60)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func decisionWord(allowed bool) string {
+	if allowed {
+		return "allow"
+	}
+	return "deny"
+}
+
+func ruleOrDash(id string) string {
+	if id == "" {
+		return "-"
+	}
+	return id
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	aisentinel "github.com/mfifth/aisentinel-go-sdk"
+)
+
+// watchInterval is how often evaluate --watch polls its files.
+const watchInterval = 500 * time.Millisecond
+
+// localRulepack evaluates against a rulepack file instead of the control
+// plane, so policies can be tested before they are pushed.
+type localRulepack struct {
+	path      string
+	evaluator *aisentinel.Evaluator
+	pack      *aisentinel.Rulepack
+}
+
+func loadLocalRulepack(path string) (*localRulepack, error) {
+	l := &localRulepack{path: path, evaluator: aisentinel.NewEvaluator()}
+	if err := l.reload(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// reload re-reads and recompiles the rulepack file. On error the previously
+// loaded rulepack stays in use.
+func (l *localRulepack) reload() error {
+	pack, err := aisentinel.LoadRulepackFile(l.path)
+	if err != nil {
+		return err
+	}
+	if err := pack.Validate(); err != nil {
+		return fmt.Errorf("%s: %w", l.path, err)
+	}
+	if err := l.evaluator.Preload(pack.ID, pack.Rules); err != nil {
+		return err
+	}
+	l.pack = pack
+	return nil
+}
+
+func (l *localRulepack) Evaluate(ctx context.Context, req aisentinel.DecisionRequest) (aisentinel.DecisionResult, error) {
+	return l.evaluator.Decide(ctx, l.pack, req.Payload)
+}
+
+// watchEvaluate evaluates once and then again whenever the payload or local
+// rulepack file changes, printing the decision and how it differs from the
+// previous one. Load and evaluation errors are reported without stopping the
+// loop; it returns when ctx is cancelled.
+func watchEvaluate(ctx context.Context, d decider, local *localRulepack, rulepack, payloadInline, payloadFile string, args []string, limits payloadLimits, timeout time.Duration, format string) error {
+	var files []string
+	if payloadFile != "" {
+		files = append(files, payloadFile)
+	}
+	if local != nil {
+		files = append(files, local.path)
+	}
+	fmt.Fprintf(os.Stderr, "watching %v; press Ctrl-C to stop\n", files)
+
+	var prev *decisionOutput
+	run := func(reloadRulepack bool) {
+		fmt.Fprintf(os.Stderr, "[%s] evaluating %s\n", time.Now().Format(time.TimeOnly), rulepack)
+		cur := watchDecision(ctx, d, local, reloadRulepack, rulepack, payloadInline, payloadFile, args, limits, timeout)
+		out := &decisionWriter{w: os.Stdout, format: format}
+		if err := out.write(cur); err == nil {
+			_ = out.flush()
+		}
+		if prev != nil {
+			printDecisionDiff(os.Stdout, *prev, cur)
+		}
+		prev = &cur
+	}
+
+	mods := watchModTimes(files)
+	run(false)
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		current := watchModTimes(files)
+		changed := false
+		rulepackChanged := false
+		for i, mod := range current {
+			if !mod.Equal(mods[i]) {
+				changed = true
+				rulepackChanged = rulepackChanged || (local != nil && files[i] == local.path)
+			}
+		}
+		mods = current
+		if changed {
+			run(rulepackChanged)
+		}
+	}
+}
+
+// watchDecision performs one watch iteration, folding errors into the
+// decision so they are shown and diffed like any other outcome.
+func watchDecision(ctx context.Context, d decider, local *localRulepack, reloadRulepack bool, rulepack, payloadInline, payloadFile string, args []string, limits payloadLimits, timeout time.Duration) decisionOutput {
+	if reloadRulepack {
+		if err := local.reload(); err != nil {
+			return decisionOutput{Error: err.Error()}
+		}
+	}
+	payload, err := resolvePayload(payloadInline, payloadFile, args, limits)
+	if err != nil {
+		return decisionOutput{Error: "resolve payload: " + err.Error()}
+	}
+	evalCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	result, err := d.Evaluate(evalCtx, aisentinel.DecisionRequest{RulepackID: rulepack, Payload: payload}) // nolint:exhaustruct
+	if err != nil {
+		return decisionOutput{Error: err.Error()}
+	}
+	return decisionOutput{Allowed: result.Allowed, RuleID: result.RuleID, Reason: result.Reason, LatencyMS: result.Latency.Milliseconds()}
+}
+
+func watchModTimes(files []string) []time.Time {
+	mods := make([]time.Time, len(files))
+	for i, path := range files {
+		if info, err := os.Stat(path); err == nil {
+			mods[i] = info.ModTime()
+		}
+	}
+	return mods
+}
+
+// printDecisionDiff shows the fields that changed between two decisions.
+// Latency is ignored since it differs on every run.
+func printDecisionDiff(w io.Writer, before, after decisionOutput) {
+	if before.Allowed == after.Allowed && before.RuleID == after.RuleID &&
+		before.Reason == after.Reason && before.Error == after.Error {
+		fmt.Fprintln(w, "= decision unchanged")
+		return
+	}
+	fmt.Fprintln(w, "~ decision changed")
+	if before.Error != after.Error {
+		fmt.Fprintf(w, "    error: %q -> %q\n", before.Error, after.Error)
+	}
+	if before.Allowed != after.Allowed {
+		fmt.Fprintf(w, "    allowed: %t -> %t\n", before.Allowed, after.Allowed)
+	}
+	if before.RuleID != after.RuleID {
+		fmt.Fprintf(w, "    rule_id: %q -> %q\n", before.RuleID, after.RuleID)
+	}
+	if before.Reason != after.Reason {
+		fmt.Fprintf(w, "    reason: %q -> %q\n", before.Reason, after.Reason)
+	}
+}
@@ -0,0 +1,75 @@
+// Command aisentinel-stub-server serves rulepacks from a local directory over
+// the control plane REST API, for local development, demos and CI without
+// credentials. Point Config.APIBaseURL at it.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultAddr     = "127.0.0.1:8787"
+	shutdownTimeout = 5 * time.Second
+)
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		log.Fatalf("aisentinel-stub-server: %v", err)
+	}
+}
+
+func run(args []string) error {
+	fs := flag.NewFlagSet("aisentinel-stub-server", flag.ContinueOnError)
+	addr := fs.String("addr", defaultAddr, "Listen address")
+	dir := fs.String("dir", ".", "Directory of rulepack files (.json, .yaml or .yml)")
+	apiKey := fs.String("api-key", "", "Require this bearer token (default: accept any)")
+	poll := fs.Duration("poll", time.Second, "How often to rescan the directory for changes")
+	readOnly := fs.Bool("read-only", false, "Reject rulepack pushes instead of writing them to --dir")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if *poll <= 0 {
+		return errors.New("--poll must be positive")
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	stub := newStub(*dir, *apiKey, *readOnly, logger)
+	if err := stub.scan(); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go stub.watch(ctx, *poll)
+
+	srv := &http.Server{Addr: *addr, Handler: stub, ReadHeaderTimeout: 10 * time.Second}
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.ListenAndServe() }()
+	logger.Info("stub control plane listening", "addr", *addr, "dir", *dir, "rulepacks", stub.count())
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+	stub.closeSubscribers()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("shutdown: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	aisentinel "github.com/mfifth/aisentinel-go-sdk"
+	"gopkg.in/yaml.v3"
+)
+
+// entry is a rulepack loaded from dir.
+type entry struct {
+	pack aisentinel.Rulepack
+	data []byte
+	etag string
+	file string
+}
+
+// change is the data of a rulepack event.
+type change = aisentinel.RulepackChange
+
+// stub serves the rulepack files in dir. Files are rescanned by watch, and
+// every change is published to the event stream subscribers.
+type stub struct {
+	dir      string
+	apiKey   string
+	readOnly bool
+	logger   *slog.Logger
+
+	scanMu sync.Mutex // serialises scans
+	mu     sync.RWMutex
+	packs  map[string]entry
+	subs   map[chan change]struct{}
+}
+
+func newStub(dir, apiKey string, readOnly bool, logger *slog.Logger) *stub {
+	return &stub{
+		dir:      dir,
+		apiKey:   apiKey,
+		readOnly: readOnly,
+		logger:   logger,
+		packs:    map[string]entry{},
+		subs:     map[chan change]struct{}{},
+	}
+}
+
+func (s *stub) count() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.packs)
+}
+
+// watch rescans the directory every interval until ctx is done.
+func (s *stub) watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.scan(); err != nil {
+				s.logger.Error("rescan rulepacks", "err", err)
+			}
+		}
+	}
+}
+
+// scan loads every rulepack file in dir and publishes the differences from
+// the previous scan. Invalid files are logged and skipped, so a half-saved
+// edit does not take its rulepack offline.
+func (s *stub) scan() error {
+	s.scanMu.Lock()
+	defer s.scanMu.Unlock()
+	files, err := os.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+	s.mu.RLock()
+	previous := s.packs
+	s.mu.RUnlock()
+
+	packs := make(map[string]entry, len(files))
+	for _, f := range files {
+		switch strings.ToLower(filepath.Ext(f.Name())) {
+		case ".json", ".yaml", ".yml":
+		default:
+			continue
+		}
+		if f.IsDir() {
+			continue
+		}
+		path := filepath.Join(s.dir, f.Name())
+		e, err := loadEntry(path)
+		if err != nil {
+			s.logger.Warn("skipping rulepack file", "file", path, "err", err)
+			for _, old := range previous {
+				if old.file == path {
+					packs[old.pack.ID] = old
+				}
+			}
+			continue
+		}
+		if other, ok := packs[e.pack.ID]; ok {
+			s.logger.Warn("duplicate rulepack id", "id", e.pack.ID, "file", path, "kept", other.file)
+			continue
+		}
+		packs[e.pack.ID] = e
+	}
+
+	var changes []change
+	for id, e := range packs {
+		if old, ok := previous[id]; !ok || old.etag != e.etag {
+			changes = append(changes, change{Type: "updated", ID: id, Version: e.pack.Version, ETag: e.etag})
+		}
+	}
+	for id := range previous {
+		if _, ok := packs[id]; !ok {
+			changes = append(changes, change{Type: "removed", ID: id})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].ID < changes[j].ID })
+
+	s.mu.Lock()
+	s.packs = packs
+	for _, c := range changes {
+		s.logger.Info("rulepack "+c.Type, "id", c.ID, "version", c.Version)
+		for sub := range s.subs {
+			select {
+			case sub <- c:
+			default:
+				// A subscriber that cannot keep up misses the event; it
+				// still sees the latest version on its next fetch.
+			}
+		}
+	}
+	s.mu.Unlock()
+	return nil
+}
+
+func loadEntry(path string) (entry, error) {
+	pack, err := aisentinel.LoadRulepackFile(path)
+	if err != nil {
+		return entry{}, err
+	}
+	if err := pack.Validate(); err != nil {
+		return entry{}, err
+	}
+	if pack.UpdatedAt.IsZero() {
+		if info, err := os.Stat(path); err == nil {
+			pack.UpdatedAt = info.ModTime().UTC()
+		}
+	}
+	data, err := json.Marshal(pack)
+	if err != nil {
+		return entry{}, err
+	}
+	sum := sha256.Sum256(data)
+	return entry{pack: *pack, data: data, etag: `"` + hex.EncodeToString(sum[:8]) + `"`, file: path}, nil
+}
+
+func (s *stub) closeSubscribers() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for sub := range s.subs {
+		close(sub)
+		delete(s.subs, sub)
+	}
+}
+
+func (s *stub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.apiKey != "" && r.Header.Get("Authorization") != "Bearer "+s.apiKey {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+	id, single := strings.CutPrefix(r.URL.Path, "/rulepacks/")
+	switch {
+	case r.URL.Path == aisentinel.RulepackEventsPath && r.Method == http.MethodGet:
+		s.serveEvents(w, r)
+	case r.URL.Path == "/rulepacks" && r.Method == http.MethodGet:
+		s.serveList(w)
+	case single && r.Method == http.MethodGet:
+		s.serveRulepack(w, r, id)
+	case single && r.Method == http.MethodPut:
+		s.push(w, r, id)
+	case single || r.URL.Path == "/rulepacks":
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *stub) serveList(w http.ResponseWriter) {
+	s.mu.RLock()
+	summaries := make([]aisentinel.RulepackSummary, 0, len(s.packs))
+	for _, e := range s.packs {
+		summaries = append(summaries, aisentinel.RulepackSummary{ID: e.pack.ID, Version: e.pack.Version, UpdatedAt: e.pack.UpdatedAt})
+	}
+	s.mu.RUnlock()
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].ID < summaries[j].ID })
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(summaries)
+}
+
+// serveRulepack answers conditional requests with 304 when the client's ETag
+// is current. Only the current version of a rulepack is served; pinning
+// another is a 404.
+func (s *stub) serveRulepack(w http.ResponseWriter, r *http.Request, id string) {
+	s.mu.RLock()
+	e, ok := s.packs[id]
+	s.mu.RUnlock()
+	if version := r.URL.Query().Get("version"); !ok || (version != "" && version != e.pack.Version) {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("ETag", e.etag)
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		for _, tag := range strings.Split(match, ",") {
+			if tag = strings.TrimSpace(tag); tag == e.etag || tag == "*" {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(e.data)
+}
+
+// push writes an uploaded rulepack over the file it was loaded from, or to
+// <dir>/<id>.json for new rulepacks, and rescans so subscribers are notified.
+func (s *stub) push(w http.ResponseWriter, r *http.Request, id string) {
+	if s.readOnly {
+		http.Error(w, "rulepack pushes are disabled", http.StatusMethodNotAllowed)
+		return
+	}
+	var pack aisentinel.Rulepack
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&pack); err != nil || pack.ID != id {
+		http.Error(w, "invalid rulepack", http.StatusBadRequest)
+		return
+	}
+	if filepath.Base(id) != id {
+		http.Error(w, "invalid rulepack id", http.StatusBadRequest)
+		return
+	}
+	if err := pack.Validate(); err != nil {
+		http.Error(w, fmt.Sprintf("invalid rulepack: %v", err), http.StatusBadRequest)
+		return
+	}
+	s.mu.RLock()
+	path := s.packs[id].file
+	s.mu.RUnlock()
+	if path == "" {
+		path = filepath.Join(s.dir, id+".json")
+	}
+	var data []byte
+	var err error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		data, err = yaml.Marshal(pack)
+	default:
+		data, err = json.MarshalIndent(pack, "", "  ")
+		data = append(data, '\n')
+	}
+	if err == nil {
+		err = os.WriteFile(path, data, 0o600)
+	}
+	if err == nil {
+		err = s.scan()
+	}
+	if err != nil {
+		s.logger.Error("push rulepack", "id", id, "err", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// serveEvents streams rulepack changes until the client disconnects. The
+// optional rulepack query parameter limits the stream to one rulepack.
+func (s *stub) serveEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	filter := r.URL.Query().Get("rulepack")
+	sub := make(chan change, 16)
+	s.mu.Lock()
+	s.subs[sub] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.subs, sub)
+		s.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case c, ok := <-sub:
+			if !ok {
+				return
+			}
+			if filter != "" && c.ID != filter {
+				continue
+			}
+			data, _ := json.Marshal(c)
+			fmt.Fprintf(w, "event: rulepack\ndata: %s\n\n", data)
+			flusher.Flush()
+		}
+	}
+}
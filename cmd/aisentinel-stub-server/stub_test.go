@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	aisentinel "github.com/mfifth/aisentinel-go-sdk"
+)
+
+func TestStubServer(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("prompts.yaml", "id: prompts\nversion: \"1\"\nrules:\n  - id: prompt\n    pattern: \"@\"\n")
+	write("broken.json", "{")
+	write("notes.txt", "not a rulepack")
+
+	s := newStub(dir, "local", false, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err := s.scan(); err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+
+	ctx := context.Background()
+	gov, err := aisentinel.NewGovernor(ctx, aisentinel.Config{APIKey: "local", APIBaseURL: srv.URL})
+	if err != nil {
+		t.Fatalf("expected governor: %v", err)
+	}
+	t.Cleanup(func() { _ = gov.Close() })
+	result, err := gov.Evaluate(ctx, aisentinel.DecisionRequest{RulepackID: "prompts", Payload: []byte(`{"prompt":"mail jane@example.com"}`)})
+	if err != nil || result.Allowed || result.RuleID != "prompt" {
+		t.Fatalf("expected the file's rulepack to deny, got %+v %v", result, err)
+	}
+	packs, err := gov.ListRulepacks(ctx)
+	if err != nil || len(packs) != 1 || packs[0].ID != "prompts" || packs[0].UpdatedAt.IsZero() {
+		t.Fatalf("expected only the valid rulepack to be listed, got %+v %v", packs, err)
+	}
+
+	get := func(etag string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+"/rulepacks/prompts", nil)
+		req.Header.Set("Authorization", "Bearer local")
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+	etag := get("").Header.Get("ETag")
+	if resp := get(etag); resp.StatusCode != http.StatusNotModified {
+		t.Fatalf("expected 304 for a current ETag, got %d", resp.StatusCode)
+	}
+	if resp, _ := http.Get(srv.URL + "/rulepacks/prompts"); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected requests without the API key to be rejected, got %d", resp.StatusCode)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+aisentinel.RulepackEventsPath+"?rulepack=prompts", nil)
+	req.Header.Set("Authorization", "Bearer local")
+	stream, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Body.Close()
+	events := bufio.NewReader(stream.Body)
+	if line, _ := events.ReadString('\n'); line != ": connected\n" {
+		t.Fatalf("expected the stream to open, got %q", line)
+	}
+
+	pushed := &aisentinel.Rulepack{ID: "prompts", Version: "2", Rules: []aisentinel.RuleDefinition{{ID: "prompt", Pattern: "@", Allow: true}}}
+	if err := gov.PushRulepack(ctx, pushed); err != nil {
+		t.Fatalf("push: %v", err)
+	}
+	var got change
+	deadline := time.Now().Add(5 * time.Second)
+	for got.Version == "" && time.Now().Before(deadline) {
+		line, err := events.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read event: %v", err)
+		}
+		if data, ok := strings.CutPrefix(line, "data: "); ok {
+			_ = json.Unmarshal([]byte(data), &got)
+		}
+	}
+	if got.Type != "updated" || got.Version != "2" || got.ETag == etag {
+		t.Fatalf("expected an update event for the push, got %+v", got)
+	}
+	if resp := get(etag); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected the stale ETag to miss, got %d", resp.StatusCode)
+	}
+	if pack, err := aisentinel.LoadRulepackFile(filepath.Join(dir, "prompts.yaml")); err != nil || pack.Version != "2" {
+		t.Fatalf("expected the push to rewrite the YAML file, got %+v %v", pack, err)
+	}
+}
@@ -0,0 +1,122 @@
+package governor
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sync"
+)
+
+// compileCacheVersion is bumped whenever the cache file format changes.
+const compileCacheVersion = 1
+
+// compiledPack is the cache file of one set of rule definitions. Its
+// existence records that every pattern compiled.
+type compiledPack struct {
+	Version int            `json:"version"`
+	Digest  string         `json:"digest"`
+	Rules   []compiledRule `json:"rules"`
+}
+
+// compiledRule is the compile metadata of a rule. Literal patterns contain
+// no regexp syntax and are matched as substrings without compiling them.
+type compiledRule struct {
+	ID      string `json:"id"`
+	Literal bool   `json:"literal,omitempty"`
+}
+
+// lazyExpression compiles a pattern known to be valid on first use.
+type lazyExpression struct {
+	pattern string
+	literal []byte
+	once    sync.Once
+	re      *regexp.Regexp
+}
+
+func (l *lazyExpression) match(value []byte) bool {
+	if l.literal != nil {
+		return bytes.Contains(value, l.literal)
+	}
+	l.once.Do(func() { l.re = regexp.MustCompile(l.pattern) })
+	return l.re.Match(value)
+}
+
+// definitionsDigest identifies rule definitions together with the Go
+// release, whose regexp package decided they were valid.
+func definitionsDigest(definitions []RuleDefinition) string {
+	h := sha256.New()
+	_ = json.NewEncoder(h).Encode(struct {
+		Version int
+		Go      string
+		Rules   []RuleDefinition
+	}{compileCacheVersion, runtime.Version(), definitions})
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func compiledPath(dir, digest string) string {
+	return filepath.Join(dir, digest+".json")
+}
+
+// restoreCompiled rebuilds rules from the cache file for digest. It reports
+// false when there is no usable file, and the rules must be compiled.
+func restoreCompiled(dir, digest string, definitions []RuleDefinition) ([]Rule, bool) {
+	data, err := os.ReadFile(compiledPath(dir, digest)) // #nosec G304 -- path derived from a digest
+	if err != nil {
+		return nil, false
+	}
+	var cached compiledPack
+	if err := json.Unmarshal(data, &cached); err != nil || cached.Version != compileCacheVersion || cached.Digest != digest || len(cached.Rules) != len(definitions) {
+		return nil, false
+	}
+	rules := make([]Rule, len(definitions))
+	for i, def := range definitions {
+		if cached.Rules[i].ID != def.ID {
+			return nil, false
+		}
+		rule, err := compileRule(def, nil, true)
+		if err != nil {
+			return nil, false
+		}
+		if cached.Rules[i].Literal {
+			rule.lazy.literal = []byte(def.Pattern)
+		}
+		rules[i] = rule
+	}
+	return rules, true
+}
+
+// storeCompiled writes the cache file for definitions, which must all have
+// compiled. The file is renamed into place so readers never see a partial
+// write.
+func storeCompiled(dir, digest string, definitions []RuleDefinition) error {
+	cached := compiledPack{Version: compileCacheVersion, Digest: digest, Rules: make([]compiledRule, len(definitions))}
+	for i, def := range definitions {
+		literal := def.Operator == "" && !def.composite() && def.Pattern != "" && regexp.QuoteMeta(def.Pattern) == def.Pattern
+		cached.Rules[i] = compiledRule{ID: def.ID, Literal: literal}
+	}
+	data, err := json.Marshal(cached)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, digest+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), compiledPath(dir, digest))
+}
@@ -0,0 +1,215 @@
+package governor
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// Condition is one test of a composite rule. A leaf condition matches
+// Pattern, or applies Operator, to the value at Field; an empty Field reads
+// the rule's own field. A group condition instead holds when all of All,
+// at least one of Any and not Not hold, for those it sets.
+type Condition struct {
+	Field    string      `json:",omitempty" yaml:",omitempty"`
+	Pattern  string      `json:",omitempty" yaml:",omitempty"`
+	Operator Operator    `json:",omitempty" yaml:",omitempty"`
+	Value    any         `json:",omitempty" yaml:",omitempty"`
+	All      []Condition `json:",omitempty" yaml:",omitempty"`
+	Any      []Condition `json:",omitempty" yaml:",omitempty"`
+	Not      *Condition  `json:",omitempty" yaml:",omitempty"`
+}
+
+// group reports whether c combines other conditions.
+func (c Condition) group() bool {
+	return len(c.All) > 0 || len(c.Any) > 0 || c.Not != nil
+}
+
+// composite reports whether d matches through conditions rather than its own
+// pattern or operator.
+func (d RuleDefinition) composite() bool {
+	return len(d.All) > 0 || len(d.Any) > 0 || d.Not != nil
+}
+
+// Leaves returns the leaf conditions d tests, in definition order. A rule
+// without conditions is its own single leaf.
+func (d RuleDefinition) Leaves() []Condition {
+	if !d.composite() {
+		return []Condition{{Field: d.Field, Pattern: d.Pattern, Operator: d.Operator, Value: d.Value}}
+	}
+	var leaves []Condition
+	var walk func(c Condition)
+	walk = func(c Condition) {
+		if !c.group() {
+			leaves = append(leaves, c)
+			return
+		}
+		for _, sub := range c.All {
+			walk(sub)
+		}
+		for _, sub := range c.Any {
+			walk(sub)
+		}
+		if c.Not != nil {
+			walk(*c.Not)
+		}
+	}
+	walk(Condition{All: d.All, Any: d.Any, Not: d.Not})
+	return leaves
+}
+
+// leafPath parses the selector of a leaf condition of def.
+func leafPath(def RuleDefinition, c Condition) ([]PathStep, error) {
+	if c.Field == "" {
+		return rulePath(def)
+	}
+	return ParseFieldPath(c.Field)
+}
+
+// condition is a compiled Condition. Leaves are compiled as rules, so they
+// share the matching code of plain rules.
+type condition struct {
+	leaf     *Rule
+	all, any []condition
+	not      *condition
+}
+
+// compileConditions compiles the conditions of a composite rule. With lazy,
+// patterns known to be valid compile on first use.
+func compileConditions(def RuleDefinition, lazy bool) (*condition, error) {
+	if def.Pattern != "" || def.Operator != "" {
+		return nil, errors.New("a rule with conditions takes no pattern or operator")
+	}
+	return compileCondition(def, Condition{All: def.All, Any: def.Any, Not: def.Not}, lazy)
+}
+
+func compileCondition(def RuleDefinition, c Condition, lazy bool) (*condition, error) {
+	if !c.group() {
+		path, err := leafPath(def, c)
+		if err != nil {
+			return nil, err
+		}
+		leaf := &Rule{ID: def.ID, field: c.Field, path: path}
+		if err := leaf.compileMatcher(c.Pattern, c.Operator, c.Value, lazy); err != nil {
+			return nil, err
+		}
+		return &condition{leaf: leaf}, nil
+	}
+	if c.Field != "" || c.Pattern != "" || c.Operator != "" || c.Value != nil {
+		return nil, errors.New("a condition is either a test or a group of all, any and not")
+	}
+	compiled := &condition{}
+	for _, sub := range c.All {
+		cc, err := compileCondition(def, sub, lazy)
+		if err != nil {
+			return nil, err
+		}
+		compiled.all = append(compiled.all, *cc)
+	}
+	for _, sub := range c.Any {
+		cc, err := compileCondition(def, sub, lazy)
+		if err != nil {
+			return nil, err
+		}
+		compiled.any = append(compiled.any, *cc)
+	}
+	if c.Not != nil {
+		cc, err := compileCondition(def, *c.Not, lazy)
+		if err != nil {
+			return nil, err
+		}
+		compiled.not = cc
+	}
+	return compiled, nil
+}
+
+// compileMatcher sets the pattern or operator r applies to its value.
+func (r *Rule) compileMatcher(pattern string, op Operator, value any, lazy bool) error {
+	r.pattern, r.operator = pattern, op
+	var err error
+	switch {
+	case op != "":
+		r.operand, err = compileOperand(pattern, op, value)
+	case lazy:
+		r.lazy = &lazyExpression{pattern: pattern}
+	default:
+		r.Expression, err = regexp.Compile(pattern)
+	}
+	return err
+}
+
+// ConditionTrace records a leaf condition evaluated for a composite rule.
+// Leaves are listed in evaluation order; those not needed to settle the
+// rule are left out.
+type ConditionTrace struct {
+	Field    string      `json:"field,omitempty" yaml:"field,omitempty"`
+	Pattern  string      `json:"pattern,omitempty" yaml:"pattern,omitempty"`
+	Operator Operator    `json:"operator,omitempty" yaml:"operator,omitempty"`
+	Operand  any         `json:"operand,omitempty" yaml:"operand,omitempty"`
+	Outcome  RuleOutcome `json:"outcome" yaml:"outcome"`
+	Value    string      `json:"value,omitempty" yaml:"value,omitempty"`
+}
+
+// eval reports whether c holds for document, appending evaluated leaves to
+// trace when it is non-nil.
+func (c *condition) eval(document map[string]payloadField, trace *[]ConditionTrace) (bool, error) {
+	if c.leaf != nil {
+		matched, outcome, field, err := c.leaf.test(document)
+		if err == nil && trace != nil {
+			*trace = append(*trace, ConditionTrace{
+				Field:    c.leaf.field,
+				Pattern:  c.leaf.pattern,
+				Operator: c.leaf.operator,
+				Operand:  c.leaf.operand,
+				Outcome:  outcome,
+				Value:    traceValue(outcome, field),
+			})
+		}
+		return matched, err
+	}
+	for i := range c.all {
+		if ok, err := c.all[i].eval(document, trace); err != nil || !ok {
+			return false, err
+		}
+	}
+	if len(c.any) > 0 {
+		found := false
+		for i := range c.any {
+			ok, err := c.any[i].eval(document, trace)
+			if err != nil {
+				return false, err
+			}
+			if ok {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false, nil
+		}
+	}
+	if c.not != nil {
+		if ok, err := c.not.eval(document, trace); err != nil || ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// validateRule compiles def's pattern, operator or conditions, reporting the
+// first error.
+func validateRule(def RuleDefinition) error {
+	var err error
+	switch {
+	case def.composite():
+		_, err = compileConditions(def, false)
+	case def.Operator != "":
+		_, err = compileOperand(def.Pattern, def.Operator, def.Value)
+	default:
+		_, err = regexp.Compile(def.Pattern)
+	}
+	if err != nil && def.composite() {
+		return fmt.Errorf("conditions: %w", err)
+	}
+	return err
+}
@@ -9,11 +9,44 @@ import (
 	"time"
 )
 
+// FailurePolicy decides the outcome of an evaluation when the rulepack cannot
+// be loaded, for example because the control plane is unreachable.
+type FailurePolicy string
+
+const (
+	// FailClosed returns the load error to the caller, which must treat it as
+	// a denial. This is the default.
+	FailClosed FailurePolicy = "fail-closed"
+	// FailOpen allows the request and reports the load error in the reason.
+	FailOpen FailurePolicy = "fail-open"
+)
+
+// DefaultDecision is the outcome of an evaluation no rule decided.
+type DefaultDecision string
+
+const (
+	// DefaultDeny denies unmatched payloads. It is the default.
+	DefaultDeny DefaultDecision = "deny"
+	// DefaultAllow allows unmatched payloads, for low-risk rulepacks.
+	DefaultAllow DefaultDecision = "allow"
+)
+
+// Valid reports whether d is a known decision or empty.
+func (d DefaultDecision) Valid() bool {
+	return d == "" || d == DefaultDeny || d == DefaultAllow
+}
+
+// NoFetchRetries, as Config.FetchRetries, disables fetch retries. A zero
+// FetchRetries is unset and takes the default when merged, so a 0 from the
+// environment or a config file is stored as NoFetchRetries.
+const NoFetchRetries = -1
+
 // Config encapsulates runtime configuration for the Governor. It mirrors the
 // Python SDK configuration surface while staying idiomatic to Go.
 type Config struct {
 	APIBaseURL        string
 	APIKey            string
+	APIKeyFile        string
 	CacheTTL          time.Duration
 	HTTPTimeout       time.Duration
 	OfflineMode       bool
@@ -23,30 +56,164 @@ type Config struct {
 	MetricsEnabled    bool
 	MetricsEndpoint   string
 	EnvironmentPrefix string
+	LogLevel          string
+	FetchRetries      int
+	FailurePolicy     FailurePolicy
+	DefaultDecision   DefaultDecision
+	Profile           string
+	DefaultRulepackID string
+
+	// StrictMode makes Validate fail on any ValidationWarning.
+	StrictMode bool
+
+	// Per-operation timeouts. Each falls back to HTTPTimeout when zero.
+	// FetchTimeout bounds a single rulepack download attempt on the decision
+	// path, TelemetryTimeout a metrics push and AuditFlushTimeout an audit
+	// write to storage.
+	FetchTimeout      time.Duration
+	TelemetryTimeout  time.Duration
+	AuditFlushTimeout time.Duration
+
+	// HTTPProxy, HTTPSProxy and NoProxy route control plane traffic through
+	// a dedicated proxy (http, https or socks5 URLs). When all are empty the
+	// standard proxy environment variables apply.
+	HTTPProxy  string
+	HTTPSProxy string
+	NoProxy    string
+
+	// TLS settings for control plane connections. TLSRootCAsFile is a PEM
+	// bundle replacing the system roots, TLSMinVersion is one of "1.0" to
+	// "1.3" (default "1.2"), TLSCipherSuites lists Go cipher suite names
+	// (TLS 1.3 suites are not configurable) and TLSServerName overrides SNI
+	// and certificate verification host name.
+	TLSRootCAsFile  string
+	TLSMinVersion   string
+	TLSCipherSuites []string
+	TLSServerName   string
+
+	// RulepackPublicKeys lists the Ed25519 or ECDSA keys trusted to sign
+	// rulepacks, as PEM encoded PKIX public keys or paths to files holding
+	// them. When set, fetched rulepacks without a valid
+	// RulepackSignatureHeader are rejected.
+	RulepackPublicKeys []string
+
+	// RulepackPins pins rulepacks to a version, as "id@version" entries
+	// such as "governance@v12", so production traffic is not affected by
+	// unreviewed updates. Requests naming a version themselves are exempt.
+	RulepackPins []string
+
+	// MetricsPushInterval controls how often snapshots are pushed to
+	// MetricsEndpoint when it is set.
+	MetricsPushInterval time.Duration
+
+	// SlowEvaluationThreshold emits a warning event with the slowest rules
+	// whenever an evaluation takes longer. Zero disables detection.
+	SlowEvaluationThreshold time.Duration
+
+	// MaxPayloadBytes rejects larger payloads with a PayloadTooLargeError
+	// before they are decoded. Zero means no limit.
+	MaxPayloadBytes int64
+
+	// CompileCacheDir persists compiled rulepack metadata so restarts skip
+	// recompiling rulepacks seen before; see Evaluator.SetCompileCache.
+	CompileCacheDir string
+
+	// DecisionCacheTTL memoizes decisions for that long, keyed by rulepack
+	// version and rules, payload and usage Subject and Model, so identical
+	// requests skip rule evaluation. Zero disables the cache.
+	DecisionCacheTTL time.Duration
+
+	// RulepackRefreshInterval re-fetches cached rulepacks in the background
+	// on that interval, conditionally on their ETag, so evaluations do not
+	// wait for a fetch once a rulepack is cached. It should be shorter than
+	// CacheTTL. Zero disables refreshing.
+	RulepackRefreshInterval time.Duration
+
+	// AuditRetention deletes audit records from storage once they are older
+	// than that, checking every tenth of it, between a minute and an hour.
+	// Zero keeps them forever.
+	AuditRetention time.Duration
+
+	// AuditAsync moves audit writes off the decision path: Evaluate queues
+	// the record and a background writer stores queued records in batches
+	// of up to AuditBatchSize, at least every AuditFlushInterval. At most
+	// AuditBufferSize records wait; beyond that AuditBackpressure decides
+	// whether Evaluate drops the record or waits for room. Close writes the
+	// queued records, but they are lost if the process dies first, and
+	// QueryAudit only sees records once written.
+	AuditAsync         bool
+	AuditBatchSize     int
+	AuditFlushInterval time.Duration
+	AuditBufferSize    int
+	AuditBackpressure  BackpressurePolicy
+
+	// StorageEncryptionKey, a base64 AES key of 16, 24 or 32 bytes,
+	// encrypts record values at rest with AES-GCM; see
+	// storage.EncryptedStore and WithStorageEncryption.
+	StorageEncryptionKey string
+
+	// RulepackSubscribe keeps a server-sent events connection to the
+	// control plane open and re-fetches a cached rulepack as soon as it is
+	// announced as updated, instead of when it expires or is next refreshed.
+	RulepackSubscribe bool
+
+	// DebugEndpoints exposes pprof and expvar handlers when the SDK runs as a
+	// server. DebugToken must be set; requests without it are rejected.
+	DebugEndpoints bool
+	DebugToken     string
 }
 
 // DefaultConfig returns a configuration populated with production ready defaults.
 func DefaultConfig() Config {
 	return Config{
-		APIBaseURL:        "https://api.aisentinel.ai",
-		CacheTTL:          5 * time.Minute,
-		HTTPTimeout:       10 * time.Second,
-		OfflineMode:       false,
-		OfflineQueueSize:  1024,
-		StorageBackend:    "memory",
-		MetricsEnabled:    true,
-		EnvironmentPrefix: "AISENTINEL_",
+		APIBaseURL:          "https://api.aisentinel.ai",
+		CacheTTL:            5 * time.Minute,
+		HTTPTimeout:         10 * time.Second,
+		OfflineMode:         false,
+		OfflineQueueSize:    1024,
+		StorageBackend:      "memory",
+		MetricsEnabled:      true,
+		MetricsPushInterval: 30 * time.Second,
+		EnvironmentPrefix:   "AISENTINEL_",
+		LogLevel:            "info",
+		FetchRetries:        2,
+		FailurePolicy:       FailClosed,
+		DefaultDecision:     DefaultDeny,
+		AuditBatchSize:      100,
+		AuditFlushInterval:  time.Second,
+		AuditBufferSize:     10000,
+		AuditBackpressure:   BackpressureDrop,
 	}
 }
 
 // ApplyEnv overlays configuration values from environment variables using the
 // configured prefix. The behaviour matches the Python SDK to ease migration.
 func (c *Config) ApplyEnv() error {
-	prefix := c.EnvironmentPrefix
-	if prefix == "" {
-		prefix = "AISENTINEL_"
+	prefix := c.envPrefix()
+	for key, fn := range c.setters() {
+		if value, ok := os.LookupEnv(prefix + key); ok {
+			if err := fn(value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// envPrefix returns the environment variable prefix, defaulting to
+// AISENTINEL_.
+func (c *Config) envPrefix() string {
+	if c.EnvironmentPrefix == "" {
+		return "AISENTINEL_"
 	}
-	overlay := map[string]func(string) error{
+	return c.EnvironmentPrefix
+}
+
+// setters maps configuration keys (the environment variable names without
+// prefix) to parsers that assign the field. Environment variables and config
+// files share these keys.
+func (c *Config) setters() map[string]func(string) error {
+	return map[string]func(string) error{
 		"API_BASE_URL": func(v string) error {
 			if _, err := url.ParseRequestURI(v); err != nil {
 				return fmt.Errorf("invalid API_BASE_URL: %w", err)
@@ -58,6 +225,10 @@ func (c *Config) ApplyEnv() error {
 			c.APIKey = v
 			return nil
 		},
+		"API_KEY_FILE": func(v string) error {
+			c.APIKeyFile = v
+			return nil
+		},
 		"CACHE_TTL": func(v string) error {
 			d, err := time.ParseDuration(v)
 			if err != nil {
@@ -74,6 +245,30 @@ func (c *Config) ApplyEnv() error {
 			c.HTTPTimeout = d
 			return nil
 		},
+		"FETCH_TIMEOUT": func(v string) error {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return fmt.Errorf("invalid FETCH_TIMEOUT: %w", err)
+			}
+			c.FetchTimeout = d
+			return nil
+		},
+		"TELEMETRY_TIMEOUT": func(v string) error {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return fmt.Errorf("invalid TELEMETRY_TIMEOUT: %w", err)
+			}
+			c.TelemetryTimeout = d
+			return nil
+		},
+		"AUDIT_FLUSH_TIMEOUT": func(v string) error {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return fmt.Errorf("invalid AUDIT_FLUSH_TIMEOUT: %w", err)
+			}
+			c.AuditFlushTimeout = d
+			return nil
+		},
 		"OFFLINE_MODE": func(v string) error {
 			b, err := strconv.ParseBool(v)
 			if err != nil {
@@ -93,6 +288,45 @@ func (c *Config) ApplyEnv() error {
 			c.OfflineQueueSize = i
 			return nil
 		},
+		"HTTP_PROXY": func(v string) error {
+			c.HTTPProxy = v
+			return nil
+		},
+		"HTTPS_PROXY": func(v string) error {
+			c.HTTPSProxy = v
+			return nil
+		},
+		"NO_PROXY": func(v string) error {
+			c.NoProxy = v
+			return nil
+		},
+		"TLS_ROOT_CAS_FILE": func(v string) error {
+			c.TLSRootCAsFile = v
+			return nil
+		},
+		"TLS_MIN_VERSION": func(v string) error {
+			if _, ok := tlsVersions[v]; !ok {
+				return fmt.Errorf("invalid TLS_MIN_VERSION: %q", v)
+			}
+			c.TLSMinVersion = v
+			return nil
+		},
+		"TLS_CIPHER_SUITES": func(v string) error {
+			c.TLSCipherSuites = splitList(v)
+			return nil
+		},
+		"RULEPACK_PUBLIC_KEYS": func(v string) error {
+			c.RulepackPublicKeys = splitList(v)
+			return nil
+		},
+		"RULEPACK_PINS": func(v string) error {
+			c.RulepackPins = splitList(v)
+			return nil
+		},
+		"TLS_SERVER_NAME": func(v string) error {
+			c.TLSServerName = v
+			return nil
+		},
 		"STORAGE_BACKEND": func(v string) error {
 			c.StorageBackend = strings.ToLower(v)
 			return nil
@@ -101,6 +335,10 @@ func (c *Config) ApplyEnv() error {
 			c.StorageDSN = v
 			return nil
 		},
+		"STORAGE_ENCRYPTION_KEY": func(v string) error {
+			c.StorageEncryptionKey = v
+			return nil
+		},
 		"METRICS_ENABLED": func(v string) error {
 			b, err := strconv.ParseBool(v)
 			if err != nil {
@@ -113,20 +351,185 @@ func (c *Config) ApplyEnv() error {
 			c.MetricsEndpoint = v
 			return nil
 		},
-	}
-
-	for key, fn := range overlay {
-		if value, ok := os.LookupEnv(prefix + key); ok {
-			if err := fn(value); err != nil {
-				return err
+		"METRICS_PUSH_INTERVAL": func(v string) error {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return fmt.Errorf("invalid METRICS_PUSH_INTERVAL: %w", err)
 			}
-		}
+			c.MetricsPushInterval = d
+			return nil
+		},
+		"SLOW_EVALUATION_THRESHOLD": func(v string) error {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return fmt.Errorf("invalid SLOW_EVALUATION_THRESHOLD: %w", err)
+			}
+			c.SlowEvaluationThreshold = d
+			return nil
+		},
+		"DECISION_CACHE_TTL": func(v string) error {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return fmt.Errorf("invalid DECISION_CACHE_TTL: %w", err)
+			}
+			c.DecisionCacheTTL = d
+			return nil
+		},
+		"RULEPACK_REFRESH_INTERVAL": func(v string) error {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return fmt.Errorf("invalid RULEPACK_REFRESH_INTERVAL: %w", err)
+			}
+			c.RulepackRefreshInterval = d
+			return nil
+		},
+		"AUDIT_RETENTION": func(v string) error {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return fmt.Errorf("invalid AUDIT_RETENTION: %w", err)
+			}
+			c.AuditRetention = d
+			return nil
+		},
+		"AUDIT_ASYNC": func(v string) error {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return fmt.Errorf("invalid AUDIT_ASYNC: %w", err)
+			}
+			c.AuditAsync = b
+			return nil
+		},
+		"AUDIT_BATCH_SIZE": func(v string) error {
+			i, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("invalid AUDIT_BATCH_SIZE: %w", err)
+			}
+			c.AuditBatchSize = i
+			return nil
+		},
+		"AUDIT_FLUSH_INTERVAL": func(v string) error {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return fmt.Errorf("invalid AUDIT_FLUSH_INTERVAL: %w", err)
+			}
+			c.AuditFlushInterval = d
+			return nil
+		},
+		"AUDIT_BUFFER_SIZE": func(v string) error {
+			i, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("invalid AUDIT_BUFFER_SIZE: %w", err)
+			}
+			c.AuditBufferSize = i
+			return nil
+		},
+		"AUDIT_BACKPRESSURE": func(v string) error {
+			policy := BackpressurePolicy(strings.ToLower(v))
+			if !policy.Valid() {
+				return fmt.Errorf("invalid AUDIT_BACKPRESSURE: %q", v)
+			}
+			c.AuditBackpressure = policy
+			return nil
+		},
+		"RULEPACK_SUBSCRIBE": func(v string) error {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return fmt.Errorf("invalid RULEPACK_SUBSCRIBE: %w", err)
+			}
+			c.RulepackSubscribe = b
+			return nil
+		},
+		"COMPILE_CACHE_DIR": func(v string) error {
+			c.CompileCacheDir = v
+			return nil
+		},
+		"MAX_PAYLOAD_BYTES": func(v string) error {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid MAX_PAYLOAD_BYTES: %w", err)
+			}
+			if n < 0 {
+				return fmt.Errorf("max payload bytes must be >= 0")
+			}
+			c.MaxPayloadBytes = n
+			return nil
+		},
+		"LOG_LEVEL": func(v string) error {
+			if _, err := ParseEventLevel(v); err != nil {
+				return fmt.Errorf("invalid LOG_LEVEL: %w", err)
+			}
+			c.LogLevel = strings.ToLower(v)
+			return nil
+		},
+		"FETCH_RETRIES": func(v string) error {
+			i, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("invalid FETCH_RETRIES: %w", err)
+			}
+			if i < NoFetchRetries {
+				return fmt.Errorf("fetch retries must be >= 0, or -1 to disable them")
+			}
+			// An explicit 0 disables retries; stored as 0 it would be
+			// unset and take the default when the Config is merged.
+			if i == 0 {
+				i = NoFetchRetries
+			}
+			c.FetchRetries = i
+			return nil
+		},
+		"FAILURE_POLICY": func(v string) error {
+			policy := FailurePolicy(strings.ToLower(v))
+			if policy != FailClosed && policy != FailOpen {
+				return fmt.Errorf("invalid FAILURE_POLICY: %q", v)
+			}
+			c.FailurePolicy = policy
+			return nil
+		},
+		"DEFAULT_DECISION": func(v string) error {
+			decision := DefaultDecision(strings.ToLower(v))
+			if decision != DefaultDeny && decision != DefaultAllow {
+				return fmt.Errorf("invalid DEFAULT_DECISION: %q", v)
+			}
+			c.DefaultDecision = decision
+			return nil
+		},
+		"STRICT_MODE": func(v string) error {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return fmt.Errorf("invalid STRICT_MODE: %w", err)
+			}
+			c.StrictMode = b
+			return nil
+		},
+		"DEFAULT_RULEPACK_ID": func(v string) error {
+			c.DefaultRulepackID = v
+			return nil
+		},
+		"PROFILE": func(v string) error {
+			c.Profile = v
+			return nil
+		},
+		"ENVIRONMENT_PREFIX": func(v string) error {
+			c.EnvironmentPrefix = v
+			return nil
+		},
+		"DEBUG_ENDPOINTS": func(v string) error {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return fmt.Errorf("invalid DEBUG_ENDPOINTS: %w", err)
+			}
+			c.DebugEndpoints = b
+			return nil
+		},
+		"DEBUG_TOKEN": func(v string) error {
+			c.DebugToken = v
+			return nil
+		},
 	}
-
-	return nil
 }
 
-// Validate performs sanity checks on the configuration.
+// Validate performs sanity checks on the configuration. In StrictMode any
+// Warnings are returned as a *StrictModeError.
 func (c Config) Validate() error {
 	if c.APIBaseURL == "" {
 		return fmt.Errorf("APIBaseURL is required")
@@ -134,8 +537,8 @@ func (c Config) Validate() error {
 	if _, err := url.ParseRequestURI(c.APIBaseURL); err != nil {
 		return fmt.Errorf("invalid APIBaseURL: %w", err)
 	}
-	if c.APIKey == "" {
-		return fmt.Errorf("APIKey is required")
+	if c.APIKey == "" && c.APIKeyFile == "" {
+		return fmt.Errorf("APIKey or APIKeyFile is required")
 	}
 	if c.CacheTTL <= 0 {
 		return fmt.Errorf("CacheTTL must be > 0")
@@ -143,9 +546,80 @@ func (c Config) Validate() error {
 	if c.HTTPTimeout <= 0 {
 		return fmt.Errorf("HTTPTimeout must be > 0")
 	}
+	if c.FetchTimeout < 0 || c.TelemetryTimeout < 0 || c.AuditFlushTimeout < 0 {
+		return fmt.Errorf("operation timeouts must be >= 0")
+	}
 	if c.OfflineQueueSize <= 0 {
 		return fmt.Errorf("OfflineQueueSize must be > 0")
 	}
+	if _, err := proxyFunc(c); err != nil {
+		return err
+	}
+	if _, err := tlsConfig(c); err != nil {
+		return err
+	}
+	if c.MetricsEndpoint != "" {
+		if _, err := url.ParseRequestURI(c.MetricsEndpoint); err != nil {
+			return fmt.Errorf("invalid MetricsEndpoint: %w", err)
+		}
+		if c.MetricsPushInterval <= 0 {
+			return fmt.Errorf("MetricsPushInterval must be > 0")
+		}
+	}
+	if c.SlowEvaluationThreshold < 0 {
+		return fmt.Errorf("SlowEvaluationThreshold must be >= 0")
+	}
+	if c.MaxPayloadBytes < 0 {
+		return fmt.Errorf("MaxPayloadBytes must be >= 0")
+	}
+	if c.DecisionCacheTTL < 0 {
+		return fmt.Errorf("DecisionCacheTTL must be >= 0")
+	}
+	if c.RulepackRefreshInterval < 0 {
+		return fmt.Errorf("RulepackRefreshInterval must be >= 0")
+	}
+	if c.AuditRetention < 0 {
+		return fmt.Errorf("AuditRetention must be >= 0")
+	}
+	if c.AuditAsync {
+		if c.AuditBatchSize <= 0 || c.AuditBufferSize <= 0 {
+			return fmt.Errorf("AuditBatchSize and AuditBufferSize must be > 0")
+		}
+		if c.AuditFlushInterval <= 0 {
+			return fmt.Errorf("AuditFlushInterval must be > 0")
+		}
+		if !c.AuditBackpressure.Valid() {
+			return fmt.Errorf("invalid AuditBackpressure %q", c.AuditBackpressure)
+		}
+	}
+	if c.StorageEncryptionKey != "" {
+		if _, err := parseStorageEncryptionKey(c.StorageEncryptionKey); err != nil {
+			return err
+		}
+	}
+	if _, err := parseRulepackPins(c.RulepackPins); err != nil {
+		return err
+	}
+	if _, err := ParseEventLevel(c.LogLevel); err != nil {
+		return fmt.Errorf("invalid LogLevel: %w", err)
+	}
+	if c.FetchRetries < NoFetchRetries {
+		return fmt.Errorf("FetchRetries must be >= 0, or NoFetchRetries")
+	}
+	if c.FailurePolicy != FailClosed && c.FailurePolicy != FailOpen {
+		return fmt.Errorf("invalid FailurePolicy %q", c.FailurePolicy)
+	}
+	if c.DefaultDecision != DefaultDeny && c.DefaultDecision != DefaultAllow {
+		return fmt.Errorf("invalid DefaultDecision %q", c.DefaultDecision)
+	}
+	if c.DebugEndpoints && c.DebugToken == "" {
+		return fmt.Errorf("DebugToken is required when DebugEndpoints is enabled")
+	}
+	if c.StrictMode {
+		if warnings := c.Warnings(); len(warnings) > 0 {
+			return &StrictModeError{Warnings: warnings}
+		}
+	}
 	return nil
 }
 
@@ -157,28 +631,149 @@ func (c Config) Merge(other Config) Config {
 	if other.APIKey != "" {
 		c.APIKey = other.APIKey
 	}
+	if other.APIKeyFile != "" {
+		c.APIKeyFile = other.APIKeyFile
+	}
 	if other.CacheTTL != 0 {
 		c.CacheTTL = other.CacheTTL
 	}
 	if other.HTTPTimeout != 0 {
 		c.HTTPTimeout = other.HTTPTimeout
 	}
+	if other.FetchTimeout != 0 {
+		c.FetchTimeout = other.FetchTimeout
+	}
+	if other.TelemetryTimeout != 0 {
+		c.TelemetryTimeout = other.TelemetryTimeout
+	}
+	if other.AuditFlushTimeout != 0 {
+		c.AuditFlushTimeout = other.AuditFlushTimeout
+	}
 	if other.OfflineQueueSize != 0 {
 		c.OfflineQueueSize = other.OfflineQueueSize
 	}
+	if other.HTTPProxy != "" {
+		c.HTTPProxy = other.HTTPProxy
+	}
+	if other.HTTPSProxy != "" {
+		c.HTTPSProxy = other.HTTPSProxy
+	}
+	if other.NoProxy != "" {
+		c.NoProxy = other.NoProxy
+	}
+	if other.TLSRootCAsFile != "" {
+		c.TLSRootCAsFile = other.TLSRootCAsFile
+	}
+	if other.TLSMinVersion != "" {
+		c.TLSMinVersion = other.TLSMinVersion
+	}
+	if len(other.TLSCipherSuites) > 0 {
+		c.TLSCipherSuites = other.TLSCipherSuites
+	}
+	if len(other.RulepackPublicKeys) > 0 {
+		c.RulepackPublicKeys = other.RulepackPublicKeys
+	}
+	if len(other.RulepackPins) > 0 {
+		c.RulepackPins = other.RulepackPins
+	}
+	if other.TLSServerName != "" {
+		c.TLSServerName = other.TLSServerName
+	}
 	if other.StorageBackend != "" {
 		c.StorageBackend = other.StorageBackend
 	}
 	if other.StorageDSN != "" {
 		c.StorageDSN = other.StorageDSN
 	}
+	if other.StorageEncryptionKey != "" {
+		c.StorageEncryptionKey = other.StorageEncryptionKey
+	}
+	if other.AuditBatchSize != 0 {
+		c.AuditBatchSize = other.AuditBatchSize
+	}
+	if other.AuditFlushInterval != 0 {
+		c.AuditFlushInterval = other.AuditFlushInterval
+	}
+	if other.AuditBufferSize != 0 {
+		c.AuditBufferSize = other.AuditBufferSize
+	}
+	if other.AuditBackpressure != "" {
+		c.AuditBackpressure = other.AuditBackpressure
+	}
 	if other.MetricsEndpoint != "" {
 		c.MetricsEndpoint = other.MetricsEndpoint
 	}
+	if other.MetricsPushInterval != 0 {
+		c.MetricsPushInterval = other.MetricsPushInterval
+	}
 	if other.EnvironmentPrefix != "" {
 		c.EnvironmentPrefix = other.EnvironmentPrefix
 	}
+	if other.SlowEvaluationThreshold != 0 {
+		c.SlowEvaluationThreshold = other.SlowEvaluationThreshold
+	}
+	if other.MaxPayloadBytes != 0 {
+		c.MaxPayloadBytes = other.MaxPayloadBytes
+	}
+	if other.DecisionCacheTTL != 0 {
+		c.DecisionCacheTTL = other.DecisionCacheTTL
+	}
+	if other.RulepackRefreshInterval != 0 {
+		c.RulepackRefreshInterval = other.RulepackRefreshInterval
+	}
+	if other.AuditRetention != 0 {
+		c.AuditRetention = other.AuditRetention
+	}
+	if other.CompileCacheDir != "" {
+		c.CompileCacheDir = other.CompileCacheDir
+	}
+	if other.LogLevel != "" {
+		c.LogLevel = other.LogLevel
+	}
+	if other.FetchRetries == NoFetchRetries {
+		c.FetchRetries = 0
+	} else if other.FetchRetries != 0 {
+		c.FetchRetries = other.FetchRetries
+	}
+	if other.Profile != "" {
+		c.Profile = other.Profile
+	}
+	if other.DefaultRulepackID != "" {
+		c.DefaultRulepackID = other.DefaultRulepackID
+	}
+	if other.FailurePolicy != "" {
+		c.FailurePolicy = other.FailurePolicy
+	}
+	if other.DefaultDecision != "" {
+		c.DefaultDecision = other.DefaultDecision
+	}
+	if other.DebugToken != "" {
+		c.DebugToken = other.DebugToken
+	}
 	c.OfflineMode = other.OfflineMode
 	c.MetricsEnabled = other.MetricsEnabled
+	c.DebugEndpoints = other.DebugEndpoints
+	c.StrictMode = other.StrictMode
+	c.RulepackSubscribe = other.RulepackSubscribe
+	c.AuditAsync = other.AuditAsync
 	return c
 }
+
+// operationTimeout returns timeout, or HTTPTimeout when timeout is zero.
+func (c Config) operationTimeout(timeout time.Duration) time.Duration {
+	if timeout > 0 {
+		return timeout
+	}
+	return c.HTTPTimeout
+}
+
+// splitList parses a comma separated list, dropping empty entries.
+func splitList(v string) []string {
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
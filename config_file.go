@@ -0,0 +1,181 @@
+package governor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// LoadConfig reads a YAML (.yaml, .yml), TOML (.toml) or JSON (.json) config
+// file. Keys use the environment variable names in snake case, for example
+// api_base_url or cache_ttl; durations are written as Go duration strings
+// ("5m") and lists either as arrays or comma separated strings.
+//
+// A file may declare named profiles under a "profiles" key. The profile is
+// chosen by the profile key in the file, overridden by AISENTINEL_PROFILE;
+// its values replace the top-level ones.
+//
+// Values are layered with the following precedence, lowest first:
+//
+//  1. DefaultConfig
+//  2. top-level values in the config file
+//  3. values of the selected profile
+//  4. environment variables using EnvironmentPrefix
+//
+// The returned Config can be passed straight to NewGovernor.
+func LoadConfig(path string) (Config, error) {
+	return LoadConfigProfile(path, "")
+}
+
+// LoadConfigProfile is LoadConfig with an explicit profile that takes
+// precedence over the file and environment selection. An empty profile falls
+// back to the LoadConfig rules.
+func LoadConfigProfile(path, profile string) (Config, error) {
+	values, err := readConfigFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+	profiles, err := splitProfiles(values)
+	if err != nil {
+		return Config{}, fmt.Errorf("load config %s: %w", path, err)
+	}
+
+	cfg := DefaultConfig()
+	if err := cfg.applyValues(values); err != nil {
+		return Config{}, fmt.Errorf("load config %s: %w", path, err)
+	}
+
+	if profile == "" {
+		profile = cfg.Profile
+		if env, ok := os.LookupEnv(cfg.envPrefix() + "PROFILE"); ok {
+			profile = env
+		}
+	}
+	if profile != "" {
+		overrides, ok := profiles[profile]
+		if !ok {
+			return Config{}, fmt.Errorf("load config %s: unknown profile %q", path, profile)
+		}
+		if err := cfg.applyValues(overrides); err != nil {
+			return Config{}, fmt.Errorf("load config %s: profile %q: %w", path, profile, err)
+		}
+	}
+
+	if err := cfg.ApplyEnv(); err != nil {
+		return Config{}, err
+	}
+	cfg.Profile = profile
+	return cfg, nil
+}
+
+// splitProfiles removes the "profiles" section from values and returns it
+// keyed by profile name.
+func splitProfiles(values map[string]any) (map[string]map[string]any, error) {
+	raw, ok := values["profiles"]
+	if !ok {
+		return nil, nil
+	}
+	delete(values, "profiles")
+	section, ok := raw.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("profiles must be a mapping")
+	}
+	profiles := make(map[string]map[string]any, len(section))
+	for name, v := range section {
+		profile, ok := v.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("profile %q must be a mapping", name)
+		}
+		profiles[name] = profile
+	}
+	return profiles, nil
+}
+
+// readConfigFile decodes path into a generic document based on its extension.
+func readConfigFile(path string) (map[string]any, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- operators choose the config path
+	if err != nil {
+		return nil, fmt.Errorf("read config: %w", err)
+	}
+	values := map[string]any{}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &values)
+	case ".toml":
+		err = toml.Unmarshal(data, &values)
+	case ".json":
+		err = json.Unmarshal(data, &values)
+	default:
+		return nil, fmt.Errorf("unsupported config file extension %q", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse config %s: %w", path, err)
+	}
+	return values, nil
+}
+
+// applyValues assigns file values through the same setters used by ApplyEnv.
+// Keys are applied in sorted order so errors are deterministic.
+func (c *Config) applyValues(values map[string]any) error {
+	setters := c.setters()
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		name := strings.ToUpper(strings.ReplaceAll(key, "-", "_"))
+		fn, ok := setters[name]
+		if !ok {
+			return fmt.Errorf("unknown config key %q", key)
+		}
+		value, err := stringifyConfigValue(values[key])
+		if err != nil {
+			return fmt.Errorf("config key %q: %w", key, err)
+		}
+		if err := fn(value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stringifyConfigValue renders a decoded scalar or list in the textual form
+// accepted by the setters.
+func stringifyConfigValue(v any) (string, error) {
+	switch value := v.(type) {
+	case string:
+		return value, nil
+	case bool:
+		return strconv.FormatBool(value), nil
+	case int:
+		return strconv.Itoa(value), nil
+	case int64:
+		return strconv.FormatInt(value, 10), nil
+	case uint64:
+		return strconv.FormatUint(value, 10), nil
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64), nil
+	case []any:
+		parts := make([]string, 0, len(value))
+		for _, item := range value {
+			s, err := stringifyConfigValue(item)
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, s)
+		}
+		return strings.Join(parts, ","), nil
+	case nil:
+		return "", nil
+	default:
+		return "", fmt.Errorf("unsupported value of type %T", v)
+	}
+}
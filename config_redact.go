@@ -0,0 +1,55 @@
+package governor
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+)
+
+// EventConfigLoaded is emitted by NewGovernor with the redacted configuration.
+const EventConfigLoaded EventType = "config_loaded"
+
+// redactedValue replaces secrets in Config.Redacted.
+const redactedValue = "REDACTED"
+
+// dsnPassword matches password settings in key=value DSNs such as
+// "host=db user=app password=secret".
+var dsnPassword = regexp.MustCompile(`(?i)(password=)[^\s;&]*`)
+
+// Redacted returns a copy of c that is safe to print. The API key, debug
+// token and storage encryption key are masked, as are passwords embedded in URLs and the storage DSN.
+func (c Config) Redacted() Config {
+	if c.APIKey != "" {
+		c.APIKey = redactedValue
+	}
+	if c.DebugToken != "" {
+		c.DebugToken = redactedValue
+	}
+	if c.StorageEncryptionKey != "" {
+		c.StorageEncryptionKey = redactedValue
+	}
+	c.APIBaseURL = redactURL(c.APIBaseURL)
+	c.MetricsEndpoint = redactURL(c.MetricsEndpoint)
+	c.HTTPProxy = redactURL(c.HTTPProxy)
+	c.HTTPSProxy = redactURL(c.HTTPSProxy)
+	c.StorageDSN = dsnPassword.ReplaceAllString(redactURL(c.StorageDSN), "${1}"+redactedValue)
+	return c
+}
+
+// String formats the redacted configuration, so printing a Config never
+// leaks credentials.
+func (c Config) String() string {
+	type plain Config // drops the String method to avoid recursion
+	return fmt.Sprintf("%+v", plain(c.Redacted()))
+}
+
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.User == nil {
+		return raw
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), redactedValue)
+	}
+	return u.String()
+}
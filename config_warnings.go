@@ -0,0 +1,128 @@
+package governor
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mfifth/aisentinel-go-sdk/storage"
+)
+
+// EventConfigWarning is emitted by NewGovernor for every validation warning.
+const EventConfigWarning EventType = "config_warning"
+
+// ValidationWarning describes a configuration that is accepted but probably
+// not what the operator intended.
+type ValidationWarning struct {
+	Field   string
+	Message string
+}
+
+// String formats the warning as "Field: message".
+func (w ValidationWarning) String() string {
+	return w.Field + ": " + w.Message
+}
+
+// StrictModeError is returned by Validate when StrictMode is enabled and the
+// configuration produced warnings.
+type StrictModeError struct {
+	Warnings []ValidationWarning
+}
+
+func (e *StrictModeError) Error() string {
+	parts := make([]string, len(e.Warnings))
+	for i, w := range e.Warnings {
+		parts[i] = w.String()
+	}
+	return "strict mode: " + strings.Join(parts, "; ")
+}
+
+// minDebugTokenLength is the shortest debug token not flagged as weak.
+const minDebugTokenLength = 16
+
+// Warnings reports non-fatal configuration problems. Validate promotes them
+// to errors when StrictMode is set.
+func (c Config) Warnings() []ValidationWarning {
+	var warnings []ValidationWarning
+	warn := func(field, format string, args ...any) {
+		warnings = append(warnings, ValidationWarning{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if u, err := url.Parse(c.APIBaseURL); err == nil && u.Scheme == "http" && !isLoopbackHost(u.Hostname()) {
+		warn("APIBaseURL", "plain http sends the API key unencrypted")
+	}
+	if c.MetricsEnabled && c.MetricsEndpoint == "" {
+		warn("MetricsEndpoint", "metrics enabled but no endpoint configured; snapshots are only available in-process")
+	}
+	if c.CacheTTL > 0 && c.CacheTTL < time.Second {
+		warn("CacheTTL", "%s is very short; most evaluations will fetch the rulepack", c.CacheTTL)
+	}
+	if c.RulepackRefreshInterval > 0 && c.RulepackRefreshInterval >= c.CacheTTL {
+		warn("RulepackRefreshInterval", "%s is not shorter than CacheTTL, so cached rulepacks expire before they are refreshed", c.RulepackRefreshInterval)
+	}
+	if c.RulepackSubscribe && c.OfflineMode {
+		warn("RulepackSubscribe", "has no effect in offline mode")
+	}
+	backend := storage.BackendType(c.StorageBackend)
+	_, registered := storage.Lookup(backend)
+	switch backend {
+	case storage.BackendMemory, "":
+	case storage.BackendBolt, storage.BackendBadger, storage.BackendSQLite:
+		if c.StorageDSN != "" && !writableDir(filepath.Dir(c.StorageDSN)) {
+			warn("StorageDSN", "%s is not writable", filepath.Dir(c.StorageDSN))
+		}
+		switch {
+		case registered:
+		case backend == storage.BackendSQLite:
+			warn("StorageBackend", "no %s store is registered, so NewGovernor fails; import %s", backend, storeModules[backend])
+		default:
+			warn("StorageBackend", "no %s store is registered, so audit records are kept in memory and lost on exit; import %s", backend, storeModules[backend])
+		}
+	case storage.BackendPostgres:
+		if !registered {
+			warn("StorageBackend", "no %s store is registered, so NewGovernor fails; import %s", backend, storeModules[backend])
+		}
+	default:
+		if !registered {
+			warn("StorageBackend", "unknown backend %q; falling back to memory", c.StorageBackend)
+		}
+	}
+	if c.FailurePolicy == FailOpen {
+		warn("FailurePolicy", "fail-open allows every request while rulepacks are unavailable")
+	}
+	if c.DefaultDecision == DefaultAllow {
+		warn("DefaultDecision", "allow lets through every payload no rule matches, unless a rulepack sets its own default")
+	}
+	if c.DebugEndpoints && len(c.DebugToken) < minDebugTokenLength {
+		warn("DebugToken", "token shorter than %d characters", minDebugTokenLength)
+	}
+	return warnings
+}
+
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// storeModules names the modules registering persistent stores for the
+// backends the core module can only keep in memory, or not at all.
+var storeModules = map[storage.BackendType]string{
+	storage.BackendBolt:     "github.com/mfifth/aisentinel-go-sdk/contrib/boltstore",
+	storage.BackendBadger:   "github.com/mfifth/aisentinel-go-sdk/contrib/badgerstore",
+	storage.BackendSQLite:   "github.com/mfifth/aisentinel-go-sdk/contrib/sqlitestore",
+	storage.BackendPostgres: "github.com/mfifth/aisentinel-go-sdk/contrib/pgstore",
+}
+
+// writableDir reports whether dir is a directory with any write permission.
+// It only stats dir, so computing warnings leaves the filesystem untouched.
+func writableDir(dir string) bool {
+	info, err := os.Stat(dir)
+	return err == nil && info.IsDir() && info.Mode().Perm()&0o222 != 0
+}
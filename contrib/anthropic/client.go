@@ -0,0 +1,303 @@
+// Package anthropic governs calls made with the official Anthropic Go client.
+// New wraps a client so whole and streamed messages go through the Governor,
+// and Unwrap returns it for calls that are not governed:
+//
+//	client := anthropic.NewClient()
+//	governed := aisentinelanthropic.New(&client, gov, aisentinelanthropic.Options{RulepackID: "llm-egress"})
+//	msg, err := governed.NewMessage(ctx, params)
+//
+// Prompts are evaluated before a request is sent and the reply after it is
+// received. Payloads are flattened so rules can match them by field: requests
+// carry "operation", "model" and "prompt" (the system prompt and every text
+// block of the messages joined by newlines) and replies carry "operation",
+// "model" and "completion". Decisions go through Governor.Evaluate and are
+// audited like any other.
+package anthropic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	anthropiclib "github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/anthropics/anthropic-sdk-go/packages/ssestream"
+	governor "github.com/mfifth/aisentinel-go-sdk"
+	"github.com/mfifth/aisentinel-go-sdk/aisentinelhttp"
+	"github.com/mfifth/aisentinel-go-sdk/pii"
+)
+
+// operation is the "operation" field of every payload.
+const operation = "message"
+
+// Options configures a Client. The zero value evaluates prompts and replies
+// against the Governor's default rulepack, blocks denied ones and fails
+// closed.
+type Options struct {
+	// RulepackID is evaluated against prompts; empty selects
+	// Config.DefaultRulepackID.
+	RulepackID string
+	// ResponseRulepackID is evaluated against replies; empty uses
+	// RulepackID.
+	ResponseRulepackID string
+	// SkipResponse disables reply evaluation.
+	SkipResponse bool
+	// OnDeny is the action for denied text; the default is
+	// aisentinelhttp.ActionBlock. Streamed replies are always blocked, as
+	// deltas already handed out cannot be redacted.
+	OnDeny aisentinelhttp.Action
+	// Redact rewrites denied text for aisentinelhttp.ActionRedact. The
+	// default replaces PII with placeholders such as [EMAIL].
+	Redact func(text string) string
+	// FailOpen lets calls through when the Governor cannot decide.
+	FailOpen bool
+}
+
+// Client wraps an Anthropic client so message calls are governed. Denied
+// calls fail with an *aisentinelhttp.DeniedError, which matches
+// aisentinelhttp.ErrDenied; denied prompts are never sent.
+type Client struct {
+	client *anthropiclib.Client
+	gov    *governor.Governor
+	opts   Options
+}
+
+// New returns a Client calling the Anthropic API through client.
+func New(client *anthropiclib.Client, gov *governor.Governor, opts Options) *Client {
+	if opts.ResponseRulepackID == "" {
+		opts.ResponseRulepackID = opts.RulepackID
+	}
+	if opts.Redact == nil {
+		detector := pii.New()
+		opts.Redact = func(text string) string {
+			redacted, _ := detector.Redact(text, pii.StrategyPlaceholder)
+			return redacted
+		}
+	}
+	return &Client{client: client, gov: gov, opts: opts}
+}
+
+// Unwrap returns the underlying Anthropic client for calls the wrapper does
+// not govern.
+func (c *Client) Unwrap() *anthropiclib.Client { return c.client }
+
+// NewMessage governs client.Messages.New. Every text block of the reply is
+// evaluated separately.
+func (c *Client) NewMessage(ctx context.Context, params anthropiclib.MessageNewParams, opts ...option.RequestOption) (*anthropiclib.Message, error) {
+	opts, err := c.governRequest(ctx, params, opts)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Messages.New(ctx, params, opts...)
+	if err != nil || c.opts.SkipResponse {
+		return resp, err
+	}
+	for i, block := range resp.Content {
+		if block.Type != "text" {
+			continue
+		}
+		text, err := c.governResponse(ctx, string(resp.Model), block.Text)
+		if err != nil {
+			return nil, err
+		}
+		resp.Content[i].Text = text
+	}
+	return resp, nil
+}
+
+// NewMessageStreaming governs client.Messages.NewStreaming. The prompt is
+// evaluated before the stream is opened, and every text delta flows through
+// a governor.StreamEvaluator, so the reply is evaluated as it grows and
+// audited once complete. A denied reply ends the stream and Err reports the
+// denial.
+func (c *Client) NewMessageStreaming(ctx context.Context, params anthropiclib.MessageNewParams, opts ...option.RequestOption) *MessageStream {
+	opts, err := c.governRequest(ctx, params, opts)
+	if err != nil {
+		return &MessageStream{err: err}
+	}
+	s := &MessageStream{ctx: ctx, failOpen: c.opts.FailOpen, stream: c.client.Messages.NewStreaming(ctx, params, opts...)}
+	if !c.opts.SkipResponse {
+		s.eval = c.gov.NewStreamEvaluator(c.opts.ResponseRulepackID, "completion", map[string]string{"operation": operation, "model": string(params.Model)})
+	}
+	return s
+}
+
+// MessageStream is a governed stream of message events, used like the
+// ssestream.Stream it wraps.
+type MessageStream struct {
+	ctx      context.Context
+	stream   *ssestream.Stream[anthropiclib.MessageStreamEventUnion]
+	eval     *governor.StreamEvaluator
+	failOpen bool
+	err      error
+}
+
+// Next advances to the next event. It returns false when the stream ends, an
+// error occurs or a delta is denied. The complete reply is evaluated and
+// audited once the stream ends, and Err reports a denial then.
+func (s *MessageStream) Next() bool {
+	if s.err != nil || s.stream == nil {
+		return false
+	}
+	if !s.stream.Next() {
+		if s.eval != nil && s.stream.Err() == nil {
+			result, err := s.eval.Finish(s.ctx)
+			s.govern(result, err)
+			s.eval = nil
+		}
+		return false
+	}
+	event := s.stream.Current()
+	if s.eval == nil || event.Type != "content_block_delta" || event.Delta.Type != "text_delta" {
+		return true
+	}
+	return s.govern(s.eval.Write(s.ctx, event.Delta.Text))
+}
+
+// govern applies a reply decision, reporting whether the stream may go on.
+func (s *MessageStream) govern(result governor.DecisionResult, err error) bool {
+	switch {
+	case err != nil && s.failOpen:
+		s.eval = nil
+	case err != nil:
+		s.err = fmt.Errorf("anthropic: policy evaluation: %w", err)
+		return false
+	case !result.Allowed:
+		s.err = &aisentinelhttp.DeniedError{Phase: "response", Result: result}
+		return false
+	}
+	return true
+}
+
+// Current returns the event Next advanced to.
+func (s *MessageStream) Current() anthropiclib.MessageStreamEventUnion {
+	return s.stream.Current()
+}
+
+// Err returns the error that ended the stream, if any.
+func (s *MessageStream) Err() error {
+	if s.err != nil || s.stream == nil {
+		return s.err
+	}
+	return s.stream.Err()
+}
+
+// Close closes the underlying stream.
+func (s *MessageStream) Close() error {
+	if s.stream == nil {
+		return nil
+	}
+	return s.stream.Close()
+}
+
+// textField is a piece of request text and its sjson path in the body.
+type textField struct {
+	path string
+	text string
+}
+
+// governRequest evaluates the text of params and returns opts extended to
+// send redacted text in its place when needed.
+func (c *Client) governRequest(ctx context.Context, params anthropiclib.MessageNewParams, opts []option.RequestOption) ([]option.RequestOption, error) {
+	body, err := params.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	var document map[string]any
+	if err := json.Unmarshal(body, &document); err != nil {
+		return nil, err
+	}
+	model := string(params.Model)
+	fields := requestText(document)
+
+	result, err := c.evaluate(ctx, c.opts.RulepackID, requestPayload(model, fields))
+	if err != nil || result.Allowed {
+		return opts, err
+	}
+	if c.opts.OnDeny == aisentinelhttp.ActionRedact {
+		redacted := make([]textField, len(fields))
+		for i, f := range fields {
+			redacted[i] = textField{path: f.path, text: c.opts.Redact(f.text)}
+		}
+		if result, err = c.evaluate(ctx, c.opts.RulepackID, requestPayload(model, redacted)); err != nil || result.Allowed {
+			for i, f := range redacted {
+				if f.text != fields[i].text {
+					opts = append(opts, option.WithJSONSet(f.path, f.text))
+				}
+			}
+			return opts, err
+		}
+	}
+	return nil, &aisentinelhttp.DeniedError{Phase: "request", Result: result}
+}
+
+// governResponse evaluates one text block and returns the text to hand back.
+func (c *Client) governResponse(ctx context.Context, model, text string) (string, error) {
+	payload := map[string]string{"operation": operation, "model": model, "completion": text}
+	result, err := c.evaluate(ctx, c.opts.ResponseRulepackID, payload)
+	if err != nil || result.Allowed {
+		return text, err
+	}
+	if c.opts.OnDeny == aisentinelhttp.ActionRedact {
+		payload["completion"] = c.opts.Redact(text)
+		if result, err = c.evaluate(ctx, c.opts.ResponseRulepackID, payload); err != nil || result.Allowed {
+			return payload["completion"], err
+		}
+	}
+	return "", &aisentinelhttp.DeniedError{Phase: "response", Result: result}
+}
+
+func (c *Client) evaluate(ctx context.Context, rulepack string, payload map[string]string) (governor.DecisionResult, error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return governor.DecisionResult{}, err
+	}
+	result, err := c.gov.Evaluate(ctx, governor.DecisionRequest{RulepackID: rulepack, Payload: raw})
+	if err != nil && c.opts.FailOpen {
+		return governor.DecisionResult{Allowed: true}, nil
+	}
+	if err != nil {
+		return result, fmt.Errorf("anthropic: policy evaluation: %w", err)
+	}
+	return result, nil
+}
+
+func requestPayload(model string, fields []textField) map[string]string {
+	texts := make([]string, len(fields))
+	for i, f := range fields {
+		texts[i] = f.text
+	}
+	return map[string]string{"operation": operation, "model": model, "prompt": strings.Join(texts, "\n")}
+}
+
+// requestText collects the text of a request body: the system prompt and the
+// content of every message, as plain strings or text blocks.
+func requestText(document map[string]any) []textField {
+	fields := appendText(nil, "system", document["system"])
+	if messages, ok := document["messages"].([]any); ok {
+		for i, m := range messages {
+			message, _ := m.(map[string]any)
+			fields = appendText(fields, "messages."+strconv.Itoa(i)+".content", message["content"])
+		}
+	}
+	return fields
+}
+
+// appendText appends v when it is a string and the "text" of v's blocks when
+// it is an array.
+func appendText(fields []textField, path string, v any) []textField {
+	switch v := v.(type) {
+	case string:
+		fields = append(fields, textField{path: path, text: v})
+	case []any:
+		for i, item := range v {
+			block, _ := item.(map[string]any)
+			if text, ok := block["text"].(string); ok {
+				fields = append(fields, textField{path: path + "." + strconv.Itoa(i) + ".text", text: text})
+			}
+		}
+	}
+	return fields
+}
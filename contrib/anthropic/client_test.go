@@ -0,0 +1,146 @@
+package anthropic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	anthropiclib "github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/option"
+	governor "github.com/mfifth/aisentinel-go-sdk"
+	"github.com/mfifth/aisentinel-go-sdk/aisentinelhttp"
+	"github.com/mfifth/aisentinel-go-sdk/governortest"
+)
+
+// newClient returns a Client for an Anthropic stand-in that replies with the
+// given text blocks, streamed one delta per block when asked to, and a
+// pointer to the last request body it saw.
+func newClient(t *testing.T, reply []string, opts Options) (*Client, *string) {
+	t.Helper()
+	pack := governor.Rulepack{ID: "llm", Rules: []governor.RuleDefinition{
+		{ID: "prompt", Pattern: `@`, Allow: false},
+		{ID: "completion", Pattern: `secret`, Allow: false},
+		{ID: "operation", Pattern: `.`, Allow: true},
+	}}
+	gov := governortest.NewControlPlane(t, pack).NewGovernor(t)
+
+	var sent string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		sent = string(body)
+		if !strings.Contains(sent, `"stream":true`) {
+			content := make([]any, len(reply))
+			for i, text := range reply {
+				content[i] = map[string]any{"type": "text", "text": text}
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"id": "msg_1", "type": "message", "role": "assistant", "model": "claude-sonnet-4-5",
+				"content": content, "stop_reason": "end_turn",
+			})
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"id\":\"msg_1\",\"type\":\"message\",\"role\":\"assistant\",\"model\":\"claude-sonnet-4-5\",\"content\":[]}}\n\n")
+		fmt.Fprint(w, "event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":0,\"content_block\":{\"type\":\"text\",\"text\":\"\"}}\n\n")
+		for _, text := range reply {
+			delta, _ := json.Marshal(map[string]any{"type": "content_block_delta", "index": 0, "delta": map[string]any{"type": "text_delta", "text": text}})
+			fmt.Fprintf(w, "event: content_block_delta\ndata: %s\n\n", delta)
+		}
+		fmt.Fprint(w, "event: content_block_stop\ndata: {\"type\":\"content_block_stop\",\"index\":0}\n\n")
+		fmt.Fprint(w, "event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n")
+	}))
+	t.Cleanup(upstream.Close)
+
+	client := anthropiclib.NewClient(option.WithAPIKey("sk-test"), option.WithBaseURL(upstream.URL), option.WithMaxRetries(0))
+	opts.RulepackID = "llm"
+	return New(&client, gov, opts), &sent
+}
+
+func messageParams(prompt string) anthropiclib.MessageNewParams {
+	return anthropiclib.MessageNewParams{
+		Model:     anthropiclib.ModelClaudeSonnet4_5,
+		MaxTokens: 256,
+		System:    []anthropiclib.TextBlockParam{{Text: "Be brief."}},
+		Messages:  []anthropiclib.MessageParam{anthropiclib.NewUserMessage(anthropiclib.NewTextBlock(prompt))},
+	}
+}
+
+func TestNewMessage(t *testing.T) {
+	client, sent := newClient(t, []string{"Hello there."}, Options{})
+
+	resp, err := client.NewMessage(context.Background(), messageParams("Say hello"))
+	if err != nil || resp.Content[0].Text != "Hello there." {
+		t.Fatalf("expected the reply, got %+v %v", resp, err)
+	}
+
+	*sent = ""
+	_, err = client.NewMessage(context.Background(), messageParams("Mail jane@example.com"))
+	var denied *aisentinelhttp.DeniedError
+	if !errors.As(err, &denied) || denied.Phase != "request" {
+		t.Fatalf("expected the prompt to be denied, got %v", err)
+	}
+	if *sent != "" {
+		t.Fatalf("expected the denied prompt not to be sent, got %s", *sent)
+	}
+}
+
+func TestNewMessageRedacts(t *testing.T) {
+	client, sent := newClient(t, []string{"Noted.", "The secret is 42."}, Options{OnDeny: aisentinelhttp.ActionRedact, Redact: func(text string) string {
+		return strings.NewReplacer("jane@example.com", "[EMAIL]", "secret", "[REDACTED]").Replace(text)
+	}})
+
+	resp, err := client.NewMessage(context.Background(), messageParams("Mail jane@example.com"))
+	if err != nil {
+		t.Fatalf("expected the redacted prompt to be allowed, got %v", err)
+	}
+	if strings.Contains(*sent, "jane@example.com") || !strings.Contains(*sent, "[EMAIL]") || !strings.Contains(*sent, "Be brief.") {
+		t.Fatalf("expected only the email to be redacted, sent %s", *sent)
+	}
+	if resp.Content[0].Text != "Noted." || resp.Content[1].Text != "The [REDACTED] is 42." {
+		t.Fatalf("expected only the denied block to be redacted, got %+v", resp.Content)
+	}
+}
+
+func TestNewMessageStreaming(t *testing.T) {
+	client, _ := newClient(t, []string{"The ", "sec", "ret", " is 42."}, Options{})
+
+	stream := client.NewMessageStreaming(context.Background(), messageParams("Tell me"))
+	defer stream.Close()
+	var text strings.Builder
+	for stream.Next() {
+		if event := stream.Current(); event.Type == "content_block_delta" {
+			text.WriteString(event.Delta.Text)
+		}
+	}
+	var denied *aisentinelhttp.DeniedError
+	if !errors.As(stream.Err(), &denied) || denied.Phase != "response" {
+		t.Fatalf("expected the stream to be denied, got %v", stream.Err())
+	}
+	if text.String() != "The sec" {
+		t.Fatalf("expected the stream to stop before the denied delta, got %q", text.String())
+	}
+
+	client.opts.SkipResponse = true
+	stream = client.NewMessageStreaming(context.Background(), messageParams("Tell me"))
+	defer stream.Close()
+	var message anthropiclib.Message
+	for stream.Next() {
+		if err := message.Accumulate(stream.Current()); err != nil {
+			t.Fatalf("accumulate: %v", err)
+		}
+	}
+	if stream.Err() != nil || message.Content[0].Text != "The secret is 42." {
+		t.Fatalf("expected SkipResponse to pass the stream, got %+v %v", message.Content, stream.Err())
+	}
+
+	if stream := client.NewMessageStreaming(context.Background(), messageParams("Mail jane@example.com")); stream.Next() || !errors.Is(stream.Err(), aisentinelhttp.ErrDenied) {
+		t.Fatalf("expected the prompt to be denied, got %v", stream.Err())
+	}
+}
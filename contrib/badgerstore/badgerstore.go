@@ -0,0 +1,239 @@
+// Package badgerstore persists Governor audit records in a BadgerDB
+// directory. Importing it registers the store for the "badger" storage
+// backend, with Config.StorageDSN as the directory and
+// storage.DefaultBadgerOptions:
+//
+//	import _ "github.com/mfifth/aisentinel-go-sdk/contrib/badgerstore"
+//
+//	gov, err := governor.NewGovernor(ctx, governor.Config{
+//		StorageBackend: "badger",
+//		StorageDSN:     "/var/lib/app/audit",
+//	})
+//
+// Use Open and governor.WithStorage for other options.
+package badgerstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/mfifth/aisentinel-go-sdk/storage"
+)
+
+func init() {
+	storage.Register(storage.BackendBadger, func(dsn string) (storage.Store, error) {
+		return Open(dsn, storage.DefaultBadgerOptions())
+	})
+}
+
+// iterBatch is how many records Iter reads per read transaction, so a slow
+// fn does not pin old versions against garbage collection.
+const iterBatch = 256
+
+// Store is a storage.Store backed by a BadgerDB directory. Every Put and
+// Delete is a committed transaction. It is safe for concurrent use.
+type Store struct {
+	db     *badger.DB
+	stop   chan struct{}
+	wg     sync.WaitGroup
+	closed sync.Once
+}
+
+var (
+	_ storage.Store         = (*Store)(nil)
+	_ storage.RangeIterator = (*Store)(nil)
+	_ storage.BatchWriter   = (*Store)(nil)
+)
+
+// Open opens or creates the database in dir and, with opts.GCInterval,
+// starts collecting value log garbage in the background until Close.
+func Open(dir string, opts storage.BadgerOptions) (*Store, error) {
+	badgerOpts := badger.DefaultOptions(dir).
+		WithSyncWrites(opts.SyncWrites).
+		WithLoggingLevel(badger.WARNING)
+	if opts.ValueLogFileSize > 0 {
+		badgerOpts = badgerOpts.WithValueLogFileSize(opts.ValueLogFileSize)
+	}
+	db, err := badger.Open(badgerOpts)
+	if err != nil {
+		return nil, fmt.Errorf("badgerstore: open %s: %w", dir, err)
+	}
+	s := &Store{db: db, stop: make(chan struct{})}
+	if opts.GCInterval > 0 {
+		s.wg.Add(1)
+		go s.collectGarbage(opts.GCInterval, opts.GCDiscardRatio)
+	}
+	return s, nil
+}
+
+// collectGarbage runs value log GC every interval until Close. Each run
+// rewrites files until none has discardRatio garbage left.
+func (s *Store) collectGarbage(interval time.Duration, discardRatio float64) {
+	defer s.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			for s.db.RunValueLogGC(discardRatio) == nil {
+			}
+		}
+	}
+}
+
+// Put stores a record.
+func (s *Store) Put(ctx context.Context, record storage.Record) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	// Badger may reference the value until the transaction commits;
+	// cloning keeps the caller free to reuse its buffer either way.
+	value := bytes.Clone(record.Value)
+	return s.db.Update(func(txn *badger.Txn) error {
+		return txn.Set([]byte(record.Key), value)
+	})
+}
+
+// PutBatch stores records with a write batch, which splits batches too
+// large for one transaction, so a failed batch may be partially written.
+func (s *Store) PutBatch(ctx context.Context, records []storage.Record) error {
+	return s.writeBatch(ctx, func(wb *badger.WriteBatch) error {
+		for _, record := range records {
+			if err := wb.Set([]byte(record.Key), bytes.Clone(record.Value)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// writeBatch commits the writes made by fn as one write batch.
+func (s *Store) writeBatch(ctx context.Context, fn func(*badger.WriteBatch) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	wb := s.db.NewWriteBatch()
+	defer wb.Cancel()
+	if err := fn(wb); err != nil {
+		return err
+	}
+	return wb.Flush()
+}
+
+// Get retrieves a record by key, returning storage.ErrNotFound() when there
+// is none.
+func (s *Store) Get(ctx context.Context, key string) (storage.Record, error) {
+	if err := ctx.Err(); err != nil {
+		return storage.Record{}, err
+	}
+	var value []byte
+	err := s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(key))
+		if err != nil {
+			return err
+		}
+		value, err = item.ValueCopy(nil)
+		return err
+	})
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return storage.Record{}, storage.ErrNotFound()
+	}
+	if err != nil {
+		return storage.Record{}, err
+	}
+	return storage.Record{Key: key, Value: value}, nil
+}
+
+// Iter iterates over all records in key order. Records are read in batches,
+// each from its own read transaction, so fn sees records written by earlier
+// calls.
+func (s *Store) Iter(ctx context.Context, fn func(storage.Record) error) error {
+	return s.IterRange(ctx, "", "", fn)
+}
+
+// IterRange iterates in key order over the records with keys from start,
+// inclusive, to end, exclusive; an empty end is unbounded. Records are read
+// in batches, as by Iter.
+func (s *Store) IterRange(ctx context.Context, start, end string, fn func(storage.Record) error) error {
+	from, after := []byte(start), false
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		batch := make([]storage.Record, 0, iterBatch)
+		err := s.db.View(func(txn *badger.Txn) error {
+			it := txn.NewIterator(badger.DefaultIteratorOptions)
+			defer it.Close()
+			it.Seek(from)
+			if after && it.Valid() && bytes.Equal(it.Item().Key(), from) {
+				it.Next()
+			}
+			for ; it.Valid() && len(batch) < iterBatch; it.Next() {
+				item := it.Item()
+				if end != "" && string(item.Key()) >= end {
+					break
+				}
+				value, err := item.ValueCopy(nil)
+				if err != nil {
+					return err
+				}
+				batch = append(batch, storage.Record{Key: string(item.Key()), Value: value})
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, record := range batch {
+			if err := fn(record); err != nil {
+				return err
+			}
+		}
+		if len(batch) < iterBatch {
+			return nil
+		}
+		from, after = []byte(batch[len(batch)-1].Key), true
+	}
+}
+
+// Delete removes a record by key.
+func (s *Store) Delete(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.db.Update(func(txn *badger.Txn) error {
+		return txn.Delete([]byte(key))
+	})
+}
+
+// DeleteBatch removes records by key with a write batch, as PutBatch
+// writes them.
+func (s *Store) DeleteBatch(ctx context.Context, keys []string) error {
+	return s.writeBatch(ctx, func(wb *badger.WriteBatch) error {
+		for _, key := range keys {
+			if err := wb.Delete([]byte(key)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Close stops garbage collection and closes the database, flushing
+// pending writes.
+func (s *Store) Close() error {
+	err := errors.New("badgerstore: already closed")
+	s.closed.Do(func() {
+		close(s.stop)
+		s.wg.Wait()
+		err = s.db.Close()
+	})
+	return err
+}
@@ -0,0 +1,95 @@
+package badgerstore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	governor "github.com/mfifth/aisentinel-go-sdk"
+	"github.com/mfifth/aisentinel-go-sdk/storage"
+	"github.com/mfifth/aisentinel-go-sdk/storage/storagetest"
+)
+
+func TestStore(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Store {
+		store, err := Open(t.TempDir(), storage.DefaultBadgerOptions())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return store
+	})
+}
+
+func TestReopen(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	opts := storage.DefaultBadgerOptions()
+	store, err := Open(dir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < iterBatch+10; i++ {
+		if err := store.Put(ctx, storage.Record{Key: fmt.Sprintf("k%04d", i), Value: []byte(fmt.Sprint(i))}); err != nil {
+			t.Fatalf("put: %v", err)
+		}
+	}
+	if _, err := Open(dir, opts); err == nil {
+		t.Fatal("expected a second open of the directory to fail")
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if err := store.Close(); err == nil {
+		t.Fatal("expected a second close to fail")
+	}
+
+	store, err = Open(dir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	var keys []string
+	err = store.Iter(ctx, func(r storage.Record) error {
+		keys = append(keys, r.Key)
+		return store.Put(ctx, storage.Record{Key: "a-" + r.Key})
+	})
+	if err != nil {
+		t.Fatalf("iter: %v", err)
+	}
+	if len(keys) != iterBatch+10 || keys[0] != "k0000" || keys[1] != "k0001" {
+		t.Fatalf("expected the records to persist in key order, got %d keys starting %v", len(keys), keys[:2])
+	}
+}
+
+func TestRegisteredBackend(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	pack := &governor.Rulepack{ID: "prompts", Rules: []governor.RuleDefinition{{ID: "prompt", Pattern: "ok", Allow: true}}}
+	cfg := governor.Config{APIKey: "test", OfflineMode: true, StorageBackend: "badger", StorageDSN: dir}
+	for _, w := range cfg.Warnings() {
+		if w.Field == "StorageBackend" {
+			t.Fatalf("expected no in-memory warning once registered, got %q", w.Message)
+		}
+	}
+	gov, err := governor.NewGovernor(ctx, cfg, governor.WithRulepacks(pack))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := gov.Evaluate(ctx, governor.DecisionRequest{RulepackID: "prompts", Payload: json.RawMessage(`{"prompt":"ok"}`)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := gov.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := Open(dir, storage.BadgerOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	n := 0
+	if err := store.Iter(ctx, func(storage.Record) error { n++; return nil }); err != nil || n != 1 {
+		t.Fatalf("expected the audit record persisted, got %d %v", n, err)
+	}
+}
@@ -0,0 +1,216 @@
+// Package boltstore persists Governor audit records in a bbolt database
+// file. Importing it registers the store for the "bolt" storage backend,
+// with Config.StorageDSN as the file path:
+//
+//	import _ "github.com/mfifth/aisentinel-go-sdk/contrib/boltstore"
+//
+//	gov, err := governor.NewGovernor(ctx, governor.Config{
+//		StorageBackend: "bolt",
+//		StorageDSN:     "/var/lib/app/audit.db",
+//	})
+//
+// Use Open and governor.WithStorage for other Options.
+package boltstore
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/mfifth/aisentinel-go-sdk/storage"
+	bolt "go.etcd.io/bbolt"
+)
+
+func init() {
+	storage.Register(storage.BackendBolt, func(dsn string) (storage.Store, error) {
+		return Open(dsn, DefaultOptions())
+	})
+}
+
+// bucket holds the records, keyed by record key.
+var bucket = []byte("records")
+
+// iterBatch is how many records Iter reads per read transaction. fn runs
+// outside the transaction, so it may write to the store.
+const iterBatch = 256
+
+// Options tune a Store.
+type Options struct {
+	// Timeout bounds the wait for the file lock when another process has
+	// the database open. Zero waits forever.
+	Timeout time.Duration
+	// NoSync skips the fsync after each write. Writes are faster, but
+	// the latest records may be lost if the machine crashes.
+	NoSync bool
+	// Mode is the permission of a newly created file; zero means 0600.
+	Mode os.FileMode
+}
+
+// DefaultOptions returns the Options the registered backend uses: a one
+// second lock timeout, so a second process fails fast, and synced writes.
+func DefaultOptions() Options {
+	return Options{Timeout: time.Second}
+}
+
+// Store is a storage.Store backed by a bbolt database file. Every Put and
+// Delete is a committed transaction, so records survive a crash once it
+// returns. It is safe for concurrent use.
+type Store struct {
+	db *bolt.DB
+}
+
+var (
+	_ storage.Store         = (*Store)(nil)
+	_ storage.RangeIterator = (*Store)(nil)
+	_ storage.BatchWriter   = (*Store)(nil)
+)
+
+// Open opens or creates the database at path.
+func Open(path string, opts Options) (*Store, error) {
+	mode := opts.Mode
+	if mode == 0 {
+		mode = 0o600
+	}
+	db, err := bolt.Open(path, mode, &bolt.Options{Timeout: opts.Timeout, NoSync: opts.NoSync})
+	if err != nil {
+		return nil, fmt.Errorf("boltstore: open %s: %w", path, err)
+	}
+	if err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bucket)
+		return err
+	}); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("boltstore: open %s: %w", path, err)
+	}
+	return &Store{db: db}, nil
+}
+
+// Put stores a record.
+func (s *Store) Put(ctx context.Context, record storage.Record) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).Put([]byte(record.Key), record.Value)
+	})
+}
+
+// PutBatch stores records in one transaction, so they are written together
+// or not at all.
+func (s *Store) PutBatch(ctx context.Context, records []storage.Record) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucket)
+		for _, record := range records {
+			if err := b.Put([]byte(record.Key), record.Value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Get retrieves a record by key, returning storage.ErrNotFound() when there
+// is none.
+func (s *Store) Get(ctx context.Context, key string) (storage.Record, error) {
+	if err := ctx.Err(); err != nil {
+		return storage.Record{}, err
+	}
+	var (
+		value []byte
+		found bool
+	)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		// Seek rather than Get tells an empty value from a missing key.
+		k, v := tx.Bucket(bucket).Cursor().Seek([]byte(key))
+		if found = k != nil && string(k) == key; found {
+			value = bytes.Clone(v)
+		}
+		return nil
+	})
+	if err != nil {
+		return storage.Record{}, err
+	}
+	if !found {
+		return storage.Record{}, storage.ErrNotFound()
+	}
+	return storage.Record{Key: key, Value: value}, nil
+}
+
+// Iter iterates over all records in key order. Records are read in batches,
+// each from its own read transaction, so fn sees records written by earlier
+// calls but never blocks writers.
+func (s *Store) Iter(ctx context.Context, fn func(storage.Record) error) error {
+	return s.IterRange(ctx, "", "", fn)
+}
+
+// IterRange iterates in key order over the records with keys from start,
+// inclusive, to end, exclusive; an empty end is unbounded. Records are read
+// in batches, as by Iter.
+func (s *Store) IterRange(ctx context.Context, start, end string, fn func(storage.Record) error) error {
+	from, after := []byte(start), false
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		batch := make([]storage.Record, 0, iterBatch)
+		err := s.db.View(func(tx *bolt.Tx) error {
+			c := tx.Bucket(bucket).Cursor()
+			k, v := c.Seek(from)
+			if after && k != nil && bytes.Equal(k, from) {
+				k, v = c.Next()
+			}
+			for ; k != nil && (end == "" || string(k) < end) && len(batch) < iterBatch; k, v = c.Next() {
+				batch = append(batch, storage.Record{Key: string(k), Value: bytes.Clone(v)})
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, record := range batch {
+			if err := fn(record); err != nil {
+				return err
+			}
+		}
+		if len(batch) < iterBatch {
+			return nil
+		}
+		from, after = []byte(batch[len(batch)-1].Key), true
+	}
+}
+
+// Delete removes a record by key.
+func (s *Store) Delete(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).Delete([]byte(key))
+	})
+}
+
+// DeleteBatch removes records by key in one transaction.
+func (s *Store) DeleteBatch(ctx context.Context, keys []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucket)
+		for _, key := range keys {
+			if err := b.Delete([]byte(key)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Close closes the database file, releasing its lock.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,95 @@
+package boltstore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	governor "github.com/mfifth/aisentinel-go-sdk"
+	"github.com/mfifth/aisentinel-go-sdk/storage"
+	"github.com/mfifth/aisentinel-go-sdk/storage/storagetest"
+)
+
+func TestStore(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Store {
+		store, err := Open(filepath.Join(t.TempDir(), "audit.db"), DefaultOptions())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return store
+	})
+}
+
+func TestReopen(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "audit.db")
+	store, err := Open(path, DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < iterBatch+10; i++ {
+		if err := store.Put(ctx, storage.Record{Key: fmt.Sprintf("k%04d", i), Value: []byte(fmt.Sprint(i))}); err != nil {
+			t.Fatalf("put: %v", err)
+		}
+	}
+	if _, err := Open(path, Options{Timeout: 50 * time.Millisecond}); err == nil {
+		t.Fatal("expected a second open to time out on the file lock")
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	store, err = Open(path, DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	var keys []string
+	err = store.Iter(ctx, func(r storage.Record) error {
+		keys = append(keys, r.Key)
+		// Writing while iterating must not deadlock. The keys sort before
+		// the cursor, so they are not iterated.
+		return store.Put(ctx, storage.Record{Key: "a-" + r.Key})
+	})
+	if err != nil {
+		t.Fatalf("iter: %v", err)
+	}
+	if len(keys) != iterBatch+10 || keys[0] != "k0000" || keys[1] != "k0001" {
+		t.Fatalf("expected the records to persist in key order, got %d keys starting %v", len(keys), keys[:2])
+	}
+}
+
+func TestRegisteredBackend(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "audit.db")
+	pack := &governor.Rulepack{ID: "prompts", Rules: []governor.RuleDefinition{{ID: "prompt", Pattern: "ok", Allow: true}}}
+	cfg := governor.Config{APIKey: "test", OfflineMode: true, StorageBackend: "bolt", StorageDSN: path}
+	for _, w := range cfg.Warnings() {
+		if w.Field == "StorageBackend" {
+			t.Fatalf("expected no in-memory warning once registered, got %q", w.Message)
+		}
+	}
+	gov, err := governor.NewGovernor(ctx, cfg, governor.WithRulepacks(pack))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := gov.Evaluate(ctx, governor.DecisionRequest{RulepackID: "prompts", Payload: json.RawMessage(`{"prompt":"ok"}`)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := gov.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := Open(path, DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	n := 0
+	if err := store.Iter(ctx, func(storage.Record) error { n++; return nil }); err != nil || n != 1 {
+		t.Fatalf("expected the audit record persisted, got %d %v", n, err)
+	}
+}
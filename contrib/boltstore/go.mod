@@ -0,0 +1,16 @@
+module github.com/mfifth/aisentinel-go-sdk/contrib/boltstore
+
+go 1.23
+
+replace github.com/mfifth/aisentinel-go-sdk => ../..
+
+require (
+	github.com/mfifth/aisentinel-go-sdk v0.0.0
+	go.etcd.io/bbolt v1.4.3
+)
+
+require (
+	github.com/BurntSushi/toml v1.6.0 // indirect
+	golang.org/x/sys v0.29.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
@@ -0,0 +1,124 @@
+// Package echo guards Echo routes with the Governor. Middleware evaluates
+// each request, by default its JSON body, before the next handler runs:
+//
+//	e.Use(aisentinelecho.Middleware(gov, aisentinelecho.Options{RulepackID: "prompts"}))
+//
+// Denied requests fail with a 403 *echo.HTTPError carrying the reason and
+// rule ID, and requests that cannot be evaluated with 400 or 503, so the
+// application's HTTPErrorHandler renders them; Options.OnDeny and
+// Options.OnError replace these. Handlers read the decision that admitted a
+// request with Decision.
+package echo
+
+import (
+	"errors"
+	"net/http"
+
+	echolib "github.com/labstack/echo/v4"
+	governor "github.com/mfifth/aisentinel-go-sdk"
+	"github.com/mfifth/aisentinel-go-sdk/aisentinelhttp"
+)
+
+// ContextKey is the echo.Context key holding the DecisionResult that admitted
+// a request.
+const ContextKey = "aisentinel.decision"
+
+// Extractor builds the decision request for an Echo request. An empty
+// RulepackID is filled from Options.RulepackID.
+type Extractor func(c echolib.Context) (governor.DecisionRequest, error)
+
+// Options configures Middleware. The zero value evaluates the JSON request
+// body against the Governor's default rulepack and fails closed.
+type Options struct {
+	// RulepackID is the rulepack to evaluate; empty selects
+	// Config.DefaultRulepackID.
+	RulepackID string
+	// Extract builds the decision request. Defaults to the request body, as
+	// aisentinelhttp.JSONBody(aisentinelhttp.DefaultMaxBodyBytes).
+	Extract Extractor
+	// Skipper lets matching requests through without evaluation.
+	Skipper func(c echolib.Context) bool
+	// OnDeny produces the response for a denied request. The default returns
+	// a 403 *echo.HTTPError carrying the reason and rule ID, so the
+	// application's HTTPErrorHandler renders it.
+	OnDeny func(c echolib.Context, result governor.DecisionResult) error
+	// OnError produces the response when the request cannot be extracted or
+	// evaluated. The default returns a 400 *echo.HTTPError for extraction
+	// errors and 503 otherwise.
+	OnError func(c echolib.Context, err error) error
+	// FailOpen calls the next handler when the Governor cannot decide.
+	FailOpen bool
+}
+
+// Middleware evaluates every request with gov before the next handler runs.
+// Admitted decisions are stored under ContextKey; see Decision.
+func Middleware(gov *governor.Governor, opts Options) echolib.MiddlewareFunc {
+	if opts.Extract == nil {
+		body := aisentinelhttp.JSONBody(aisentinelhttp.DefaultMaxBodyBytes)
+		opts.Extract = func(c echolib.Context) (governor.DecisionRequest, error) { return body(c.Request()) }
+	}
+	if opts.OnDeny == nil {
+		opts.OnDeny = func(_ echolib.Context, result governor.DecisionResult) error {
+			return echolib.NewHTTPError(http.StatusForbidden, map[string]string{"error": "request denied by policy", "reason": result.Reason, "rule_id": result.RuleID})
+		}
+	}
+	if opts.OnError == nil {
+		opts.OnError = defaultOnError
+	}
+	return func(next echolib.HandlerFunc) echolib.HandlerFunc {
+		return func(c echolib.Context) error {
+			if opts.Skipper != nil && opts.Skipper(c) {
+				return next(c)
+			}
+			req, err := opts.Extract(c)
+			if err != nil {
+				return opts.OnError(c, &extractError{err})
+			}
+			if req.RulepackID == "" {
+				req.RulepackID = opts.RulepackID
+			}
+			result, err := gov.Evaluate(c.Request().Context(), req)
+			switch {
+			case err != nil && opts.FailOpen:
+				return next(c)
+			case err != nil:
+				return opts.OnError(c, err)
+			case !result.Allowed:
+				return opts.OnDeny(c, result)
+			}
+			c.Set(ContextKey, result)
+			return next(c)
+		}
+	}
+}
+
+// Decision returns the decision that admitted the request.
+func Decision(c echolib.Context) (governor.DecisionResult, bool) {
+	result, ok := c.Get(ContextKey).(governor.DecisionResult)
+	return result, ok
+}
+
+// extractError marks failures to build the decision request.
+type extractError struct{ err error }
+
+func (e *extractError) Error() string { return e.err.Error() }
+
+func (e *extractError) Unwrap() error { return e.err }
+
+// IsExtractError reports whether err, as passed to Options.OnError, came from
+// the Extractor rather than the Governor.
+func IsExtractError(err error) bool {
+	var target *extractError
+	return errors.As(err, &target)
+}
+
+func defaultOnError(_ echolib.Context, err error) error {
+	switch {
+	case errors.Is(err, aisentinelhttp.ErrBodyTooLarge):
+		return echolib.NewHTTPError(http.StatusRequestEntityTooLarge, err.Error()).SetInternal(err)
+	case IsExtractError(err):
+		return echolib.NewHTTPError(http.StatusBadRequest, err.Error()).SetInternal(err)
+	default:
+		return echolib.NewHTTPError(http.StatusServiceUnavailable, "policy evaluation failed").SetInternal(err)
+	}
+}
@@ -0,0 +1,69 @@
+package echo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	echolib "github.com/labstack/echo/v4"
+	governor "github.com/mfifth/aisentinel-go-sdk"
+	"github.com/mfifth/aisentinel-go-sdk/governortest"
+)
+
+func newServer(t *testing.T, opts Options) *echolib.Echo {
+	t.Helper()
+	pack := governor.Rulepack{ID: "remote", Rules: []governor.RuleDefinition{{ID: "prompt", Pattern: "^ok", Allow: true}}}
+	gov := governortest.NewControlPlane(t, pack).NewGovernor(t)
+
+	e := echolib.New()
+	e.Use(Middleware(gov, opts))
+	e.POST("/chat", func(c echolib.Context) error {
+		result, ok := Decision(c)
+		if !ok {
+			t.Error("expected the decision in the context")
+		}
+		var body struct{ Prompt string }
+		if err := c.Bind(&body); err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, map[string]string{"echo": body.Prompt, "rule_id": result.RuleID})
+	})
+	return e
+}
+
+func post(e *echolib.Echo, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/chat", strings.NewReader(body))
+	req.Header.Set(echolib.HeaderContentType, echolib.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestMiddleware(t *testing.T) {
+	e := newServer(t, Options{RulepackID: "remote"})
+
+	if rec := post(e, `{"prompt":"ok then"}`); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"echo":"ok then"`) {
+		t.Fatalf("expected the body to reach the handler, got %d %s", rec.Code, rec.Body)
+	}
+	if rec := post(e, `{"prompt":"leak"}`); rec.Code != http.StatusForbidden || !strings.Contains(rec.Body.String(), "request denied by policy") {
+		t.Fatalf("expected 403, got %d %s", rec.Code, rec.Body)
+	}
+}
+
+func TestMiddlewareCustomResponses(t *testing.T) {
+	e := newServer(t, Options{
+		RulepackID: "remote",
+		OnDeny: func(c echolib.Context, result governor.DecisionResult) error {
+			return c.String(http.StatusTeapot, "denied: "+result.Reason)
+		},
+	})
+	if rec := post(e, `{"prompt":"leak"}`); rec.Code != http.StatusTeapot || !strings.HasPrefix(rec.Body.String(), "denied: ") {
+		t.Fatalf("expected the custom deny response, got %d %s", rec.Code, rec.Body)
+	}
+
+	unknown := newServer(t, Options{RulepackID: ""})
+	if rec := post(unknown, `{"prompt":"ok"}`); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 without a rulepack, got %d %s", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,139 @@
+// Package fiber guards Fiber routes with the Governor. Fiber runs on fasthttp
+// rather than net/http, so aisentinelhttp.Middleware cannot be used directly;
+// Middleware evaluates each request, by default its body as read by
+// JSONBody, before the next handler runs:
+//
+//	app.Use(aisentinelfiber.Middleware(gov, aisentinelfiber.Options{RulepackID: "prompts"}))
+//
+// Denied requests get 403 and a JSON body carrying the reason and rule ID,
+// and requests that cannot be evaluated a 400 or 503 *fiber.Error for the
+// application's ErrorHandler, unless Options.OnDeny and Options.OnError
+// respond otherwise. Handlers read the decision that admitted a request with
+// Decision.
+package fiber
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+
+	fiberlib "github.com/gofiber/fiber/v2"
+	governor "github.com/mfifth/aisentinel-go-sdk"
+)
+
+// ContextKey is the fiber.Ctx Locals key holding the DecisionResult that
+// admitted a request.
+const ContextKey = "aisentinel.decision"
+
+// Extractor builds the decision request for a Fiber request. An empty
+// RulepackID is filled from Options.RulepackID.
+type Extractor func(c *fiberlib.Ctx) (governor.DecisionRequest, error)
+
+// Options configures Middleware. The zero value evaluates the JSON request
+// body against the Governor's default rulepack and fails closed.
+type Options struct {
+	// RulepackID is the rulepack to evaluate; empty selects
+	// Config.DefaultRulepackID.
+	RulepackID string
+	// Extract builds the decision request. Defaults to JSONBody.
+	Extract Extractor
+	// Next lets matching requests through without evaluation, like the Next
+	// option of Fiber's own middleware.
+	Next func(c *fiberlib.Ctx) bool
+	// OnDeny produces the response for a denied request. The default responds
+	// 403 with a JSON body carrying the reason and rule ID.
+	OnDeny func(c *fiberlib.Ctx, result governor.DecisionResult) error
+	// OnError produces the response when the request cannot be extracted or
+	// evaluated. The default returns a 400 *fiber.Error for extraction errors
+	// and 503 otherwise, so the application's ErrorHandler renders them.
+	OnError func(c *fiberlib.Ctx, err error) error
+	// FailOpen calls the next handler when the Governor cannot decide.
+	FailOpen bool
+}
+
+// Middleware evaluates every request with gov before the next handler runs.
+// Admitted decisions are stored in Locals under ContextKey; see Decision.
+func Middleware(gov *governor.Governor, opts Options) fiberlib.Handler {
+	if opts.Extract == nil {
+		opts.Extract = JSONBody
+	}
+	if opts.OnDeny == nil {
+		opts.OnDeny = func(c *fiberlib.Ctx, result governor.DecisionResult) error {
+			return c.Status(fiberlib.StatusForbidden).JSON(fiberlib.Map{"error": "request denied by policy", "reason": result.Reason, "rule_id": result.RuleID})
+		}
+	}
+	if opts.OnError == nil {
+		opts.OnError = defaultOnError
+	}
+	return func(c *fiberlib.Ctx) error {
+		if opts.Next != nil && opts.Next(c) {
+			return c.Next()
+		}
+		req, err := opts.Extract(c)
+		if err != nil {
+			return opts.OnError(c, &extractError{err})
+		}
+		if req.RulepackID == "" {
+			req.RulepackID = opts.RulepackID
+		}
+		result, err := gov.Evaluate(c.UserContext(), req)
+		switch {
+		case err != nil && opts.FailOpen:
+			return c.Next()
+		case err != nil:
+			return opts.OnError(c, err)
+		case !result.Allowed:
+			return opts.OnDeny(c, result)
+		}
+		c.Locals(ContextKey, result)
+		return c.Next()
+	}
+}
+
+// Decision returns the decision that admitted the request.
+func Decision(c *fiberlib.Ctx) (governor.DecisionResult, bool) {
+	result, ok := c.Locals(ContextKey).(governor.DecisionResult)
+	return result, ok
+}
+
+// JSONBody uses the request body as the payload, as aisentinelhttp.JSONBody
+// does: JSON is used as is, other text is wrapped as {"body": "<text>"} and
+// an empty body becomes {}. Fiber's BodyLimit bounds the body size.
+func JSONBody(c *fiberlib.Ctx) (governor.DecisionRequest, error) {
+	var req governor.DecisionRequest
+	body := c.Body()
+	switch {
+	case len(bytes.TrimSpace(body)) == 0:
+		req.Payload = json.RawMessage("{}")
+	case json.Valid(body):
+		req.Payload = append(json.RawMessage(nil), body...)
+	default:
+		raw, err := json.Marshal(map[string]string{"body": string(body)})
+		if err != nil {
+			return req, err
+		}
+		req.Payload = raw
+	}
+	return req, nil
+}
+
+// extractError marks failures to build the decision request.
+type extractError struct{ err error }
+
+func (e *extractError) Error() string { return e.err.Error() }
+
+func (e *extractError) Unwrap() error { return e.err }
+
+// IsExtractError reports whether err, as passed to Options.OnError, came from
+// the Extractor rather than the Governor.
+func IsExtractError(err error) bool {
+	var target *extractError
+	return errors.As(err, &target)
+}
+
+func defaultOnError(_ *fiberlib.Ctx, err error) error {
+	if IsExtractError(err) {
+		return fiberlib.NewError(fiberlib.StatusBadRequest, err.Error())
+	}
+	return fiberlib.NewError(fiberlib.StatusServiceUnavailable, "policy evaluation failed")
+}
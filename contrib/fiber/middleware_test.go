@@ -0,0 +1,86 @@
+package fiber
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	fiberlib "github.com/gofiber/fiber/v2"
+	governor "github.com/mfifth/aisentinel-go-sdk"
+	"github.com/mfifth/aisentinel-go-sdk/governortest"
+)
+
+func newApp(t *testing.T, opts Options) *fiberlib.App {
+	t.Helper()
+	pack := governor.Rulepack{ID: "remote", Rules: []governor.RuleDefinition{{ID: "prompt", Pattern: "^ok", Allow: true}}}
+	gov := governortest.NewControlPlane(t, pack).NewGovernor(t)
+
+	app := fiberlib.New()
+	app.Use(Middleware(gov, opts))
+	app.Post("/chat", func(c *fiberlib.Ctx) error {
+		result, ok := Decision(c)
+		if !ok {
+			t.Error("expected the decision in Locals")
+		}
+		var body struct{ Prompt string }
+		if err := c.BodyParser(&body); err != nil {
+			return err
+		}
+		return c.JSON(fiberlib.Map{"echo": body.Prompt, "rule_id": result.RuleID})
+	})
+	return app
+}
+
+func post(t *testing.T, app *fiberlib.App, body string) (int, string) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/chat", strings.NewReader(body))
+	req.Header.Set(fiberlib.HeaderContentType, fiberlib.MIMEApplicationJSON)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	defer resp.Body.Close()
+	out, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(out)
+}
+
+func TestMiddleware(t *testing.T) {
+	app := newApp(t, Options{RulepackID: "remote"})
+
+	if code, body := post(t, app, `{"prompt":"ok then"}`); code != http.StatusOK || !strings.Contains(body, `"echo":"ok then"`) || !strings.Contains(body, `"rule_id":"prompt"`) {
+		t.Fatalf("expected the body to reach the handler, got %d %s", code, body)
+	}
+	if code, body := post(t, app, `{"prompt":"leak"}`); code != http.StatusForbidden || !strings.Contains(body, "request denied by policy") {
+		t.Fatalf("expected 403, got %d %s", code, body)
+	}
+}
+
+func TestMiddlewareCustomResponses(t *testing.T) {
+	app := newApp(t, Options{
+		RulepackID: "remote",
+		OnDeny: func(c *fiberlib.Ctx, result governor.DecisionResult) error {
+			return c.Status(http.StatusTeapot).SendString("denied: " + result.Reason)
+		},
+	})
+	if code, body := post(t, app, `{"prompt":"leak"}`); code != http.StatusTeapot || !strings.HasPrefix(body, "denied: ") {
+		t.Fatalf("expected the custom deny response, got %d %s", code, body)
+	}
+
+	unknown := newApp(t, Options{})
+	if code, body := post(t, unknown, `{"prompt":"ok"}`); code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 without a rulepack, got %d %s", code, body)
+	}
+
+	failing := newApp(t, Options{
+		RulepackID: "remote",
+		Extract: func(*fiberlib.Ctx) (governor.DecisionRequest, error) {
+			return governor.DecisionRequest{}, errors.New("no prompt")
+		},
+	})
+	if code, body := post(t, failing, `{"prompt":"ok"}`); code != http.StatusBadRequest || !strings.Contains(body, "no prompt") {
+		t.Fatalf("expected 400 for an extraction error, got %d %s", code, body)
+	}
+}
@@ -0,0 +1,127 @@
+// Package gin guards Gin routes with the Governor. Middleware evaluates each
+// request, by default its JSON body, before the remaining handlers run:
+//
+//	router.Use(aisentinelgin.Middleware(gov, aisentinelgin.Options{RulepackID: "prompts"}))
+//
+// Denied requests are aborted with 403 and a JSON body carrying the reason
+// and rule ID, and requests that cannot be evaluated with 400 or 503, unless
+// Options.OnDeny and Options.OnError respond otherwise. Handlers read the
+// decision that admitted a request with Decision.
+package gin
+
+import (
+	"errors"
+	"net/http"
+
+	ginlib "github.com/gin-gonic/gin"
+	governor "github.com/mfifth/aisentinel-go-sdk"
+	"github.com/mfifth/aisentinel-go-sdk/aisentinelhttp"
+)
+
+// ContextKey is the Gin context key holding the DecisionResult that admitted
+// a request.
+const ContextKey = "aisentinel.decision"
+
+// Extractor builds the decision request for a Gin request. An empty
+// RulepackID is filled from Options.RulepackID.
+type Extractor func(c *ginlib.Context) (governor.DecisionRequest, error)
+
+// Options configures Middleware. The zero value evaluates the JSON request
+// body against the Governor's default rulepack and fails closed.
+type Options struct {
+	// RulepackID is the rulepack to evaluate; empty selects
+	// Config.DefaultRulepackID.
+	RulepackID string
+	// Extract builds the decision request. Defaults to the request body, as
+	// aisentinelhttp.JSONBody(aisentinelhttp.DefaultMaxBodyBytes).
+	Extract Extractor
+	// Skip lets matching requests through without evaluation.
+	Skip func(c *ginlib.Context) bool
+	// OnDeny responds to a denied request and must abort the chain. The
+	// default aborts with 403 and a JSON body carrying the reason and rule ID.
+	OnDeny func(c *ginlib.Context, result governor.DecisionResult)
+	// OnError responds when the request cannot be extracted or evaluated and
+	// must abort the chain. The default aborts with 400 for extraction errors
+	// and 503 otherwise.
+	OnError func(c *ginlib.Context, err error)
+	// FailOpen continues the chain when the Governor cannot decide.
+	FailOpen bool
+}
+
+// Middleware evaluates every request with gov before the remaining handlers
+// run. Admitted decisions are stored under ContextKey; see Decision.
+func Middleware(gov *governor.Governor, opts Options) ginlib.HandlerFunc {
+	if opts.Extract == nil {
+		body := aisentinelhttp.JSONBody(aisentinelhttp.DefaultMaxBodyBytes)
+		opts.Extract = func(c *ginlib.Context) (governor.DecisionRequest, error) { return body(c.Request) }
+	}
+	if opts.OnDeny == nil {
+		opts.OnDeny = func(c *ginlib.Context, result governor.DecisionResult) {
+			c.AbortWithStatusJSON(http.StatusForbidden, ginlib.H{"error": "request denied by policy", "reason": result.Reason, "rule_id": result.RuleID})
+		}
+	}
+	if opts.OnError == nil {
+		opts.OnError = defaultOnError
+	}
+	return func(c *ginlib.Context) {
+		if opts.Skip != nil && opts.Skip(c) {
+			c.Next()
+			return
+		}
+		req, err := opts.Extract(c)
+		if err != nil {
+			opts.OnError(c, &extractError{err})
+			return
+		}
+		if req.RulepackID == "" {
+			req.RulepackID = opts.RulepackID
+		}
+		result, err := gov.Evaluate(c.Request.Context(), req)
+		switch {
+		case err != nil && opts.FailOpen:
+			c.Next()
+		case err != nil:
+			opts.OnError(c, err)
+		case !result.Allowed:
+			opts.OnDeny(c, result)
+		default:
+			c.Set(ContextKey, result)
+			c.Next()
+		}
+	}
+}
+
+// Decision returns the decision that admitted the request.
+func Decision(c *ginlib.Context) (governor.DecisionResult, bool) {
+	v, ok := c.Get(ContextKey)
+	if !ok {
+		return governor.DecisionResult{}, false
+	}
+	result, ok := v.(governor.DecisionResult)
+	return result, ok
+}
+
+// extractError marks failures to build the decision request.
+type extractError struct{ err error }
+
+func (e *extractError) Error() string { return e.err.Error() }
+
+func (e *extractError) Unwrap() error { return e.err }
+
+// IsExtractError reports whether err, as passed to Options.OnError, came from
+// the Extractor rather than the Governor.
+func IsExtractError(err error) bool {
+	var target *extractError
+	return errors.As(err, &target)
+}
+
+func defaultOnError(c *ginlib.Context, err error) {
+	switch {
+	case errors.Is(err, aisentinelhttp.ErrBodyTooLarge):
+		c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, ginlib.H{"error": err.Error()})
+	case IsExtractError(err):
+		c.AbortWithStatusJSON(http.StatusBadRequest, ginlib.H{"error": err.Error()})
+	default:
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, ginlib.H{"error": "policy evaluation failed"})
+	}
+}
@@ -0,0 +1,70 @@
+package gin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	ginlib "github.com/gin-gonic/gin"
+	governor "github.com/mfifth/aisentinel-go-sdk"
+	"github.com/mfifth/aisentinel-go-sdk/governortest"
+)
+
+func newRouter(t *testing.T, opts Options) *ginlib.Engine {
+	t.Helper()
+	pack := governor.Rulepack{ID: "remote", Rules: []governor.RuleDefinition{{ID: "prompt", Pattern: "^ok", Allow: true}}}
+	gov := governortest.NewControlPlane(t, pack).NewGovernor(t)
+
+	ginlib.SetMode(ginlib.TestMode)
+	r := ginlib.New()
+	r.Use(Middleware(gov, opts))
+	r.POST("/chat", func(c *ginlib.Context) {
+		result, ok := Decision(c)
+		if !ok {
+			t.Error("expected the decision in the context")
+		}
+		var body struct{ Prompt string }
+		_ = c.BindJSON(&body)
+		c.JSON(http.StatusOK, ginlib.H{"echo": body.Prompt, "rule_id": result.RuleID})
+	})
+	return r
+}
+
+func TestMiddleware(t *testing.T) {
+	r := newRouter(t, Options{RulepackID: "remote"})
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/chat", strings.NewReader(`{"prompt":"ok then"}`)))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"echo":"ok then"`) {
+		t.Fatalf("expected the body to reach the handler, got %d %s", rec.Code, rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/chat", strings.NewReader(`{"prompt":"leak"}`)))
+	if rec.Code != http.StatusForbidden || !strings.Contains(rec.Body.String(), "request denied by policy") {
+		t.Fatalf("expected 403, got %d %s", rec.Code, rec.Body)
+	}
+}
+
+func TestMiddlewareHooks(t *testing.T) {
+	r := newRouter(t, Options{
+		RulepackID: "remote",
+		Extract: func(c *ginlib.Context) (governor.DecisionRequest, error) {
+			payload, err := json.Marshal(map[string]string{"prompt": c.GetHeader("X-Prompt")})
+			return governor.DecisionRequest{Payload: payload}, err
+		},
+		OnDeny: func(c *ginlib.Context, result governor.DecisionResult) {
+			c.AbortWithStatus(http.StatusTeapot)
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/chat", strings.NewReader(`{"prompt":"ok"}`))
+	req.Header.Set("X-Prompt", "nope")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Code != http.StatusTeapot {
+		t.Fatalf("expected the custom deny handler, got %d", rec.Code)
+	}
+}
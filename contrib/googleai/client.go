@@ -0,0 +1,276 @@
+// Package googleai governs Gemini calls made with the Google Gen AI Go SDK
+// (google.golang.org/genai), for the Gemini API and Vertex AI alike. New
+// wraps a client so GenerateContent and GenerateContentStream, which take the
+// arguments of client.Models, go through the Governor:
+//
+//	governed := aisentinelgoogleai.New(client, gov, aisentinelgoogleai.Options{RulepackID: "llm-egress"})
+//	resp, err := governed.GenerateContent(ctx, "gemini-2.5-flash", genai.Text(prompt), nil)
+//
+// Prompts are evaluated before a request is sent and the reply after it is
+// received. Payloads are flattened so rules can match them by field: requests
+// carry "operation", "model" and "prompt" (the system instruction and every
+// text part of the contents joined by newlines) and replies carry
+// "operation", "model" and "completion". Decisions go through
+// Governor.Evaluate and are audited like any other.
+package googleai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"strings"
+
+	governor "github.com/mfifth/aisentinel-go-sdk"
+	"github.com/mfifth/aisentinel-go-sdk/aisentinelhttp"
+	"github.com/mfifth/aisentinel-go-sdk/pii"
+	"google.golang.org/genai"
+)
+
+// operation is the "operation" field of every payload.
+const operation = "generate_content"
+
+// Options configures a Client. The zero value evaluates prompts and replies
+// against the Governor's default rulepack, blocks denied ones and fails
+// closed.
+type Options struct {
+	// RulepackID is evaluated against prompts; empty selects
+	// Config.DefaultRulepackID.
+	RulepackID string
+	// ResponseRulepackID is evaluated against replies; empty uses
+	// RulepackID.
+	ResponseRulepackID string
+	// SkipResponse disables reply evaluation.
+	SkipResponse bool
+	// OnDeny is the action for denied text; the default is
+	// aisentinelhttp.ActionBlock. Streamed replies are always blocked, as
+	// chunks already handed out cannot be redacted.
+	OnDeny aisentinelhttp.Action
+	// Redact rewrites denied text for aisentinelhttp.ActionRedact. The
+	// default replaces PII with placeholders such as [EMAIL].
+	Redact func(text string) string
+	// FailOpen lets calls through when the Governor cannot decide.
+	FailOpen bool
+}
+
+// Client wraps a Gen AI client so content generation is governed. Denied
+// calls fail with an *aisentinelhttp.DeniedError, which matches
+// aisentinelhttp.ErrDenied; denied prompts are never sent.
+type Client struct {
+	client *genai.Client
+	gov    *governor.Governor
+	opts   Options
+}
+
+// New returns a Client calling Gemini through client.
+func New(client *genai.Client, gov *governor.Governor, opts Options) *Client {
+	if opts.ResponseRulepackID == "" {
+		opts.ResponseRulepackID = opts.RulepackID
+	}
+	if opts.Redact == nil {
+		detector := pii.New()
+		opts.Redact = func(text string) string {
+			redacted, _ := detector.Redact(text, pii.StrategyPlaceholder)
+			return redacted
+		}
+	}
+	return &Client{client: client, gov: gov, opts: opts}
+}
+
+// Unwrap returns the underlying Gen AI client for calls the wrapper does not
+// govern.
+func (c *Client) Unwrap() *genai.Client { return c.client }
+
+// GenerateContent governs client.Models.GenerateContent. Every text part of
+// every candidate is evaluated separately.
+func (c *Client) GenerateContent(ctx context.Context, model string, contents []*genai.Content, config *genai.GenerateContentConfig) (*genai.GenerateContentResponse, error) {
+	contents, config, err := c.governRequest(ctx, model, contents, config)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Models.GenerateContent(ctx, model, contents, config)
+	if err != nil || c.opts.SkipResponse {
+		return resp, err
+	}
+	for _, candidate := range resp.Candidates {
+		if candidate.Content == nil {
+			continue
+		}
+		for _, part := range candidate.Content.Parts {
+			if part == nil || part.Text == "" || part.Thought {
+				continue
+			}
+			if part.Text, err = c.governResponse(ctx, model, part.Text); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return resp, nil
+}
+
+// GenerateContentStream governs client.Models.GenerateContentStream. The
+// prompt is evaluated before the stream is opened, and the text of every
+// chunk flows through a governor.StreamEvaluator per candidate, so the reply
+// is evaluated as it grows and audited once complete. A denied reply ends
+// the stream with an *aisentinelhttp.DeniedError.
+func (c *Client) GenerateContentStream(ctx context.Context, model string, contents []*genai.Content, config *genai.GenerateContentConfig) iter.Seq2[*genai.GenerateContentResponse, error] {
+	return func(yield func(*genai.GenerateContentResponse, error) bool) {
+		contents, config, err := c.governRequest(ctx, model, contents, config)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		evals := map[int32]*governor.StreamEvaluator{}
+		for chunk, err := range c.client.Models.GenerateContentStream(ctx, model, contents, config) {
+			if err == nil && !c.opts.SkipResponse {
+				err = c.governChunk(ctx, model, chunk, evals)
+			}
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if !yield(chunk, nil) {
+				return
+			}
+		}
+		for _, eval := range evals {
+			if err := c.governStreamResult(eval.Finish(ctx)); err != nil {
+				yield(nil, err)
+				return
+			}
+		}
+	}
+}
+
+// governChunk feeds the text of a streamed chunk to the evaluators of its
+// candidates.
+func (c *Client) governChunk(ctx context.Context, model string, chunk *genai.GenerateContentResponse, evals map[int32]*governor.StreamEvaluator) error {
+	for _, candidate := range chunk.Candidates {
+		if candidate.Content == nil {
+			continue
+		}
+		eval, ok := evals[candidate.Index]
+		if !ok {
+			eval = c.gov.NewStreamEvaluator(c.opts.ResponseRulepackID, "completion", map[string]string{"operation": operation, "model": model})
+			evals[candidate.Index] = eval
+		}
+		for _, part := range candidate.Content.Parts {
+			if part == nil || part.Text == "" || part.Thought {
+				continue
+			}
+			if err := c.governStreamResult(eval.Write(ctx, part.Text)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// governStreamResult turns a streamed reply decision into the error ending
+// the stream, if any.
+func (c *Client) governStreamResult(result governor.DecisionResult, err error) error {
+	switch {
+	case err != nil && c.opts.FailOpen:
+		return nil
+	case err != nil:
+		return fmt.Errorf("googleai: policy evaluation: %w", err)
+	case !result.Allowed:
+		return &aisentinelhttp.DeniedError{Phase: "response", Result: result}
+	}
+	return nil
+}
+
+// governRequest evaluates the text of contents and the system instruction
+// and returns what to send: the arguments themselves or copies with the text
+// redacted.
+func (c *Client) governRequest(ctx context.Context, model string, contents []*genai.Content, config *genai.GenerateContentConfig) ([]*genai.Content, *genai.GenerateContentConfig, error) {
+	var texts []string
+	collect := func(text string) string {
+		texts = append(texts, text)
+		return text
+	}
+	if config != nil {
+		rewriteText(config.SystemInstruction, collect)
+	}
+	for _, content := range contents {
+		rewriteText(content, collect)
+	}
+
+	result, err := c.evaluate(ctx, c.opts.RulepackID, requestPayload(model, texts))
+	if err != nil || result.Allowed {
+		return contents, config, err
+	}
+	if c.opts.OnDeny == aisentinelhttp.ActionRedact {
+		texts = texts[:0]
+		redact := func(text string) string { return collect(c.opts.Redact(text)) }
+		redactedConfig := config
+		if config != nil && config.SystemInstruction != nil {
+			copied := *config
+			copied.SystemInstruction = rewriteText(config.SystemInstruction, redact)
+			redactedConfig = &copied
+		}
+		redacted := make([]*genai.Content, len(contents))
+		for i, content := range contents {
+			redacted[i] = rewriteText(content, redact)
+		}
+		if result, err = c.evaluate(ctx, c.opts.RulepackID, requestPayload(model, texts)); err != nil || result.Allowed {
+			return redacted, redactedConfig, err
+		}
+	}
+	return nil, nil, &aisentinelhttp.DeniedError{Phase: "request", Result: result}
+}
+
+// rewriteText returns a copy of content with the text of every part passed
+// through fn. The parts themselves are copied so the caller's are untouched.
+func rewriteText(content *genai.Content, fn func(string) string) *genai.Content {
+	if content == nil {
+		return nil
+	}
+	copied := *content
+	copied.Parts = make([]*genai.Part, len(content.Parts))
+	for i, part := range content.Parts {
+		if part == nil || part.Text == "" {
+			copied.Parts[i] = part
+			continue
+		}
+		p := *part
+		p.Text = fn(part.Text)
+		copied.Parts[i] = &p
+	}
+	return &copied
+}
+
+// governResponse evaluates one text part and returns the text to hand back.
+func (c *Client) governResponse(ctx context.Context, model, text string) (string, error) {
+	payload := map[string]string{"operation": operation, "model": model, "completion": text}
+	result, err := c.evaluate(ctx, c.opts.ResponseRulepackID, payload)
+	if err != nil || result.Allowed {
+		return text, err
+	}
+	if c.opts.OnDeny == aisentinelhttp.ActionRedact {
+		payload["completion"] = c.opts.Redact(text)
+		if result, err = c.evaluate(ctx, c.opts.ResponseRulepackID, payload); err != nil || result.Allowed {
+			return payload["completion"], err
+		}
+	}
+	return "", &aisentinelhttp.DeniedError{Phase: "response", Result: result}
+}
+
+func (c *Client) evaluate(ctx context.Context, rulepack string, payload map[string]string) (governor.DecisionResult, error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return governor.DecisionResult{}, err
+	}
+	result, err := c.gov.Evaluate(ctx, governor.DecisionRequest{RulepackID: rulepack, Payload: raw})
+	if err != nil && c.opts.FailOpen {
+		return governor.DecisionResult{Allowed: true}, nil
+	}
+	if err != nil {
+		return result, fmt.Errorf("googleai: policy evaluation: %w", err)
+	}
+	return result, nil
+}
+
+func requestPayload(model string, texts []string) map[string]string {
+	return map[string]string{"operation": operation, "model": model, "prompt": strings.Join(texts, "\n")}
+}
@@ -0,0 +1,135 @@
+package googleai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	governor "github.com/mfifth/aisentinel-go-sdk"
+	"github.com/mfifth/aisentinel-go-sdk/aisentinelhttp"
+	"github.com/mfifth/aisentinel-go-sdk/governortest"
+	"google.golang.org/genai"
+)
+
+// newClient returns a Client for a Gemini API stand-in that replies with the
+// given text parts, streamed one chunk per part when asked to, and a pointer
+// to the last request body it saw.
+func newClient(t *testing.T, reply []string, opts Options) (*Client, *string) {
+	t.Helper()
+	pack := governor.Rulepack{ID: "llm", Rules: []governor.RuleDefinition{
+		{ID: "prompt", Pattern: `@`, Allow: false},
+		{ID: "completion", Pattern: `secret`, Allow: false},
+		{ID: "operation", Pattern: `.`, Allow: true},
+	}}
+	gov := governortest.NewControlPlane(t, pack).NewGovernor(t)
+
+	chunk := func(texts ...string) []byte {
+		parts := make([]any, len(texts))
+		for i, text := range texts {
+			parts[i] = map[string]any{"text": text}
+		}
+		data, _ := json.Marshal(map[string]any{"candidates": []any{map[string]any{"index": 0, "content": map[string]any{"role": "model", "parts": parts}}}})
+		return data
+	}
+	var sent string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		sent = string(body)
+		if strings.HasSuffix(r.URL.Path, ":streamGenerateContent") {
+			w.Header().Set("Content-Type", "text/event-stream")
+			for _, text := range reply {
+				fmt.Fprintf(w, "data: %s\n\n", chunk(text))
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(chunk(reply...))
+	}))
+	t.Cleanup(upstream.Close)
+
+	client, err := genai.NewClient(context.Background(), &genai.ClientConfig{
+		APIKey:      "test",
+		Backend:     genai.BackendGeminiAPI,
+		HTTPOptions: genai.HTTPOptions{BaseURL: upstream.URL},
+	})
+	if err != nil {
+		t.Fatalf("expected client: %v", err)
+	}
+	opts.RulepackID = "llm"
+	return New(client, gov, opts), &sent
+}
+
+var config = &genai.GenerateContentConfig{SystemInstruction: genai.NewContentFromText("Be brief.", genai.RoleUser)}
+
+func TestGenerateContent(t *testing.T) {
+	client, sent := newClient(t, []string{"Hello there."}, Options{})
+
+	resp, err := client.GenerateContent(context.Background(), "gemini-2.5-flash", genai.Text("Say hello"), config)
+	if err != nil || resp.Text() != "Hello there." {
+		t.Fatalf("expected the reply, got %+v %v", resp, err)
+	}
+
+	*sent = ""
+	_, err = client.GenerateContent(context.Background(), "gemini-2.5-flash", genai.Text("Mail jane@example.com"), config)
+	var denied *aisentinelhttp.DeniedError
+	if !errors.As(err, &denied) || denied.Phase != "request" {
+		t.Fatalf("expected the prompt to be denied, got %v", err)
+	}
+	if *sent != "" {
+		t.Fatalf("expected the denied prompt not to be sent, got %s", *sent)
+	}
+}
+
+func TestGenerateContentRedacts(t *testing.T) {
+	client, sent := newClient(t, []string{"Noted.", "The secret is 42."}, Options{OnDeny: aisentinelhttp.ActionRedact, Redact: func(text string) string {
+		return strings.NewReplacer("jane@example.com", "[EMAIL]", "secret", "[REDACTED]").Replace(text)
+	}})
+
+	contents := genai.Text("Mail jane@example.com")
+	resp, err := client.GenerateContent(context.Background(), "gemini-2.5-flash", contents, config)
+	if err != nil {
+		t.Fatalf("expected the redacted prompt to be allowed, got %v", err)
+	}
+	if strings.Contains(*sent, "jane@example.com") || !strings.Contains(*sent, "[EMAIL]") || !strings.Contains(*sent, "Be brief.") {
+		t.Fatalf("expected only the email to be redacted, sent %s", *sent)
+	}
+	if contents[0].Parts[0].Text != "Mail jane@example.com" {
+		t.Fatal("expected the caller's contents to be left alone")
+	}
+	if got := resp.Text(); got != "Noted.The [REDACTED] is 42." {
+		t.Fatalf("expected only the denied part to be redacted, got %q", got)
+	}
+}
+
+func TestGenerateContentStream(t *testing.T) {
+	client, _ := newClient(t, []string{"The ", "sec", "ret", " is 42."}, Options{})
+
+	var text strings.Builder
+	var streamErr error
+	for chunk, err := range client.GenerateContentStream(context.Background(), "gemini-2.5-flash", genai.Text("Tell me"), config) {
+		if err != nil {
+			streamErr = err
+			break
+		}
+		text.WriteString(chunk.Text())
+	}
+	var denied *aisentinelhttp.DeniedError
+	if !errors.As(streamErr, &denied) || denied.Phase != "response" {
+		t.Fatalf("expected the stream to be denied, got %v", streamErr)
+	}
+	if text.String() != "The sec" {
+		t.Fatalf("expected the stream to stop before the denied chunk, got %q", text.String())
+	}
+
+	for _, err := range client.GenerateContentStream(context.Background(), "gemini-2.5-flash", genai.Text("Mail jane@example.com"), config) {
+		if !errors.Is(err, aisentinelhttp.ErrDenied) {
+			t.Fatalf("expected the prompt to be denied, got %v", err)
+		}
+	}
+}
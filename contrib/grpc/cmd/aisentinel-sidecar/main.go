@@ -0,0 +1,81 @@
+// Command aisentinel-sidecar runs the HTTP decision sidecar together with the
+// gRPC decision service, sharing a single Governor.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"log/slog"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+
+	aisentinel "github.com/mfifth/aisentinel-go-sdk"
+	aisgrpc "github.com/mfifth/aisentinel-go-sdk/contrib/grpc"
+	"github.com/mfifth/aisentinel-go-sdk/server"
+	grpclib "google.golang.org/grpc"
+)
+
+func main() {
+	if err := run(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run() error {
+	httpAddr := flag.String("addr", server.DefaultAddr, "HTTP listen address")
+	grpcAddr := flag.String("grpc-addr", "127.0.0.1:9090", "gRPC listen address")
+	configFile := flag.String("config", "", "Path to a YAML, TOML or JSON config file")
+	profile := flag.String("profile", "", "Config file profile to apply")
+	maxConcurrent := flag.Int("max-concurrent", server.DefaultMaxConcurrent, "Maximum in-flight HTTP evaluations")
+	extAuthz := flag.Bool("ext-authz", false, "Also serve the Envoy external authorization API on the gRPC listener")
+	extAuthzRulepack := flag.String("ext-authz-rulepack", "", "Rulepack for ext_authz checks without an aisentinel_rulepack context extension")
+	flag.Parse()
+
+	cfg := aisentinel.Config{} // nolint:exhaustruct
+	if *configFile != "" {
+		loaded, err := aisentinel.LoadConfigProfile(*configFile, *profile)
+		if err != nil {
+			return fmt.Errorf("load config: %w", err)
+		}
+		cfg = loaded
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	governor, err := aisentinel.NewGovernor(ctx, cfg, aisentinel.WithLogger(logger))
+	if err != nil {
+		return fmt.Errorf("initialise governor: %w", err)
+	}
+	defer governor.Close()
+
+	ln, err := net.Listen("tcp", *grpcAddr)
+	if err != nil {
+		return err
+	}
+	grpcServer := grpclib.NewServer()
+	hs := aisgrpc.Register(grpcServer, governor)
+	if *extAuthz {
+		aisgrpc.RegisterExtAuthz(grpcServer, governor, aisgrpc.ExtAuthzOptions{RulepackID: *extAuthzRulepack})
+	}
+	go func() {
+		if err := grpcServer.Serve(ln); err != nil {
+			logger.Error("grpc server stopped", "error", err)
+		}
+	}()
+	logger.Info("grpc decision service listening", "addr", *grpcAddr)
+
+	srv := server.New(governor, server.Options{Addr: *httpAddr, MaxConcurrent: *maxConcurrent})
+	logger.Info("decision sidecar listening", "addr", *httpAddr)
+	err = srv.ListenAndServe(ctx)
+
+	// Report NOT_SERVING first so load balancers drain before the stop.
+	hs.Shutdown()
+	grpcServer.GracefulStop()
+	return err
+}
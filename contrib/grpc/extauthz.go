@@ -0,0 +1,159 @@
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	typev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	governor "github.com/mfifth/aisentinel-go-sdk"
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RulepackExtension is the ext_authz context extension that selects the
+// rulepack for a route, set in Envoy with
+// typed_per_filter_config.envoy.filters.http.ext_authz.check_settings.context_extensions.
+const RulepackExtension = "aisentinel_rulepack"
+
+// ExtAuthzOptions configures the Envoy external authorization service.
+type ExtAuthzOptions struct {
+	// RulepackID is evaluated for routes that set no RulepackExtension; empty
+	// selects Config.DefaultRulepackID.
+	RulepackID string
+}
+
+// ExtAuthz implements Envoy's envoy.service.auth.v3.Authorization service, so
+// the Governor can gate traffic at the mesh layer without application
+// changes. Each CheckRequest becomes a DecisionRequest whose payload holds
+// the request attributes as string fields: "http.method", "http.path",
+// "http.host", "http.scheme", one "http.header.<name>" per header (names in
+// lower case), "source.principal", "source.address" and
+// "destination.principal". A JSON object body, forwarded when the filter sets
+// with_request_body, contributes its top-level fields as they are so prompt
+// rules apply unchanged, except "http", "source", "destination" and names in
+// their namespaces; other bodies appear as "http.body". The source principal
+// is the usage Subject.
+type ExtAuthz struct {
+	authv3.UnimplementedAuthorizationServer
+	gov  *governor.Governor
+	opts ExtAuthzOptions
+}
+
+// NewExtAuthz returns an ExtAuthz backed by gov.
+func NewExtAuthz(gov *governor.Governor, opts ExtAuthzOptions) *ExtAuthz {
+	return &ExtAuthz{gov: gov, opts: opts}
+}
+
+// RegisterExtAuthz installs the external authorization service on srv.
+func RegisterExtAuthz(srv *grpclib.Server, gov *governor.Governor, opts ExtAuthzOptions) {
+	authv3.RegisterAuthorizationServer(srv, NewExtAuthz(gov, opts))
+}
+
+// Check answers an authorization request. Denied requests get a 403 with a
+// JSON body carrying the reason and rule ID. Evaluation failures are returned
+// as gRPC errors so Envoy's failure_mode_allow decides what happens.
+func (a *ExtAuthz) Check(ctx context.Context, req *authv3.CheckRequest) (*authv3.CheckResponse, error) {
+	attrs := req.GetAttributes()
+	rulepack := attrs.GetContextExtensions()[RulepackExtension]
+	if rulepack == "" {
+		rulepack = a.opts.RulepackID
+	}
+	payload, err := json.Marshal(checkPayload(attrs))
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	result, err := a.gov.Evaluate(ctx, governor.DecisionRequest{
+		RulepackID: rulepack,
+		Payload:    payload,
+		Subject:    attrs.GetSource().GetPrincipal(),
+	})
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	if result.Allowed {
+		return &authv3.CheckResponse{
+			Status: status.New(codes.OK, "").Proto(),
+			HttpResponse: &authv3.CheckResponse_OkResponse{OkResponse: &authv3.OkHttpResponse{
+				Headers: ruleHeader(result.RuleID),
+			}},
+		}, nil
+	}
+	body, _ := json.Marshal(map[string]string{"error": "request denied by policy", "reason": result.Reason, "rule_id": result.RuleID})
+	return &authv3.CheckResponse{
+		Status: status.New(codes.PermissionDenied, result.Reason).Proto(),
+		HttpResponse: &authv3.CheckResponse_DeniedResponse{DeniedResponse: &authv3.DeniedHttpResponse{
+			Status:  &typev3.HttpStatus{Code: typev3.StatusCode_Forbidden},
+			Headers: append(ruleHeader(result.RuleID), header("content-type", "application/json")),
+			Body:    string(body),
+		}},
+	}, nil
+}
+
+// checkPayload flattens the attributes of a CheckRequest into payload
+// fields.
+func checkPayload(attrs *authv3.AttributeContext) map[string]any {
+	httpReq := attrs.GetRequest().GetHttp()
+	fields := map[string]any{}
+	body := httpReq.GetBody()
+	if raw := httpReq.GetRawBody(); len(raw) > 0 {
+		body = string(raw)
+	}
+	if body != "" {
+		var document map[string]any
+		if err := json.Unmarshal([]byte(body), &document); err == nil {
+			for k, v := range document {
+				if !isAttribute(k) {
+					fields[k] = v
+				}
+			}
+		} else {
+			fields["http.body"] = body
+		}
+	}
+
+	set := func(name, value string) {
+		if value != "" {
+			fields[name] = value
+		}
+	}
+	set("http.method", httpReq.GetMethod())
+	set("http.path", httpReq.GetPath())
+	set("http.host", httpReq.GetHost())
+	set("http.scheme", httpReq.GetScheme())
+	for name, value := range httpReq.GetHeaders() {
+		set("http.header."+strings.ToLower(name), value)
+	}
+	set("source.principal", attrs.GetSource().GetPrincipal())
+	set("source.address", attrs.GetSource().GetAddress().GetSocketAddress().GetAddress())
+	set("destination.principal", attrs.GetDestination().GetPrincipal())
+	return fields
+}
+
+// isAttribute reports whether name belongs to the request attribute fields,
+// which a body must not be able to forge. The bare namespace names are
+// included, since a selector such as http.method also reads a nested "http"
+// object.
+func isAttribute(name string) bool {
+	for _, namespace := range []string{"http", "source", "destination"} {
+		if name == namespace || strings.HasPrefix(name, namespace+".") {
+			return true
+		}
+	}
+	return false
+}
+
+func ruleHeader(ruleID string) []*corev3.HeaderValueOption {
+	if ruleID == "" {
+		return nil
+	}
+	return []*corev3.HeaderValueOption{header("x-aisentinel-rule-id", ruleID)}
+}
+
+func header(key, value string) *corev3.HeaderValueOption {
+	return &corev3.HeaderValueOption{Header: &corev3.HeaderValue{Key: key, Value: value}}
+}
@@ -0,0 +1,78 @@
+package grpc
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	governor "github.com/mfifth/aisentinel-go-sdk"
+	"github.com/mfifth/aisentinel-go-sdk/governortest"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestExtAuthzCheck(t *testing.T) {
+	gov := governortest.NewControlPlane(t,
+		governor.Rulepack{ID: "mesh", Rules: []governor.RuleDefinition{
+			{ID: "http.header.x-team", Pattern: "^payments$", Allow: true},
+			{ID: "http.method", Pattern: "^GET$", Allow: true},
+			{ID: "team", Field: "http.header.x-team", Pattern: "^payments$", Allow: true},
+			{ID: "method", Field: "http.method", Pattern: "^GET$", Allow: true},
+			{ID: "principal", Field: "source.principal", Pattern: "payments", Allow: true},
+		}},
+		governor.Rulepack{ID: "llm", Rules: []governor.RuleDefinition{
+			{ID: "prompt", Pattern: "@", Allow: false},
+			{ID: "http.path", Pattern: "^/v1/", Allow: true},
+		}},
+	).NewGovernor(t)
+	authz := NewExtAuthz(gov, ExtAuthzOptions{RulepackID: "mesh"})
+
+	check := func(method, path string, headers map[string]string, body string, extensions map[string]string) *authv3.CheckResponse {
+		t.Helper()
+		resp, err := authz.Check(context.Background(), &authv3.CheckRequest{Attributes: &authv3.AttributeContext{
+			Request: &authv3.AttributeContext_Request{Http: &authv3.AttributeContext_HttpRequest{
+				Method: method, Path: path, Headers: headers, Body: body,
+			}},
+			ContextExtensions: extensions,
+		}})
+		if err != nil {
+			t.Fatalf("check: %v", err)
+		}
+		return resp
+	}
+
+	if resp := check("POST", "/charge", map[string]string{"X-Team": "payments"}, "", nil); codes.Code(resp.GetStatus().GetCode()) != codes.OK {
+		t.Fatalf("expected the header rule to allow, got %v", resp)
+	}
+	resp := check("POST", "/charge", nil, "", nil)
+	denied := resp.GetDeniedResponse()
+	if codes.Code(resp.GetStatus().GetCode()) != codes.PermissionDenied || denied.GetStatus().GetCode() != 403 || !strings.Contains(denied.GetBody(), "no matching rule") {
+		t.Fatalf("expected a 403 denial, got %v", resp)
+	}
+
+	for _, forged := range []string{
+		`{"http.header.x-team":"payments"}`,
+		`{"http":{"header":{"x-team":"payments"}}}`,
+		`{"http":{"method":"GET"}}`,
+		`{"source":{"principal":"spiffe://mesh/payments"}}`,
+	} {
+		if resp := check("POST", "/charge", nil, forged, nil); codes.Code(resp.GetStatus().GetCode()) != codes.PermissionDenied {
+			t.Fatalf("expected body %s not to forge an attribute, got %v", forged, resp)
+		}
+	}
+
+	llm := map[string]string{RulepackExtension: "llm"}
+	if resp := check("POST", "/v1/chat", nil, `{"prompt":"hello"}`, llm); codes.Code(resp.GetStatus().GetCode()) != codes.OK {
+		t.Fatalf("expected the route rulepack to allow, got %v", resp)
+	}
+	resp = check("POST", "/v1/chat", nil, `{"prompt":"mail jane@example.com"}`, llm)
+	if codes.Code(resp.GetStatus().GetCode()) != codes.PermissionDenied || !strings.Contains(resp.GetDeniedResponse().GetBody(), `"rule_id":"prompt"`) {
+		t.Fatalf("expected body fields to be evaluated, got %v", resp)
+	}
+
+	_, err := authz.Check(context.Background(), &authv3.CheckRequest{Attributes: &authv3.AttributeContext{ContextExtensions: map[string]string{RulepackExtension: "missing"}}})
+	if status.Code(err) != codes.Unavailable {
+		t.Fatalf("expected an evaluation failure to be a gRPC error, got %v", err)
+	}
+}
@@ -0,0 +1,125 @@
+// Package grpc serves Governor decisions over gRPC for polyglot services that
+// prefer it to the REST sidecar on the decision hot path. Register installs
+// the DecisionService of decisionpb, with unary and streaming evaluation,
+// alongside the standard health and reflection services:
+//
+//	srv := grpc.NewServer()
+//	hs := aisentinelgrpc.Register(srv, gov)
+//
+// ExtAuthz answers Envoy's ext_authz Check calls from the same Governor, so a
+// mesh can enforce rulepacks in front of services that never call the SDK.
+package grpc
+
+//go:generate buf generate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+
+	governor "github.com/mfifth/aisentinel-go-sdk"
+	"github.com/mfifth/aisentinel-go-sdk/contrib/grpc/decisionpb"
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+)
+
+// Service implements decisionpb.DecisionServiceServer on top of a Governor.
+type Service struct {
+	decisionpb.UnimplementedDecisionServiceServer
+	gov *governor.Governor
+}
+
+// NewService returns a Service backed by gov.
+func NewService(gov *governor.Governor) *Service {
+	return &Service{gov: gov}
+}
+
+// Register installs the decision service, the standard health service and
+// server reflection on srv. The returned health server reports SERVING until
+// the caller marks it otherwise, typically during shutdown.
+func Register(srv *grpclib.Server, gov *governor.Governor) *health.Server {
+	decisionpb.RegisterDecisionServiceServer(srv, NewService(gov))
+	hs := health.NewServer()
+	hs.SetServingStatus(decisionpb.DecisionService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(srv, hs)
+	reflection.Register(srv)
+	return hs
+}
+
+// Evaluate returns a single decision.
+func (s *Service) Evaluate(ctx context.Context, req *decisionpb.EvaluateRequest) (*decisionpb.EvaluateResponse, error) {
+	resp, err := s.evaluate(ctx, req)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return resp, nil
+}
+
+// EvaluateStream answers each request on the stream in order.
+func (s *Service) EvaluateStream(stream decisionpb.DecisionService_EvaluateStreamServer) error {
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		resp, err := s.evaluate(stream.Context(), req)
+		if err != nil {
+			resp = &decisionpb.EvaluateResponse{RequestId: req.GetRequestId(), Error: err.Error()}
+		}
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+	}
+}
+
+// Ping reports Governor liveness.
+func (s *Service) Ping(ctx context.Context, _ *decisionpb.PingRequest) (*decisionpb.PingResponse, error) {
+	return &decisionpb.PingResponse{Status: string(s.gov.Liveness().Status)}, nil
+}
+
+func (s *Service) evaluate(ctx context.Context, req *decisionpb.EvaluateRequest) (*decisionpb.EvaluateResponse, error) {
+	if payload := req.GetPayload(); len(payload) > 0 && !json.Valid(payload) {
+		return nil, errInvalidPayload
+	}
+	result, err := s.gov.Evaluate(ctx, governor.DecisionRequest{
+		RulepackID:       req.GetRulepackId(),
+		Payload:          req.GetPayload(),
+		Subject:          req.GetSubject(),
+		Model:            req.GetModel(),
+		PromptTokens:     int(req.GetPromptTokens()),
+		CompletionTokens: int(req.GetCompletionTokens()),
+		EstimatedCost:    req.GetEstimatedCost(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &decisionpb.EvaluateResponse{
+		Allowed:   result.Allowed,
+		Reason:    result.Reason,
+		LatencyUs: result.Latency.Microseconds(),
+		RequestId: req.GetRequestId(),
+	}, nil
+}
+
+var errInvalidPayload = errors.New("payload is not valid JSON")
+
+func statusError(err error) error {
+	switch {
+	case errors.Is(err, errInvalidPayload), errors.Is(err, governor.ErrNoRulepackID):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	default:
+		return status.Error(codes.Unavailable, err.Error())
+	}
+}
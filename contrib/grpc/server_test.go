@@ -0,0 +1,81 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	governor "github.com/mfifth/aisentinel-go-sdk"
+	"github.com/mfifth/aisentinel-go-sdk/contrib/grpc/decisionpb"
+	"github.com/mfifth/aisentinel-go-sdk/governortest"
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func newClient(t *testing.T) decisionpb.DecisionServiceClient {
+	t.Helper()
+	pack := governor.Rulepack{ID: "remote", Rules: []governor.RuleDefinition{{ID: "prompt", Pattern: "ok", Allow: true}}}
+	gov := governortest.NewControlPlane(t, pack).NewGovernor(t)
+
+	ln := bufconn.Listen(1 << 20)
+	srv := grpclib.NewServer()
+	Register(srv, gov)
+	go func() { _ = srv.Serve(ln) }()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpclib.NewClient("passthrough:///bufnet",
+		grpclib.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return ln.DialContext(ctx) }),
+		grpclib.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return decisionpb.NewDecisionServiceClient(conn)
+}
+
+func TestEvaluate(t *testing.T) {
+	client := newClient(t)
+	ctx := context.Background()
+
+	resp, err := client.Evaluate(ctx, &decisionpb.EvaluateRequest{RulepackId: "remote", Payload: []byte(`{"prompt":"ok"}`)})
+	if err != nil || !resp.GetAllowed() {
+		t.Fatalf("expected allowed decision, got %v, %v", resp, err)
+	}
+
+	_, err = client.Evaluate(ctx, &decisionpb.EvaluateRequest{Payload: []byte(`{}`)})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument without rulepack, got %v", err)
+	}
+}
+
+func TestEvaluateStream(t *testing.T) {
+	client := newClient(t)
+	stream, err := client.EvaluateStream(context.Background())
+	if err != nil {
+		t.Fatalf("open stream: %v", err)
+	}
+	requests := []*decisionpb.EvaluateRequest{
+		{RequestId: "a", RulepackId: "remote", Payload: []byte(`{"prompt":"ok"}`)},
+		{RequestId: "b", RulepackId: "remote", Payload: []byte(`not json`)},
+	}
+	for _, req := range requests {
+		if err := stream.Send(req); err != nil {
+			t.Fatalf("send: %v", err)
+		}
+	}
+	if err := stream.CloseSend(); err != nil {
+		t.Fatalf("close send: %v", err)
+	}
+
+	first, err := stream.Recv()
+	if err != nil || first.GetRequestId() != "a" || !first.GetAllowed() {
+		t.Fatalf("unexpected first response %v, %v", first, err)
+	}
+	second, err := stream.Recv()
+	if err != nil || second.GetRequestId() != "b" || second.GetError() == "" {
+		t.Fatalf("expected per-message error, got %v, %v", second, err)
+	}
+}
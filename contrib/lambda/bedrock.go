@@ -0,0 +1,137 @@
+package lambda
+
+import (
+	"encoding/json"
+	"net/http"
+
+	governor "github.com/mfifth/aisentinel-go-sdk"
+)
+
+// BedrockAgentEvent is the input of a Lambda function backing a Bedrock agent
+// action group. Action groups are defined either by an API schema (APIPath,
+// HTTPMethod, RequestBody) or by function details (Function).
+type BedrockAgentEvent struct {
+	MessageVersion          string                   `json:"messageVersion"`
+	Agent                   BedrockAgent             `json:"agent"`
+	InputText               string                   `json:"inputText"`
+	SessionID               string                   `json:"sessionId"`
+	ActionGroup             string                   `json:"actionGroup"`
+	APIPath                 string                   `json:"apiPath,omitempty"`
+	HTTPMethod              string                   `json:"httpMethod,omitempty"`
+	Function                string                   `json:"function,omitempty"`
+	Parameters              []BedrockAgentParameter  `json:"parameters,omitempty"`
+	RequestBody             *BedrockAgentRequestBody `json:"requestBody,omitempty"`
+	SessionAttributes       map[string]string        `json:"sessionAttributes,omitempty"`
+	PromptSessionAttributes map[string]string        `json:"promptSessionAttributes,omitempty"`
+}
+
+// BedrockAgent identifies the agent that invoked the action group.
+type BedrockAgent struct {
+	Name    string `json:"name"`
+	ID      string `json:"id"`
+	Alias   string `json:"alias"`
+	Version string `json:"version"`
+}
+
+// BedrockAgentParameter is a parameter or request body property the agent
+// elicited.
+type BedrockAgentParameter struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// BedrockAgentRequestBody holds the request body properties by content type.
+type BedrockAgentRequestBody struct {
+	Content map[string]BedrockAgentContent `json:"content"`
+}
+
+// BedrockAgentContent lists the properties of one request body content type.
+type BedrockAgentContent struct {
+	Properties []BedrockAgentParameter `json:"properties"`
+}
+
+// BedrockAgentResponse is the output of a Lambda function backing a Bedrock
+// agent action group.
+type BedrockAgentResponse struct {
+	MessageVersion          string                     `json:"messageVersion"`
+	Response                BedrockAgentActionResponse `json:"response"`
+	SessionAttributes       map[string]string          `json:"sessionAttributes,omitempty"`
+	PromptSessionAttributes map[string]string          `json:"promptSessionAttributes,omitempty"`
+}
+
+// BedrockAgentActionResponse answers an API schema action (ResponseBody) or a
+// function action (FunctionResponse).
+type BedrockAgentActionResponse struct {
+	ActionGroup      string                        `json:"actionGroup"`
+	APIPath          string                        `json:"apiPath,omitempty"`
+	HTTPMethod       string                        `json:"httpMethod,omitempty"`
+	HTTPStatusCode   int                           `json:"httpStatusCode,omitempty"`
+	ResponseBody     map[string]BedrockAgentBody   `json:"responseBody,omitempty"`
+	Function         string                        `json:"function,omitempty"`
+	FunctionResponse *BedrockAgentFunctionResponse `json:"functionResponse,omitempty"`
+}
+
+// BedrockAgentFunctionResponse is the result of a function action.
+// ResponseState is empty on success, or "FAILURE" or "REPROMPT".
+type BedrockAgentFunctionResponse struct {
+	ResponseState string                      `json:"responseState,omitempty"`
+	ResponseBody  map[string]BedrockAgentBody `json:"responseBody"`
+}
+
+// BedrockAgentBody is a response body for one content type.
+type BedrockAgentBody struct {
+	Body string `json:"body"`
+}
+
+// payload flattens the event into payload fields.
+func (e BedrockAgentEvent) payload() map[string]string {
+	fields := map[string]string{
+		"operation":    "bedrock_agent",
+		"prompt":       e.InputText,
+		"action_group": e.ActionGroup,
+	}
+	if e.APIPath != "" {
+		fields["api_path"] = e.APIPath
+	}
+	if e.Function != "" {
+		fields["function"] = e.Function
+	}
+	for _, p := range e.Parameters {
+		fields["parameters."+p.Name] = p.Value
+	}
+	if e.RequestBody != nil {
+		for _, content := range e.RequestBody.Content {
+			for _, p := range content.Properties {
+				fields["body."+p.Name] = p.Value
+			}
+		}
+	}
+	return fields
+}
+
+// deny answers a denied action with the reason, so the agent can tell the
+// user rather than fail the session: API schema actions get a 403 and
+// function actions ask the model to reprompt.
+func (e BedrockAgentEvent) deny(result governor.DecisionResult) BedrockAgentResponse {
+	body, _ := json.Marshal(map[string]string{"error": "action denied by policy", "reason": result.Reason, "rule_id": result.RuleID})
+	resp := BedrockAgentResponse{
+		MessageVersion:          e.MessageVersion,
+		Response:                BedrockAgentActionResponse{ActionGroup: e.ActionGroup},
+		SessionAttributes:       e.SessionAttributes,
+		PromptSessionAttributes: e.PromptSessionAttributes,
+	}
+	if e.Function != "" {
+		resp.Response.Function = e.Function
+		resp.Response.FunctionResponse = &BedrockAgentFunctionResponse{
+			ResponseState: "REPROMPT",
+			ResponseBody:  map[string]BedrockAgentBody{"TEXT": {Body: string(body)}},
+		}
+		return resp
+	}
+	resp.Response.APIPath = e.APIPath
+	resp.Response.HTTPMethod = e.HTTPMethod
+	resp.Response.HTTPStatusCode = http.StatusForbidden
+	resp.Response.ResponseBody = map[string]BedrockAgentBody{"application/json": {Body: string(body)}}
+	return resp
+}
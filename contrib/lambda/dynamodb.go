@@ -0,0 +1,124 @@
+package lambda
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/mfifth/aisentinel-go-sdk/storage"
+)
+
+// DynamoDB attribute names used by DynamoDBStore. The table's partition key
+// must be the string attribute "key".
+const (
+	dynamoKey   = "key"
+	dynamoValue = "value"
+)
+
+// DynamoDBAPI is the subset of *dynamodb.Client used by DynamoDBStore.
+type DynamoDBAPI interface {
+	PutItem(ctx context.Context, in *dynamodb.PutItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	GetItem(ctx context.Context, in *dynamodb.GetItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+	DeleteItem(ctx context.Context, in *dynamodb.DeleteItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error)
+	Scan(ctx context.Context, in *dynamodb.ScanInput, opts ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error)
+}
+
+// DynamoDBStore is a storage.Store backed by a DynamoDB table, so audit
+// records outlive Lambda execution environments. Records are items with a
+// string partition key "key" and a binary attribute "value".
+type DynamoDBStore struct {
+	client DynamoDBAPI
+	table  string
+}
+
+var _ storage.Store = (*DynamoDBStore)(nil)
+
+// NewDynamoDBStore returns a store writing to table through client.
+func NewDynamoDBStore(client DynamoDBAPI, table string) *DynamoDBStore {
+	return &DynamoDBStore{client: client, table: table}
+}
+
+// Put stores a record.
+func (s *DynamoDBStore) Put(ctx context.Context, record storage.Record) error {
+	_, err := s.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(s.table),
+		Item: map[string]types.AttributeValue{
+			dynamoKey:   &types.AttributeValueMemberS{Value: record.Key},
+			dynamoValue: &types.AttributeValueMemberB{Value: record.Value},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("dynamodb put %s: %w", record.Key, err)
+	}
+	return nil
+}
+
+// Get retrieves a record by key, returning storage.ErrNotFound() when there
+// is none.
+func (s *DynamoDBStore) Get(ctx context.Context, key string) (storage.Record, error) {
+	out, err := s.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(s.table),
+		Key:            map[string]types.AttributeValue{dynamoKey: &types.AttributeValueMemberS{Value: key}},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return storage.Record{}, fmt.Errorf("dynamodb get %s: %w", key, err)
+	}
+	if out.Item == nil {
+		return storage.Record{}, storage.ErrNotFound()
+	}
+	return itemRecord(out.Item)
+}
+
+// Iter iterates over all records, scanning the table page by page.
+func (s *DynamoDBStore) Iter(ctx context.Context, fn func(storage.Record) error) error {
+	in := &dynamodb.ScanInput{TableName: aws.String(s.table)}
+	for {
+		out, err := s.client.Scan(ctx, in)
+		if err != nil {
+			return fmt.Errorf("dynamodb scan: %w", err)
+		}
+		for _, item := range out.Items {
+			record, err := itemRecord(item)
+			if err != nil {
+				return err
+			}
+			if err := fn(record); err != nil {
+				return err
+			}
+		}
+		if len(out.LastEvaluatedKey) == 0 {
+			return nil
+		}
+		in.ExclusiveStartKey = out.LastEvaluatedKey
+	}
+}
+
+// Delete removes a record by key.
+func (s *DynamoDBStore) Delete(ctx context.Context, key string) error {
+	_, err := s.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(s.table),
+		Key:       map[string]types.AttributeValue{dynamoKey: &types.AttributeValueMemberS{Value: key}},
+	})
+	if err != nil {
+		return fmt.Errorf("dynamodb delete %s: %w", key, err)
+	}
+	return nil
+}
+
+// Close is a no-op; the client is owned by the caller.
+func (s *DynamoDBStore) Close() error { return nil }
+
+func itemRecord(item map[string]types.AttributeValue) (storage.Record, error) {
+	key, ok := item[dynamoKey].(*types.AttributeValueMemberS)
+	if !ok {
+		return storage.Record{}, fmt.Errorf("dynamodb: item without a string %q attribute", dynamoKey)
+	}
+	value, ok := item[dynamoValue].(*types.AttributeValueMemberB)
+	if !ok {
+		return storage.Record{}, fmt.Errorf("dynamodb: item %s without a binary %q attribute", key.Value, dynamoValue)
+	}
+	return storage.Record{Key: key.Value, Value: value.Value}, nil
+}
@@ -0,0 +1,80 @@
+package lambda
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/mfifth/aisentinel-go-sdk/storage"
+)
+
+// fakeDynamoDB keeps items in memory and returns scans one item per page.
+type fakeDynamoDB struct {
+	items map[string]map[string]types.AttributeValue
+}
+
+func (f *fakeDynamoDB) PutItem(_ context.Context, in *dynamodb.PutItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	f.items[in.Item[dynamoKey].(*types.AttributeValueMemberS).Value] = in.Item
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func (f *fakeDynamoDB) GetItem(_ context.Context, in *dynamodb.GetItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	return &dynamodb.GetItemOutput{Item: f.items[in.Key[dynamoKey].(*types.AttributeValueMemberS).Value]}, nil
+}
+
+func (f *fakeDynamoDB) DeleteItem(_ context.Context, in *dynamodb.DeleteItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	delete(f.items, in.Key[dynamoKey].(*types.AttributeValueMemberS).Value)
+	return &dynamodb.DeleteItemOutput{}, nil
+}
+
+func (f *fakeDynamoDB) Scan(_ context.Context, in *dynamodb.ScanInput, _ ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+	keys := make([]string, 0, len(f.items))
+	for k := range f.items {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	start := 0
+	if in.ExclusiveStartKey != nil {
+		start = sort.SearchStrings(keys, in.ExclusiveStartKey[dynamoKey].(*types.AttributeValueMemberS).Value) + 1
+	}
+	if start >= len(keys) {
+		return &dynamodb.ScanOutput{}, nil
+	}
+	item := f.items[keys[start]]
+	return &dynamodb.ScanOutput{Items: []map[string]types.AttributeValue{item}, LastEvaluatedKey: map[string]types.AttributeValue{dynamoKey: item[dynamoKey]}}, nil
+}
+
+func TestDynamoDBStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewDynamoDBStore(&fakeDynamoDB{items: map[string]map[string]types.AttributeValue{}}, "audit")
+
+	for _, key := range []string{"a", "b", "c"} {
+		if err := store.Put(ctx, storage.Record{Key: key, Value: []byte("value-" + key)}); err != nil {
+			t.Fatalf("put: %v", err)
+		}
+	}
+	record, err := store.Get(ctx, "b")
+	if err != nil || string(record.Value) != "value-b" {
+		t.Fatalf("expected record b, got %+v %v", record, err)
+	}
+	if err := store.Delete(ctx, "b"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := store.Get(ctx, "b"); !errors.Is(err, storage.ErrNotFound()) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+
+	var keys []string
+	if err := store.Iter(ctx, func(r storage.Record) error {
+		keys = append(keys, r.Key)
+		return nil
+	}); err != nil {
+		t.Fatalf("iter: %v", err)
+	}
+	if len(keys) != 2 || keys[0] != "a" || keys[1] != "c" {
+		t.Fatalf("expected every page to be scanned, got %v", keys)
+	}
+}
@@ -0,0 +1,22 @@
+module github.com/mfifth/aisentinel-go-sdk/contrib/lambda
+
+go 1.23
+
+replace github.com/mfifth/aisentinel-go-sdk => ../..
+
+require (
+	github.com/aws/aws-lambda-go v1.54.0
+	github.com/aws/aws-sdk-go-v2 v1.41.2
+	github.com/aws/aws-sdk-go-v2/service/dynamodb v1.56.0
+	github.com/mfifth/aisentinel-go-sdk v0.0.0
+)
+
+require (
+	github.com/BurntSushi/toml v1.6.0 // indirect
+	github.com/aws/aws-sdk-go-v2/internal/configsources v1.4.18 // indirect
+	github.com/aws/aws-sdk-go-v2/internal/endpoints/v2 v2.7.18 // indirect
+	github.com/aws/aws-sdk-go-v2/service/internal/accept-encoding v1.13.5 // indirect
+	github.com/aws/aws-sdk-go-v2/service/internal/endpoint-discovery v1.11.18 // indirect
+	github.com/aws/smithy-go v1.24.1 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
@@ -0,0 +1,48 @@
+package lambda
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	governor "github.com/mfifth/aisentinel-go-sdk"
+	"github.com/mfifth/aisentinel-go-sdk/storage"
+)
+
+var (
+	sharedMu  sync.Mutex
+	sharedGov *governor.Governor
+)
+
+// Governor returns the Governor shared by every invocation in the execution
+// environment, creating it on first use. Warm invocations reuse its rulepack
+// cache and connections instead of paying the cold-start cost again, so call
+// it from the handler, or from init to create it during the init phase. cfg
+// and opts only apply to the first successful call; a failed construction is
+// not cached and is retried by the next invocation.
+//
+// Lambda's filesystem is read-only apart from /tmp, which does not outlive
+// the execution environment, so an embedded bolt, badger or sqlite store must have its
+// StorageDSN under /tmp. Audit records that must survive belong in a durable
+// store, such as a DynamoDBStore passed with governor.WithStorage.
+func Governor(ctx context.Context, cfg governor.Config, opts ...governor.Option) (*governor.Governor, error) {
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+	if sharedGov != nil {
+		return sharedGov, nil
+	}
+	switch storage.BackendType(cfg.StorageBackend) {
+	case storage.BackendBolt, storage.BackendBadger, storage.BackendSQLite:
+		if dsn := filepath.Clean(cfg.StorageDSN); dsn != "/tmp" && !strings.HasPrefix(dsn, "/tmp/") {
+			return nil, fmt.Errorf("lambda: %s StorageDSN %q must be under /tmp", cfg.StorageBackend, cfg.StorageDSN)
+		}
+	}
+	gov, err := governor.NewGovernor(ctx, cfg, opts...)
+	if err != nil {
+		return nil, err
+	}
+	sharedGov = gov
+	return gov, nil
+}
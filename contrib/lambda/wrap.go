@@ -0,0 +1,167 @@
+// Package lambda guards AWS Lambda handlers with the Governor. Wrap evaluates
+// each event before the handler runs, with payloads built for API Gateway,
+// SQS and Bedrock agent events:
+//
+//	gov, err := aisentinellambda.Governor(ctx, cfg)
+//	...
+//	lambda.Start(aisentinellambda.Wrap(gov, handle, aisentinellambda.Options{RulepackID: "prompts"}))
+//
+// Governor shares one Governor across the warm invocations of an execution
+// environment, and DynamoDBStore keeps its audit records beyond it.
+package lambda
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+	governor "github.com/mfifth/aisentinel-go-sdk"
+	"github.com/mfifth/aisentinel-go-sdk/aisentinelhttp"
+)
+
+// Options configures Wrap. The zero value evaluates every event against the
+// Governor's default rulepack and fails closed.
+type Options struct {
+	// RulepackID is the rulepack to evaluate; empty selects
+	// Config.DefaultRulepackID.
+	RulepackID string
+	// OnDrop is called for each SQS record withheld from the handler, for
+	// example to forward it to a dead-letter queue. Dropped records are not
+	// retried.
+	OnDrop func(ctx context.Context, record events.SQSMessage, result governor.DecisionResult)
+	// FailOpen invokes the handler when the Governor cannot decide.
+	FailOpen bool
+}
+
+// Wrap returns a handler that evaluates each event with gov before handler
+// runs, for use with lambda.Start. Events are turned into payloads by type:
+//
+//   - API Gateway REST and HTTP API requests are evaluated by body, like
+//     aisentinelhttp.JSONBody. Denied requests get a 403 JSON response when
+//     the handler returns the matching response type.
+//   - SQS events are evaluated record by record, and the handler receives
+//     only the admitted records; see Options.OnDrop.
+//   - Bedrock agent action group events are flattened into "prompt" (the
+//     user's input text), "action_group", "api_path", "function",
+//     "parameters.<name>" and "body.<name>". Denied actions answer the agent
+//     with the reason instead of running.
+//   - Any other event is marshaled to JSON and evaluated as it is.
+//
+// Otherwise denials fail the invocation with an *aisentinelhttp.DeniedError.
+// Evaluation failures fail the invocation so the event source retries it,
+// unless Options.FailOpen is set.
+func Wrap[In, Out any](gov *governor.Governor, handler func(context.Context, In) (Out, error), opts Options) func(context.Context, In) (Out, error) {
+	return func(ctx context.Context, event In) (Out, error) {
+		var zero Out
+		if sqs, ok := any(event).(events.SQSEvent); ok {
+			admitted, err := filterSQS(ctx, gov, opts, sqs)
+			if err != nil {
+				return zero, err
+			}
+			if len(admitted.Records) == 0 {
+				return zero, nil
+			}
+			return handler(ctx, any(admitted).(In))
+		}
+
+		payload, err := eventPayload(event)
+		if err != nil {
+			return zero, fmt.Errorf("lambda: build payload: %w", err)
+		}
+		result, err := gov.Evaluate(ctx, governor.DecisionRequest{RulepackID: opts.RulepackID, Payload: payload})
+		switch {
+		case err != nil && opts.FailOpen:
+			return handler(ctx, event)
+		case err != nil:
+			return zero, fmt.Errorf("lambda: policy evaluation: %w", err)
+		case result.Allowed:
+			return handler(ctx, event)
+		}
+		if resp, ok := denyResponse(event, result).(Out); ok {
+			return resp, nil
+		}
+		return zero, &aisentinelhttp.DeniedError{Phase: "request", Result: result}
+	}
+}
+
+// filterSQS returns event without the records the Governor denies.
+func filterSQS(ctx context.Context, gov *governor.Governor, opts Options, event events.SQSEvent) (events.SQSEvent, error) {
+	admitted := make([]events.SQSMessage, 0, len(event.Records))
+	for _, record := range event.Records {
+		result, err := gov.Evaluate(ctx, governor.DecisionRequest{RulepackID: opts.RulepackID, Payload: bodyPayload([]byte(record.Body))})
+		switch {
+		case err != nil && opts.FailOpen:
+		case err != nil:
+			return event, fmt.Errorf("lambda: policy evaluation of message %s: %w", record.MessageId, err)
+		case !result.Allowed:
+			if opts.OnDrop != nil {
+				opts.OnDrop(ctx, record, result)
+			}
+			continue
+		}
+		admitted = append(admitted, record)
+	}
+	event.Records = admitted
+	return event, nil
+}
+
+func eventPayload(event any) (json.RawMessage, error) {
+	switch e := event.(type) {
+	case events.APIGatewayProxyRequest:
+		return gatewayPayload(e.Body, e.IsBase64Encoded)
+	case events.APIGatewayV2HTTPRequest:
+		return gatewayPayload(e.Body, e.IsBase64Encoded)
+	case BedrockAgentEvent:
+		return json.Marshal(e.payload())
+	}
+	raw, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+	return bodyPayload(raw), nil
+}
+
+func gatewayPayload(body string, encoded bool) (json.RawMessage, error) {
+	if !encoded {
+		return bodyPayload([]byte(body)), nil
+	}
+	decoded, err := base64.StdEncoding.DecodeString(body)
+	if err != nil {
+		return nil, fmt.Errorf("decode body: %w", err)
+	}
+	return bodyPayload(decoded), nil
+}
+
+// bodyPayload turns a body into a payload: JSON objects are used as they are,
+// other text is wrapped as {"body": "<text>"} and an empty body becomes {}.
+func bodyPayload(body []byte) json.RawMessage {
+	trimmed := bytes.TrimSpace(body)
+	switch {
+	case len(trimmed) == 0:
+		return json.RawMessage("{}")
+	case trimmed[0] == '{' && json.Valid(trimmed):
+		return trimmed
+	}
+	raw, _ := json.Marshal(map[string]string{"body": string(body)})
+	return raw
+}
+
+// denyResponse returns the response a denied event is answered with, or nil
+// when the event type has none.
+func denyResponse(event any, result governor.DecisionResult) any {
+	body, _ := json.Marshal(map[string]string{"error": "request denied by policy", "reason": result.Reason, "rule_id": result.RuleID})
+	headers := map[string]string{"Content-Type": "application/json"}
+	switch e := event.(type) {
+	case events.APIGatewayProxyRequest:
+		return events.APIGatewayProxyResponse{StatusCode: http.StatusForbidden, Headers: headers, Body: string(body)}
+	case events.APIGatewayV2HTTPRequest:
+		return events.APIGatewayV2HTTPResponse{StatusCode: http.StatusForbidden, Headers: headers, Body: string(body)}
+	case BedrockAgentEvent:
+		return e.deny(result)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package lambda
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	governor "github.com/mfifth/aisentinel-go-sdk"
+	"github.com/mfifth/aisentinel-go-sdk/aisentinelhttp"
+	"github.com/mfifth/aisentinel-go-sdk/governortest"
+)
+
+func newGovernor(t *testing.T) *governor.Governor {
+	t.Helper()
+	pack := governor.Rulepack{ID: "lambda", Rules: []governor.RuleDefinition{
+		{ID: "prompt", Pattern: "@", Allow: false},
+		{ID: "body", Pattern: "secret", Allow: false},
+		{ID: "action_group", Pattern: ".", Allow: true},
+		{ID: "kind", Pattern: "^order$", Allow: true},
+	}}
+	return governortest.NewControlPlane(t, pack).NewGovernor(t, governor.WithDefaultRulepackID(pack.ID))
+}
+
+func TestWrapAPIGateway(t *testing.T) {
+	gov := newGovernor(t)
+	handler := Wrap(gov, func(_ context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		return events.APIGatewayProxyResponse{StatusCode: http.StatusOK, Body: "handled"}, nil
+	}, Options{})
+
+	resp, err := handler(context.Background(), events.APIGatewayProxyRequest{Body: `{"kind":"order"}`})
+	if err != nil || resp.Body != "handled" {
+		t.Fatalf("expected the handler to run, got %+v %v", resp, err)
+	}
+	encoded := base64.StdEncoding.EncodeToString([]byte(`{"prompt":"mail jane@example.com"}`))
+	resp, err = handler(context.Background(), events.APIGatewayProxyRequest{Body: encoded, IsBase64Encoded: true})
+	if err != nil || resp.StatusCode != http.StatusForbidden || !strings.Contains(resp.Body, `"rule_id":"prompt"`) {
+		t.Fatalf("expected a 403 response, got %+v %v", resp, err)
+	}
+}
+
+func TestWrapSQS(t *testing.T) {
+	gov := newGovernor(t)
+	var handled []string
+	var dropped []string
+	handler := Wrap(gov, func(_ context.Context, event events.SQSEvent) (events.SQSEventResponse, error) {
+		for _, record := range event.Records {
+			handled = append(handled, record.MessageId)
+		}
+		return events.SQSEventResponse{}, nil
+	}, Options{OnDrop: func(_ context.Context, record events.SQSMessage, result governor.DecisionResult) {
+		dropped = append(dropped, record.MessageId+":"+result.RuleID)
+	}})
+
+	_, err := handler(context.Background(), events.SQSEvent{Records: []events.SQSMessage{
+		{MessageId: "1", Body: `{"kind":"order"}`},
+		{MessageId: "2", Body: "the secret plan"},
+		{MessageId: "3", Body: `{"kind":"order"}`},
+	}})
+	if err != nil {
+		t.Fatalf("handler: %v", err)
+	}
+	if strings.Join(handled, ",") != "1,3" || strings.Join(dropped, ",") != "2:body" {
+		t.Fatalf("expected record 2 to be dropped, handled %v dropped %v", handled, dropped)
+	}
+}
+
+func TestWrapBedrockAgent(t *testing.T) {
+	gov := newGovernor(t)
+	ran := false
+	handler := Wrap(gov, func(_ context.Context, event BedrockAgentEvent) (BedrockAgentResponse, error) {
+		ran = true
+		return BedrockAgentResponse{}, nil
+	}, Options{})
+
+	if _, err := handler(context.Background(), BedrockAgentEvent{ActionGroup: "orders", Function: "lookup", InputText: "where is my order"}); err != nil || !ran {
+		t.Fatalf("expected the action to run, got %v", err)
+	}
+	ran = false
+	resp, err := handler(context.Background(), BedrockAgentEvent{ActionGroup: "orders", Function: "lookup", InputText: "email it to jane@example.com"})
+	if err != nil || ran {
+		t.Fatalf("expected the action to be denied without an error, ran %v err %v", ran, err)
+	}
+	fr := resp.Response.FunctionResponse
+	if fr == nil || fr.ResponseState != "REPROMPT" || !strings.Contains(fr.ResponseBody["TEXT"].Body, "action denied by policy") {
+		t.Fatalf("expected a reprompt response, got %+v", resp)
+	}
+}
+
+func TestWrapOtherEvents(t *testing.T) {
+	gov := newGovernor(t)
+	handler := Wrap(gov, func(_ context.Context, event map[string]string) (string, error) {
+		return "ok", nil
+	}, Options{})
+
+	if out, err := handler(context.Background(), map[string]string{"kind": "order"}); err != nil || out != "ok" {
+		t.Fatalf("expected the handler to run, got %q %v", out, err)
+	}
+	_, err := handler(context.Background(), map[string]string{"kind": "refund"})
+	var denied *aisentinelhttp.DeniedError
+	if !errors.As(err, &denied) || denied.Result.Reason != "no matching rule" {
+		t.Fatalf("expected a DeniedError, got %v", err)
+	}
+
+	unknown := Wrap(gov, func(context.Context, map[string]string) (string, error) { return "ok", nil }, Options{RulepackID: "missing"})
+	if _, err := unknown(context.Background(), map[string]string{"kind": "order"}); err == nil || !strings.Contains(err.Error(), "policy evaluation") {
+		t.Fatalf("expected an evaluation error, got %v", err)
+	}
+}
+
+func TestGovernorRequiresTmpStorage(t *testing.T) {
+	_, err := Governor(context.Background(), governor.Config{APIKey: "test", StorageBackend: "bolt", StorageDSN: "/var/task/audit.db"})
+	if err == nil || !strings.Contains(err.Error(), "must be under /tmp") {
+		t.Fatalf("expected the storage path to be rejected, got %v", err)
+	}
+}
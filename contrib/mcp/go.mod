@@ -0,0 +1,21 @@
+module github.com/mfifth/aisentinel-go-sdk/contrib/mcp
+
+go 1.23.0
+
+replace github.com/mfifth/aisentinel-go-sdk => ../..
+
+require (
+	github.com/mfifth/aisentinel-go-sdk v0.0.0-00010101000000-000000000000
+	github.com/modelcontextprotocol/go-sdk v1.3.1
+)
+
+require (
+	github.com/BurntSushi/toml v1.6.0 // indirect
+	github.com/google/jsonschema-go v0.4.2 // indirect
+	github.com/segmentio/asm v1.1.3 // indirect
+	github.com/segmentio/encoding v0.5.3 // indirect
+	github.com/yosida95/uritemplate/v3 v3.0.2 // indirect
+	golang.org/x/oauth2 v0.30.0 // indirect
+	golang.org/x/sys v0.35.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
@@ -0,0 +1,215 @@
+// Package mcp guards Model Context Protocol tool calls made with the official
+// MCP Go SDK, so an agent can neither run a tool the rulepack refuses nor read
+// a result it must not see.
+//
+// Middleware evaluates every tools/call request before the tool runs and its
+// result before it reaches the model. Payloads are flattened so rules can
+// match them by field: calls carry "operation" ("tool_call"), "tool", the
+// JSON "arguments" and one "arguments.<name>" field per string argument;
+// results carry "operation" ("tool_result"), "tool" and "result": the text
+// content, the text of embedded resources and the structured content as
+// JSON, joined by newlines. Results linking or embedding resources add their
+// URIs as "resources", and content that cannot be read as text, such as
+// images, audio and binary resources, adds its MIME types as "media", one per
+// line, so rules can refuse it.
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	governor "github.com/mfifth/aisentinel-go-sdk"
+	"github.com/mfifth/aisentinel-go-sdk/aisentinelhttp"
+	mcplib "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const methodCallTool = "tools/call"
+
+// Options configures Middleware. The zero value evaluates calls and results
+// against the Governor's default rulepack and fails closed.
+type Options struct {
+	// RulepackID is evaluated against tool calls; empty selects
+	// Config.DefaultRulepackID.
+	RulepackID string
+	// ResultRulepackID is evaluated against tool results; empty uses
+	// RulepackID.
+	ResultRulepackID string
+	// SkipResults disables result evaluation.
+	SkipResults bool
+	// OnDeny builds the answer to a denied call or result. The default is a
+	// tool error naming the rule, so the model learns the call was refused.
+	OnDeny func(ctx context.Context, denied *aisentinelhttp.DeniedError) (*mcplib.CallToolResult, error)
+	// FailOpen lets calls and results through when the Governor cannot
+	// decide.
+	FailOpen bool
+}
+
+// Middleware returns MCP middleware governing tool calls. Install it on a
+// server to guard the tools it executes, or on a client to guard the calls
+// it makes:
+//
+//	server.AddReceivingMiddleware(aisentinelmcp.Middleware(gov, aisentinelmcp.Options{RulepackID: "tools"}))
+//	client.AddSendingMiddleware(aisentinelmcp.Middleware(gov, aisentinelmcp.Options{RulepackID: "tools"}))
+//
+// Other methods pass through untouched.
+func Middleware(gov *governor.Governor, opts Options) mcplib.Middleware {
+	if opts.ResultRulepackID == "" {
+		opts.ResultRulepackID = opts.RulepackID
+	}
+	if opts.OnDeny == nil {
+		opts.OnDeny = deniedResult
+	}
+	g := &guard{gov: gov, opts: opts}
+	return func(next mcplib.MethodHandler) mcplib.MethodHandler {
+		return func(ctx context.Context, method string, req mcplib.Request) (mcplib.Result, error) {
+			if method != methodCallTool {
+				return next(ctx, method, req)
+			}
+			name, args, err := callParams(req.GetParams())
+			if err != nil {
+				return nil, err
+			}
+			payload, err := callPayload(name, args)
+			if err != nil {
+				return nil, err
+			}
+			if denied, err := g.check(ctx, "request", opts.RulepackID, payload); err != nil || denied != nil {
+				return g.deny(ctx, denied, err)
+			}
+
+			res, err := next(ctx, method, req)
+			if err != nil || opts.SkipResults {
+				return res, err
+			}
+			result, ok := res.(*mcplib.CallToolResult)
+			if !ok || result == nil {
+				return res, nil
+			}
+			if payload, err = resultPayload(name, result); err != nil {
+				return nil, err
+			}
+			if denied, err := g.check(ctx, "response", opts.ResultRulepackID, payload); err != nil || denied != nil {
+				return g.deny(ctx, denied, err)
+			}
+			return res, nil
+		}
+	}
+}
+
+type guard struct {
+	gov  *governor.Governor
+	opts Options
+}
+
+// check evaluates payload and returns a *DeniedError when it is denied.
+func (g *guard) check(ctx context.Context, phase, rulepack string, payload map[string]string) (*aisentinelhttp.DeniedError, error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	result, err := g.gov.Evaluate(ctx, governor.DecisionRequest{RulepackID: rulepack, Payload: raw})
+	switch {
+	case err != nil && g.opts.FailOpen:
+		return nil, nil
+	case err != nil:
+		return nil, fmt.Errorf("mcp: policy evaluation: %w", err)
+	case !result.Allowed:
+		return &aisentinelhttp.DeniedError{Phase: phase, Result: result}, nil
+	}
+	return nil, nil
+}
+
+func (g *guard) deny(ctx context.Context, denied *aisentinelhttp.DeniedError, err error) (mcplib.Result, error) {
+	if err != nil {
+		return nil, err
+	}
+	result, err := g.opts.OnDeny(ctx, denied)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func deniedResult(_ context.Context, denied *aisentinelhttp.DeniedError) (*mcplib.CallToolResult, error) {
+	what := "tool call"
+	if denied.Phase == "response" {
+		what = "tool result"
+	}
+	text := fmt.Sprintf("%s denied by policy (rule %s): %s", what, denied.Result.RuleID, denied.Result.Reason)
+	return &mcplib.CallToolResult{IsError: true, Content: []mcplib.Content{&mcplib.TextContent{Text: text}}}, nil
+}
+
+// callParams returns the tool name and JSON arguments of a tools/call
+// request, as received by a server or sent by a client.
+func callParams(params mcplib.Params) (string, json.RawMessage, error) {
+	switch p := params.(type) {
+	case *mcplib.CallToolParamsRaw:
+		return p.Name, p.Arguments, nil
+	case *mcplib.CallToolParams:
+		args, err := json.Marshal(p.Arguments)
+		return p.Name, args, err
+	default:
+		return "", nil, fmt.Errorf("mcp: unexpected tools/call params %T", params)
+	}
+}
+
+func callPayload(name string, args json.RawMessage) (map[string]string, error) {
+	if len(args) == 0 {
+		args = json.RawMessage("{}")
+	}
+	payload := map[string]string{"operation": "tool_call", "tool": name, "arguments": string(args)}
+	var fields map[string]any
+	if err := json.Unmarshal(args, &fields); err != nil {
+		return nil, fmt.Errorf("mcp: tool arguments: %w", err)
+	}
+	for k, v := range fields {
+		if s, ok := v.(string); ok {
+			payload["arguments."+k] = s
+		}
+	}
+	return payload, nil
+}
+
+// resultPayload flattens a tool result into payload fields.
+func resultPayload(tool string, result *mcplib.CallToolResult) (map[string]string, error) {
+	var texts, resources, media []string
+	for _, c := range result.Content {
+		switch c := c.(type) {
+		case *mcplib.TextContent:
+			texts = append(texts, c.Text)
+		case *mcplib.ImageContent:
+			media = append(media, c.MIMEType)
+		case *mcplib.AudioContent:
+			media = append(media, c.MIMEType)
+		case *mcplib.ResourceLink:
+			resources = append(resources, c.URI)
+		case *mcplib.EmbeddedResource:
+			if c.Resource == nil {
+				continue
+			}
+			resources = append(resources, c.Resource.URI)
+			if c.Resource.Blob != nil {
+				media = append(media, c.Resource.MIMEType)
+			} else {
+				texts = append(texts, c.Resource.Text)
+			}
+		}
+	}
+	if result.StructuredContent != nil {
+		structured, err := json.Marshal(result.StructuredContent)
+		if err != nil {
+			return nil, fmt.Errorf("mcp: structured content: %w", err)
+		}
+		texts = append(texts, string(structured))
+	}
+	payload := map[string]string{"operation": "tool_result", "tool": tool, "result": strings.Join(texts, "\n")}
+	if len(resources) > 0 {
+		payload["resources"] = strings.Join(resources, "\n")
+	}
+	if len(media) > 0 {
+		payload["media"] = strings.Join(media, "\n")
+	}
+	return payload, nil
+}
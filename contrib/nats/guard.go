@@ -0,0 +1,126 @@
+// Package nats guards NATS subscribers with the Governor. Middleware wraps a
+// message handler so every message on the guarded subjects is evaluated
+// before the handler sees it. Denied messages, and messages that cannot be
+// evaluated unless Options.FailOpen is set, are dropped or republished on a
+// quarantine subject with the original subject and the reason in the
+// Aisentinel-* headers.
+package nats
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+
+	governor "github.com/mfifth/aisentinel-go-sdk"
+	natslib "github.com/nats-io/nats.go"
+)
+
+// Headers set on quarantined messages.
+const (
+	HeaderSubject = "Aisentinel-Subject"
+	HeaderReason  = "Aisentinel-Reason"
+	HeaderRuleID  = "Aisentinel-Rule-Id"
+	HeaderError   = "Aisentinel-Error"
+)
+
+// Options configures Middleware. The zero value evaluates every message
+// against the Governor's default rulepack, drops denied ones and fails
+// closed.
+type Options struct {
+	// RulepackID is the rulepack to evaluate; empty selects
+	// Config.DefaultRulepackID.
+	RulepackID string
+	// Subjects limits evaluation to messages on matching subjects, with the
+	// usual * and > wildcards. Messages on other subjects reach the handler
+	// unevaluated. Empty evaluates every message.
+	Subjects []string
+	// QuarantineSubject receives denied messages, and messages that could not
+	// be evaluated, with the original subject and the reason in headers.
+	// Empty drops them.
+	QuarantineSubject string
+	// FailOpen invokes the handler when the Governor cannot decide.
+	FailOpen bool
+}
+
+// Middleware returns a wrapper for message handlers subscribed on nc. Each
+// message is evaluated before the handler runs; denied messages go to
+// Options.QuarantineSubject instead. JSON object messages are evaluated as
+// they are and other messages as {"body": data}. JetStream messages that are
+// kept from the handler are terminated so they are not redelivered.
+//
+//	guard := aisentinelnats.Middleware(nc, gov, aisentinelnats.Options{RulepackID: "prompts", QuarantineSubject: "prompts.quarantine"})
+//	nc.Subscribe("prompts.>", guard(handle))
+func Middleware(nc *natslib.Conn, gov *governor.Governor, opts Options) func(natslib.MsgHandler) natslib.MsgHandler {
+	return func(next natslib.MsgHandler) natslib.MsgHandler {
+		return func(msg *natslib.Msg) {
+			if !matchesAny(opts.Subjects, msg.Subject) {
+				next(msg)
+				return
+			}
+			result, err := gov.Evaluate(context.Background(), governor.DecisionRequest{RulepackID: opts.RulepackID, Payload: payload(msg.Data)})
+			if (err == nil && result.Allowed) || (err != nil && opts.FailOpen) {
+				next(msg)
+				return
+			}
+			if opts.QuarantineSubject != "" {
+				quarantine(nc, opts.QuarantineSubject, msg, result, err)
+			}
+			_ = msg.Term()
+		}
+	}
+}
+
+// quarantine republishes msg with the decision in headers.
+func quarantine(nc *natslib.Conn, subject string, msg *natslib.Msg, result governor.DecisionResult, err error) {
+	out := natslib.NewMsg(subject)
+	out.Data = msg.Data
+	for k, v := range msg.Header {
+		out.Header[k] = append([]string(nil), v...)
+	}
+	out.Header.Set(HeaderSubject, msg.Subject)
+	if err != nil {
+		out.Header.Set(HeaderError, err.Error())
+	} else {
+		out.Header.Set(HeaderReason, result.Reason)
+		if result.RuleID != "" {
+			out.Header.Set(HeaderRuleID, result.RuleID)
+		}
+	}
+	_ = nc.PublishMsg(out)
+}
+
+func payload(data []byte) json.RawMessage {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' && json.Valid(trimmed) {
+		return trimmed
+	}
+	raw, _ := json.Marshal(map[string]string{"body": string(data)})
+	return raw
+}
+
+func matchesAny(patterns []string, subject string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		if matchSubject(p, subject) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchSubject reports whether subject matches pattern, where * matches one
+// token and a trailing > matches one or more.
+func matchSubject(pattern, subject string) bool {
+	pt, st := strings.Split(pattern, "."), strings.Split(subject, ".")
+	for i, p := range pt {
+		if p == ">" {
+			return i == len(pt)-1 && len(st) > i
+		}
+		if i >= len(st) || (p != "*" && p != st[i]) {
+			return false
+		}
+	}
+	return len(pt) == len(st)
+}
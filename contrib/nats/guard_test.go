@@ -0,0 +1,87 @@
+package nats
+
+import (
+	"testing"
+	"time"
+
+	governor "github.com/mfifth/aisentinel-go-sdk"
+	"github.com/mfifth/aisentinel-go-sdk/governortest"
+	natsserver "github.com/nats-io/nats-server/v2/test"
+	natslib "github.com/nats-io/nats.go"
+)
+
+func TestMiddleware(t *testing.T) {
+	pack := governor.Rulepack{ID: "prompts", Rules: []governor.RuleDefinition{{ID: "prompt", Pattern: "^ok", Allow: true}}}
+	gov := governortest.NewControlPlane(t, pack).NewGovernor(t)
+
+	opts := natsserver.DefaultTestOptions
+	opts.Port = -1
+	srv := natsserver.RunServer(&opts)
+	t.Cleanup(srv.Shutdown)
+	nc, err := natslib.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(nc.Close)
+
+	handled := make(chan string, 4)
+	quarantined := make(chan *natslib.Msg, 4)
+	guard := Middleware(nc, gov, Options{RulepackID: "prompts", Subjects: []string{"prompts.>"}, QuarantineSubject: "quarantine"})
+	if _, err := nc.Subscribe(">", guard(func(msg *natslib.Msg) {
+		if msg.Subject != "quarantine" {
+			handled <- msg.Subject
+		}
+	})); err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	if _, err := nc.ChanSubscribe("quarantine", quarantined); err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+
+	_ = nc.Publish("prompts.chat", []byte(`{"prompt":"ok then"}`))
+	_ = nc.Publish("prompts.chat", []byte(`{"prompt":"leak"}`))
+	_ = nc.Publish("metrics.cpu", []byte(`not json`))
+
+	for _, want := range []string{"prompts.chat", "metrics.cpu"} {
+		select {
+		case got := <-handled:
+			if got != want {
+				t.Fatalf("expected %s to be handled, got %s", want, got)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for %s", want)
+		}
+	}
+	select {
+	case msg := <-quarantined:
+		if string(msg.Data) != `{"prompt":"leak"}` || msg.Header.Get(HeaderSubject) != "prompts.chat" || msg.Header.Get(HeaderReason) != "no matching rule" {
+			t.Fatalf("expected the denied message with its reason, got %s %v", msg.Data, msg.Header)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for the quarantined message")
+	}
+	select {
+	case got := <-handled:
+		t.Fatalf("expected the denied message not to be handled, got %s", got)
+	default:
+	}
+}
+
+func TestMatchSubject(t *testing.T) {
+	cases := []struct {
+		pattern, subject string
+		want             bool
+	}{
+		{"prompts.chat", "prompts.chat", true},
+		{"prompts.*", "prompts.chat", true},
+		{"prompts.*", "prompts.chat.eu", false},
+		{"prompts.>", "prompts.chat.eu", true},
+		{"prompts.>", "prompts", false},
+		{"*.chat", "tools.chat", true},
+	}
+	for _, c := range cases {
+		if got := matchSubject(c.pattern, c.subject); got != c.want {
+			t.Errorf("matchSubject(%q, %q) = %v, want %v", c.pattern, c.subject, got, c.want)
+		}
+	}
+}
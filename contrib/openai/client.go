@@ -0,0 +1,248 @@
+// Package openai governs calls made with the official OpenAI Go client. New
+// wraps a client so chat, completion and embedding calls go through the
+// Governor, and Unwrap returns it for calls that are not governed:
+//
+//	client := openai.NewClient()
+//	governed := aisentinelopenai.New(&client, gov, aisentinelopenai.Options{RulepackID: "llm-egress"})
+//	resp, err := governed.NewChatCompletion(ctx, params)
+//
+// Prompts are evaluated before a request is sent and completions after the
+// response is received. Payloads are flattened so rules can match them by
+// field: requests carry "operation", "model" and "prompt" (every text part of
+// the request joined by newlines) and responses carry "operation", "model"
+// and "completion". Decisions go through Governor.Evaluate and are audited
+// like any other.
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	governor "github.com/mfifth/aisentinel-go-sdk"
+	"github.com/mfifth/aisentinel-go-sdk/aisentinelhttp"
+	"github.com/mfifth/aisentinel-go-sdk/pii"
+	openailib "github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+)
+
+// Options configures a Client. The zero value evaluates prompts and
+// completions against the Governor's default rulepack, blocks denied ones
+// and fails closed.
+type Options struct {
+	// RulepackID is evaluated against prompts; empty selects
+	// Config.DefaultRulepackID.
+	RulepackID string
+	// ResponseRulepackID is evaluated against completions; empty uses
+	// RulepackID.
+	ResponseRulepackID string
+	// SkipResponse disables completion evaluation.
+	SkipResponse bool
+	// OnDeny is the action for denied text; the default is
+	// aisentinelhttp.ActionBlock.
+	OnDeny aisentinelhttp.Action
+	// Redact rewrites denied text for aisentinelhttp.ActionRedact. The
+	// default replaces PII with placeholders such as [EMAIL].
+	Redact func(text string) string
+	// FailOpen lets calls through when the Governor cannot decide.
+	FailOpen bool
+}
+
+// Client wraps an OpenAI client so chat, completion and embedding calls are
+// governed. Denied calls fail with an *aisentinelhttp.DeniedError, which
+// matches aisentinelhttp.ErrDenied; denied prompts are never sent.
+type Client struct {
+	client *openailib.Client
+	gov    *governor.Governor
+	opts   Options
+}
+
+// New returns a Client calling the OpenAI API through client.
+func New(client *openailib.Client, gov *governor.Governor, opts Options) *Client {
+	if opts.ResponseRulepackID == "" {
+		opts.ResponseRulepackID = opts.RulepackID
+	}
+	if opts.Redact == nil {
+		detector := pii.New()
+		opts.Redact = func(text string) string {
+			redacted, _ := detector.Redact(text, pii.StrategyPlaceholder)
+			return redacted
+		}
+	}
+	return &Client{client: client, gov: gov, opts: opts}
+}
+
+// Unwrap returns the underlying OpenAI client for calls the wrapper does not
+// govern.
+func (c *Client) Unwrap() *openailib.Client { return c.client }
+
+// NewChatCompletion governs client.Chat.Completions.New. Every choice of the
+// response is evaluated separately.
+func (c *Client) NewChatCompletion(ctx context.Context, params openailib.ChatCompletionNewParams, opts ...option.RequestOption) (*openailib.ChatCompletion, error) {
+	opts, err := c.governRequest(ctx, "chat", params, opts)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Chat.Completions.New(ctx, params, opts...)
+	if err != nil || c.opts.SkipResponse {
+		return resp, err
+	}
+	for i := range resp.Choices {
+		text, err := c.governResponse(ctx, "chat", resp.Model, resp.Choices[i].Message.Content)
+		if err != nil {
+			return nil, err
+		}
+		resp.Choices[i].Message.Content = text
+	}
+	return resp, nil
+}
+
+// NewCompletion governs client.Completions.New, the legacy completions API.
+func (c *Client) NewCompletion(ctx context.Context, params openailib.CompletionNewParams, opts ...option.RequestOption) (*openailib.Completion, error) {
+	opts, err := c.governRequest(ctx, "completion", params, opts)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Completions.New(ctx, params, opts...)
+	if err != nil || c.opts.SkipResponse {
+		return resp, err
+	}
+	for i := range resp.Choices {
+		text, err := c.governResponse(ctx, "completion", resp.Model, resp.Choices[i].Text)
+		if err != nil {
+			return nil, err
+		}
+		resp.Choices[i].Text = text
+	}
+	return resp, nil
+}
+
+// NewEmbedding governs client.Embeddings.New. Only the input is evaluated;
+// vectors carry no text.
+func (c *Client) NewEmbedding(ctx context.Context, params openailib.EmbeddingNewParams, opts ...option.RequestOption) (*openailib.CreateEmbeddingResponse, error) {
+	opts, err := c.governRequest(ctx, "embedding", params, opts)
+	if err != nil {
+		return nil, err
+	}
+	return c.client.Embeddings.New(ctx, params, opts...)
+}
+
+// textField is a piece of request text and its sjson path in the body.
+type textField struct {
+	path string
+	text string
+}
+
+// governRequest evaluates the text of params and returns opts extended to
+// send redacted text in its place when needed.
+func (c *Client) governRequest(ctx context.Context, operation string, params json.Marshaler, opts []option.RequestOption) ([]option.RequestOption, error) {
+	body, err := params.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	var document map[string]any
+	if err := json.Unmarshal(body, &document); err != nil {
+		return nil, err
+	}
+	model, _ := document["model"].(string)
+	fields := requestText(document)
+
+	result, err := c.evaluate(ctx, c.opts.RulepackID, requestPayload(operation, model, fields))
+	if err != nil || result.Allowed {
+		return opts, err
+	}
+	if c.opts.OnDeny == aisentinelhttp.ActionRedact {
+		redacted := make([]textField, len(fields))
+		for i, f := range fields {
+			redacted[i] = textField{path: f.path, text: c.opts.Redact(f.text)}
+		}
+		if result, err = c.evaluate(ctx, c.opts.RulepackID, requestPayload(operation, model, redacted)); err != nil || result.Allowed {
+			for i, f := range redacted {
+				if f.text != fields[i].text {
+					opts = append(opts, option.WithJSONSet(f.path, f.text))
+				}
+			}
+			return opts, err
+		}
+	}
+	return nil, &aisentinelhttp.DeniedError{Phase: "request", Result: result}
+}
+
+// governResponse evaluates one completion and returns the text to hand back.
+func (c *Client) governResponse(ctx context.Context, operation, model, text string) (string, error) {
+	payload := map[string]string{"operation": operation, "model": model, "completion": text}
+	result, err := c.evaluate(ctx, c.opts.ResponseRulepackID, payload)
+	if err != nil || result.Allowed {
+		return text, err
+	}
+	if c.opts.OnDeny == aisentinelhttp.ActionRedact {
+		payload["completion"] = c.opts.Redact(text)
+		if result, err = c.evaluate(ctx, c.opts.ResponseRulepackID, payload); err != nil || result.Allowed {
+			return payload["completion"], err
+		}
+	}
+	return "", &aisentinelhttp.DeniedError{Phase: "response", Result: result}
+}
+
+func (c *Client) evaluate(ctx context.Context, rulepack string, payload map[string]string) (governor.DecisionResult, error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return governor.DecisionResult{}, err
+	}
+	result, err := c.gov.Evaluate(ctx, governor.DecisionRequest{RulepackID: rulepack, Payload: raw})
+	if err != nil && c.opts.FailOpen {
+		return governor.DecisionResult{Allowed: true}, nil
+	}
+	if err != nil {
+		return result, fmt.Errorf("openai: policy evaluation: %w", err)
+	}
+	return result, nil
+}
+
+func requestPayload(operation, model string, fields []textField) map[string]string {
+	texts := make([]string, len(fields))
+	for i, f := range fields {
+		texts[i] = f.text
+	}
+	return map[string]string{"operation": operation, "model": model, "prompt": strings.Join(texts, "\n")}
+}
+
+// requestText collects the text of a request body: message contents and
+// their text parts for chat, the prompt for completions and the input for
+// embeddings. Token arrays are skipped.
+func requestText(document map[string]any) []textField {
+	var fields []textField
+	if messages, ok := document["messages"].([]any); ok {
+		for i, m := range messages {
+			message, _ := m.(map[string]any)
+			fields = appendText(fields, "messages."+strconv.Itoa(i)+".content", message["content"])
+		}
+	}
+	fields = appendText(fields, "prompt", document["prompt"])
+	fields = appendText(fields, "input", document["input"])
+	return fields
+}
+
+// appendText appends v when it is a string, the strings of v when it is an
+// array, and the "text" of v's parts when they are objects.
+func appendText(fields []textField, path string, v any) []textField {
+	switch v := v.(type) {
+	case string:
+		fields = append(fields, textField{path: path, text: v})
+	case []any:
+		for i, item := range v {
+			itemPath := path + "." + strconv.Itoa(i)
+			switch item := item.(type) {
+			case string:
+				fields = append(fields, textField{path: itemPath, text: item})
+			case map[string]any:
+				if text, ok := item["text"].(string); ok {
+					fields = append(fields, textField{path: itemPath + ".text", text: text})
+				}
+			}
+		}
+	}
+	return fields
+}
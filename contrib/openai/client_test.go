@@ -0,0 +1,127 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	governor "github.com/mfifth/aisentinel-go-sdk"
+	"github.com/mfifth/aisentinel-go-sdk/aisentinelhttp"
+	"github.com/mfifth/aisentinel-go-sdk/governortest"
+	openailib "github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+)
+
+// newClient returns a Client for an OpenAI stand-in that answers every chat
+// completion with reply, and a pointer to the last request body it saw.
+func newClient(t *testing.T, reply string, opts Options) (*Client, *string) {
+	t.Helper()
+	pack := governor.Rulepack{ID: "llm", Rules: []governor.RuleDefinition{
+		{ID: "prompt", Pattern: `@`, Allow: false},
+		{ID: "completion", Pattern: `secret`, Allow: false},
+		{ID: "operation", Pattern: `.`, Allow: true},
+	}}
+	gov := governortest.NewControlPlane(t, pack).NewGovernor(t)
+
+	var sent string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		sent = string(body)
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/chat/completions":
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"id": "chatcmpl-1", "object": "chat.completion", "model": "gpt-4o",
+				"choices": []any{map[string]any{"index": 0, "finish_reason": "stop", "message": map[string]any{"role": "assistant", "content": reply}}},
+			})
+		case "/embeddings":
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"object": "list", "model": "text-embedding-3-small",
+				"data": []any{map[string]any{"object": "embedding", "index": 0, "embedding": []float64{0.1, 0.2}}},
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(upstream.Close)
+
+	client := openailib.NewClient(option.WithAPIKey("sk-test"), option.WithBaseURL(upstream.URL), option.WithMaxRetries(0))
+	opts.RulepackID = "llm"
+	return New(&client, gov, opts), &sent
+}
+
+func chatParams(prompt string) openailib.ChatCompletionNewParams {
+	return openailib.ChatCompletionNewParams{
+		Model: openailib.ChatModelGPT4o,
+		Messages: []openailib.ChatCompletionMessageParamUnion{
+			openailib.SystemMessage("Be brief."),
+			openailib.UserMessage(prompt),
+		},
+	}
+}
+
+func TestNewChatCompletion(t *testing.T) {
+	client, sent := newClient(t, "Hello there.", Options{})
+
+	resp, err := client.NewChatCompletion(context.Background(), chatParams("Say hello"))
+	if err != nil || resp.Choices[0].Message.Content != "Hello there." {
+		t.Fatalf("expected the completion, got %+v %v", resp, err)
+	}
+
+	*sent = ""
+	_, err = client.NewChatCompletion(context.Background(), chatParams("Mail jane@example.com"))
+	var denied *aisentinelhttp.DeniedError
+	if !errors.As(err, &denied) || denied.Phase != "request" || !errors.Is(err, aisentinelhttp.ErrDenied) {
+		t.Fatalf("expected the prompt to be denied, got %v", err)
+	}
+	if *sent != "" {
+		t.Fatalf("expected the denied prompt not to be sent, got %s", *sent)
+	}
+}
+
+func TestNewChatCompletionDeniesResponse(t *testing.T) {
+	client, _ := newClient(t, "The secret is 42.", Options{})
+
+	_, err := client.NewChatCompletion(context.Background(), chatParams("Tell me"))
+	var denied *aisentinelhttp.DeniedError
+	if !errors.As(err, &denied) || denied.Phase != "response" {
+		t.Fatalf("expected the completion to be denied, got %v", err)
+	}
+
+	client.opts.SkipResponse = true
+	if _, err := client.NewChatCompletion(context.Background(), chatParams("Tell me")); err != nil {
+		t.Fatalf("expected SkipResponse to pass the completion, got %v", err)
+	}
+}
+
+func TestNewChatCompletionRedacts(t *testing.T) {
+	client, sent := newClient(t, "Noted.", Options{OnDeny: aisentinelhttp.ActionRedact})
+
+	if _, err := client.NewChatCompletion(context.Background(), chatParams("Mail jane@example.com")); err != nil {
+		t.Fatalf("expected the redacted prompt to be allowed, got %v", err)
+	}
+	if strings.Contains(*sent, "jane@example.com") || !strings.Contains(*sent, "[EMAIL]") || !strings.Contains(*sent, "Be brief.") {
+		t.Fatalf("expected only the email to be redacted, sent %s", *sent)
+	}
+}
+
+func TestNewEmbedding(t *testing.T) {
+	client, _ := newClient(t, "", Options{})
+
+	params := openailib.EmbeddingNewParams{
+		Model: openailib.EmbeddingModelTextEmbedding3Small,
+		Input: openailib.EmbeddingNewParamsInputUnion{OfArrayOfStrings: []string{"fine", "jane@example.com"}},
+	}
+	if _, err := client.NewEmbedding(context.Background(), params); !errors.Is(err, aisentinelhttp.ErrDenied) {
+		t.Fatalf("expected the input to be denied, got %v", err)
+	}
+	params.Input = openailib.EmbeddingNewParamsInputUnion{OfString: openailib.String("fine")}
+	if resp, err := client.NewEmbedding(context.Background(), params); err != nil || len(resp.Data) != 1 {
+		t.Fatalf("expected the embedding, got %+v %v", resp, err)
+	}
+}
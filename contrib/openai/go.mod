@@ -0,0 +1,19 @@
+module github.com/mfifth/aisentinel-go-sdk/contrib/openai
+
+go 1.23
+
+require (
+	github.com/mfifth/aisentinel-go-sdk v0.0.0
+	github.com/openai/openai-go v1.12.0
+)
+
+require (
+	github.com/BurntSushi/toml v1.6.0 // indirect
+	github.com/tidwall/gjson v1.14.4 // indirect
+	github.com/tidwall/match v1.1.1 // indirect
+	github.com/tidwall/pretty v1.2.1 // indirect
+	github.com/tidwall/sjson v1.2.5 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/mfifth/aisentinel-go-sdk => ../..
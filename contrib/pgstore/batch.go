@@ -0,0 +1,99 @@
+package pgstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/mfifth/aisentinel-go-sdk/storage"
+)
+
+// ErrClosed is returned by Puts on a closed Store.
+var ErrClosed = errors.New("pgstore: store closed")
+
+// pendingPut is a Put waiting for its batch to be written.
+type pendingPut struct {
+	record storage.Record
+	done   chan error
+}
+
+// batcher coalesces concurrent Puts. A single writer takes whatever Puts are
+// waiting, up to max, and flushes them together, so Puts arriving while a
+// flush is in progress share the next one. A lone Put is flushed at once.
+type batcher struct {
+	max     int
+	flush   func([]storage.Record) error
+	puts    chan pendingPut
+	stopped chan struct{}
+	wg      sync.WaitGroup
+	once    sync.Once
+}
+
+func newBatcher(max int, flush func([]storage.Record) error) *batcher {
+	if max <= 0 {
+		max = DefaultOptions().MaxBatch
+	}
+	b := &batcher{max: max, flush: flush, puts: make(chan pendingPut), stopped: make(chan struct{})}
+	b.wg.Add(1)
+	go b.run()
+	return b
+}
+
+// put hands a copy of record to the writer and waits for its flush. When
+// ctx is done first put returns, but a record already handed over is still
+// flushed, so the writer must not share the caller's Value.
+func (b *batcher) put(ctx context.Context, record storage.Record) error {
+	record.Value = bytes.Clone(record.Value)
+	p := pendingPut{record: record, done: make(chan error, 1)}
+	select {
+	case b.puts <- p:
+	case <-b.stopped:
+		return ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case err := <-p.done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b *batcher) run() {
+	defer b.wg.Done()
+	batch := make([]pendingPut, 0, b.max)
+	records := make([]storage.Record, 0, b.max)
+	for {
+		select {
+		case <-b.stopped:
+			return
+		case p := <-b.puts:
+			batch = append(batch[:0], p)
+		}
+	drain:
+		for len(batch) < b.max {
+			select {
+			case p := <-b.puts:
+				batch = append(batch, p)
+			default:
+				break drain
+			}
+		}
+		records = records[:0]
+		for _, p := range batch {
+			records = append(records, p.record)
+		}
+		err := b.flush(records)
+		for _, p := range batch {
+			p.done <- err
+		}
+	}
+}
+
+// stop waits for the flush in progress and fails later Puts.
+func (b *batcher) stop() {
+	b.once.Do(func() { close(b.stopped) })
+	b.wg.Wait()
+}
@@ -0,0 +1,299 @@
+// Package pgstore persists Governor audit records in PostgreSQL. Importing it
+// registers the store for the "postgres" storage backend, with
+// Config.StorageDSN as the connection string:
+//
+//	import _ "github.com/mfifth/aisentinel-go-sdk/contrib/pgstore"
+//
+//	gov, err := governor.NewGovernor(ctx, governor.Config{
+//		StorageBackend: "postgres",
+//		StorageDSN:     "postgres://audit@db.internal/compliance?pool_max_conns=8",
+//	})
+//
+// Open migrates the schema: records live in the aisentinel_records table,
+// in the schema the connection's search_path selects. Besides the key and
+// the value, audit records fill the indexed rulepack_id, timestamp and
+// allowed columns, so decisions can be queried with SQL. The columns are
+// NULL for records that are not audit records.
+//
+// Connections are pooled; the pool_* parameters of the connection string
+// tune the pool. Concurrent Puts are coalesced into multi-row inserts.
+package pgstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mfifth/aisentinel-go-sdk/storage"
+)
+
+func init() {
+	storage.Register(storage.BackendPostgres, func(dsn string) (storage.Store, error) {
+		return Open(context.Background(), dsn, DefaultOptions())
+	})
+}
+
+// iterBatch is how many records Iter reads per query. fn runs between
+// queries, so it may write to the store.
+const iterBatch = 256
+
+// migrations are applied in order, each once; a database's version is the
+// number applied. Append to change the schema, never edit.
+var migrations = []string{
+	`CREATE TABLE aisentinel_records (
+	key         TEXT COLLATE "C" PRIMARY KEY,
+	value       BYTEA NOT NULL,
+	rulepack_id TEXT,
+	timestamp   TIMESTAMPTZ,
+	allowed     BOOLEAN
+);
+CREATE INDEX aisentinel_records_rulepack_id ON aisentinel_records (rulepack_id, timestamp);
+CREATE INDEX aisentinel_records_timestamp ON aisentinel_records (timestamp);
+CREATE INDEX aisentinel_records_allowed ON aisentinel_records (allowed, timestamp);`,
+}
+
+// Options tune a Store.
+type Options struct {
+	// MaxBatch caps how many concurrent Puts one insert writes. Zero means
+	// 100.
+	MaxBatch int
+	// MaxConns caps the connection pool. Zero keeps the connection
+	// string's pool_max_conns, or pgxpool's default.
+	MaxConns int32
+}
+
+// DefaultOptions returns the Options the registered backend uses.
+func DefaultOptions() Options {
+	return Options{MaxBatch: 100}
+}
+
+// Store is a storage.Store backed by a PostgreSQL connection pool. Put
+// returns once its record is committed. It is safe for concurrent use, and
+// any number of Stores, in any number of processes, may share a database.
+type Store struct {
+	pool  *pgxpool.Pool
+	puts  *batcher
+	close context.CancelFunc
+}
+
+var (
+	_ storage.Store         = (*Store)(nil)
+	_ storage.RangeIterator = (*Store)(nil)
+	_ storage.BatchWriter   = (*Store)(nil)
+)
+
+// Open connects to the database at dsn and migrates its schema. ctx bounds
+// connecting and migrating.
+func Open(ctx context.Context, dsn string, opts Options) (*Store, error) {
+	cfg, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("pgstore: %w", err)
+	}
+	if opts.MaxConns > 0 {
+		cfg.MaxConns = opts.MaxConns
+	}
+	pool, err := pgxpool.NewWithConfig(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("pgstore: %w", err)
+	}
+	if err := migrate(ctx, pool); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("pgstore: migrate: %w", err)
+	}
+	// Inserts outlive the Puts that started them, so they run until Close.
+	insertCtx, cancel := context.WithCancel(context.Background())
+	s := &Store{pool: pool, close: cancel}
+	s.puts = newBatcher(opts.MaxBatch, func(records []storage.Record) error {
+		return s.insert(insertCtx, records)
+	})
+	return s, nil
+}
+
+// migrate applies the migrations the database lacks. An advisory lock keeps
+// Stores opening at the same time from applying them twice.
+func migrate(ctx context.Context, pool *pgxpool.Pool) error {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock(hashtext('aisentinel_schema_migrations'))"); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `CREATE TABLE IF NOT EXISTS aisentinel_schema_migrations (
+	version    INTEGER PRIMARY KEY,
+	applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+)`); err != nil {
+		return err
+	}
+	var version int
+	if err := tx.QueryRow(ctx, "SELECT coalesce(max(version), 0) FROM aisentinel_schema_migrations").Scan(&version); err != nil {
+		return err
+	}
+	if version > len(migrations) {
+		return fmt.Errorf("schema version %d is newer than %d", version, len(migrations))
+	}
+	for i, migration := range migrations[version:] {
+		if _, err := tx.Exec(ctx, migration); err != nil {
+			return fmt.Errorf("version %d: %w", version+i+1, err)
+		}
+		if _, err := tx.Exec(ctx, "INSERT INTO aisentinel_schema_migrations (version) VALUES ($1)", version+i+1); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// auditColumns are the indexed columns of an audit record. Fields missing
+// from the value, or values that are not JSON objects, leave them NULL.
+type auditColumns struct {
+	RulepackID *string    `json:"rulepack_id"`
+	Time       *time.Time `json:"time"`
+	Allowed    *bool      `json:"allowed"`
+}
+
+func columns(value []byte) auditColumns {
+	var c auditColumns
+	if json.Unmarshal(value, &c) != nil {
+		return auditColumns{}
+	}
+	return c
+}
+
+// insert writes records in one statement. Only the last record of a key is
+// written, since a statement cannot update a row twice.
+func (s *Store) insert(ctx context.Context, records []storage.Record) error {
+	last := make(map[string]int, len(records))
+	for i, record := range records {
+		last[record.Key] = i
+	}
+	var (
+		keys        = make([]string, 0, len(last))
+		values      = make([][]byte, 0, len(last))
+		rulepackIDs = make([]*string, 0, len(last))
+		timestamps  = make([]*time.Time, 0, len(last))
+		allowed     = make([]*bool, 0, len(last))
+	)
+	for i, record := range records {
+		if last[record.Key] != i {
+			continue
+		}
+		value := record.Value
+		if value == nil {
+			value = []byte{}
+		}
+		c := columns(value)
+		keys = append(keys, record.Key)
+		values = append(values, value)
+		rulepackIDs = append(rulepackIDs, c.RulepackID)
+		timestamps = append(timestamps, c.Time)
+		allowed = append(allowed, c.Allowed)
+	}
+	_, err := s.pool.Exec(ctx, `
+INSERT INTO aisentinel_records (key, value, rulepack_id, timestamp, allowed)
+SELECT * FROM unnest($1::text[], $2::bytea[], $3::text[], $4::timestamptz[], $5::boolean[])
+ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value, rulepack_id = EXCLUDED.rulepack_id,
+	timestamp = EXCLUDED.timestamp, allowed = EXCLUDED.allowed`,
+		keys, values, rulepackIDs, timestamps, allowed)
+	return err
+}
+
+// Put stores a record. When ctx is done first Put returns its error, but
+// the record may still be written, from a copy of its Value taken by Put.
+func (s *Store) Put(ctx context.Context, record storage.Record) error {
+	return s.puts.put(ctx, record)
+}
+
+// PutBatch stores records with one insert, bypassing the coalescing of
+// concurrent Puts, so they are written together or not at all.
+func (s *Store) PutBatch(ctx context.Context, records []storage.Record) error {
+	return s.insert(ctx, records)
+}
+
+// Get retrieves a record by key, returning storage.ErrNotFound() when there
+// is none.
+func (s *Store) Get(ctx context.Context, key string) (storage.Record, error) {
+	var value []byte
+	err := s.pool.QueryRow(ctx, "SELECT value FROM aisentinel_records WHERE key = $1", key).Scan(&value)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return storage.Record{}, storage.ErrNotFound()
+	}
+	if err != nil {
+		return storage.Record{}, err
+	}
+	return storage.Record{Key: key, Value: value}, nil
+}
+
+// Iter iterates over all records in key order. Records are read in batches,
+// each with its own query, so fn sees records written by earlier calls.
+func (s *Store) Iter(ctx context.Context, fn func(storage.Record) error) error {
+	return s.IterRange(ctx, "", "", fn)
+}
+
+// IterRange iterates in key order over the records with keys from start,
+// inclusive, to end, exclusive; an empty end is unbounded. Records are read
+// in batches, as by Iter.
+func (s *Store) IterRange(ctx context.Context, start, end string, fn func(storage.Record) error) error {
+	from, op := start, ">="
+	for {
+		query, args := "SELECT key, value FROM aisentinel_records WHERE key "+op+" $1", []any{from}
+		if end != "" {
+			query += " AND key < $2"
+			args = append(args, end)
+		}
+		query += fmt.Sprintf(" ORDER BY key LIMIT %d", iterBatch)
+		rows, err := s.pool.Query(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		batch, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (storage.Record, error) {
+			var record storage.Record
+			err := row.Scan(&record.Key, &record.Value)
+			return record, err
+		})
+		if err != nil {
+			return err
+		}
+		for _, record := range batch {
+			if err := fn(record); err != nil {
+				return err
+			}
+		}
+		if len(batch) < iterBatch {
+			return nil
+		}
+		from, op = batch[len(batch)-1].Key, ">"
+	}
+}
+
+// Delete removes a record by key.
+func (s *Store) Delete(ctx context.Context, key string) error {
+	_, err := s.pool.Exec(ctx, "DELETE FROM aisentinel_records WHERE key = $1", key)
+	return err
+}
+
+// DeleteBatch removes records by key with one statement.
+func (s *Store) DeleteBatch(ctx context.Context, keys []string) error {
+	_, err := s.pool.Exec(ctx, "DELETE FROM aisentinel_records WHERE key = ANY($1)", keys)
+	return err
+}
+
+// Pool returns the connection pool for SQL queries over the
+// aisentinel_records table. Writing to it bypasses the indexed columns'
+// upkeep.
+func (s *Store) Pool() *pgxpool.Pool {
+	return s.pool
+}
+
+// Close waits for the insert in progress, fails Puts still waiting, and
+// closes the pool.
+func (s *Store) Close() error {
+	s.puts.stop()
+	s.close()
+	s.pool.Close()
+	return nil
+}
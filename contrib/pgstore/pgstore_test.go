@@ -0,0 +1,195 @@
+package pgstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	governor "github.com/mfifth/aisentinel-go-sdk"
+	"github.com/mfifth/aisentinel-go-sdk/storage"
+	"github.com/mfifth/aisentinel-go-sdk/storage/storagetest"
+)
+
+func TestBatcher(t *testing.T) {
+	ctx := context.Background()
+	var (
+		mu      sync.Mutex
+		batches [][]storage.Record
+	)
+	release := make(chan struct{})
+	b := newBatcher(3, func(records []storage.Record) error {
+		<-release
+		mu.Lock()
+		defer mu.Unlock()
+		batches = append(batches, append([]storage.Record(nil), records...))
+		if len(records) == 1 && records[0].Key == "bad" {
+			return errors.New("rejected")
+		}
+		return nil
+	})
+
+	// The first Put is flushed alone; the four queued behind it while it is
+	// written are coalesced into batches of at most three.
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := b.put(ctx, storage.Record{Key: fmt.Sprint(i)}); err != nil {
+				t.Errorf("put: %v", err)
+			}
+		}()
+		if i == 0 {
+			time.Sleep(50 * time.Millisecond) // let the first flush start
+		}
+	}
+	time.Sleep(50 * time.Millisecond) // let the Puts queue
+	close(release)
+	wg.Wait()
+	if err := b.put(ctx, storage.Record{Key: "bad"}); err == nil {
+		t.Fatal("expected the flush error returned to the Put")
+	}
+	b.stop()
+	if err := b.put(ctx, storage.Record{Key: "late"}); !errors.Is(err, ErrClosed) {
+		t.Fatalf("expected ErrClosed after stop, got %v", err)
+	}
+
+	var sizes []int
+	for _, batch := range batches {
+		sizes = append(sizes, len(batch))
+	}
+	if fmt.Sprint(sizes) != "[1 3 1 1]" {
+		t.Fatalf("expected batches of 1, 3, 1 and 1 records, got %v", sizes)
+	}
+}
+
+func TestBatcherCancelledPut(t *testing.T) {
+	entered, release := make(chan struct{}), make(chan struct{})
+	var written string
+	b := newBatcher(1, func(records []storage.Record) error {
+		close(entered)
+		<-release
+		written = string(records[0].Value)
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	value := []byte("original")
+	errc := make(chan error, 1)
+	go func() { errc <- b.put(ctx, storage.Record{Key: "k", Value: value}) }()
+	<-entered
+	cancel()
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the cancelled Put to return, got %v", err)
+	}
+	copy(value, "reused!!")
+	close(release)
+	b.stop()
+	if written != "original" {
+		t.Fatalf("expected the record written as it was put, got %q", written)
+	}
+}
+
+// openTestStore opens a Store on the database named by
+// AISENTINEL_TEST_POSTGRES_DSN, emptied first, or skips the test.
+func openTestStore(t *testing.T) (*Store, string) {
+	t.Helper()
+	dsn := os.Getenv("AISENTINEL_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("AISENTINEL_TEST_POSTGRES_DSN not set")
+	}
+	store, err := Open(context.Background(), dsn, DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Pool().Exec(context.Background(), "TRUNCATE aisentinel_records"); err != nil {
+		t.Fatal(err)
+	}
+	return store, dsn
+}
+
+func TestStore(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Store {
+		store, _ := openTestStore(t)
+		return store
+	})
+}
+
+func TestReopen(t *testing.T) {
+	ctx := context.Background()
+	store, dsn := openTestStore(t)
+	var wg sync.WaitGroup
+	for i := 0; i < iterBatch+10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := store.Put(ctx, storage.Record{Key: fmt.Sprintf("k%04d", i), Value: []byte(fmt.Sprint(i))}); err != nil {
+				t.Errorf("put: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if err := store.Put(ctx, storage.Record{Key: ""}); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	// Reopening finds the schema migrated.
+	store, err := Open(ctx, dsn, DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	var keys []string
+	err = store.Iter(ctx, func(r storage.Record) error {
+		keys = append(keys, r.Key)
+		// The keys sort before the cursor, so they are not iterated.
+		return store.Put(ctx, storage.Record{Key: "a-" + r.Key})
+	})
+	if err != nil {
+		t.Fatalf("iter: %v", err)
+	}
+	if len(keys) != iterBatch+11 || keys[0] != "" || keys[1] != "k0000" || keys[2] != "k0001" {
+		t.Fatalf("expected the records to persist in key order, got %d keys starting %q", len(keys), keys[:3])
+	}
+}
+
+func TestRegisteredBackend(t *testing.T) {
+	ctx := context.Background()
+	store, dsn := openTestStore(t)
+	defer store.Close()
+	pack := &governor.Rulepack{ID: "prompts", Rules: []governor.RuleDefinition{{ID: "prompt", Pattern: "ok", Allow: true}}}
+	cfg := governor.Config{APIKey: "test", OfflineMode: true, StorageBackend: "postgres", StorageDSN: dsn}
+	for _, w := range cfg.Warnings() {
+		if w.Field == "StorageBackend" {
+			t.Fatalf("expected no in-memory warning once registered, got %q", w.Message)
+		}
+	}
+	gov, err := governor.NewGovernor(ctx, cfg, governor.WithRulepacks(pack))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, prompt := range []string{"ok", "ok", "nope"} {
+		if _, err := gov.Evaluate(ctx, governor.DecisionRequest{RulepackID: "prompts", Payload: json.RawMessage(`{"prompt":"` + prompt + `"}`)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := gov.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var allowed, denied int
+	err = store.Pool().QueryRow(ctx, `
+SELECT count(*) FILTER (WHERE allowed), count(*) FILTER (WHERE NOT allowed) FROM aisentinel_records
+WHERE rulepack_id = 'prompts' AND timestamp > now() - interval '1 hour'`).Scan(&allowed, &denied)
+	if err != nil || allowed != 2 || denied != 1 {
+		t.Fatalf("expected the decisions queryable by column, got %d allowed %d denied %v", allowed, denied, err)
+	}
+}
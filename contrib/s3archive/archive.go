@@ -0,0 +1,316 @@
+// Package s3archive archives Governor audit records to S3, or any
+// S3-compatible object store, for long-term retention. Store wraps the
+// Store serving reads, and batches a copy of every audit record into
+// time-partitioned JSONL objects:
+//
+//	s3://bucket/<prefix>dt=2026-10-15/hour=13/<flush time>-<random>.jsonl
+//
+// Each line is an audit record as JSON, partitioned by its time in UTC, so
+// Athena, BigQuery or Hive can query the objects as a table partitioned by
+// dt and hour:
+//
+//	archive := s3archive.New(primary, s3.NewFromConfig(cfg), s3archive.Options{
+//		Bucket: "compliance",
+//		Prefix: "aisentinel/audit/",
+//	})
+//	gov, err := governor.NewGovernor(ctx, cfg, governor.WithStorage(archive))
+//
+// The primary store keeps serving Get and Iter, and Delete only removes
+// records from it, so it can hold a short window while the archive keeps
+// everything. Configure lifecycle rules on the bucket to expire archived
+// objects.
+package s3archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/mfifth/aisentinel-go-sdk/storage"
+)
+
+// ErrBufferFull is returned by Put and PutBatch when Options.MaxBuffered
+// records are waiting to be archived, typically because uploads keep
+// failing. The records were still written to the primary store.
+var ErrBufferFull = errors.New("s3archive: archive buffer full")
+
+// PutObjectAPI is the subset of *s3.Client used by Store. Clients for
+// other S3-compatible stores only need their endpoint configured.
+type PutObjectAPI interface {
+	PutObject(ctx context.Context, in *s3.PutObjectInput, opts ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+}
+
+// Options configure a Store.
+type Options struct {
+	// Bucket receives the archived objects.
+	Bucket string
+	// Prefix is prepended to object keys; end it with "/" for a
+	// directory.
+	Prefix string
+	// MaxRecords flushes once that many records are buffered. Zero means
+	// 1000.
+	MaxRecords int
+	// FlushInterval flushes buffered records at least this often. Zero
+	// means one minute.
+	FlushInterval time.Duration
+	// MaxBuffered bounds the records kept while uploads fail. Zero means
+	// 100 times MaxRecords.
+	MaxBuffered int
+	// Gzip compresses objects, named .jsonl.gz.
+	Gzip bool
+	// OnError is called with errors from background flushes, whose
+	// records stay buffered for the next one.
+	OnError func(error)
+}
+
+func (o Options) withDefaults() Options {
+	if o.MaxRecords <= 0 {
+		o.MaxRecords = 1000
+	}
+	if o.FlushInterval <= 0 {
+		o.FlushInterval = time.Minute
+	}
+	if o.MaxBuffered <= 0 {
+		o.MaxBuffered = 100 * o.MaxRecords
+	}
+	return o
+}
+
+// Store is a storage.Store that archives audit records while delegating
+// to a primary store. It is safe for concurrent use.
+type Store struct {
+	primary storage.Store
+	client  PutObjectAPI
+	opts    Options
+
+	mu         sync.Mutex
+	partitions map[string][][]byte // partition → JSONL lines
+	buffered   int
+
+	flushMu sync.Mutex // serializes uploads
+	full    chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+	closed  sync.Once
+}
+
+var (
+	_ storage.Store         = (*Store)(nil)
+	_ storage.RangeIterator = (*Store)(nil)
+	_ storage.BatchWriter   = (*Store)(nil)
+)
+
+// New returns a Store archiving to opts.Bucket through client and serving
+// reads from primary. It flushes in the background until Close.
+func New(primary storage.Store, client PutObjectAPI, opts Options) *Store {
+	s := &Store{
+		primary:    primary,
+		client:     client,
+		opts:       opts.withDefaults(),
+		partitions: map[string][][]byte{},
+		full:       make(chan struct{}, 1),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+// auditRecord holds the fields identifying an audit record.
+type auditRecord struct {
+	RulepackID string    `json:"rulepack_id"`
+	Time       time.Time `json:"time"`
+}
+
+// Put writes record to the primary store and, when it is an audit record,
+// buffers it for archiving. Other records, such as usage aggregates, are
+// rewritten in place and are not archived.
+func (s *Store) Put(ctx context.Context, record storage.Record) error {
+	if err := s.primary.Put(ctx, record); err != nil {
+		return err
+	}
+	return s.buffer(record)
+}
+
+// PutBatch writes records to the primary store as a batch and buffers the
+// audit records among them, as Put does.
+func (s *Store) PutBatch(ctx context.Context, records []storage.Record) error {
+	if err := storage.PutBatch(ctx, s.primary, records); err != nil {
+		return err
+	}
+	return s.buffer(records...)
+}
+
+// buffer queues the audit records among records for the next flush.
+func (s *Store) buffer(records ...storage.Record) error {
+	type line struct {
+		partition string
+		data      []byte
+	}
+	lines := make([]line, 0, len(records))
+	for _, record := range records {
+		var audit auditRecord
+		if json.Unmarshal(record.Value, &audit) != nil || audit.RulepackID == "" || audit.Time.IsZero() {
+			continue
+		}
+		var data bytes.Buffer
+		if err := json.Compact(&data, record.Value); err != nil {
+			continue
+		}
+		data.WriteByte('\n')
+		lines = append(lines, line{audit.Time.UTC().Format("dt=2006-01-02/hour=15"), data.Bytes()})
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, l := range lines {
+		if s.buffered >= s.opts.MaxBuffered {
+			return ErrBufferFull
+		}
+		s.partitions[l.partition] = append(s.partitions[l.partition], l.data)
+		s.buffered++
+	}
+	if s.buffered >= s.opts.MaxRecords {
+		select {
+		case s.full <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Get reads a record from the primary store.
+func (s *Store) Get(ctx context.Context, key string) (storage.Record, error) {
+	return s.primary.Get(ctx, key)
+}
+
+// Iter iterates over the primary store.
+func (s *Store) Iter(ctx context.Context, fn func(storage.Record) error) error {
+	return s.primary.Iter(ctx, fn)
+}
+
+// IterRange iterates over a key range of the primary store.
+func (s *Store) IterRange(ctx context.Context, start, end string, fn func(storage.Record) error) error {
+	return storage.IterRange(ctx, s.primary, start, end, fn)
+}
+
+// Delete removes a record from the primary store. Archived copies are kept.
+func (s *Store) Delete(ctx context.Context, key string) error {
+	return s.primary.Delete(ctx, key)
+}
+
+func (s *Store) run() {
+	defer close(s.done)
+	ticker := time.NewTicker(s.opts.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		case <-s.full:
+		}
+		if err := s.Flush(context.Background()); err != nil && s.opts.OnError != nil {
+			s.opts.OnError(err)
+		}
+	}
+}
+
+// Flush uploads the buffered records, one object per partition. Partitions
+// that fail to upload stay buffered for the next flush.
+func (s *Store) Flush(ctx context.Context) error {
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+
+	s.mu.Lock()
+	partitions := s.partitions
+	s.partitions = map[string][][]byte{}
+	s.mu.Unlock()
+
+	names := make([]string, 0, len(partitions))
+	for name := range partitions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var errs []error
+	for _, name := range names {
+		lines := partitions[name]
+		if err := s.upload(ctx, name, lines); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		s.mu.Lock()
+		s.buffered -= len(lines)
+		s.mu.Unlock()
+		delete(partitions, name)
+	}
+	if len(partitions) > 0 {
+		// Put the failed lines back ahead of those buffered meanwhile.
+		s.mu.Lock()
+		for name, lines := range partitions {
+			s.partitions[name] = append(lines, s.partitions[name]...)
+		}
+		s.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+// upload writes lines as one object in partition.
+func (s *Store) upload(ctx context.Context, partition string, lines [][]byte) error {
+	var suffix [4]byte
+	_, _ = rand.Read(suffix[:])
+	key := fmt.Sprintf("%s%s/%d-%s.jsonl", s.opts.Prefix, partition, time.Now().UnixNano(), hex.EncodeToString(suffix[:]))
+	body, contentType := bytes.Join(lines, nil), "application/x-ndjson"
+	if s.opts.Gzip {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		_, _ = zw.Write(body)
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		key += ".gz"
+		body, contentType = buf.Bytes(), "application/gzip"
+	}
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(s.opts.Bucket),
+		Key:           aws.String(key),
+		Body:          bytes.NewReader(body),
+		ContentLength: aws.Int64(int64(len(body))),
+		ContentType:   aws.String(contentType),
+	})
+	if err != nil {
+		return fmt.Errorf("s3archive: put %s: %w", key, err)
+	}
+	return nil
+}
+
+// DeleteBatch removes records from the primary store. Archived copies are
+// kept.
+func (s *Store) DeleteBatch(ctx context.Context, keys []string) error {
+	return storage.DeleteBatch(ctx, s.primary, keys)
+}
+
+// Close stops background flushing, flushes the remaining records and closes
+// the primary store.
+func (s *Store) Close() error {
+	err := errors.New("s3archive: already closed")
+	s.closed.Do(func() {
+		close(s.stop)
+		<-s.done
+		err = errors.Join(s.Flush(context.Background()), s.primary.Close())
+	})
+	return err
+}
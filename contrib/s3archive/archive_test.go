@@ -0,0 +1,145 @@
+package s3archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	governor "github.com/mfifth/aisentinel-go-sdk"
+	"github.com/mfifth/aisentinel-go-sdk/storage"
+)
+
+// fakeS3 keeps uploaded objects, failing while fail is set.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	fail    bool
+}
+
+func (f *fakeS3) PutObject(_ context.Context, in *s3.PutObjectInput, _ ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.fail {
+		return nil, errors.New("unavailable")
+	}
+	body, err := io.ReadAll(in.Body)
+	if err != nil {
+		return nil, err
+	}
+	if f.objects == nil {
+		f.objects = map[string][]byte{}
+	}
+	f.objects[aws.ToString(in.Bucket)+"/"+aws.ToString(in.Key)] = body
+	return &s3.PutObjectOutput{}, nil
+}
+
+// lines returns the JSONL lines uploaded under each partition directory.
+func (f *fakeS3) lines(t *testing.T) map[string][]string {
+	t.Helper()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := map[string][]string{}
+	for key, body := range f.objects {
+		if strings.HasSuffix(key, ".gz") {
+			zr, err := gzip.NewReader(bytes.NewReader(body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if body, err = io.ReadAll(zr); err != nil {
+				t.Fatal(err)
+			}
+		}
+		dir := key[:strings.LastIndex(key, "/")]
+		out[dir] = append(out[dir], strings.Split(strings.TrimSuffix(string(body), "\n"), "\n")...)
+	}
+	return out
+}
+
+func testRecord(t *testing.T, key, at string) storage.Record {
+	t.Helper()
+	ts, err := time.Parse(time.RFC3339, at)
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err := json.MarshalIndent(governor.AuditRecord{RulepackID: "prompts", Allowed: true, Time: ts}, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return storage.Record{Key: key, Value: value}
+}
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	client := &fakeS3{fail: true}
+	var flushErrs []error
+	store := New(storage.NewMemory(), client, Options{Bucket: "audit", Prefix: "gov/", MaxRecords: 3, Gzip: true,
+		OnError: func(err error) { flushErrs = append(flushErrs, err) }})
+
+	for _, record := range []storage.Record{
+		testRecord(t, "a", "2026-10-15T13:05:00Z"),
+		testRecord(t, "b", "2026-10-15T14:59:00+02:00"),
+		{Key: "usage/alice/gpt", Value: []byte(`{"subject":"alice"}`)},
+	} {
+		if err := store.Put(ctx, record); err != nil {
+			t.Fatalf("put: %v", err)
+		}
+	}
+	if _, err := store.Get(ctx, "usage/alice/gpt"); err != nil {
+		t.Fatalf("expected reads served by the primary store, got %v", err)
+	}
+	if err := store.Flush(ctx); err == nil {
+		t.Fatal("expected the failed upload reported")
+	}
+
+	client.mu.Lock()
+	client.fail = false
+	client.mu.Unlock()
+	if err := store.Put(ctx, testRecord(t, "c", "2026-10-15T15:00:00Z")); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	got := client.lines(t)
+	if len(got["audit/gov/dt=2026-10-15/hour=12"]) != 1 || len(got["audit/gov/dt=2026-10-15/hour=13"]) != 1 || len(got["audit/gov/dt=2026-10-15/hour=15"]) != 1 || len(got) != 3 {
+		t.Fatalf("expected one audit record in each hour partition, got %v", got)
+	}
+	var record governor.AuditRecord
+	if err := json.Unmarshal([]byte(got["audit/gov/dt=2026-10-15/hour=15"][0]), &record); err != nil || record.RulepackID != "prompts" {
+		t.Fatalf("expected a compact audit record per line, got %v %v", got, err)
+	}
+	if len(flushErrs) > 1 {
+		t.Fatalf("expected at most one background flush error, got %v", flushErrs)
+	}
+}
+
+func TestBufferFull(t *testing.T) {
+	ctx := context.Background()
+	store := New(storage.NewMemory(), &fakeS3{fail: true}, Options{Bucket: "audit", MaxRecords: 10, MaxBuffered: 2, FlushInterval: time.Hour})
+	defer store.Close()
+	for i, key := range []string{"a", "b", "c"} {
+		err := store.Put(ctx, testRecord(t, key, "2026-10-15T13:05:00Z"))
+		if (i == 2) != errors.Is(err, ErrBufferFull) {
+			t.Fatalf("put %s: unexpected error %v", key, err)
+		}
+	}
+	if _, err := store.Get(ctx, "c"); err != nil {
+		t.Fatalf("expected the record written to the primary store anyway, got %v", err)
+	}
+	batch := []storage.Record{testRecord(t, "d", "2026-10-15T13:05:00Z"), testRecord(t, "e", "2026-10-15T13:05:00Z")}
+	if err := store.PutBatch(ctx, batch); !errors.Is(err, ErrBufferFull) {
+		t.Fatalf("expected a full buffer, got %v", err)
+	}
+	if _, err := store.Get(ctx, "e"); err != nil {
+		t.Fatalf("expected the batch written to the primary store anyway, got %v", err)
+	}
+}
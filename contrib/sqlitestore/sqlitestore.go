@@ -0,0 +1,289 @@
+// Package sqlitestore persists Governor audit records in a SQLite database
+// file. Importing it registers the store for the "sqlite" storage backend,
+// with Config.StorageDSN as the file path:
+//
+//	import _ "github.com/mfifth/aisentinel-go-sdk/contrib/sqlitestore"
+//
+//	gov, err := governor.NewGovernor(ctx, governor.Config{
+//		StorageBackend: "sqlite",
+//		StorageDSN:     "/var/lib/app/audit.sqlite",
+//	})
+//
+// Records live in the records table. Besides the key and the JSON value,
+// audit records fill the indexed rulepack_id, timestamp and allowed columns,
+// so decisions can be queried with SQL:
+//
+//	SELECT rulepack_id, count(*) FROM records
+//	WHERE allowed = 0 AND timestamp >= '2026-10-01'
+//	GROUP BY rulepack_id;
+//
+// timestamp is UTC text with nanoseconds, which SQLite date functions
+// accept. The columns are NULL for records that are not audit records.
+// The driver is pure Go, so the module builds without cgo.
+package sqlitestore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/mfifth/aisentinel-go-sdk/storage"
+	_ "modernc.org/sqlite"
+)
+
+func init() {
+	storage.Register(storage.BackendSQLite, func(dsn string) (storage.Store, error) {
+		return Open(dsn)
+	})
+}
+
+// iterBatch is how many records Iter reads per query. fn runs between
+// queries, so it may write to the store.
+const iterBatch = 256
+
+// timeLayout formats the timestamp column. Its fixed width keeps text order
+// and time order the same.
+const timeLayout = "2006-01-02T15:04:05.000000000Z"
+
+// schemaVersion is stored as the database's user_version.
+const schemaVersion = 1
+
+const schema = `
+CREATE TABLE IF NOT EXISTS records (
+	key         TEXT PRIMARY KEY,
+	value       BLOB NOT NULL,
+	rulepack_id TEXT,
+	timestamp   TEXT,
+	allowed     INTEGER
+);
+CREATE INDEX IF NOT EXISTS records_rulepack_id ON records (rulepack_id, timestamp);
+CREATE INDEX IF NOT EXISTS records_timestamp ON records (timestamp);
+CREATE INDEX IF NOT EXISTS records_allowed ON records (allowed, timestamp);
+`
+
+// Store is a storage.Store backed by a SQLite database file in WAL mode.
+// Every Put and Delete is a committed transaction. It is safe for
+// concurrent use, and other processes may read the file while it is open.
+type Store struct {
+	db *sql.DB
+}
+
+var (
+	_ storage.Store         = (*Store)(nil)
+	_ storage.RangeIterator = (*Store)(nil)
+	_ storage.BatchWriter   = (*Store)(nil)
+)
+
+// Open opens or creates the database at path and creates its schema.
+func Open(path string) (*Store, error) {
+	// Writers wait up to five seconds for each other instead of failing
+	// with SQLITE_BUSY.
+	dsn := "file:" + (&url.URL{Path: path}).EscapedPath() +
+		"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_txlock=immediate"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("sqlitestore: open %s: %w", path, err)
+	}
+	if err := migrate(db); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("sqlitestore: open %s: %w", path, err)
+	}
+	return &Store{db: db}, nil
+}
+
+// migrate creates the schema, refusing databases written by a newer
+// version of this package.
+func migrate(db *sql.DB) error {
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	}
+	if version > schemaVersion {
+		return fmt.Errorf("schema version %d is newer than %d", version, schemaVersion)
+	}
+	if _, err := db.Exec(schema); err != nil {
+		return err
+	}
+	_, err := db.Exec(fmt.Sprintf("PRAGMA user_version = %d", schemaVersion))
+	return err
+}
+
+// auditColumns are the indexed columns of an audit record. Fields missing
+// from the value, or values that are not JSON objects, leave them NULL.
+type auditColumns struct {
+	RulepackID *string    `json:"rulepack_id"`
+	Time       *time.Time `json:"time"`
+	Allowed    *bool      `json:"allowed"`
+}
+
+func columns(value []byte) (rulepackID, timestamp, allowed any) {
+	var c auditColumns
+	if json.Unmarshal(value, &c) != nil {
+		return nil, nil, nil
+	}
+	if c.RulepackID != nil {
+		rulepackID = *c.RulepackID
+	}
+	if c.Time != nil {
+		timestamp = c.Time.UTC().Format(timeLayout)
+	}
+	if c.Allowed != nil {
+		allowed = *c.Allowed
+	}
+	return rulepackID, timestamp, allowed
+}
+
+const upsert = `
+INSERT INTO records (key, value, rulepack_id, timestamp, allowed) VALUES (?, ?, ?, ?, ?)
+ON CONFLICT (key) DO UPDATE SET value = excluded.value, rulepack_id = excluded.rulepack_id,
+	timestamp = excluded.timestamp, allowed = excluded.allowed`
+
+// args returns the upsert arguments for record.
+func args(record storage.Record) []any {
+	rulepackID, timestamp, allowed := columns(record.Value)
+	value := record.Value
+	if value == nil {
+		value = []byte{}
+	}
+	return []any{record.Key, value, rulepackID, timestamp, allowed}
+}
+
+// Put stores a record.
+func (s *Store) Put(ctx context.Context, record storage.Record) error {
+	_, err := s.db.ExecContext(ctx, upsert, args(record)...)
+	return err
+}
+
+// PutBatch stores records in one transaction, so they are written together
+// or not at all.
+func (s *Store) PutBatch(ctx context.Context, records []storage.Record) error {
+	return s.inTx(ctx, upsert, func(stmt *sql.Stmt) error {
+		for _, record := range records {
+			if _, err := stmt.ExecContext(ctx, args(record)...); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// inTx runs fn with query prepared in a transaction, committing it when fn
+// succeeds.
+func (s *Store) inTx(ctx context.Context, query string, fn func(*sql.Stmt) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	if err := fn(stmt); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Get retrieves a record by key, returning storage.ErrNotFound() when there
+// is none.
+func (s *Store) Get(ctx context.Context, key string) (storage.Record, error) {
+	var value []byte
+	err := s.db.QueryRowContext(ctx, "SELECT value FROM records WHERE key = ?", key).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.Record{}, storage.ErrNotFound()
+	}
+	if err != nil {
+		return storage.Record{}, err
+	}
+	return storage.Record{Key: key, Value: value}, nil
+}
+
+// Iter iterates over all records in key order. Records are read in batches,
+// each with its own query, so fn sees records written by earlier calls.
+func (s *Store) Iter(ctx context.Context, fn func(storage.Record) error) error {
+	return s.IterRange(ctx, "", "", fn)
+}
+
+// IterRange iterates in key order over the records with keys from start,
+// inclusive, to end, exclusive; an empty end is unbounded. Records are read
+// in batches, as by Iter.
+func (s *Store) IterRange(ctx context.Context, start, end string, fn func(storage.Record) error) error {
+	from := start
+	for first := true; ; first = false {
+		batch, err := s.batch(ctx, from, !first, end)
+		if err != nil {
+			return err
+		}
+		for _, record := range batch {
+			if err := fn(record); err != nil {
+				return err
+			}
+		}
+		if len(batch) < iterBatch {
+			return nil
+		}
+		from = batch[len(batch)-1].Key
+	}
+}
+
+// batch reads up to iterBatch records with keys from from, or after it,
+// up to end.
+func (s *Store) batch(ctx context.Context, from string, after bool, end string) ([]storage.Record, error) {
+	query, args := "SELECT key, value FROM records WHERE key >= ?", []any{from}
+	if after {
+		query = "SELECT key, value FROM records WHERE key > ?"
+	}
+	if end != "" {
+		query += " AND key < ?"
+		args = append(args, end)
+	}
+	rows, err := s.db.QueryContext(ctx, query+" ORDER BY key LIMIT ?", append(args, iterBatch)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	batch := make([]storage.Record, 0, iterBatch)
+	for rows.Next() {
+		var record storage.Record
+		if err := rows.Scan(&record.Key, &record.Value); err != nil {
+			return nil, err
+		}
+		batch = append(batch, record)
+	}
+	return batch, rows.Err()
+}
+
+// Delete removes a record by key.
+func (s *Store) Delete(ctx context.Context, key string) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM records WHERE key = ?", key)
+	return err
+}
+
+// DeleteBatch removes records by key in one transaction.
+func (s *Store) DeleteBatch(ctx context.Context, keys []string) error {
+	return s.inTx(ctx, "DELETE FROM records WHERE key = ?", func(stmt *sql.Stmt) error {
+		for _, key := range keys {
+			if _, err := stmt.ExecContext(ctx, key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DB returns the underlying database for SQL queries over the records
+// table. Writing to it bypasses the indexed columns' upkeep.
+func (s *Store) DB() *sql.DB {
+	return s.db
+}
+
+// Close closes the database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
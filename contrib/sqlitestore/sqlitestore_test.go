@@ -0,0 +1,99 @@
+package sqlitestore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	governor "github.com/mfifth/aisentinel-go-sdk"
+	"github.com/mfifth/aisentinel-go-sdk/storage"
+	"github.com/mfifth/aisentinel-go-sdk/storage/storagetest"
+)
+
+func TestStore(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Store {
+		store, err := Open(filepath.Join(t.TempDir(), "audit.sqlite"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return store
+	})
+}
+
+func TestReopen(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "audit.sqlite")
+	store, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < iterBatch+10; i++ {
+		if err := store.Put(ctx, storage.Record{Key: fmt.Sprintf("k%04d", i), Value: []byte(fmt.Sprint(i))}); err != nil {
+			t.Fatalf("put: %v", err)
+		}
+	}
+	if err := store.Put(ctx, storage.Record{Key: ""}); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	store, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	var keys []string
+	err = store.Iter(ctx, func(r storage.Record) error {
+		keys = append(keys, r.Key)
+		// Writing while iterating must not deadlock. The keys sort before
+		// the cursor, so they are not iterated.
+		return store.Put(ctx, storage.Record{Key: "a-" + r.Key})
+	})
+	if err != nil {
+		t.Fatalf("iter: %v", err)
+	}
+	if len(keys) != iterBatch+11 || keys[0] != "" || keys[1] != "k0000" || keys[2] != "k0001" {
+		t.Fatalf("expected the records to persist in key order, got %d keys starting %q", len(keys), keys[:3])
+	}
+}
+
+func TestRegisteredBackend(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "audit.sqlite")
+	pack := &governor.Rulepack{ID: "prompts", Rules: []governor.RuleDefinition{{ID: "prompt", Pattern: "ok", Allow: true}}}
+	cfg := governor.Config{APIKey: "test", OfflineMode: true, StorageBackend: "sqlite", StorageDSN: path}
+	for _, w := range cfg.Warnings() {
+		if w.Field == "StorageBackend" {
+			t.Fatalf("expected no in-memory warning once registered, got %q", w.Message)
+		}
+	}
+	gov, err := governor.NewGovernor(ctx, cfg, governor.WithRulepacks(pack))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, prompt := range []string{"ok", "ok", "nope"} {
+		if _, err := gov.Evaluate(ctx, governor.DecisionRequest{RulepackID: "prompts", Payload: json.RawMessage(`{"prompt":"` + prompt + `"}`)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := gov.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	var allowed, denied int
+	err = store.DB().QueryRowContext(ctx, `
+SELECT count(*) FILTER (WHERE allowed), count(*) FILTER (WHERE NOT allowed) FROM records
+WHERE rulepack_id = 'prompts' AND timestamp >= '2000-01-01' AND datetime(timestamp) IS NOT NULL`).Scan(&allowed, &denied)
+	if err != nil || allowed != 2 || denied != 1 {
+		t.Fatalf("expected the decisions queryable by column, got %d allowed %d denied %v", allowed, denied, err)
+	}
+}
@@ -0,0 +1,177 @@
+// Package temporal guards Temporal activities with the Governor. The worker
+// interceptor returned by NewInterceptor evaluates activity inputs, and
+// optionally results, so agents orchestrated as workflows are governed
+// without changing activity code:
+//
+//	w := worker.New(c, "agents", worker.Options{
+//		Interceptors: []interceptor.WorkerInterceptor{
+//			aisentineltemporal.NewInterceptor(gov, aisentineltemporal.Options{RulepackID: "agents"}),
+//		},
+//	})
+//
+// Denials fail the activity without retries; workflows detect them with
+// IsDenied.
+package temporal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	governor "github.com/mfifth/aisentinel-go-sdk"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/interceptor"
+	temporallib "go.temporal.io/sdk/temporal"
+)
+
+// ErrorType is the application error type of activity failures caused by a
+// denial. Workflows can match it with IsDenied or temporal.ApplicationError's
+// Type.
+const ErrorType = "AISentinelDenied"
+
+// Denial is the detail attached to a denial failure, retrievable with
+// temporal.ApplicationError's Details.
+type Denial struct {
+	// Phase is "input" or "result".
+	Phase  string `json:"phase"`
+	Reason string `json:"reason"`
+	RuleID string `json:"rule_id,omitempty"`
+}
+
+// Options configures NewInterceptor. The zero value evaluates the inputs of
+// every activity against the Governor's default rulepack and fails closed.
+type Options struct {
+	// RulepackID is the rulepack inputs are evaluated against; empty selects
+	// Config.DefaultRulepackID.
+	RulepackID string
+	// EvaluateResults also evaluates what activities return, against
+	// ResultRulepackID, which defaults to RulepackID.
+	EvaluateResults  bool
+	ResultRulepackID string
+	// Activities limits evaluation to the named activity types. Empty
+	// evaluates every activity.
+	Activities []string
+	// FailOpen runs the activity when the Governor cannot decide. Otherwise
+	// the attempt fails with a retryable error.
+	FailOpen bool
+}
+
+// NewInterceptor returns a worker interceptor evaluating activities with gov.
+// Register it with worker.Options.Interceptors. Inputs are evaluated before
+// the activity runs and, with Options.EvaluateResults, results after it
+// returns; a denial fails the activity with a non-retryable application
+// error of type ErrorType carrying a Denial.
+//
+// Payloads hold "operation" ("activity" or "activity_result"), "activity"
+// and "workflow" (the type names), and, for each argument or the result, its
+// top-level fields when it is a JSON object or "args.<index>" (or "result")
+// when it is a string, so prompt rules apply unchanged. Workflow code is not
+// evaluated, as calling the Governor from it would break determinism.
+func NewInterceptor(gov *governor.Governor, opts Options) interceptor.WorkerInterceptor {
+	if opts.ResultRulepackID == "" {
+		opts.ResultRulepackID = opts.RulepackID
+	}
+	return &workerInterceptor{gov: gov, opts: opts}
+}
+
+// IsDenied reports whether err is, or wraps, an activity failure caused by a
+// denial. It works on both the activity and the workflow side.
+func IsDenied(err error) bool {
+	var appErr *temporallib.ApplicationError
+	return errors.As(err, &appErr) && appErr.Type() == ErrorType
+}
+
+type workerInterceptor struct {
+	interceptor.WorkerInterceptorBase
+	gov  *governor.Governor
+	opts Options
+}
+
+func (w *workerInterceptor) InterceptActivity(_ context.Context, next interceptor.ActivityInboundInterceptor) interceptor.ActivityInboundInterceptor {
+	return &activityInterceptor{ActivityInboundInterceptorBase: interceptor.ActivityInboundInterceptorBase{Next: next}, parent: w}
+}
+
+type activityInterceptor struct {
+	interceptor.ActivityInboundInterceptorBase
+	parent *workerInterceptor
+}
+
+func (a *activityInterceptor) ExecuteActivity(ctx context.Context, in *interceptor.ExecuteActivityInput) (interface{}, error) {
+	info := activity.GetInfo(ctx)
+	opts := a.parent.opts
+	if !governs(opts.Activities, info.ActivityType.Name) {
+		return a.Next.ExecuteActivity(ctx, in)
+	}
+	fields := map[string]any{"operation": "activity", "activity": info.ActivityType.Name, "workflow": info.WorkflowType.Name}
+	for i, arg := range in.Args {
+		addValue(fields, "args."+strconv.Itoa(i), arg)
+	}
+	if err := a.evaluate(ctx, "input", opts.RulepackID, fields); err != nil {
+		return nil, err
+	}
+
+	result, err := a.Next.ExecuteActivity(ctx, in)
+	if err != nil || !opts.EvaluateResults {
+		return result, err
+	}
+	fields = map[string]any{"operation": "activity_result", "activity": info.ActivityType.Name, "workflow": info.WorkflowType.Name}
+	addValue(fields, "result", result)
+	if err := a.evaluate(ctx, "result", opts.ResultRulepackID, fields); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (a *activityInterceptor) evaluate(ctx context.Context, phase, rulepack string, fields map[string]any) error {
+	payload, err := json.Marshal(fields)
+	if err != nil {
+		return fmt.Errorf("temporal: build payload: %w", err)
+	}
+	result, err := a.parent.gov.Evaluate(ctx, governor.DecisionRequest{RulepackID: rulepack, Payload: payload})
+	switch {
+	case err != nil && a.parent.opts.FailOpen:
+		return nil
+	case err != nil:
+		return fmt.Errorf("temporal: policy evaluation: %w", err)
+	case result.Allowed:
+		return nil
+	}
+	msg := fmt.Sprintf("activity %s denied by policy: %s", phase, result.Reason)
+	return temporallib.NewNonRetryableApplicationError(msg, ErrorType, nil, Denial{Phase: phase, Reason: result.Reason, RuleID: result.RuleID})
+}
+
+// addValue merges v into fields: the top-level fields of a JSON object, or
+// the string itself under name. Fields already set are kept.
+func addValue(fields map[string]any, name string, v any) {
+	if s, ok := v.(string); ok {
+		fields[name] = s
+		return
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	var object map[string]any
+	if json.Unmarshal(raw, &object) != nil {
+		return
+	}
+	for k, value := range object {
+		if _, ok := fields[k]; !ok {
+			fields[k] = value
+		}
+	}
+}
+
+func governs(activities []string, name string) bool {
+	if len(activities) == 0 {
+		return true
+	}
+	for _, a := range activities {
+		if a == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package temporal
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	governor "github.com/mfifth/aisentinel-go-sdk"
+	"github.com/mfifth/aisentinel-go-sdk/governortest"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/interceptor"
+	temporallib "go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/worker"
+	"go.temporal.io/sdk/workflow"
+)
+
+type summarizeInput struct {
+	Prompt string `json:"prompt"`
+}
+
+func newGovernor(t *testing.T) *governor.Governor {
+	t.Helper()
+	pack := governor.Rulepack{ID: "agents", Rules: []governor.RuleDefinition{
+		{ID: "prompt", Pattern: "@", Allow: false},
+		{ID: "result", Pattern: "secret", Allow: false},
+		{ID: "operation", Pattern: ".", Allow: true},
+	}}
+	return governortest.NewControlPlane(t, pack).NewGovernor(t)
+}
+
+// run executes a workflow calling Summarize once, with retries, and reports
+// how often the activity ran and the workflow error.
+func run(t *testing.T, opts Options, prompt, reply string) (int32, error) {
+	t.Helper()
+	var calls atomic.Int32
+	summarize := func(_ context.Context, in summarizeInput) (string, error) {
+		calls.Add(1)
+		return reply, nil
+	}
+	agent := func(ctx workflow.Context, prompt string) (string, error) {
+		ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+			StartToCloseTimeout: time.Minute,
+			RetryPolicy:         &temporallib.RetryPolicy{MaximumAttempts: 3},
+		})
+		var out string
+		err := workflow.ExecuteActivity(ctx, "Summarize", summarizeInput{Prompt: prompt}).Get(ctx, &out)
+		if IsDenied(err) {
+			return "", err
+		}
+		return out, err
+	}
+
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.SetWorkerOptions(worker.Options{Interceptors: []interceptor.WorkerInterceptor{NewInterceptor(newGovernor(t), opts)}})
+	env.RegisterWorkflow(agent)
+	env.RegisterActivityWithOptions(summarize, activity.RegisterOptions{Name: "Summarize"})
+	env.ExecuteWorkflow(agent, prompt)
+	if !env.IsWorkflowCompleted() {
+		t.Fatal("expected the workflow to complete")
+	}
+	return calls.Load(), env.GetWorkflowError()
+}
+
+func TestInterceptorInputs(t *testing.T) {
+	opts := Options{RulepackID: "agents"}
+	if calls, err := run(t, opts, "summarize the ticket", "done"); err != nil || calls != 1 {
+		t.Fatalf("expected the activity to run once, got %v after %d calls", err, calls)
+	}
+
+	calls, err := run(t, opts, "mail jane@example.com", "done")
+	if !IsDenied(err) || calls != 0 {
+		t.Fatalf("expected a denial before the activity ran, got %v after %d calls", err, calls)
+	}
+	var appErr *temporallib.ApplicationError
+	var denial Denial
+	if !errors.As(err, &appErr) || !appErr.NonRetryable() || appErr.Details(&denial) != nil || denial.Phase != "input" || denial.RuleID != "prompt" {
+		t.Fatalf("expected a non-retryable failure with the denial, got %v %+v", err, denial)
+	}
+
+	if calls, err := run(t, Options{RulepackID: "agents", Activities: []string{"Other"}}, "mail jane@example.com", "done"); err != nil || calls != 1 {
+		t.Fatalf("expected unlisted activities to run unevaluated, got %v after %d calls", err, calls)
+	}
+}
+
+func TestInterceptorResults(t *testing.T) {
+	if _, err := run(t, Options{RulepackID: "agents"}, "summarize", "the secret"); err != nil {
+		t.Fatalf("expected results to pass without EvaluateResults, got %v", err)
+	}
+	calls, err := run(t, Options{RulepackID: "agents", EvaluateResults: true}, "summarize", "the secret")
+	var appErr *temporallib.ApplicationError
+	var denial Denial
+	if !errors.As(err, &appErr) || appErr.Details(&denial) != nil || denial.Phase != "result" || calls != 1 {
+		t.Fatalf("expected the result to be denied without retries, got %v %+v after %d calls", err, denial, calls)
+	}
+}
+
+func TestInterceptorEvaluationFailure(t *testing.T) {
+	calls, err := run(t, Options{RulepackID: "missing"}, "summarize", "done")
+	if err == nil || IsDenied(err) || calls != 0 {
+		t.Fatalf("expected a retryable evaluation failure, got %v after %d calls", err, calls)
+	}
+	if calls, err := run(t, Options{RulepackID: "missing", FailOpen: true}, "summarize", "done"); err != nil || calls != 1 {
+		t.Fatalf("expected FailOpen to run the activity, got %v after %d calls", err, calls)
+	}
+}
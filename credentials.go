@@ -0,0 +1,97 @@
+package governor
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mfifth/aisentinel-go-sdk/secrets"
+)
+
+// Credential events emitted by the API key refresher.
+const (
+	EventCredentialRotated       EventType = "credential_rotated"
+	EventCredentialRefreshFailed EventType = "credential_refresh_failed"
+)
+
+// SecretSource fetches a secret, such as the API key, from an external store.
+// Implementations for Vault, AWS Secrets Manager and GCP Secret Manager live
+// in the secrets package.
+type SecretSource interface {
+	FetchSecret(ctx context.Context) (string, error)
+}
+
+// SecretSourceFunc adapts a function into a SecretSource.
+type SecretSourceFunc func(ctx context.Context) (string, error)
+
+// FetchSecret calls f.
+func (f SecretSourceFunc) FetchSecret(ctx context.Context) (string, error) { return f(ctx) }
+
+// apiKeyFileRefresh is how often Config.APIKeyFile is re-read.
+const apiKeyFileRefresh = 10 * time.Second
+
+// WithAPIKeySource resolves the API key from source during NewGovernor and,
+// when refresh is positive, re-fetches it on that interval so rotated keys
+// are picked up without a restart. The source takes precedence over
+// Config.APIKey and AISENTINEL_API_KEY.
+func WithAPIKeySource(source SecretSource, refresh time.Duration) Option {
+	return func(g *Governor) error {
+		if source == nil {
+			return fmt.Errorf("secret source cannot be nil")
+		}
+		g.keySource = source
+		g.keyRefresh = refresh
+		return nil
+	}
+}
+
+// apiKey returns the current API key; it may change when a SecretSource
+// rotates it.
+func (g *Governor) apiKey() string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.cfg.APIKey
+}
+
+// resolveAPIKey fetches the initial key from the configured source. Without
+// an explicit source, Config.APIKeyFile is read and then watched for changes.
+func (g *Governor) resolveAPIKey(ctx context.Context) error {
+	if g.keySource == nil && g.cfg.APIKeyFile != "" {
+		g.keySource = secrets.File{Path: g.cfg.APIKeyFile}
+		g.keyRefresh = apiKeyFileRefresh
+	}
+	if g.keySource == nil {
+		return nil
+	}
+	key, err := g.keySource.FetchSecret(ctx)
+	if err != nil {
+		return fmt.Errorf("fetch api key: %w", err)
+	}
+	g.cfg.APIKey = key
+	return nil
+}
+
+// refreshAPIKey re-fetches the API key every keyRefresh until ctx is done.
+func (g *Governor) refreshAPIKey(ctx context.Context, source SecretSource, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			key, err := source.FetchSecret(ctx)
+			if err != nil {
+				g.events.emit(Event{Type: EventCredentialRefreshFailed, Level: LevelError, Message: "api key refresh failed", Err: err})
+				continue
+			}
+			if key == "" || key == g.apiKey() {
+				continue
+			}
+			g.mu.Lock()
+			g.cfg.APIKey = key
+			g.mu.Unlock()
+			g.events.emit(Event{Type: EventCredentialRotated, Level: LevelInfo, Message: "api key rotated"})
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package governor
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+)
+
+// decisionCacheSize bounds the decision cache. Once it is full of unexpired
+// entries, new decisions are evaluated but not memoized.
+const decisionCacheSize = 10000
+
+// decisionKey identifies a decision by the rulepack revision, the digest of
+// the rules compiled for it, its strategy and default decision, the payload,
+// the request metadata, Subject and Model, and the configured default
+// decision, which can be reloaded. A rulepack recompiled with other rules
+// under the same version therefore misses. Strings are length prefixed so
+// distinct requests never hash alike.
+func decisionKey(pack *Rulepack, rulesDigest string, req DecisionRequest, defaultDecision DefaultDecision) string {
+	h := sha256.New()
+	var buf [8]byte
+	for _, s := range [...]string{pack.ID, pack.Version, rulesDigest, string(pack.Strategy), string(pack.DefaultDecision), req.Subject, req.Model, string(defaultDecision)} {
+		binary.BigEndian.PutUint64(buf[:], uint64(len(s)))
+		h.Write(buf[:])
+		h.Write([]byte(s))
+	}
+	binary.BigEndian.PutUint64(buf[:], uint64(pack.UpdatedAt.UnixNano()))
+	h.Write(buf[:])
+	h.Write(req.Payload)
+	var sum [sha256.Size]byte
+	return string(h.Sum(sum[:0]))
+}
+
+// memoize stores v under key unless the decision cache is full.
+func (g *Governor) memoize(key string, v verdict) {
+	if g.decisions.Len() >= decisionCacheSize {
+		g.decisions.purgeExpired()
+		if g.decisions.Len() >= decisionCacheSize {
+			return
+		}
+	}
+	g.decisions.Set(key, v)
+}
@@ -0,0 +1,52 @@
+package governor
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	"github.com/mfifth/aisentinel-go-sdk/storage"
+)
+
+// WithStorageEncryption encrypts audit and usage records at rest with keys
+// from keys, wrapping the configured store in a storage.EncryptedStore. It
+// takes precedence over Config.StorageEncryptionKey.
+func WithStorageEncryption(keys storage.KeyProvider) Option {
+	return func(g *Governor) error {
+		if keys == nil {
+			return fmt.Errorf("key provider cannot be nil")
+		}
+		g.storageKeys = keys
+		return nil
+	}
+}
+
+// parseStorageEncryptionKey decodes Config.StorageEncryptionKey, a base64
+// AES key.
+func parseStorageEncryptionKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("StorageEncryptionKey must be base64: %w", err)
+	}
+	switch len(key) {
+	case 16, 24, 32:
+		return key, nil
+	}
+	return nil, fmt.Errorf("StorageEncryptionKey must be a 16, 24 or 32 byte key, got %d bytes", len(key))
+}
+
+// encryptStorage wraps the store in a storage.EncryptedStore when
+// encryption is configured.
+func (g *Governor) encryptStorage() error {
+	keys := g.storageKeys
+	if keys == nil && g.cfg.StorageEncryptionKey != "" {
+		key, err := parseStorageEncryptionKey(g.cfg.StorageEncryptionKey)
+		if err != nil {
+			return err
+		}
+		keys = storage.StaticKey(key)
+	}
+	if keys != nil {
+		g.storage = storage.NewEncryptedStore(g.storage, keys)
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+// Package evaltest runs table-driven policy tests from go test. A Suite
+// loads a rulepack file and checks payloads against expected decisions,
+// reporting mismatches as a diff of the decision followed by the rule trace
+// that produced it:
+//
+//	func TestPromptPolicy(t *testing.T) {
+//		suite := evaltest.Load(t, "testdata/prompts.yaml")
+//		suite.Run(t, []evaltest.Case{
+//			{Name: "email", Payload: map[string]any{"prompt": "mail jane@example.com"}, Allowed: false, RuleID: "pii"},
+//			{Name: "question", Payload: map[string]any{"prompt": "what is a mutex?"}, Allowed: true},
+//		})
+//	}
+package evaltest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	governor "github.com/mfifth/aisentinel-go-sdk"
+)
+
+// Case is a payload and the decision it should produce.
+type Case struct {
+	Name    string
+	Payload map[string]any
+	Allowed bool
+	// RuleID, Reason and Code are only checked when set. Use RuleID "-" to
+	// expect the default decision, which has no rule.
+	RuleID string
+	Reason string
+	Code   governor.ReasonCode
+	// Obligations lists the IDs of the rules expected to attach
+	// obligations, in order. It is only checked when non-nil, so an empty
+	// slice expects none.
+	Obligations []string
+}
+
+// Suite evaluates cases against one rulepack, offline.
+type Suite struct {
+	pack      *governor.Rulepack
+	evaluator *governor.Evaluator
+	path      string
+}
+
+// Load reads and validates the rulepack file at path, failing the test on
+// error.
+func Load(t testing.TB, path string) *Suite {
+	t.Helper()
+	pack, err := governor.LoadRulepackFile(path)
+	if err != nil {
+		t.Fatalf("evaltest: %v", err)
+	}
+	s := New(t, pack)
+	s.path = path
+	return s
+}
+
+// New returns a Suite for pack, failing the test when it is invalid.
+func New(t testing.TB, pack *governor.Rulepack) *Suite {
+	t.Helper()
+	if err := pack.Validate(); err != nil {
+		t.Fatalf("evaltest: rulepack %s: %v", pack.ID, err)
+	}
+	evaluator := governor.NewEvaluator()
+	evaluator.SetClock(func() time.Time { return time.Time{} })
+	if err := evaluator.Preload(pack.ID, pack.Rules); err != nil {
+		t.Fatalf("evaltest: rulepack %s: %v", pack.ID, err)
+	}
+	return &Suite{pack: pack, evaluator: evaluator}
+}
+
+// Run checks every case in a subtest named after it.
+func (s *Suite) Run(t *testing.T, cases []Case) {
+	t.Helper()
+	for i, c := range cases {
+		name := c.Name
+		if name == "" {
+			name = fmt.Sprintf("case %d", i+1)
+		}
+		t.Run(name, func(t *testing.T) {
+			diff, err := s.Check(context.Background(), c)
+			if err != nil {
+				t.Fatalf("evaluate: %v", err)
+			}
+			if diff != "" {
+				t.Errorf("decision mismatch for rulepack %s:\n%s", s.pack.ID, diff)
+			}
+		})
+	}
+}
+
+// RunFixtures runs the fixtures embedded in the rulepack file the Suite was
+// loaded from, as the CLI's test command does.
+func (s *Suite) RunFixtures(t *testing.T) {
+	t.Helper()
+	if s.path == "" {
+		t.Fatal("evaltest: RunFixtures needs a Suite created by Load")
+	}
+	fixtures, err := governor.LoadRulepackFixtures(s.path)
+	if err != nil {
+		t.Fatalf("evaltest: %v", err)
+	}
+	cases := make([]Case, len(fixtures))
+	for i, f := range fixtures {
+		x := f.Expect
+		cases[i] = Case{Name: f.Name, Payload: f.Payload, Allowed: *x.Allowed, RuleID: x.RuleID, Reason: x.Reason, Code: x.Code, Obligations: x.Obligations}
+	}
+	s.Run(t, cases)
+}
+
+// Check evaluates c and returns a readable diff between the expected and
+// actual decision, or "" when they agree.
+func (s *Suite) Check(ctx context.Context, c Case) (string, error) {
+	payload, err := json.Marshal(c.Payload)
+	if err != nil {
+		return "", err
+	}
+	got, err := s.evaluator.Explain(ctx, s.pack, payload)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	mismatch := false
+	line := func(field, want, have string, checked bool) {
+		switch {
+		case !checked || want == have:
+			fmt.Fprintf(&b, "  %s: %s\n", field, have)
+		default:
+			mismatch = true
+			fmt.Fprintf(&b, "- %s: %s\n+ %s: %s\n", field, want, field, have)
+		}
+	}
+	b.WriteString("--- want\n+++ got\n")
+	line("allowed", fmt.Sprint(c.Allowed), fmt.Sprint(got.Allowed), true)
+	ruleID, wantRule := orNone(got.RuleID), c.RuleID
+	if wantRule == "-" {
+		wantRule = "(none)"
+	}
+	line("rule_id", wantRule, ruleID, c.RuleID != "")
+	line("reason", fmt.Sprintf("%q", c.Reason), fmt.Sprintf("%q", got.Reason), c.Reason != "")
+	line("code", string(c.Code), string(got.Code), c.Code != "")
+	obligations := make([]string, len(got.Obligations))
+	for i, o := range got.Obligations {
+		obligations[i] = o.RuleID
+	}
+	line("obligations", fmt.Sprint(c.Obligations), fmt.Sprint(obligations), c.Obligations != nil)
+	if !mismatch {
+		return "", nil
+	}
+
+	fmt.Fprintf(&b, "payload: %s\ntrace:\n", payload)
+	for _, r := range got.Rules {
+		effect := string(r.Action)
+		fmt.Fprintf(&b, "  %-5s %s /%s/: %s", effect, r.RuleID, r.Pattern, r.Outcome)
+		if r.Value != "" {
+			fmt.Fprintf(&b, " %q", r.Value)
+		}
+		b.WriteByte('\n')
+	}
+	return b.String(), nil
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return s
+}
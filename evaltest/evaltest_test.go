@@ -0,0 +1,69 @@
+package evaltest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const rulepackYAML = `id: prompts
+version: "3"
+rules:
+  - id: prompt
+    pattern: "@"
+    allow: false
+  - id: model
+    pattern: "."
+    allow: true
+fixtures:
+  - name: email
+    payload: {prompt: "mail jane@example.com", model: gpt}
+    expect: {allowed: false, rule_id: prompt}
+  - name: no fields
+    payload: {}
+    expect: {allowed: false, reason: no matching rule}
+`
+
+func load(t *testing.T) *Suite {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "prompts.yaml")
+	if err := os.WriteFile(path, []byte(rulepackYAML), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return Load(t, path)
+}
+
+func TestSuiteRun(t *testing.T) {
+	suite := load(t)
+	suite.Run(t, []Case{
+		{Name: "email", Payload: map[string]any{"prompt": "mail jane@example.com"}, Allowed: false, RuleID: "prompt"},
+		{Name: "question", Payload: map[string]any{"prompt": "what is a mutex?", "model": "gpt"}, Allowed: true, RuleID: "model"},
+		{Name: "default deny", Payload: map[string]any{"prompt": "hi"}, Allowed: false, RuleID: "-", Code: "default_deny", Obligations: []string{}},
+	})
+	suite.RunFixtures(t)
+}
+
+func TestSuiteCheckDiff(t *testing.T) {
+	suite := load(t)
+	diff, err := suite.Check(context.Background(), Case{Payload: map[string]any{"prompt": "mail jane@example.com", "model": "gpt"}, Allowed: true, RuleID: "model", Code: "default_allow"})
+	if err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	for _, want := range []string{
+		"- allowed: true\n+ allowed: false\n",
+		"- rule_id: model\n+ rule_id: prompt\n",
+		"- code: default_allow\n+ code: rule_matched\n",
+		`  deny  prompt /@/: matched "mail jane@example.com"`,
+		"  allow model /./: skipped",
+	} {
+		if !strings.Contains(diff, want) {
+			t.Errorf("expected the diff to contain %q, got:\n%s", want, diff)
+		}
+	}
+
+	if diff, err := suite.Check(context.Background(), Case{Payload: map[string]any{"model": "gpt"}, Allowed: true}); err != nil || diff != "" {
+		t.Fatalf("expected no diff for a passing case, got %q %v", diff, err)
+	}
+}
@@ -0,0 +1,196 @@
+package evaltest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	governor "github.com/mfifth/aisentinel-go-sdk"
+)
+
+var update = flag.Bool("evaltest.update", false, "rewrite golden decision files instead of comparing against them")
+
+// Explainer produces decisions with their rule trace. *governor.Governor and
+// *Suite implement it; build Governors with governor.WithClock and a fixed
+// clock so latencies and rule durations are reproducible.
+type Explainer interface {
+	Explain(ctx context.Context, req governor.DecisionRequest) (governor.Explanation, error)
+}
+
+// GoldenCase is a request whose decision is recorded under Name.
+type GoldenCase struct {
+	Name    string
+	Request governor.DecisionRequest
+}
+
+// GoldenDecision is one entry of a golden file: a request and the decision,
+// rulepack version and trace it produced.
+type GoldenDecision struct {
+	Name       string               `json:"name"`
+	RulepackID string               `json:"rulepack_id,omitempty"`
+	Payload    json.RawMessage      `json:"payload"`
+	Decision   governor.Explanation `json:"decision"`
+}
+
+// Golden explains every case with ex and compares the decisions with those
+// recorded in the golden file at path, reporting each change as a diff. When
+// the file does not exist, or go test runs with -evaltest.update, the file is
+// written instead.
+func Golden(t testing.TB, ex Explainer, path string, cases []GoldenCase) {
+	t.Helper()
+	got := make([]GoldenDecision, 0, len(cases))
+	for _, c := range cases {
+		d, err := record(ex, c.Name, c.Request.RulepackID, c.Request.Payload)
+		if err != nil {
+			t.Fatalf("evaltest: %s: %v", c.Name, err)
+		}
+		got = append(got, d)
+	}
+
+	want, err := LoadGolden(path)
+	if *update || errors.Is(err, fs.ErrNotExist) {
+		if err := WriteGolden(path, got); err != nil {
+			t.Fatalf("evaltest: %v", err)
+		}
+		t.Logf("evaltest: wrote %d decisions to %s", len(got), path)
+		return
+	}
+	if err != nil {
+		t.Fatalf("evaltest: %v", err)
+	}
+	recorded := make(map[string]GoldenDecision, len(want))
+	for _, d := range want {
+		recorded[d.Name] = d
+	}
+	for _, d := range got {
+		w, ok := recorded[d.Name]
+		if !ok {
+			t.Errorf("evaltest: %s: not in %s; run go test with -evaltest.update to record it", d.Name, path)
+			continue
+		}
+		delete(recorded, d.Name)
+		compare(t, w, d)
+	}
+	for name := range recorded {
+		t.Errorf("evaltest: %s: recorded in %s but no longer tested; run go test with -evaltest.update to remove it", name, path)
+	}
+}
+
+// Replay explains the requests recorded in the golden file at path with ex
+// and fails for every decision that changed, for example after upgrading the
+// SDK.
+func Replay(t testing.TB, ex Explainer, path string) {
+	t.Helper()
+	want, err := LoadGolden(path)
+	if err != nil {
+		t.Fatalf("evaltest: %v", err)
+	}
+	for _, w := range want {
+		got, err := record(ex, w.Name, w.RulepackID, w.Payload)
+		if err != nil {
+			t.Errorf("evaltest: %s: %v", w.Name, err)
+			continue
+		}
+		compare(t, w, got)
+	}
+}
+
+// LoadGolden reads a golden file.
+func LoadGolden(path string) ([]GoldenDecision, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var decisions []GoldenDecision
+	if err := json.Unmarshal(data, &decisions); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return decisions, nil
+}
+
+// WriteGolden writes decisions to the golden file at path, creating its
+// directory.
+func WriteGolden(path string, decisions []GoldenDecision) error {
+	data, err := json.MarshalIndent(decisions, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// Explain implements Explainer for the Suite's rulepack. The evaluator runs
+// on a fixed clock, so its decisions are reproducible.
+func (s *Suite) Explain(ctx context.Context, req governor.DecisionRequest) (governor.Explanation, error) {
+	if req.RulepackID != "" && req.RulepackID != s.pack.ID {
+		return governor.Explanation{}, fmt.Errorf("evaltest: suite evaluates rulepack %s, not %s", s.pack.ID, req.RulepackID)
+	}
+	return s.evaluator.Explain(ctx, s.pack, req.Payload)
+}
+
+func record(ex Explainer, name, rulepack string, payload json.RawMessage) (GoldenDecision, error) {
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, payload); err != nil {
+		return GoldenDecision{}, fmt.Errorf("payload: %w", err)
+	}
+	explanation, err := ex.Explain(context.Background(), governor.DecisionRequest{RulepackID: rulepack, Payload: payload})
+	if err != nil {
+		return GoldenDecision{}, err
+	}
+	return GoldenDecision{Name: name, RulepackID: rulepack, Payload: compact.Bytes(), Decision: explanation}, nil
+}
+
+func compare(t testing.TB, want, got GoldenDecision) {
+	t.Helper()
+	w, _ := json.MarshalIndent(want, "", "  ")
+	g, _ := json.MarshalIndent(got, "", "  ")
+	if !bytes.Equal(w, g) {
+		t.Errorf("evaltest: %s: decision changed:\n%s", want.Name, diffLines(strings.Split(string(w), "\n"), strings.Split(string(g), "\n")))
+	}
+}
+
+// diffLines returns a line diff of want and got, with "-" marking lines only
+// in want, "+" lines only in got and two spaces common lines.
+func diffLines(want, got []string) string {
+	// lcs[i][j] is the length of the longest common subsequence of want[i:]
+	// and got[j:].
+	lcs := make([][]int, len(want)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(got)+1)
+	}
+	for i := len(want) - 1; i >= 0; i-- {
+		for j := len(got) - 1; j >= 0; j-- {
+			if want[i] == got[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	var b strings.Builder
+	i, j := 0, 0
+	for i < len(want) || j < len(got) {
+		switch {
+		case i < len(want) && j < len(got) && want[i] == got[j]:
+			fmt.Fprintf(&b, "  %s\n", want[i])
+			i, j = i+1, j+1
+		case i < len(want) && (j == len(got) || lcs[i+1][j] >= lcs[i][j+1]):
+			fmt.Fprintf(&b, "- %s\n", want[i])
+			i++
+		default:
+			fmt.Fprintf(&b, "+ %s\n", got[j])
+			j++
+		}
+	}
+	return b.String()
+}
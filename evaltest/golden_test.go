@@ -0,0 +1,77 @@
+package evaltest
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	governor "github.com/mfifth/aisentinel-go-sdk"
+)
+
+// errorRecorder captures test failures instead of reporting them.
+type errorRecorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *errorRecorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func goldenCases() []GoldenCase {
+	return []GoldenCase{
+		{Name: "email", Request: governor.DecisionRequest{Payload: []byte(`{"prompt": "mail jane@example.com"}`)}},
+		{Name: "question", Request: governor.DecisionRequest{Payload: []byte(`{"prompt": "what is a mutex?", "model": "gpt"}`)}},
+	}
+}
+
+func TestGolden(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "golden", "prompts.json")
+	suite := load(t)
+	Golden(t, suite, path, goldenCases())
+	decisions, err := LoadGolden(path)
+	if err != nil || len(decisions) != 2 || decisions[0].Decision.RulepackVersion != "3" || decisions[0].Decision.RuleID != "prompt" {
+		t.Fatalf("expected the decisions to be recorded, got %+v %v", decisions, err)
+	}
+	Golden(t, suite, path, goldenCases())
+
+	changed := New(t, &governor.Rulepack{ID: "prompts", Version: "4", Rules: []governor.RuleDefinition{
+		{ID: "prompt", Pattern: "jane", Allow: false},
+		{ID: "model", Pattern: "^claude", Allow: true},
+	}})
+	rec := &errorRecorder{TB: t}
+	Golden(rec, changed, path, append(goldenCases(), GoldenCase{Name: "new", Request: governor.DecisionRequest{Payload: []byte(`{}`)}}))
+	report := strings.Join(rec.errors, "\n")
+	for _, want := range []string{
+		`-     "rulepack_version": "3",`,
+		`+     "rulepack_version": "4",`,
+		`-     "allowed": true,`,
+		"new: not in",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("expected the report to contain %q, got:\n%s", want, report)
+		}
+	}
+}
+
+func TestReplayGovernor(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prompts.json")
+	suite := load(t)
+	Golden(t, suite, path, goldenCases())
+
+	pack, err := governor.LoadRulepackFile(suite.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gov, err := governor.NewGovernor(context.Background(), governor.Config{APIKey: "test", OfflineMode: true, DefaultRulepackID: "prompts"},
+		governor.WithRulepacks(pack),
+		governor.WithClock(func() time.Time { return time.Unix(0, 0) }))
+	if err != nil {
+		t.Fatalf("expected governor: %v", err)
+	}
+	t.Cleanup(func() { _ = gov.Close() })
+	Replay(t, gov, path)
+}
@@ -5,93 +5,593 @@ import (
 	"encoding/json"
 	"fmt"
 	"regexp"
+	"sort"
 	"sync"
+	"time"
 )
 
 // Rule defines a governance rule compiled for high performance evaluation.
 type Rule struct {
 	ID          string
 	Description string
-	Expression  *regexp.Regexp
-	Allow       bool
+	// Expression is the compiled pattern. Rules restored from a compile
+	// cache compile it on first use instead, and leave it nil.
+	Expression *regexp.Regexp
+	Allow      bool
+
+	priority int
+	pattern  string
+	lazy     *lazyExpression
+	// operator and operand replace the pattern when the rule sets Operator.
+	operator Operator
+	operand  any
+	// field is the rule's Field selector and path its parsed form.
+	field string
+	path  []PathStep
+	// cond replaces the pattern or operator of composite rules.
+	cond *condition
+	// action is the rule's effect, and obligation what it attaches to the
+	// decision when it is a warn, redact or transform rule.
+	action     Action
+	obligation *Obligation
+}
+
+// match reports whether value matches the rule's pattern.
+func (r *Rule) match(value []byte) bool {
+	if r.Expression != nil {
+		return r.Expression.Match(value)
+	}
+	return r.lazy.match(value)
+}
+
+// test applies the rule's pattern or operator to the payload value at its
+// path. field is the value tested, when there is one.
+func (r *Rule) test(document map[string]payloadField) (bool, RuleOutcome, payloadField, error) {
+	field, ok := document[r.path[0].Key]
+	if ok && len(r.path) > 1 {
+		var err error
+		if field, ok, err = selectPath(field.raw, r.path[1:]); err != nil {
+			return false, "", field, err
+		}
+	}
+	var matched bool
+	switch {
+	case !ok:
+		return false, OutcomeMissingField, field, nil
+	case r.operator != "":
+		matched = r.compare(field)
+	case !field.isString:
+		return false, OutcomeNotString, field, nil
+	default:
+		matched = r.match(field.value)
+	}
+	if matched {
+		return true, OutcomeMatched, field, nil
+	}
+	return false, OutcomeNoMatch, field, nil
+}
+
+// traceValue renders the value a test was applied to: strings as text and
+// other values as JSON.
+func traceValue(outcome RuleOutcome, field payloadField) string {
+	switch {
+	case outcome != OutcomeMatched && outcome != OutcomeNoMatch:
+		return ""
+	case field.isString:
+		return string(field.value)
+	default:
+		return string(field.raw)
+	}
+}
+
+// compileRule compiles def, whose selector parses to path. With lazy,
+// patterns already known to be valid are compiled on first use instead.
+func compileRule(def RuleDefinition, path []PathStep, lazy bool) (Rule, error) {
+	if err := validateAction(def); err != nil {
+		return Rule{}, err
+	}
+	action := def.Effect()
+	rule := Rule{ID: def.ID, Description: def.Description, Allow: action != ActionDeny, priority: def.Priority, field: def.Field, path: path, action: action, obligation: newObligation(def)}
+	if def.composite() {
+		cond, err := compileConditions(def, lazy)
+		rule.cond = cond
+		return rule, err
+	}
+	return rule, rule.compileMatcher(def.Pattern, def.Operator, def.Value, lazy)
 }
 
 // Evaluator performs rule evaluations with concurrency safety.
 type Evaluator struct {
 	mu    sync.RWMutex
 	rules map[string][]Rule
+	// fields holds the top-level payload fields each rulepack's rules read,
+	// so evaluations decode only those. A field is true when a rule reads
+	// more than its string value; see fieldsRead.
+	fields map[string]map[string]bool
+	// digests holds the definitionsDigest of each rulepack's compiled rules,
+	// so decisions memoized before a recompile are not served after it.
+	digests map[string]string
+	now     func() time.Time
+	// defaultDecision decides for rulepacks without their own.
+	defaultDecision DefaultDecision
+	// cacheDir, when set, persists compile results; see SetCompileCache.
+	cacheDir string
 }
 
 // NewEvaluator creates an evaluator instance.
 func NewEvaluator() *Evaluator {
-	return &Evaluator{rules: make(map[string][]Rule)}
+	return &Evaluator{rules: make(map[string][]Rule), fields: make(map[string]map[string]bool), digests: make(map[string]string), now: time.Now}
+}
+
+// SetClock replaces the clock used to measure latencies and rule durations.
+// A fixed clock makes them zero, so decisions and traces are reproducible.
+func (e *Evaluator) SetClock(now func() time.Time) {
+	e.mu.Lock()
+	e.now = now
+	e.mu.Unlock()
 }
 
-// Preload compiles rules for a specific rulepack.
+// SetDefaultDecision sets the decision for payloads no rule matched, in
+// rulepacks that do not set Rulepack.DefaultDecision. Empty means DefaultDeny.
+func (e *Evaluator) SetDefaultDecision(d DefaultDecision) {
+	e.mu.Lock()
+	e.defaultDecision = d
+	e.mu.Unlock()
+}
+
+// Preload compiles rules for a specific rulepack. With a compile cache, rules
+// compiled before are restored without recompiling their patterns.
 func (e *Evaluator) Preload(rulepackID string, definitions []RuleDefinition) error {
 	e.mu.Lock()
 	defer e.mu.Unlock()
+	fields, paths, err := fieldsRead(definitions)
+	if err != nil {
+		return err
+	}
+	digest := definitionsDigest(definitions)
+	if e.cacheDir != "" {
+		if rules, ok := restoreCompiled(e.cacheDir, digest, definitions); ok {
+			for i := range rules {
+				rules[i].path = paths[i]
+			}
+			sortByPriority(rules)
+			e.rules[rulepackID] = rules
+			e.fields[rulepackID] = fields
+			e.digests[rulepackID] = digest
+			return nil
+		}
+	}
+
 	rules := make([]Rule, 0, len(definitions))
-	for _, def := range definitions {
-		re, err := regexp.Compile(def.Pattern)
+	for i, def := range definitions {
+		rule, err := compileRule(def, paths[i], false)
 		if err != nil {
 			return fmt.Errorf("compile rule %s: %w", def.ID, err)
 		}
-		rules = append(rules, Rule{ID: def.ID, Description: def.Description, Expression: re, Allow: def.Allow})
+		rules = append(rules, rule)
 	}
+	sortByPriority(rules)
 	e.rules[rulepackID] = rules
+	e.fields[rulepackID] = fields
+	e.digests[rulepackID] = digest
+	if e.cacheDir != "" {
+		// The cache only saves time; a failed write is retried next start.
+		_ = storeCompiled(e.cacheDir, digest, definitions)
+	}
 	return nil
 }
 
-// RuleDefinition mirrors rule definitions from rulepacks.
+// SetCompileCache persists compile results under dir, keyed by a digest of
+// the rules, so a restarted process skips validating and compiling rulepacks
+// it has compiled before: restored patterns are compiled on first use, and
+// plain literals never. An empty dir disables the cache.
+func (e *Evaluator) SetCompileCache(dir string) {
+	e.mu.Lock()
+	e.cacheDir = dir
+	e.mu.Unlock()
+}
+
+// rulesDigest returns the definitionsDigest of the rules compiled for the
+// rulepack key, or "" when none are.
+func (e *Evaluator) rulesDigest(key string) string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.digests[key]
+}
+
+// fieldsOf returns the top-level payload fields read by pack's rules.
+func (e *Evaluator) fieldsOf(pack *Rulepack) (map[string]bool, error) {
+	e.mu.RLock()
+	fields, ok := e.fields[pack.key()]
+	e.mu.RUnlock()
+	if ok {
+		return fields, nil
+	}
+	fields, _, err := fieldsRead(pack.Rules)
+	return fields, err
+}
+
+// fieldsRead parses the selector of every rule and returns the top-level
+// fields they read, marking those read through a nested selector or by an
+// operator, which need more than string values.
+func fieldsRead(definitions []RuleDefinition) (map[string]bool, [][]PathStep, error) {
+	fields := make(map[string]bool, len(definitions))
+	paths := make([][]PathStep, len(definitions))
+	for i, def := range definitions {
+		path, err := rulePath(def)
+		if err != nil {
+			return nil, nil, fmt.Errorf("rule %s: %w", def.ID, err)
+		}
+		paths[i] = path
+		for _, leaf := range def.Leaves() {
+			path, err := leafPath(def, leaf)
+			if err != nil {
+				return nil, nil, fmt.Errorf("rule %s: %w", def.ID, err)
+			}
+			fields[path[0].Key] = fields[path[0].Key] || len(path) > 1 || leaf.Operator != ""
+		}
+	}
+	return fields, paths, nil
+}
+
+// RuleDefinition mirrors rule definitions from rulepacks. A rule matches
+// Pattern against the top-level payload string named after its ID, or the
+// value Field selects, such as request.messages[0].content; see
+// ParseFieldPath. A rule with an Operator compares the value with Value
+// instead, and leaves Pattern empty. A composite rule sets All, Any or Not
+// in place of both, and matches when its conditions hold; see Condition.
+// A matching rule allows or denies according to Allow, unless it sets an
+// Action; see Action. Params are passed to the caller with the obligations
+// of warn, redact and transform rules. Rules are evaluated by descending
+// Priority, and rules of equal priority in rulepack order.
 type RuleDefinition struct {
 	ID          string
 	Description string
+	Field       string `json:",omitempty" yaml:",omitempty"`
 	Pattern     string
+	Operator    Operator    `json:",omitempty" yaml:",omitempty"`
+	Value       any         `json:",omitempty" yaml:",omitempty"`
+	All         []Condition `json:",omitempty" yaml:",omitempty"`
+	Any         []Condition `json:",omitempty" yaml:",omitempty"`
+	Not         *Condition  `json:",omitempty" yaml:",omitempty"`
 	Allow       bool
+	Action      Action            `json:",omitempty" yaml:",omitempty"`
+	Params      map[string]string `json:",omitempty" yaml:",omitempty"`
+	Priority    int               `json:",omitempty" yaml:",omitempty"`
+}
+
+// MatchStrategy decides how the rules matching a payload combine into a
+// decision. Under every strategy a payload no rule decided gets the default
+// decision; see Rulepack.DefaultDecision.
+type MatchStrategy string
+
+const (
+	// MatchFirst lets the first matching rule decide. It is the default.
+	MatchFirst MatchStrategy = "first-match"
+	// MatchAll allows only when every rule matches and is an allow rule;
+	// the first rule that does not match, or denies, decides.
+	MatchAll MatchStrategy = "all-match"
+	// MatchDenyOverrides denies when any deny rule matches, and otherwise
+	// lets the first matching allow rule decide.
+	MatchDenyOverrides MatchStrategy = "deny-overrides"
+	// MatchAllowOverrides allows when any allow rule matches, and otherwise
+	// lets the first matching deny rule decide.
+	MatchAllowOverrides MatchStrategy = "allow-overrides"
+)
+
+// Valid reports whether s is a known strategy or empty.
+func (s MatchStrategy) Valid() bool {
+	switch s {
+	case "", MatchFirst, MatchAll, MatchDenyOverrides, MatchAllowOverrides:
+		return true
+	}
+	return false
+}
+
+// sortByPriority orders rules for evaluation.
+func sortByPriority(rules []Rule) {
+	sort.SliceStable(rules, func(i, j int) bool { return rules[i].priority > rules[j].priority })
+}
+
+// RuleTiming records how long a single rule took to evaluate.
+type RuleTiming struct {
+	RuleID   string        `json:"rule_id"`
+	Duration time.Duration `json:"duration"`
 }
 
 // Evaluate evaluates a payload against the provided rulepack.
 func (e *Evaluator) Evaluate(ctx context.Context, pack *Rulepack, payload json.RawMessage) (bool, string, error) {
+	v, err := e.evaluate(ctx, pack, payload, nil, nil)
+	return v.allowed, v.reason, err
+}
+
+// Decide is Evaluate returning a DecisionResult, including the matching rule
+// and latency. Unlike the Governor it neither caches nor audits, which suits
+// evaluating local rulepack files.
+func (e *Evaluator) Decide(ctx context.Context, pack *Rulepack, payload json.RawMessage) (DecisionResult, error) {
+	e.mu.RLock()
+	now := e.now
+	e.mu.RUnlock()
+	start := now()
+	v, err := e.evaluate(ctx, pack, payload, nil, nil)
+	if err != nil {
+		return DecisionResult{}, err
+	}
+	return newDecisionResult(pack, v, now().Sub(start)), nil
+}
+
+// RuleOutcome is what happened to a rule during an explained evaluation.
+type RuleOutcome string
+
+const (
+	// OutcomeMatched marks a rule whose pattern matched. Under MatchFirst
+	// only the deciding rule matches; Explanation.RuleID names it.
+	OutcomeMatched RuleOutcome = "matched"
+	// OutcomeNoMatch means the payload field did not match the pattern.
+	OutcomeNoMatch RuleOutcome = "no_match"
+	// OutcomeMissingField means the payload has no value at the rule's field.
+	OutcomeMissingField RuleOutcome = "missing_field"
+	// OutcomeNotString means the payload field is not a string, which
+	// patterns need.
+	OutcomeNotString RuleOutcome = "not_string"
+	// OutcomeSkipped marks rules after a rule that settled the decision,
+	// which are not evaluated.
+	OutcomeSkipped RuleOutcome = "skipped"
+)
+
+// RuleTrace records one rule of an explained evaluation. Value is the payload
+// field the pattern or operator was applied to, as JSON unless it is a
+// string, and Field its selector when the rule sets one.
+type RuleTrace struct {
+	RuleID      string        `json:"rule_id" yaml:"rule_id"`
+	Description string        `json:"description,omitempty" yaml:"description,omitempty"`
+	Field       string        `json:"field,omitempty" yaml:"field,omitempty"`
+	Pattern     string        `json:"pattern" yaml:"pattern"`
+	Operator    Operator      `json:"operator,omitempty" yaml:"operator,omitempty"`
+	Operand     any           `json:"operand,omitempty" yaml:"operand,omitempty"`
+	Allow       bool          `json:"allow" yaml:"allow"`
+	Action      Action        `json:"action" yaml:"action"`
+	Priority    int           `json:"priority,omitempty" yaml:"priority,omitempty"`
+	Outcome     RuleOutcome   `json:"outcome" yaml:"outcome"`
+	Value       string        `json:"value,omitempty" yaml:"value,omitempty"`
+	Duration    time.Duration `json:"duration" yaml:"duration"`
+	// Conditions traces the leaf conditions of a composite rule.
+	Conditions []ConditionTrace `json:"conditions,omitempty" yaml:"conditions,omitempty"`
+}
+
+func newRuleTrace(rule Rule, outcome RuleOutcome, value string, d time.Duration) RuleTrace {
+	return RuleTrace{
+		RuleID:      rule.ID,
+		Description: rule.Description,
+		Field:       rule.field,
+		Pattern:     rule.pattern,
+		Operator:    rule.operator,
+		Operand:     rule.operand,
+		Allow:       rule.Allow,
+		Action:      rule.action,
+		Priority:    rule.priority,
+		Outcome:     outcome,
+		Value:       value,
+		Duration:    d,
+	}
+}
+
+// Explanation is the rule-by-rule trace of a decision, in evaluation order.
+// RuleID is the deciding rule and empty for the default decision.
+type Explanation struct {
+	RulepackID      string        `json:"rulepack_id" yaml:"rulepack_id"`
+	RulepackVersion string        `json:"rulepack_version,omitempty" yaml:"rulepack_version,omitempty"`
+	Strategy        MatchStrategy `json:"strategy,omitempty" yaml:"strategy,omitempty"`
+	Allowed         bool          `json:"allowed" yaml:"allowed"`
+	Reason          string        `json:"reason" yaml:"reason"`
+	Code            ReasonCode    `json:"code" yaml:"code"`
+	RuleID          string        `json:"rule_id,omitempty" yaml:"rule_id,omitempty"`
+	Obligations     []Obligation  `json:"obligations,omitempty" yaml:"obligations,omitempty"`
+	Rules           []RuleTrace   `json:"rules" yaml:"rules"`
+}
+
+// Explain evaluates payload like Evaluate and records the outcome of every
+// rule in the pack.
+func (e *Evaluator) Explain(ctx context.Context, pack *Rulepack, payload json.RawMessage) (Explanation, error) {
+	trace := []RuleTrace{}
+	v, err := e.evaluate(ctx, pack, payload, nil, &trace)
+	if err != nil {
+		return Explanation{}, err
+	}
+	return newExplanation(pack, v, trace), nil
+}
+
+func newDecisionResult(pack *Rulepack, v verdict, latency time.Duration) DecisionResult {
+	return DecisionResult{
+		Allowed:         v.allowed,
+		Reason:          v.reason,
+		Code:            v.code,
+		RuleID:          v.ruleID,
+		MatchedRuleIDs:  v.matched,
+		RulepackVersion: pack.Version,
+		Obligations:     v.obligations,
+		Latency:         latency,
+	}
+}
+
+func newExplanation(pack *Rulepack, v verdict, trace []RuleTrace) Explanation {
+	return Explanation{RulepackID: pack.ID, RulepackVersion: pack.Version, Strategy: pack.Strategy, Allowed: v.allowed, Reason: v.reason, Code: v.code, RuleID: v.ruleID, Obligations: v.obligations, Rules: trace}
+}
+
+// ReasonCode classifies how a decision was reached, so callers can tell a
+// default decision from a rule's without parsing Reason.
+type ReasonCode string
+
+const (
+	// ReasonRuleMatched means a matching rule decided.
+	ReasonRuleMatched ReasonCode = "rule_matched"
+	// ReasonRuleNotMatched means a rule that did not match denied, under
+	// MatchAll.
+	ReasonRuleNotMatched ReasonCode = "rule_not_matched"
+	// ReasonAllMatched means every rule matched and allowed, under MatchAll.
+	ReasonAllMatched ReasonCode = "all_matched"
+	// ReasonDefaultDeny and ReasonDefaultAllow mean no rule decided and the
+	// default decision applied.
+	ReasonDefaultDeny  ReasonCode = "default_deny"
+	ReasonDefaultAllow ReasonCode = "default_allow"
+	// ReasonFailOpen means the rulepack could not be loaded and
+	// Config.FailurePolicy allowed the request.
+	ReasonFailOpen ReasonCode = "fail_open"
+)
+
+// verdict is the outcome of evaluate. ruleID is empty when no rule matched.
+type verdict struct {
+	allowed     bool
+	reason      string
+	code        ReasonCode
+	ruleID      string
+	obligations []Obligation
+	// matched lists the rules that matched, in evaluation order.
+	matched []string
+}
+
+// oblige attaches obligations to v when it allows; a denied request needs no
+// further action.
+func (v verdict) oblige(obligations []Obligation) verdict {
+	if v.allowed {
+		v.obligations = obligations
+	}
+	return v
+}
+
+// evaluate is Evaluate with optional per-rule timing and tracing. When timings
+// is non-nil the duration of every evaluated rule is appended to it; when
+// trace is non-nil every rule of the pack is, including those skipped after
+// the deciding rule.
+func (e *Evaluator) evaluate(ctx context.Context, pack *Rulepack, payload json.RawMessage, timings *[]RuleTiming, trace *[]RuleTrace) (verdict, error) {
 	e.mu.RLock()
-	rules, ok := e.rules[pack.ID]
+	key := pack.key()
+	rules, ok := e.rules[key]
+	fields := e.fields[key]
+	now, defaultDecision := e.now, e.defaultDecision
 	e.mu.RUnlock()
 	if !ok {
-		if err := e.Preload(pack.ID, pack.Rules); err != nil {
-			return false, "", err
+		if err := e.Preload(key, pack.Rules); err != nil {
+			return verdict{}, err
 		}
 		e.mu.RLock()
-		rules = e.rules[pack.ID]
+		rules, fields = e.rules[key], e.fields[key]
 		e.mu.RUnlock()
 	}
 
-	var document map[string]any
+	document := documentPool.Get().(map[string]payloadField)
+	defer func() {
+		clear(document)
+		documentPool.Put(document)
+	}()
 	if len(payload) > 0 {
-		if err := json.Unmarshal(payload, &document); err != nil {
-			return false, "payload parse error", err
+		if err := extractFields(payload, fields, document); err != nil {
+			return verdict{reason: "payload parse error"}, &PayloadError{Err: err}
 		}
 	}
 
 	// Evaluate rules sequentially; this is intentionally simple while enabling
-	// future optimisation with goroutines.
-	for _, rule := range rules {
+	// future optimisation with goroutines. fallback is the rule that decides
+	// under the overrides strategies when no overriding rule matches.
+	// Obligation rules never decide; deciding counts the rules that can.
+	fallback, deciding := -1, 0
+	var obligations []Obligation
+	var matchedIDs []string
+	for i, rule := range rules {
 		select {
 		case <-ctx.Done():
-			return false, "context cancelled", ctx.Err()
+			return verdict{reason: "context cancelled"}, ctx.Err()
+		default:
+		}
+		var ruleStart time.Time
+		if timings != nil || trace != nil {
+			ruleStart = now()
+		}
+		var (
+			matched    bool
+			outcome    RuleOutcome
+			field      payloadField
+			conditions []ConditionTrace
+			err        error
+		)
+		if rule.cond != nil {
+			var ct *[]ConditionTrace
+			if trace != nil {
+				ct = &conditions
+			}
+			matched, err = rule.cond.eval(document, ct)
+			outcome = OutcomeNoMatch
+			if matched {
+				outcome = OutcomeMatched
+			}
+		} else {
+			matched, outcome, field, err = rule.test(document)
+		}
+		if err != nil {
+			return verdict{reason: "payload parse error"}, &PayloadError{Err: err}
+		}
+		if timings != nil {
+			*timings = append(*timings, RuleTiming{RuleID: rule.ID, Duration: now().Sub(ruleStart)})
+		}
+		if trace != nil {
+			ruleTrace := newRuleTrace(rule, outcome, traceValue(outcome, field), now().Sub(ruleStart))
+			ruleTrace.Conditions = conditions
+			*trace = append(*trace, ruleTrace)
+		}
+		if matched {
+			matchedIDs = append(matchedIDs, rule.ID)
+		}
+		if rule.obligation != nil {
+			if matched {
+				obligations = append(obligations, *rule.obligation)
+			}
+			continue
+		}
+		deciding++
+
+		decided := false
+		switch pack.Strategy {
+		case MatchAll:
+			decided = !matched || !rule.Allow
+		case MatchDenyOverrides:
+			decided = matched && !rule.Allow
+			if matched && rule.Allow && fallback < 0 {
+				fallback = i
+			}
+		case MatchAllowOverrides:
+			decided = matched && rule.Allow
+			if matched && !rule.Allow && fallback < 0 {
+				fallback = i
+			}
 		default:
+			decided = matched
 		}
-		if docValue, ok := document[rule.ID]; ok {
-			if str, ok := docValue.(string); ok {
-				if rule.Expression.MatchString(str) {
-					if rule.Allow {
-						return true, rule.Description, nil
-					}
-					return false, rule.Description, nil
+		if decided {
+			if trace != nil {
+				for _, skipped := range rules[i+1:] {
+					*trace = append(*trace, newRuleTrace(skipped, OutcomeSkipped, "", 0))
 				}
 			}
+			if !matched {
+				return verdict{reason: "rule " + rule.ID + " did not match", code: ReasonRuleNotMatched, ruleID: rule.ID, matched: matchedIDs}, nil
+			}
+			return verdict{allowed: rule.Allow, reason: rule.Description, code: ReasonRuleMatched, ruleID: rule.ID, matched: matchedIDs}.oblige(obligations), nil
 		}
 	}
 
+	if fallback >= 0 {
+		rule := rules[fallback]
+		return verdict{allowed: rule.Allow, reason: rule.Description, code: ReasonRuleMatched, ruleID: rule.ID, matched: matchedIDs}.oblige(obligations), nil
+	}
+	if pack.Strategy == MatchAll && deciding > 0 {
+		return verdict{allowed: true, reason: "all rules matched", code: ReasonAllMatched, matched: matchedIDs}.oblige(obligations), nil
+	}
+	if pack.DefaultDecision != "" {
+		defaultDecision = pack.DefaultDecision
+	}
+	if defaultDecision == DefaultAllow {
+		return verdict{allowed: true, reason: "no matching rule; default allow", code: ReasonDefaultAllow, matched: matchedIDs}.oblige(obligations), nil
+	}
 	// Default deny to match Python SDK semantics.
-	return false, "no matching rule", nil
+	return verdict{reason: "no matching rule", code: ReasonDefaultDeny, matched: matchedIDs}, nil
 }
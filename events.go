@@ -0,0 +1,171 @@
+package governor
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EventLevel describes the severity of an internal event.
+type EventLevel int
+
+const (
+	LevelDebug EventLevel = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// String returns the lower-case level name.
+func (l EventLevel) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	default:
+		return fmt.Sprintf("level(%d)", int(l))
+	}
+}
+
+// ParseEventLevel converts a level name such as "warn" into an EventLevel.
+func ParseEventLevel(v string) (EventLevel, error) {
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "debug":
+		return LevelDebug, nil
+	case "info", "":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	default:
+		return LevelInfo, fmt.Errorf("unknown log level %q", v)
+	}
+}
+
+func (l EventLevel) slogLevel() slog.Level {
+	switch l {
+	case LevelDebug:
+		return slog.LevelDebug
+	case LevelWarn:
+		return slog.LevelWarn
+	case LevelError:
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// EventType identifies the kind of internal event.
+type EventType string
+
+const (
+	EventCacheMiss       EventType = "cache_miss"
+	EventFetchRetry      EventType = "fetch_retry"
+	EventFetchFailure    EventType = "fetch_failure"
+	EventAuditFailure    EventType = "audit_failure"
+	EventQueueDrop       EventType = "queue_drop"
+	EventReplayFailure   EventType = "replay_failure"
+	EventSlowEvaluation  EventType = "slow_evaluation"
+	EventFailOpen        EventType = "fail_open"
+	EventPayloadTooLarge EventType = "payload_too_large"
+)
+
+// Event is a structured notification emitted by the Governor. Fields carries
+// event specific attributes such as attempt counts or queue sizes.
+type Event struct {
+	Type       EventType
+	Level      EventLevel
+	Time       time.Time
+	Message    string
+	RulepackID string
+	Err        error
+	Fields     map[string]any
+}
+
+// EventListener receives every event emitted by the Governor regardless of the
+// configured log level. Listeners are invoked synchronously and must not block.
+type EventListener func(Event)
+
+// eventBus fans events out to the logger and registered listeners.
+type eventBus struct {
+	mu        sync.RWMutex
+	logger    *slog.Logger
+	minLevel  EventLevel
+	listeners []EventListener
+}
+
+func (b *eventBus) setLogger(logger *slog.Logger) {
+	b.mu.Lock()
+	b.logger = logger
+	b.mu.Unlock()
+}
+
+func (b *eventBus) setLevel(level EventLevel) {
+	b.mu.Lock()
+	b.minLevel = level
+	b.mu.Unlock()
+}
+
+func (b *eventBus) subscribe(fn EventListener) {
+	b.mu.Lock()
+	b.listeners = append(b.listeners, fn)
+	b.mu.Unlock()
+}
+
+func (b *eventBus) emit(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	b.mu.RLock()
+	logger, minLevel, listeners := b.logger, b.minLevel, b.listeners
+	b.mu.RUnlock()
+
+	if logger != nil && e.Level >= minLevel {
+		attrs := make([]slog.Attr, 0, len(e.Fields)+3)
+		attrs = append(attrs, slog.String("event", string(e.Type)))
+		if e.RulepackID != "" {
+			attrs = append(attrs, slog.String("rulepack_id", e.RulepackID))
+		}
+		if e.Err != nil {
+			attrs = append(attrs, slog.String("error", e.Err.Error()))
+		}
+		for k, v := range e.Fields {
+			attrs = append(attrs, slog.Any(k, v))
+		}
+		logger.LogAttrs(context.Background(), e.Level.slogLevel(), e.Message, attrs...)
+	}
+	for _, fn := range listeners {
+		fn(e)
+	}
+}
+
+// WithLogger routes Governor events at or above Config.LogLevel to logger.
+func WithLogger(logger *slog.Logger) Option {
+	return func(g *Governor) error {
+		if logger == nil {
+			return fmt.Errorf("logger cannot be nil")
+		}
+		g.events.setLogger(logger)
+		return nil
+	}
+}
+
+// WithEventListener registers a listener for all Governor events.
+func WithEventListener(fn EventListener) Option {
+	return func(g *Governor) error {
+		if fn == nil {
+			return fmt.Errorf("event listener cannot be nil")
+		}
+		g.events.subscribe(fn)
+		return nil
+	}
+}
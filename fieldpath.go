@@ -0,0 +1,148 @@
+package governor
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PathStep is one step of a parsed field selector: an object key, or an
+// array index when Key is empty.
+type PathStep struct {
+	Key   string
+	Index int
+}
+
+// ParseFieldPath parses a rule's field selector. Selectors are dotted paths
+// with array indices, such as request.messages[0].content, optionally in
+// JSONPath form with a leading "$." and bracketed keys like $['x.y'][0]. The
+// first step is always a top-level key.
+func ParseFieldPath(path string) ([]PathStep, error) {
+	rest := path
+	if strings.HasPrefix(rest, "$") {
+		rest = strings.TrimPrefix(strings.TrimPrefix(rest, "$"), ".")
+		if rest == "" || rest[0] == '.' {
+			return nil, fmt.Errorf("field %q: missing key after $", path)
+		}
+	}
+	var steps []PathStep
+	for rest != "" {
+		switch {
+		case strings.HasPrefix(rest, "['") || strings.HasPrefix(rest, `["`):
+			end := strings.Index(rest[2:], rest[1:2]+"]")
+			if end < 0 {
+				return nil, fmt.Errorf("field %q: unterminated quoted key", path)
+			}
+			steps = append(steps, PathStep{Key: rest[2 : 2+end], Index: -1})
+			rest = rest[2+end+2:]
+		case rest[0] == '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("field %q: unterminated index", path)
+			}
+			n, err := strconv.Atoi(rest[1:end])
+			if err != nil || n < 0 || rest[1] == '+' {
+				return nil, fmt.Errorf("field %q: invalid index %q", path, rest[1:end])
+			}
+			if len(steps) == 0 {
+				return nil, fmt.Errorf("field %q: must start with a key", path)
+			}
+			steps = append(steps, PathStep{Index: n})
+			rest = rest[end+1:]
+		default:
+			if len(steps) > 0 {
+				if rest[0] != '.' {
+					return nil, fmt.Errorf("field %q: expected . or [ before %q", path, rest)
+				}
+				rest = rest[1:]
+			}
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("field %q: empty key", path)
+			}
+			steps = append(steps, PathStep{Key: rest[:end], Index: -1})
+			rest = rest[end:]
+		}
+	}
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("field %q: empty selector", path)
+	}
+	return steps, nil
+}
+
+// Selector returns the field selector the rule reads: Field, or the rule ID
+// when Field is empty.
+func (d RuleDefinition) Selector() string {
+	if d.Field != "" {
+		return d.Field
+	}
+	return d.ID
+}
+
+// rulePath parses the selector of def. A rule without Field reads the
+// top-level key named after it, whatever characters the ID holds.
+func rulePath(def RuleDefinition) ([]PathStep, error) {
+	if def.Field == "" {
+		return []PathStep{{Key: def.ID, Index: -1}}, nil
+	}
+	return ParseFieldPath(def.Field)
+}
+
+// selectPath walks path through raw, a JSON value already checked by
+// json.Valid, and returns the value it leads to. Like json.Unmarshal, the
+// last of duplicate keys wins.
+func selectPath(raw []byte, path []PathStep) (payloadField, bool, error) {
+	for _, step := range path {
+		var value []byte
+		i := skipSpace(raw, 0)
+		switch {
+		case step.Index < 0 && raw[i] == '{':
+			i = skipSpace(raw, i+1)
+			for raw[i] != '}' {
+				end := skipString(raw, i)
+				key, err := decodeString(raw[i:end])
+				if err != nil {
+					return payloadField{}, false, err
+				}
+				i = skipSpace(raw, end)
+				i = skipSpace(raw, i+1) // ':'
+				end = skipValue(raw, i)
+				if string(key) == step.Key {
+					value = raw[i:end]
+				}
+				i = skipSpace(raw, end)
+				if raw[i] == ',' {
+					i = skipSpace(raw, i+1)
+				}
+			}
+		case step.Index >= 0 && raw[i] == '[':
+			i = skipSpace(raw, i+1)
+			for n := 0; raw[i] != ']'; n++ {
+				end := skipValue(raw, i)
+				if n == step.Index {
+					value = raw[i:end]
+					break
+				}
+				i = skipSpace(raw, end)
+				if raw[i] == ',' {
+					i = skipSpace(raw, i+1)
+				}
+			}
+		}
+		if value == nil {
+			return payloadField{}, false, nil
+		}
+		raw = value
+	}
+	field := payloadField{raw: raw, isString: raw[0] == '"'}
+	if field.isString {
+		var err error
+		if field.value, err = decodeString(raw); err != nil {
+			return payloadField{}, false, err
+		}
+	}
+	return field, true, nil
+}
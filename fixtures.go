@@ -0,0 +1,129 @@
+package governor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// RulepackFixture is a test case for a rulepack: a payload and the decision
+// it should produce. Fixtures live under a top-level "fixtures" key, either
+// in the rulepack file itself or in a separate file.
+type RulepackFixture struct {
+	Name    string             `json:"name" yaml:"name"`
+	Payload map[string]any     `json:"payload" yaml:"payload"`
+	Expect  FixtureExpectation `json:"expect" yaml:"expect"`
+}
+
+// FixtureExpectation is the expected decision. Allowed is required; the
+// other fields are only checked when set. Obligations lists the IDs of the
+// rules expected to attach obligations, in order; an empty list expects
+// none.
+type FixtureExpectation struct {
+	Allowed     *bool      `json:"allowed" yaml:"allowed"`
+	RuleID      string     `json:"rule_id,omitempty" yaml:"rule_id,omitempty"`
+	Reason      string     `json:"reason,omitempty" yaml:"reason,omitempty"`
+	Code        ReasonCode `json:"code,omitempty" yaml:"code,omitempty"`
+	Obligations []string   `json:"obligations,omitempty" yaml:"obligations,omitempty"`
+}
+
+// FixtureResult is the outcome of running one fixture. Failure explains why
+// a fixture did not pass and is empty when it did.
+type FixtureResult struct {
+	Name     string         `json:"name" yaml:"name"`
+	Passed   bool           `json:"passed" yaml:"passed"`
+	Decision DecisionResult `json:"-" yaml:"-"`
+	Failure  string         `json:"failure,omitempty" yaml:"failure,omitempty"`
+}
+
+// LoadRulepackFixtures reads the fixtures from a JSON or YAML file, which may
+// be a rulepack file with embedded fixtures.
+func LoadRulepackFixtures(path string) ([]RulepackFixture, error) {
+	var file struct {
+		Fixtures []RulepackFixture `json:"fixtures" yaml:"fixtures"`
+	}
+	if err := decodeRulepackFile(path, &file); err != nil {
+		return nil, err
+	}
+	var errs []error
+	for i, f := range file.Fixtures {
+		if f.Name == "" {
+			file.Fixtures[i].Name = fmt.Sprintf("fixture %d", i+1)
+		}
+		if f.Expect.Allowed == nil {
+			errs = append(errs, fmt.Errorf("%s: %s: expect.allowed is required", path, file.Fixtures[i].Name))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return file.Fixtures, nil
+}
+
+// RunFixtures evaluates every fixture against pack and compares the decision
+// with its expectation.
+func (e *Evaluator) RunFixtures(ctx context.Context, pack *Rulepack, fixtures []RulepackFixture) ([]FixtureResult, error) {
+	if err := e.Preload(pack.ID, pack.Rules); err != nil {
+		return nil, err
+	}
+	results := make([]FixtureResult, 0, len(fixtures))
+	for _, f := range fixtures {
+		res := FixtureResult{Name: f.Name}
+		payload, err := json.Marshal(f.Payload)
+		if err == nil {
+			res.Decision, err = e.Decide(ctx, pack, payload)
+		}
+		if err != nil {
+			res.Failure = err.Error()
+		} else {
+			res.Failure = f.Expect.mismatch(res.Decision)
+		}
+		res.Passed = res.Failure == ""
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+func (x FixtureExpectation) mismatch(got DecisionResult) string {
+	var problems []string
+	if x.Allowed != nil && *x.Allowed != got.Allowed {
+		problems = append(problems, fmt.Sprintf("expected %s, got %s", decisionWord(*x.Allowed), decisionWord(got.Allowed)))
+	}
+	if x.RuleID != "" && x.RuleID != got.RuleID {
+		rule := got.RuleID
+		if rule == "" {
+			rule = "no rule"
+		}
+		problems = append(problems, fmt.Sprintf("expected rule %s, got %s", x.RuleID, rule))
+	}
+	if x.Reason != "" && x.Reason != got.Reason {
+		problems = append(problems, fmt.Sprintf("expected reason %q, got %q", x.Reason, got.Reason))
+	}
+	if x.Code != "" && x.Code != got.Code {
+		problems = append(problems, fmt.Sprintf("expected code %s, got %s", x.Code, got.Code))
+	}
+	if x.Obligations != nil {
+		if want, have := strings.Join(x.Obligations, ","), obligationRuleIDs(got.Obligations); want != have {
+			problems = append(problems, fmt.Sprintf("expected obligations [%s], got [%s]", want, have))
+		}
+	}
+	return strings.Join(problems, "; ")
+}
+
+// obligationRuleIDs joins the rule IDs of obligations with commas.
+func obligationRuleIDs(obligations []Obligation) string {
+	ids := make([]string, len(obligations))
+	for i, o := range obligations {
+		ids[i] = o.RuleID
+	}
+	return strings.Join(ids, ",")
+}
+
+func decisionWord(allowed bool) string {
+	if allowed {
+		return "allow"
+	}
+	return "deny"
+}
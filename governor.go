@@ -1,13 +1,18 @@
 package governor
 
 import (
+	"bytes"
 	"context"
+	"crypto"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"net"
+	"io"
 	"net/http"
+	"net/url"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/mfifth/aisentinel-go-sdk/storage"
@@ -16,20 +21,66 @@ import (
 // ErrOffline indicates the Governor is operating in offline mode.
 var ErrOffline = errors.New("governor: offline mode enabled")
 
+// ErrNoRulepackID is returned when a request names no rulepack and
+// Config.DefaultRulepackID is empty.
+var ErrNoRulepackID = errors.New("governor: no rulepack id and no default configured")
+
 // ErrRuleNotFound occurs when the requested rule is not found in the cache.
 var ErrRuleNotFound = errors.New("governor: rule not found")
 
 // DecisionRequest describes an authorization decision request.
 type DecisionRequest struct {
+	// RulepackID names the rulepack, optionally pinned to a version as in
+	// "governance@v12"; see Config.RulepackPins.
 	RulepackID string
 	Payload    json.RawMessage
+
+	// Optional usage metering. When any of these are set the Governor
+	// aggregates them per Subject and Model; see Governor.Usage.
+	Subject          string
+	Model            string
+	PromptTokens     int
+	CompletionTokens int
+	EstimatedCost    float64
+
+	// NoCache bypasses Config.DecisionCacheTTL: the rules are evaluated and
+	// the decision is not memoized.
+	NoCache bool
+
+	// Explain traces every rule into DecisionResult.Explanation and the
+	// audit record. It implies NoCache.
+	Explain bool
+
+	// body, set by EvaluateReader, is streamed in place of Payload.
+	body io.Reader
+	// unrecorded skips the audit record and usage metering, for the
+	// intermediate decisions of a StreamEvaluator.
+	unrecorded bool
 }
 
 // DecisionResult represents the outcome of a decision evaluation.
 type DecisionResult struct {
 	Allowed bool
 	Reason  string
-	Latency time.Duration
+	// Code classifies Reason; it tells a default decision from a rule's.
+	Code ReasonCode
+	// RuleID identifies the deciding rule; it is empty for the default
+	// decision.
+	RuleID string
+	// MatchedRuleIDs lists the rules that matched, in evaluation order,
+	// obligation rules included. Rules after the deciding rule are not
+	// evaluated, so they are never listed.
+	MatchedRuleIDs []string
+	// RulepackVersion is the version of the rulepack evaluated.
+	RulepackVersion string
+	// Obligations lists what the matching warn, redact and transform rules
+	// ask of the caller, in evaluation order. Denied decisions carry none.
+	// Obligations and MatchedRuleIDs are shared with the decision cache and
+	// must not be modified.
+	Obligations []Obligation
+	Latency     time.Duration
+	// Explanation is set for requests with Explain.
+	Explanation *Explanation
 }
 
 // Option configures Governor construction.
@@ -40,77 +91,175 @@ type Governor struct {
 	cfg         Config
 	httpClient  *http.Client
 	cache       *RuleCache[*Rulepack]
+	decisions   *RuleCache[verdict]
 	evaluator   *Evaluator
+	pinned      map[string]*Rulepack
+	chaos       *chaos
+	now         func() time.Time
 	storage     storage.Store
+	storageKeys storage.KeyProvider
+	// audit writes audit records in the background when Config.AuditAsync
+	// is set.
+	audit       *auditWriter
+	auditErrors AuditErrorHandler
+	// auditSeq numbers audit records, so decisions taken at the same
+	// instant, as under a fixed clock, get distinct keys.
+	auditSeq    atomic.Uint64
 	offline     bool
 	offlineChan chan DecisionRequest
-	mu          sync.RWMutex
+	events      *eventBus
+	metrics     *Metrics
+	keySource   SecretSource
+	keyRefresh  time.Duration
+	publicKeys  []crypto.PublicKey
+	// cfgOptions are the Config mutations of the options, applied again
+	// when the config file is reloaded.
+	cfgOptions []func(*Config)
+	// pins holds Config.RulepackPins by rulepack ID, guarded by mu.
+	pins map[string]string
+	// refreshMu guards fetched, the rulepacks kept fresh by the refresher.
+	refreshMu sync.Mutex
+	fetched   map[string]fetchedPack
+	cancel    context.CancelFunc
+	closed    bool
+	mu        sync.RWMutex
+	usageMu   sync.Mutex
 }
 
 // NewGovernor constructs a Governor instance using the provided configuration.
+//
+// Settings are resolved with the following precedence, lowest first: the
+// DefaultConfig, non-zero fields of cfg, opts, and finally environment
+// variables so operators can always override what is compiled in.
 func NewGovernor(ctx context.Context, cfg Config, opts ...Option) (*Governor, error) {
-	cfg = DefaultConfig().Merge(cfg)
-	if err := cfg.ApplyEnv(); err != nil {
+	g := &Governor{
+		cfg:    DefaultConfig().Merge(cfg),
+		events: &eventBus{},
+	}
+	for _, opt := range opts {
+		if err := opt(g); err != nil {
+			return nil, err
+		}
+	}
+	if err := g.cfg.ApplyEnv(); err != nil {
 		return nil, err
 	}
-	if err := cfg.Validate(); err != nil {
+	if err := g.resolveAPIKey(ctx); err != nil {
 		return nil, err
 	}
-
-	client := &http.Client{
-		Timeout: cfg.HTTPTimeout,
-		Transport: &http.Transport{
-			Proxy:               http.ProxyFromEnvironment,
-			DialContext:         (&net.Dialer{Timeout: 5 * time.Second}).DialContext,
-			IdleConnTimeout:     90 * time.Second,
-			MaxIdleConns:        100,
-			MaxIdleConnsPerHost: 10,
-		},
+	if err := g.cfg.Validate(); err != nil {
+		return nil, err
 	}
+	cfg = g.cfg
 
-	cache := NewRuleCache[*Rulepack](cfg.CacheTTL)
-	evaluator := NewEvaluator()
-
-	store, err := buildStore(cfg)
+	level, err := ParseEventLevel(cfg.LogLevel)
 	if err != nil {
 		return nil, err
 	}
-
-	g := &Governor{
-		cfg:         cfg,
-		httpClient:  client,
-		cache:       cache,
-		evaluator:   evaluator,
-		storage:     store,
-		offline:     cfg.OfflineMode,
-		offlineChan: make(chan DecisionRequest, cfg.OfflineQueueSize),
+	g.events.setLevel(level)
+	g.events.emit(Event{Type: EventConfigLoaded, Level: LevelInfo, Message: "configuration loaded", Fields: map[string]any{"config": cfg.String()}})
+	for _, w := range cfg.Warnings() {
+		g.events.emit(Event{Type: EventConfigWarning, Level: LevelWarn, Message: w.Message, Fields: map[string]any{"field": w.Field}})
+	}
+	if g.chaos != nil {
+		g.events.emit(Event{Type: EventConfigWarning, Level: LevelWarn, Message: "chaos fault injection is enabled", Fields: map[string]any{"field": "Chaos"}})
 	}
 
-	for _, opt := range opts {
-		if err := opt(g); err != nil {
+	if g.httpClient == nil {
+		client, err := newHTTPClient(cfg)
+		if err != nil {
 			return nil, err
 		}
+		g.httpClient = client
+	}
+	if g.publicKeys, err = loadPublicKeys(cfg.RulepackPublicKeys); err != nil {
+		return nil, err
+	}
+	if g.pins, err = parseRulepackPins(cfg.RulepackPins); err != nil {
+		return nil, err
+	}
+	if g.storage == nil {
+		store, err := buildStore(cfg)
+		if err != nil {
+			return nil, err
+		}
+		g.storage = store
+	}
+	if err := g.encryptStorage(); err != nil {
+		return nil, err
 	}
 
+	if g.now == nil {
+		g.now = time.Now
+	}
+	g.cache = NewRuleCache[*Rulepack](cfg.CacheTTL)
+	g.cache.clock = g.now
+	g.decisions = NewRuleCache[verdict](cfg.DecisionCacheTTL)
+	g.decisions.clock = g.now
+	g.evaluator = NewEvaluator()
+	g.evaluator.SetClock(g.now)
+	g.evaluator.SetDefaultDecision(cfg.DefaultDecision)
+	g.evaluator.SetCompileCache(cfg.CompileCacheDir)
+	for _, pack := range g.pinned {
+		if err := g.evaluator.Preload(pack.ID, pack.Rules); err != nil {
+			return nil, fmt.Errorf("rulepack %s: %w", pack.ID, err)
+		}
+	}
+	g.offline = cfg.OfflineMode
+	g.offlineChan = make(chan DecisionRequest, cfg.OfflineQueueSize)
+	if cfg.MetricsEnabled {
+		g.metrics = NewMetrics()
+	}
+
+	ctx, g.cancel = context.WithCancel(ctx)
 	if g.offline {
 		go g.drainOfflineQueue(ctx)
 	}
+	if g.metrics != nil && g.cfg.MetricsEndpoint != "" {
+		go (&metricsPusher{gov: g}).run(ctx)
+	}
+	if g.keySource != nil && g.keyRefresh > 0 {
+		go g.refreshAPIKey(ctx, g.keySource, g.keyRefresh)
+	}
+	if !g.offline && cfg.RulepackRefreshInterval > 0 {
+		go g.refreshRulepacks(ctx, cfg.RulepackRefreshInterval)
+	}
+	if !g.offline && cfg.RulepackSubscribe {
+		go g.subscribeRulepacks(ctx)
+	}
+	if cfg.AuditRetention > 0 {
+		go g.reapAuditRecords(ctx, cfg.AuditRetention)
+	}
+	// The audit writer outlives ctx, so Close can write the queued records.
+	if cfg.AuditAsync && g.storage != nil {
+		g.audit = startAuditWriter(g, cfg)
+	}
 
 	return g, nil
 }
 
-// buildStore creates a storage backend from configuration.
+// buildStore creates a storage backend from configuration. Backends
+// registered with storage.Register take precedence over the built-in
+// in-memory stand-ins for bolt and badger. sqlite and postgres have no
+// stand-in, so selecting them without a registered store is an error rather
+// than a silent loss of every audit record.
 func buildStore(cfg Config) (storage.Store, error) {
-	switch storage.BackendType(cfg.StorageBackend) {
-	case storage.BackendBolt:
+	backend := storage.BackendType(cfg.StorageBackend)
+	switch backend {
+	case storage.BackendBolt, storage.BackendBadger, storage.BackendSQLite, storage.BackendPostgres:
 		if cfg.StorageDSN == "" {
-			return nil, fmt.Errorf("bolt backend selected but StorageDSN empty")
+			return nil, fmt.Errorf("%s backend selected but StorageDSN empty", backend)
 		}
+	}
+	if open, ok := storage.Lookup(backend); ok {
+		return open(cfg.StorageDSN)
+	}
+	switch backend {
+	case storage.BackendSQLite, storage.BackendPostgres:
+		return nil, fmt.Errorf("%s backend selected but no %s store is registered; import %s", backend, backend, storeModules[backend])
+	case storage.BackendBolt:
 		return storage.NewBolt(cfg.StorageDSN, nil)
 	case storage.BackendBadger:
-		if cfg.StorageDSN == "" {
-			return nil, fmt.Errorf("badger backend selected but StorageDSN empty")
-		}
 		return storage.NewBadger(cfg.StorageDSN, storage.DefaultBadgerOptions())
 	default:
 		return storage.NewMemory(), nil
@@ -139,91 +288,447 @@ func WithStorage(store storage.Store) Option {
 	}
 }
 
+// WithClock replaces the clock used for decision latencies, rule durations,
+// audit and usage timestamps and rulepack cache expiry. A fixed clock makes
+// decisions reproducible, for golden files and replays.
+func WithClock(now func() time.Time) Option {
+	return func(g *Governor) error {
+		if now == nil {
+			return fmt.Errorf("clock cannot be nil")
+		}
+		g.now = now
+		return nil
+	}
+}
+
+// WithRulepacks preloads packs. They are evaluated without contacting the
+// control plane, even in offline mode, and never expire from the cache; a
+// preloaded ID is never fetched.
+func WithRulepacks(packs ...*Rulepack) Option {
+	return func(g *Governor) error {
+		if g.pinned == nil {
+			g.pinned = make(map[string]*Rulepack, len(packs))
+		}
+		for _, pack := range packs {
+			if pack == nil {
+				return fmt.Errorf("rulepack cannot be nil")
+			}
+			if err := pack.Validate(); err != nil {
+				return fmt.Errorf("rulepack %s: %w", pack.ID, err)
+			}
+			g.pinned[pack.ID] = pack
+		}
+		return nil
+	}
+}
+
 // Rulepack holds compiled rule evaluation metadata.
 type Rulepack struct {
-	ID        string           `json:"id"`
-	Version   string           `json:"version"`
-	Rules     []RuleDefinition `json:"rules"`
-	UpdatedAt time.Time        `json:"updated_at"`
+	ID      string           `json:"id" yaml:"id"`
+	Version string           `json:"version" yaml:"version"`
+	Rules   []RuleDefinition `json:"rules" yaml:"rules"`
+	// Strategy decides how matching rules combine into a decision; empty
+	// means MatchFirst.
+	Strategy MatchStrategy `json:"strategy,omitempty" yaml:"strategy,omitempty"`
+	// DefaultDecision decides payloads no rule matched; empty means
+	// Config.DefaultDecision.
+	DefaultDecision DefaultDecision `json:"default_decision,omitempty" yaml:"default_decision,omitempty"`
+	UpdatedAt       time.Time       `json:"updated_at" yaml:"updated_at"`
+
+	// ref is the pinned reference the rulepack was fetched as; see key.
+	ref string
+	// body and signature are the verified response a signed rulepack was
+	// decoded from, kept so bundles can carry the signature.
+	body      []byte
+	signature string
 }
 
-// Evaluate performs a governance decision against the current rulepack.
+// Evaluate performs a governance decision against the current rulepack. An
+// empty RulepackID selects Config.DefaultRulepackID. Decisions served from
+// the decision cache are audited and metered like evaluated ones.
 func (g *Governor) Evaluate(ctx context.Context, req DecisionRequest) (DecisionResult, error) {
-	start := time.Now()
-	pack, err := g.loadRulepack(ctx, req.RulepackID)
-	if err != nil {
-		return DecisionResult{}, err
+	start := g.now()
+	g.metrics.Inc(CounterEvaluations)
+	g.mu.RLock()
+	failurePolicy, slowThreshold := g.cfg.FailurePolicy, g.cfg.SlowEvaluationThreshold
+	defaultRulepackID, maxPayload := g.cfg.DefaultRulepackID, g.cfg.MaxPayloadBytes
+	decisionTTL, defaultDecision := g.cfg.DecisionCacheTTL, g.cfg.DefaultDecision
+	g.mu.RUnlock()
+
+	if size := int64(len(req.Payload)); req.body == nil && maxPayload > 0 && size > maxPayload {
+		return DecisionResult{}, g.rejectPayload(req.RulepackID, &PayloadTooLargeError{Size: size, Limit: maxPayload})
+	}
+
+	if req.RulepackID == "" {
+		if defaultRulepackID == "" {
+			g.metrics.Inc(CounterEvaluationErrors)
+			return DecisionResult{}, ErrNoRulepackID
+		}
+		req.RulepackID = defaultRulepackID
 	}
+	ref := g.pinRulepackRef(req.RulepackID)
+	req.RulepackID, _ = splitRulepackRef(ref)
 
-	allowed, reason, err := g.evaluator.Evaluate(ctx, pack, req.Payload)
+	pack, err := g.loadRulepack(ctx, ref)
 	if err != nil {
+		g.metrics.Inc(CounterEvaluationErrors)
+		if failurePolicy == FailOpen {
+			g.events.emit(Event{Type: EventFailOpen, Level: LevelWarn, Message: "rulepack unavailable; failing open", RulepackID: req.RulepackID, Err: err})
+			return DecisionResult{Allowed: true, Reason: "fail-open: " + err.Error(), Code: ReasonFailOpen, Latency: g.now().Sub(start)}, nil
+		}
 		return DecisionResult{}, err
 	}
+	if req.body != nil {
+		fields, err := g.evaluator.fieldsOf(pack)
+		if err != nil {
+			g.metrics.Inc(CounterEvaluationErrors)
+			return DecisionResult{}, err
+		}
+		if req.Payload, err = readFields(req.body, fields, maxPayload); err != nil {
+			if errors.Is(err, ErrPayloadTooLarge) {
+				return DecisionResult{}, g.rejectPayload(req.RulepackID, err)
+			}
+			g.metrics.Inc(CounterEvaluationErrors)
+			return DecisionResult{}, &PayloadError{Err: err}
+		}
+	}
 
-	result := DecisionResult{Allowed: allowed, Reason: reason, Latency: time.Since(start)}
-	_ = g.persistAudit(ctx, req, result)
+	var (
+		v       verdict
+		hit     bool
+		key     string
+		timings *[]RuleTiming
+		trace   *[]RuleTrace
+	)
+	if decisionTTL > 0 && !req.NoCache && !req.Explain {
+		key = decisionKey(pack, g.evaluator.rulesDigest(pack.key()), req, defaultDecision)
+		if v, hit = g.decisions.Get(key); hit {
+			g.metrics.Inc(CounterDecisionCacheHits)
+		} else {
+			g.metrics.Inc(CounterDecisionCacheMisses)
+		}
+	}
+	if !hit {
+		if slowThreshold > 0 {
+			timings = timingsPool.Get().(*[]RuleTiming)
+			defer func() {
+				*timings = (*timings)[:0]
+				timingsPool.Put(timings)
+			}()
+		}
+		if req.Explain {
+			trace = &[]RuleTrace{}
+		}
+		matchStart := g.now()
+		v, err = g.evaluator.evaluate(ctx, pack, req.Payload, timings, trace)
+		g.metrics.ObserveLatency(pack.ID, PhaseMatch, g.now().Sub(matchStart))
+		if err != nil {
+			g.metrics.Inc(CounterEvaluationErrors)
+			return DecisionResult{}, err
+		}
+		if key != "" {
+			g.memoize(key, v)
+		}
+	}
+
+	result := newDecisionResult(pack, v, g.now().Sub(start))
+	if trace != nil {
+		explanation := newExplanation(pack, v, *trace)
+		result.Explanation = &explanation
+	}
+	if !req.unrecorded {
+		auditStart := g.now()
+		g.persistAudit(ctx, req, result)
+		if err := g.recordUsage(ctx, req); err != nil {
+			g.events.emit(Event{Type: EventUsageFailure, Level: LevelError, Message: "usage not recorded", RulepackID: req.RulepackID, Err: err})
+		}
+		g.metrics.ObserveLatency(pack.ID, PhaseAudit, g.now().Sub(auditStart))
+	}
+	total := g.now().Sub(start)
+	g.metrics.ObserveLatency(pack.ID, PhaseTotal, total)
+	if timings != nil && total > slowThreshold {
+		g.reportSlowEvaluation(pack.ID, total, slowThreshold, *timings)
+	}
 	return result, nil
 }
 
-// loadRulepack retrieves a rulepack from cache or remote.
-func (g *Governor) loadRulepack(ctx context.Context, id string) (*Rulepack, error) {
-	if pack, ok := g.cache.Get(id); ok {
+// EvaluateReader is Evaluate for payloads too large to hold in memory: the
+// JSON object is streamed from body, which replaces req.Payload, and only the
+// fields read by the rulepack's rules are kept. Those fields are also all the
+// audit record holds. Config.MaxPayloadBytes applies to the bytes read.
+func (g *Governor) EvaluateReader(ctx context.Context, req DecisionRequest, body io.Reader) (DecisionResult, error) {
+	req.body, req.Payload = body, nil
+	return g.Evaluate(ctx, req)
+}
+
+// rejectPayload counts and reports a payload over Config.MaxPayloadBytes.
+func (g *Governor) rejectPayload(rulepackID string, err error) error {
+	g.metrics.Inc(CounterEvaluationErrors)
+	g.events.emit(Event{Type: EventPayloadTooLarge, Level: LevelWarn, Message: "payload rejected", RulepackID: rulepackID, Err: err})
+	return err
+}
+
+// Explain evaluates req like Evaluate and returns the rule-by-rule trace. The
+// decision is neither audited nor counted as usage; set
+// DecisionRequest.Explain for an audited decision with its trace.
+func (g *Governor) Explain(ctx context.Context, req DecisionRequest) (Explanation, error) {
+	if req.RulepackID == "" {
+		g.mu.RLock()
+		req.RulepackID = g.cfg.DefaultRulepackID
+		g.mu.RUnlock()
+		if req.RulepackID == "" {
+			return Explanation{}, ErrNoRulepackID
+		}
+	}
+	pack, err := g.loadRulepack(ctx, g.pinRulepackRef(req.RulepackID))
+	if err != nil {
+		return Explanation{}, err
+	}
+	return g.evaluator.Explain(ctx, pack, req.Payload)
+}
+
+// slowRuleReportSize bounds how many rules a slow evaluation event lists.
+const slowRuleReportSize = 5
+
+// reportSlowEvaluation emits a warning listing the slowest rules of an
+// evaluation that exceeded Config.SlowEvaluationThreshold.
+func (g *Governor) reportSlowEvaluation(rulepackID string, total, threshold time.Duration, timings []RuleTiming) {
+	g.metrics.Inc(CounterSlowEvaluations)
+	sort.Slice(timings, func(i, j int) bool { return timings[i].Duration > timings[j].Duration })
+	if len(timings) > slowRuleReportSize {
+		timings = timings[:slowRuleReportSize]
+	}
+	// timings is recycled once Evaluate returns; listeners get a copy.
+	timings = append([]RuleTiming(nil), timings...)
+	g.events.emit(Event{
+		Type:       EventSlowEvaluation,
+		Level:      LevelWarn,
+		Message:    "slow evaluation",
+		RulepackID: rulepackID,
+		Fields: map[string]any{
+			"latency":       total.String(),
+			"threshold":     threshold.String(),
+			"slowest_rules": timings,
+		},
+	})
+}
+
+// Metrics returns a snapshot of the Governor's metrics. The snapshot is empty
+// when Config.MetricsEnabled is false.
+func (g *Governor) Metrics() MetricsSnapshot {
+	return g.metrics.Snapshot()
+}
+
+// EvaluatePayload evaluates payload against Config.DefaultRulepackID.
+func (g *Governor) EvaluatePayload(ctx context.Context, payload json.RawMessage) (DecisionResult, error) {
+	return g.Evaluate(ctx, DecisionRequest{Payload: payload})
+}
+
+// loadRulepack retrieves a rulepack from cache or remote. Freshly fetched
+// packs are compiled immediately so the evaluator never serves stale rules.
+func (g *Governor) loadRulepack(ctx context.Context, ref string) (*Rulepack, error) {
+	id, version := splitRulepackRef(ref)
+	if pack, ok := g.pinned[id]; ok && (version == "" || version == pack.Version) {
 		return pack, nil
 	}
+	if pack, ok := g.cache.Get(ref); ok {
+		if !g.chaos.evict() {
+			return pack, nil
+		}
+		g.cache.Invalidate(ref)
+	}
+	g.metrics.Inc(CounterCacheMisses)
+	g.events.emit(Event{Type: EventCacheMiss, Level: LevelDebug, Message: "rulepack cache miss", RulepackID: id})
 
 	if g.offline {
-		return nil, fmt.Errorf("%w: rulepack %s unavailable", ErrOffline, id)
+		return nil, fmt.Errorf("%w: rulepack %s unavailable", ErrOffline, ref)
 	}
 
-	pack, err := g.fetchRulepack(ctx, id)
+	fetchStart := g.now()
+	pack, etag, err := g.fetchRulepack(ctx, ref)
+	g.metrics.ObserveLatency(id, PhaseFetch, g.now().Sub(fetchStart))
 	if err != nil {
 		return nil, err
 	}
-	g.cache.Set(id, pack)
+
+	compileStart := g.now()
+	if err := g.evaluator.Preload(pack.key(), pack.Rules); err != nil {
+		return nil, err
+	}
+	g.metrics.ObserveLatency(pack.ID, PhaseCompile, g.now().Sub(compileStart))
+	g.cache.Set(ref, pack)
+	g.remember(ref, pack, etag)
 	return pack, nil
 }
 
-// fetchRulepack downloads the rulepack from the control plane. A minimal
-// implementation is provided to keep the SDK functional in offline examples.
-func (g *Governor) fetchRulepack(ctx context.Context, id string) (*Rulepack, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/rulepacks/%s", g.cfg.APIBaseURL, id), nil)
+// fetchRulepack downloads the rulepack ref names from the control plane,
+// retrying transient failures up to Config.FetchRetries times with
+// exponential backoff.
+func (g *Governor) fetchRulepack(ctx context.Context, ref string) (*Rulepack, string, error) {
+	id, _ := splitRulepackRef(ref)
+	g.mu.RLock()
+	retries := max(g.cfg.FetchRetries, 0)
+	g.mu.RUnlock()
+
+	var lastErr error
+	for attempt := 0; attempt <= retries; attempt++ {
+		if attempt > 0 {
+			backoff := time.Duration(1<<(attempt-1)) * 100 * time.Millisecond
+			g.events.emit(Event{
+				Type:       EventFetchRetry,
+				Level:      LevelWarn,
+				Message:    "retrying rulepack fetch",
+				RulepackID: id,
+				Err:        lastErr,
+				Fields:     map[string]any{"attempt": attempt, "backoff": backoff.String()},
+			})
+			select {
+			case <-ctx.Done():
+				return nil, "", ctx.Err()
+			case <-time.After(backoff):
+			}
+		}
+		pack, etag, retryable, err := g.fetchRulepackOnce(ctx, ref, "")
+		if err == nil {
+			return pack, etag, nil
+		}
+		lastErr = err
+		if !retryable || ctx.Err() != nil {
+			break
+		}
+	}
+	g.events.emit(Event{Type: EventFetchFailure, Level: LevelError, Message: "rulepack fetch failed", RulepackID: id, Err: lastErr})
+	return nil, "", lastErr
+}
+
+// fetchRulepackOnce performs a single fetch attempt, returning the rulepack
+// and its ETag, and reports whether a failure is worth retrying. With etag
+// the fetch is conditional and fails with errNotModified when the rulepack
+// is unchanged. A pinned version is requested as the version query
+// parameter, and a rulepack of another version is rejected.
+func (g *Governor) fetchRulepackOnce(ctx context.Context, ref, etag string) (*Rulepack, string, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, g.cfg.operationTimeout(g.cfg.FetchTimeout))
+	defer cancel()
+	if err := g.chaos.fetch(ctx); err != nil {
+		return nil, "", true, err
+	}
+	id, version := splitRulepackRef(ref)
+	target := fmt.Sprintf("%s/rulepacks/%s", g.cfg.APIBaseURL, id)
+	if version != "" {
+		target += "?version=" + url.QueryEscape(version)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
 	if err != nil {
-		return nil, err
+		return nil, "", false, err
+	}
+	req.Header.Set("Authorization", "Bearer "+g.apiKey())
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
 	}
-	req.Header.Set("Authorization", "Bearer "+g.cfg.APIKey)
 
 	resp, err := g.httpClient.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, "", true, err
 	}
 	defer resp.Body.Close()
+	if etag != "" && resp.StatusCode == http.StatusNotModified {
+		return nil, etag, false, errNotModified
+	}
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("fetch rulepack: unexpected status %d", resp.StatusCode)
+		retryable := resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests
+		return nil, "", retryable, fmt.Errorf("fetch rulepack: unexpected status %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", true, err
+	}
+	signature := resp.Header.Get(RulepackSignatureHeader)
+	if len(g.publicKeys) > 0 {
+		if err := verifyRulepack(g.publicKeys, body, signature); err != nil {
+			return nil, "", false, fmt.Errorf("rulepack %s: %w", id, err)
+		}
 	}
 	var pack Rulepack
-	if err := json.NewDecoder(resp.Body).Decode(&pack); err != nil {
-		return nil, err
+	if err := json.Unmarshal(body, &pack); err != nil {
+		return nil, "", false, err
+	}
+	// The signature covers the body, not the URL, so a signed rulepack must
+	// also be the one requested.
+	if pack.ID != id {
+		return nil, "", false, fmt.Errorf("fetch rulepack %s: got rulepack %q", ref, pack.ID)
+	}
+	if len(g.publicKeys) > 0 {
+		pack.body, pack.signature = body, signature
+	}
+	if version != "" {
+		if pack.Version != version {
+			return nil, "", false, fmt.Errorf("fetch rulepack %s: got version %q", ref, pack.Version)
+		}
+		pack.ref = ref
 	}
-	return &pack, nil
+	return &pack, resp.Header.Get("ETag"), false, nil
 }
 
-func (g *Governor) persistAudit(ctx context.Context, req DecisionRequest, result DecisionResult) error {
+// persistAudit writes the audit record of a decision, or queues it for the
+// asynchronous writer, reporting failures.
+func (g *Governor) persistAudit(ctx context.Context, req DecisionRequest, result DecisionResult) {
 	if g.storage == nil {
-		return nil
+		return
+	}
+	now := g.now()
+	enc := auditEncoderPool.Get().(*auditEncoder)
+	defer auditEncoderPool.Put(enc)
+	enc.buf.Reset()
+	if err := enc.enc.Encode(AuditRecord{
+		RulepackID:      req.RulepackID,
+		Payload:         req.Payload,
+		Allowed:         result.Allowed,
+		Reason:          result.Reason,
+		Code:            result.Code,
+		RuleID:          result.RuleID,
+		MatchedRuleIDs:  result.MatchedRuleIDs,
+		RulepackVersion: result.RulepackVersion,
+		Obligations:     result.Obligations,
+		LatencyMS:       result.Latency.Milliseconds(),
+		Time:            now,
+		Explanation:     result.Explanation,
+	}); err != nil {
+		g.reportAuditFailure(req.RulepackID, err, nil)
+		return
 	}
+	// The encoder buffer is pooled; stores may keep the value, so they get a
+	// copy of their own.
 	record := storage.Record{
-		Key: fmt.Sprintf("%s:%d", req.RulepackID, time.Now().UnixNano()),
-		Value: mustJSON(map[string]any{
-			"rulepack_id": req.RulepackID,
-			"payload":     req.Payload,
-			"allowed":     result.Allowed,
-			"reason":      result.Reason,
-			"latency_ms":  result.Latency.Milliseconds(),
-		}),
-	}
-	return g.storage.Put(ctx, record)
+		Key:   auditKey(req.RulepackID, now, g.auditSeq.Add(1)),
+		Value: bytes.Clone(bytes.TrimSuffix(enc.buf.Bytes(), []byte("\n"))),
+	}
+	if g.audit != nil {
+		g.audit.enqueue(ctx, req.RulepackID, record)
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, g.cfg.operationTimeout(g.cfg.AuditFlushTimeout))
+	defer cancel()
+	if err := g.storage.Put(ctx, record); err != nil {
+		g.reportAuditFailure(req.RulepackID, err, []storage.Record{record})
+	}
 }
 
+// auditEncoder is a pooled buffer and encoder for audit records.
+type auditEncoder struct {
+	buf bytes.Buffer
+	enc *json.Encoder
+}
+
+// Pools for the allocations of every evaluation.
+var (
+	auditEncoderPool = sync.Pool{New: func() any {
+		e := &auditEncoder{}
+		e.enc = json.NewEncoder(&e.buf)
+		return e
+	}}
+	timingsPool = sync.Pool{New: func() any { return new([]RuleTiming) }}
+)
+
 func mustJSON(v any) []byte {
 	b, err := json.Marshal(v)
 	if err != nil {
@@ -238,7 +743,9 @@ func (g *Governor) drainOfflineQueue(ctx context.Context) {
 		case <-ctx.Done():
 			return
 		case req := <-g.offlineChan:
-			_, _ = g.Evaluate(context.Background(), req)
+			if _, err := g.Evaluate(context.Background(), req); err != nil {
+				g.events.emit(Event{Type: EventReplayFailure, Level: LevelWarn, Message: "queued request replay failed", RulepackID: req.RulepackID, Err: err})
+			}
 		}
 	}
 }
@@ -252,6 +759,13 @@ func (g *Governor) Queue(req DecisionRequest) error {
 	case g.offlineChan <- req:
 		return nil
 	default:
+		g.events.emit(Event{
+			Type:       EventQueueDrop,
+			Level:      LevelWarn,
+			Message:    "offline queue full; request dropped",
+			RulepackID: req.RulepackID,
+			Fields:     map[string]any{"capacity": cap(g.offlineChan)},
+		})
 		return fmt.Errorf("offline queue full")
 	}
 }
@@ -260,12 +774,50 @@ func (g *Governor) Queue(req DecisionRequest) error {
 func (g *Governor) Close() error {
 	g.mu.Lock()
 	defer g.mu.Unlock()
+	g.closed = true
+	g.cancel()
+	if g.audit != nil {
+		g.audit.close()
+	}
 	if g.storage != nil {
 		return g.storage.Close()
 	}
 	return nil
 }
 
+// Stats reports point-in-time runtime counters for diagnostics.
+type Stats struct {
+	CacheEntries int
+	// DecisionCacheEntries counts memoized decisions, expired ones included
+	// until they are looked up or purged.
+	DecisionCacheEntries int
+	QueueDepth           int
+	QueueCapacity        int
+	Offline              bool
+}
+
+// Config returns a copy of the Governor's current configuration.
+func (g *Governor) Config() Config {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.cfg
+}
+
+// Stats returns current runtime counters. It is cheap enough to call from
+// expvar or health endpoints.
+func (g *Governor) Stats() Stats {
+	g.mu.RLock()
+	offline := g.offline
+	g.mu.RUnlock()
+	return Stats{
+		CacheEntries:         g.cache.Len(),
+		DecisionCacheEntries: g.decisions.Len(),
+		QueueDepth:           len(g.offlineChan),
+		QueueCapacity:        cap(g.offlineChan),
+		Offline:              offline,
+	}
+}
+
 // WithOffline toggles offline mode after construction.
 func (g *Governor) WithOffline(enabled bool) {
 	g.mu.Lock()
@@ -0,0 +1,264 @@
+// Package governortest provides utilities for testing code that uses a
+// Governor: an in-process fake control plane and an offline Governor
+// preloaded with rulepacks.
+package governortest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	governor "github.com/mfifth/aisentinel-go-sdk"
+)
+
+// APIKey is the API key of the Governors created by this package.
+const APIKey = "governortest"
+
+// Fetch records a rulepack download from a ControlPlane.
+type Fetch struct {
+	RulepackID string
+	// APIKey is the bearer token the request carried.
+	APIKey string
+	Time   time.Time
+}
+
+// ControlPlane is an in-process fake of the control plane API: it serves
+// rulepacks from memory on GET /rulepacks/{id}, lists them on GET /rulepacks
+// and stores pushed ones on PUT /rulepacks/{id}. Unknown rulepacks, and
+// pinned versions other than the stored one, are 404s. Every change is
+// published on governor.RulepackEventsPath to subscribed Governors.
+// It is safe for concurrent use.
+type ControlPlane struct {
+	// URL is the base URL to use as Config.APIBaseURL.
+	URL string
+
+	mu      sync.Mutex
+	packs   map[string]governor.Rulepack
+	fetches []Fetch
+	status  int
+	subs    map[chan governor.RulepackChange]struct{}
+}
+
+// NewControlPlane starts a ControlPlane serving packs. It is closed when the
+// test finishes.
+func NewControlPlane(t testing.TB, packs ...governor.Rulepack) *ControlPlane {
+	t.Helper()
+	c := &ControlPlane{packs: make(map[string]governor.Rulepack, len(packs)), subs: map[chan governor.RulepackChange]struct{}{}}
+	for _, pack := range packs {
+		c.packs[pack.ID] = pack
+	}
+	srv := httptest.NewServer(http.HandlerFunc(c.serveHTTP))
+	t.Cleanup(srv.Close)
+	c.URL = srv.URL
+	return c
+}
+
+// SetRulepack adds or replaces a rulepack. Governors keep serving a cached
+// copy until it expires, unless they subscribe to rulepack updates.
+func (c *ControlPlane) SetRulepack(pack governor.Rulepack) {
+	c.mu.Lock()
+	c.packs[pack.ID] = pack
+	c.publish(governor.RulepackChange{Type: "updated", ID: pack.ID, Version: pack.Version})
+	c.mu.Unlock()
+}
+
+// RemoveRulepack stops serving a rulepack.
+func (c *ControlPlane) RemoveRulepack(id string) {
+	c.mu.Lock()
+	delete(c.packs, id)
+	c.publish(governor.RulepackChange{Type: "removed", ID: id})
+	c.mu.Unlock()
+}
+
+// Subscribers returns the number of open rulepack event streams.
+func (c *ControlPlane) Subscribers() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.subs)
+}
+
+// publish sends change to every subscriber. c.mu must be held.
+func (c *ControlPlane) publish(change governor.RulepackChange) {
+	for sub := range c.subs {
+		select {
+		case sub <- change:
+		default:
+		}
+	}
+}
+
+// serveEvents streams rulepack changes until the client disconnects.
+func (c *ControlPlane) serveEvents(w http.ResponseWriter, r *http.Request) {
+	sub := make(chan governor.RulepackChange, 16)
+	c.mu.Lock()
+	status := c.status
+	if status == 0 {
+		c.subs[sub] = struct{}{}
+	}
+	c.mu.Unlock()
+	if status != 0 {
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+	defer func() {
+		c.mu.Lock()
+		delete(c.subs, sub)
+		c.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.WriteHeader(http.StatusOK)
+	w.(http.Flusher).Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case change := <-sub:
+			data, _ := json.Marshal(change)
+			fmt.Fprintf(w, "event: rulepack\ndata: %s\n\n", data)
+			w.(http.Flusher).Flush()
+		}
+	}
+}
+
+// Rulepack returns the rulepack served under id, including pushed ones.
+func (c *ControlPlane) Rulepack(id string) (governor.Rulepack, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	pack, ok := c.packs[id]
+	return pack, ok
+}
+
+// Fail makes every request answer with status, to exercise retries and
+// failure policies. Fail(0) restores normal service.
+func (c *ControlPlane) Fail(status int) {
+	c.mu.Lock()
+	c.status = status
+	c.mu.Unlock()
+}
+
+// Fetches returns the rulepack downloads so far, in order, including failed
+// ones.
+func (c *ControlPlane) Fetches() []Fetch {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Fetch(nil), c.fetches...)
+}
+
+// FetchCount returns how often rulepack id was downloaded.
+func (c *ControlPlane) FetchCount(id string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := 0
+	for _, f := range c.fetches {
+		if f.RulepackID == id {
+			n++
+		}
+	}
+	return n
+}
+
+// NewGovernor returns a Governor using the control plane, closed when the
+// test finishes. opts are applied after the API key and base URL.
+func (c *ControlPlane) NewGovernor(t testing.TB, opts ...governor.Option) *governor.Governor {
+	t.Helper()
+	opts = append([]governor.Option{governor.WithAPIKey(APIKey), governor.WithAPIBaseURL(c.URL)}, opts...)
+	return newGovernor(t, opts)
+}
+
+func (c *ControlPlane) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == governor.RulepackEventsPath && r.Method == http.MethodGet {
+		c.serveEvents(w, r)
+		return
+	}
+	id, single := strings.CutPrefix(r.URL.Path, "/rulepacks/")
+	if !single && r.URL.Path != "/rulepacks" {
+		http.NotFound(w, r)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if single && r.Method == http.MethodGet {
+		c.fetches = append(c.fetches, Fetch{
+			RulepackID: id,
+			APIKey:     strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "),
+			Time:       time.Now(),
+		})
+	}
+	if c.status != 0 {
+		http.Error(w, http.StatusText(c.status), c.status)
+		return
+	}
+
+	switch {
+	case !single && r.Method == http.MethodGet:
+		summaries := make([]governor.RulepackSummary, 0, len(c.packs))
+		for _, pack := range c.packs {
+			summaries = append(summaries, governor.RulepackSummary{ID: pack.ID, Version: pack.Version, UpdatedAt: pack.UpdatedAt})
+		}
+		sort.Slice(summaries, func(i, j int) bool { return summaries[i].ID < summaries[j].ID })
+		writeJSON(w, summaries)
+	case single && r.Method == http.MethodGet:
+		pack, ok := c.packs[id]
+		if version := r.URL.Query().Get("version"); !ok || (version != "" && version != pack.Version) {
+			http.NotFound(w, r)
+			return
+		}
+		writeJSON(w, pack)
+	case single && r.Method == http.MethodPut:
+		var pack governor.Rulepack
+		if err := json.NewDecoder(r.Body).Decode(&pack); err != nil || pack.ID != id {
+			http.Error(w, "invalid rulepack", http.StatusBadRequest)
+			return
+		}
+		c.packs[id] = pack
+		c.publish(governor.RulepackChange{Type: "updated", ID: id, Version: pack.Version})
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+// NewOfflineGovernor returns a Governor that evaluates packs without any
+// network access, closed when the test finishes. The first pack is the
+// default rulepack. Rulepacks that were not preloaded fail with
+// governor.ErrOffline.
+func NewOfflineGovernor(t testing.TB, packs ...governor.Rulepack) *governor.Governor {
+	t.Helper()
+	preload := make([]*governor.Rulepack, len(packs))
+	for i := range packs {
+		preload[i] = &packs[i]
+	}
+	opts := []governor.Option{
+		governor.WithAPIKey(APIKey),
+		governor.WithOfflineMode(true),
+		governor.WithRulepacks(preload...),
+	}
+	if len(packs) > 0 {
+		opts = append(opts, governor.WithDefaultRulepackID(packs[0].ID))
+	}
+	return newGovernor(t, opts)
+}
+
+func newGovernor(t testing.TB, opts []governor.Option) *governor.Governor {
+	t.Helper()
+	gov, err := governor.NewGovernor(context.Background(), governor.Config{StorageBackend: "memory"}, opts...)
+	if err != nil {
+		t.Fatalf("governortest: new governor: %v", err)
+	}
+	t.Cleanup(func() { _ = gov.Close() })
+	return gov
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,158 @@
+package governortest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	governor "github.com/mfifth/aisentinel-go-sdk"
+)
+
+var pack = governor.Rulepack{ID: "prompts", Version: "1", Rules: []governor.RuleDefinition{
+	{ID: "prompt", Pattern: "@", Allow: false},
+	{ID: "model", Pattern: ".", Allow: true},
+}}
+
+func evaluate(t *testing.T, gov *governor.Governor, rulepack, payload string) (governor.DecisionResult, error) {
+	t.Helper()
+	return gov.Evaluate(context.Background(), governor.DecisionRequest{RulepackID: rulepack, Payload: []byte(payload)})
+}
+
+func TestControlPlane(t *testing.T) {
+	control := NewControlPlane(t, pack)
+	gov := control.NewGovernor(t, governor.WithFetchRetries(0))
+
+	for i := 0; i < 3; i++ {
+		result, err := evaluate(t, gov, "prompts", `{"prompt":"mail jane@example.com"}`)
+		if err != nil || result.Allowed || result.RuleID != "prompt" {
+			t.Fatalf("expected the served rulepack to deny, got %+v %v", result, err)
+		}
+	}
+	if n := control.FetchCount("prompts"); n != 1 {
+		t.Fatalf("expected the rulepack to be fetched once and cached, got %d fetches", n)
+	}
+	if fetches := control.Fetches(); fetches[0].APIKey != APIKey {
+		t.Fatalf("expected the fetch to carry the API key, got %+v", fetches)
+	}
+
+	if _, err := evaluate(t, gov, "missing", `{}`); err == nil {
+		t.Fatal("expected an unknown rulepack to fail")
+	}
+	control.Fail(http.StatusServiceUnavailable)
+	if _, err := gov.FetchRulepack(context.Background(), "prompts"); err == nil {
+		t.Fatal("expected fetches to fail")
+	}
+	control.Fail(0)
+
+	pushed := governor.Rulepack{ID: "pushed", Rules: []governor.RuleDefinition{{ID: "prompt", Pattern: ".", Allow: true}}}
+	if err := gov.PushRulepack(context.Background(), &pushed); err != nil {
+		t.Fatalf("push: %v", err)
+	}
+	if _, ok := control.Rulepack("pushed"); !ok {
+		t.Fatal("expected the pushed rulepack to be stored")
+	}
+	summaries, err := gov.ListRulepacks(context.Background())
+	if err != nil || len(summaries) != 2 || summaries[0].ID != "prompts" || summaries[1].ID != "pushed" {
+		t.Fatalf("expected both rulepacks to be listed, got %+v %v", summaries, err)
+	}
+}
+
+func TestRulepackSubscription(t *testing.T) {
+	control := NewControlPlane(t, pack)
+	events := make(chan governor.EventType, 16)
+	gov := control.NewGovernor(t, governor.WithFetchRetries(0), governor.WithRulepackSubscription(true), governor.WithEventListener(func(e governor.Event) {
+		switch e.Type {
+		case governor.EventRulepackSubscribed, governor.EventRulepackRefreshed:
+			events <- e.Type
+		}
+	}))
+	waitFor := func(want governor.EventType) {
+		t.Helper()
+		select {
+		case got := <-events:
+			if got != want {
+				t.Fatalf("expected %s, got %s", want, got)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %s", want)
+		}
+	}
+	waitFor(governor.EventRulepackSubscribed)
+
+	const payload = `{"prompt":"mail jane@example.com"}`
+	if result, err := evaluate(t, gov, "prompts", payload); err != nil || result.Allowed {
+		t.Fatalf("expected the served rulepack to deny, got %+v %v", result, err)
+	}
+	control.SetRulepack(governor.Rulepack{ID: "prompts", Version: "2", Rules: []governor.RuleDefinition{{ID: "prompt", Pattern: ".", Allow: true}}})
+	waitFor(governor.EventRulepackRefreshed)
+	if result, err := evaluate(t, gov, "prompts", payload); err != nil || !result.Allowed || result.RulepackVersion != "2" {
+		t.Fatalf("expected the pushed update evaluated before the cache expires, got %+v %v", result, err)
+	}
+	if n := control.FetchCount("prompts"); n != 2 {
+		t.Fatalf("expected one fetch per version, got %d", n)
+	}
+
+	control.RemoveRulepack("prompts")
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := evaluate(t, gov, "prompts", payload); err != nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the removed rulepack evicted")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestOfflineGovernor(t *testing.T) {
+	gov := NewOfflineGovernor(t, pack)
+
+	result, err := gov.EvaluatePayload(context.Background(), []byte(`{"model":"gpt"}`))
+	if err != nil || !result.Allowed || result.RuleID != "model" {
+		t.Fatalf("expected the preloaded default rulepack to allow, got %+v %v", result, err)
+	}
+	if _, err := evaluate(t, gov, "missing", `{}`); !errors.Is(err, governor.ErrOffline) {
+		t.Fatalf("expected other rulepacks to be unavailable offline, got %v", err)
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	control := NewControlPlane(t, pack)
+	cassette := filepath.Join(t.TempDir(), "cassettes", "prompts.json")
+
+	rec, err := NewRecorder(cassette, ModeRecord, nil)
+	if err != nil {
+		t.Fatalf("recorder: %v", err)
+	}
+	rec.Secrets = []string{APIKey}
+	gov := control.NewGovernor(t, governor.WithHTTPClient(rec.Client()))
+	if result, err := evaluate(t, gov, "prompts", `{"prompt":"mail jane@example.com"}`); err != nil || result.Allowed {
+		t.Fatalf("expected the recorded rulepack to deny, got %+v %v", result, err)
+	}
+	if err := rec.Save(); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	data, err := os.ReadFile(cassette)
+	if err != nil || bytes.Contains(data, []byte(APIKey)) || !bytes.Contains(data, []byte(Redacted)) {
+		t.Fatalf("expected a scrubbed cassette, got %s %v", data, err)
+	}
+
+	control.Fail(http.StatusInternalServerError)
+	gov = control.NewGovernor(t, governor.WithHTTPClient(NewRecordingClient(t, cassette)))
+	if result, err := evaluate(t, gov, "prompts", `{"prompt":"mail jane@example.com"}`); err != nil || result.Allowed || result.RuleID != "prompt" {
+		t.Fatalf("expected the replayed rulepack to deny, got %+v %v", result, err)
+	}
+	if _, err := gov.FetchRulepack(context.Background(), "other"); err == nil || !strings.Contains(err.Error(), "no recorded interaction") {
+		t.Fatalf("expected unrecorded requests to fail, got %v", err)
+	}
+	if n := control.FetchCount("prompts"); n != 1 {
+		t.Fatalf("expected replay not to reach the network, got %d fetches", n)
+	}
+}
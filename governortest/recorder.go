@@ -0,0 +1,270 @@
+package governortest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+var record = flag.Bool("governortest.record", false, "re-record cassette files against the real control plane")
+
+// Redacted replaces credentials in recorded interactions.
+const Redacted = "REDACTED"
+
+// Mode selects whether a Recorder talks to the network.
+type Mode int
+
+const (
+	// ModeReplay answers from the cassette only; requests that were not
+	// recorded fail.
+	ModeReplay Mode = iota
+	// ModeRecord sends every request to the network and records it,
+	// replacing the cassette when saved.
+	ModeRecord
+)
+
+// scrubbedHeaders and scrubbedParams carry credentials and are always
+// redacted.
+var (
+	scrubbedHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+	scrubbedParams  = []string{"api_key", "access_token", "token"}
+)
+
+// Interaction is one recorded request and its response.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is the recorded form of an outgoing request.
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// RecordedResponse is the recorded form of a response.
+type RecordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// Recorder is an http.RoundTripper that records traffic to a cassette file or
+// replays it, VCR style, so tests against the real control plane run
+// offline once captured. Credentials are scrubbed before anything is stored.
+// Responses are read in full, so streaming endpoints are not supported. It
+// is safe for concurrent use.
+type Recorder struct {
+	// Secrets are replaced by Redacted wherever they appear in recorded
+	// URLs, headers and bodies, in addition to the credential headers and
+	// query parameters that are always scrubbed.
+	Secrets []string
+
+	path string
+	mode Mode
+	next http.RoundTripper
+
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// NewRecorder returns a Recorder for the cassette at path. In ModeReplay the
+// cassette must exist. next sends recorded requests and defaults to
+// http.DefaultTransport.
+func NewRecorder(path string, mode Mode, next http.RoundTripper) (*Recorder, error) {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	r := &Recorder{path: path, mode: mode, next: next}
+	if mode == ModeRecord {
+		return r, nil
+	}
+	data, err := os.ReadFile(path) // #nosec G304 -- test cassette
+	if err != nil {
+		return nil, fmt.Errorf("governortest: read cassette: %w", err)
+	}
+	if err := json.Unmarshal(data, &r.interactions); err != nil {
+		return nil, fmt.Errorf("governortest: decode cassette %s: %w", path, err)
+	}
+	r.used = make([]bool, len(r.interactions))
+	return r, nil
+}
+
+// Client returns an HTTP client using r, for governor.WithHTTPClient.
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// RoundTrip records or replays req.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	recorded := r.scrubRequest(req, body)
+	if r.mode == ModeReplay {
+		return r.replay(req, recorded)
+	}
+
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+	header := scrubHeader(resp.Header)
+	r.mu.Lock()
+	r.interactions = append(r.interactions, Interaction{
+		Request:  recorded,
+		Response: RecordedResponse{StatusCode: resp.StatusCode, Header: r.scrubValues(header), Body: r.scrub(string(respBody))},
+	})
+	r.mu.Unlock()
+	return resp, nil
+}
+
+// replay answers with the first unused interaction matching the request's
+// method, URL and body. Once all matches are used the last one is repeated,
+// so polling keeps working.
+func (r *Recorder) replay(req *http.Request, want RecordedRequest) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	match := -1
+	for i, in := range r.interactions {
+		if in.Request.Method != want.Method || in.Request.URL != want.URL || in.Request.Body != want.Body {
+			continue
+		}
+		match = i
+		if !r.used[i] {
+			break
+		}
+	}
+	if match < 0 {
+		return nil, fmt.Errorf("governortest: no recorded interaction for %s %s in %s", want.Method, want.URL, r.path)
+	}
+	r.used[match] = true
+	rec := r.interactions[match].Response
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", rec.StatusCode, http.StatusText(rec.StatusCode)),
+		StatusCode:    rec.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        rec.Header.Clone(),
+		Body:          io.NopCloser(strings.NewReader(rec.Body)),
+		ContentLength: int64(len(rec.Body)),
+		Request:       req,
+	}, nil
+}
+
+// Save writes the recorded interactions to the cassette. It does nothing in
+// ModeReplay.
+func (r *Recorder) Save() error {
+	if r.mode == ModeReplay {
+		return nil
+	}
+	r.mu.Lock()
+	data, err := json.MarshalIndent(r.interactions, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(r.path, append(data, '\n'), 0o600)
+}
+
+func (r *Recorder) scrubRequest(req *http.Request, body []byte) RecordedRequest {
+	u := *req.URL
+	query := u.Query()
+	for _, name := range scrubbedParams {
+		if query.Has(name) {
+			query.Set(name, Redacted)
+		}
+	}
+	u.RawQuery = query.Encode()
+	return RecordedRequest{
+		Method: req.Method,
+		URL:    r.scrub(u.String()),
+		Header: r.scrubValues(scrubHeader(req.Header)),
+		Body:   r.scrub(string(body)),
+	}
+}
+
+func (r *Recorder) scrub(s string) string {
+	for _, secret := range r.Secrets {
+		if secret != "" {
+			s = strings.ReplaceAll(s, secret, Redacted)
+			s = strings.ReplaceAll(s, url.QueryEscape(secret), Redacted)
+		}
+	}
+	return s
+}
+
+func (r *Recorder) scrubValues(h http.Header) http.Header {
+	for name, values := range h {
+		for i, v := range values {
+			values[i] = r.scrub(v)
+		}
+		h[name] = values
+	}
+	return h
+}
+
+// scrubHeader returns a copy of h with credential headers redacted.
+func scrubHeader(h http.Header) http.Header {
+	h = h.Clone()
+	for _, name := range scrubbedHeaders {
+		if h.Get(name) != "" {
+			h.Set(name, Redacted)
+		}
+	}
+	return h
+}
+
+// NewRecordingClient returns an HTTP client for governor.WithHTTPClient that
+// replays the cassette at path. When the cassette does not exist, or the
+// tests run with -governortest.record, requests go to the network and are
+// recorded, and the cassette is written when the test finishes. secrets,
+// typically the API key, are scrubbed from the recording.
+func NewRecordingClient(t testing.TB, path string, secrets ...string) *http.Client {
+	t.Helper()
+	mode := ModeReplay
+	if _, err := os.Stat(path); *record || errors.Is(err, fs.ErrNotExist) {
+		mode = ModeRecord
+	}
+	r, err := NewRecorder(path, mode, nil)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	r.Secrets = secrets
+	t.Cleanup(func() {
+		if err := r.Save(); err != nil {
+			t.Errorf("governortest: save cassette: %v", err)
+		}
+	})
+	return r.Client()
+}
@@ -0,0 +1,180 @@
+package governor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mfifth/aisentinel-go-sdk/storage"
+)
+
+// HealthStatus summarises the state of a component.
+type HealthStatus string
+
+const (
+	StatusUp       HealthStatus = "up"
+	StatusDegraded HealthStatus = "degraded"
+	StatusDown     HealthStatus = "down"
+)
+
+// ComponentStatus reports the health of a single Governor dependency.
+type ComponentStatus struct {
+	Name    string        `json:"name"`
+	Status  HealthStatus  `json:"status"`
+	Message string        `json:"message,omitempty"`
+	Latency time.Duration `json:"latency_ns,omitempty"`
+}
+
+// HealthReport aggregates component statuses. Status is the worst status of
+// all components.
+type HealthReport struct {
+	Status     HealthStatus      `json:"status"`
+	Components []ComponentStatus `json:"components"`
+	CheckedAt  time.Time         `json:"checked_at"`
+}
+
+// queueDegradedRatio marks the offline queue degraded once it is this full.
+const queueDegradedRatio = 0.9
+
+// Liveness reports whether the Governor is usable at all. It performs no I/O.
+func (g *Governor) Liveness() HealthReport {
+	g.mu.RLock()
+	closed := g.closed
+	g.mu.RUnlock()
+
+	status := ComponentStatus{Name: "governor", Status: StatusUp}
+	if closed {
+		status.Status = StatusDown
+		status.Message = "governor closed"
+	}
+	return newHealthReport(status)
+}
+
+// Readiness probes the control plane, storage, cache and offline queue, and
+// the audit buffer with Config.AuditAsync.
+func (g *Governor) Readiness(ctx context.Context) HealthReport {
+	components := []ComponentStatus{
+		g.checkControlPlane(ctx),
+		g.checkStorage(ctx),
+		g.checkCache(),
+		g.checkQueue(),
+	}
+	if g.audit != nil {
+		components = append(components, g.checkAuditBuffer())
+	}
+	return newHealthReport(components...)
+}
+
+func newHealthReport(components ...ComponentStatus) HealthReport {
+	report := HealthReport{Status: StatusUp, Components: components, CheckedAt: time.Now()}
+	for _, c := range components {
+		switch {
+		case c.Status == StatusDown:
+			report.Status = StatusDown
+		case c.Status == StatusDegraded && report.Status == StatusUp:
+			report.Status = StatusDegraded
+		}
+	}
+	return report
+}
+
+func (g *Governor) checkControlPlane(ctx context.Context) ComponentStatus {
+	status := ComponentStatus{Name: "control_plane", Status: StatusUp}
+	g.mu.RLock()
+	offline := g.offline
+	g.mu.RUnlock()
+	if offline {
+		status.Message = "offline mode; control plane not required"
+		return status
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, g.cfg.operationTimeout(g.cfg.FetchTimeout))
+	defer cancel()
+	start := time.Now()
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, g.cfg.APIBaseURL, nil)
+	if err != nil {
+		status.Status, status.Message = StatusDown, err.Error()
+		return status
+	}
+	resp, err := g.httpClient.Do(req)
+	status.Latency = time.Since(start)
+	if err != nil {
+		status.Status, status.Message = StatusDown, err.Error()
+		return status
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= http.StatusInternalServerError {
+		status.Status = StatusDegraded
+		status.Message = fmt.Sprintf("unexpected status %d", resp.StatusCode)
+	}
+	return status
+}
+
+func (g *Governor) checkStorage(ctx context.Context) ComponentStatus {
+	status := ComponentStatus{Name: "storage", Status: StatusUp}
+	if g.storage == nil {
+		status.Message = "no storage configured"
+		return status
+	}
+	start := time.Now()
+	_, err := g.storage.Get(ctx, "__health__")
+	status.Latency = time.Since(start)
+	if err != nil && !errors.Is(err, storage.ErrNotFound()) {
+		status.Status, status.Message = StatusDown, err.Error()
+	}
+	return status
+}
+
+func (g *Governor) checkCache() ComponentStatus {
+	return ComponentStatus{Name: "cache", Status: StatusUp, Message: fmt.Sprintf("%d entries", g.cache.Len())}
+}
+
+func (g *Governor) checkQueue() ComponentStatus {
+	depth, capacity := len(g.offlineChan), cap(g.offlineChan)
+	status := ComponentStatus{Name: "queue", Status: StatusUp, Message: fmt.Sprintf("%d/%d queued", depth, capacity)}
+	if capacity > 0 && float64(depth) >= float64(capacity)*queueDegradedRatio {
+		status.Status = StatusDegraded
+	}
+	return status
+}
+
+// checkAuditBuffer reports the asynchronous audit buffer degraded once it is
+// as full as a degraded offline queue, when records are about to be dropped
+// or to slow down decisions.
+func (g *Governor) checkAuditBuffer() ComponentStatus {
+	depth, capacity := len(g.audit.records), cap(g.audit.records)
+	status := ComponentStatus{Name: "audit_buffer", Status: StatusUp, Message: fmt.Sprintf("%d/%d queued", depth, capacity)}
+	if float64(depth) >= float64(capacity)*queueDegradedRatio {
+		status.Status = StatusDegraded
+	}
+	return status
+}
+
+// HealthHandler returns an http.Handler serving liveness on paths ending in
+// /healthz and readiness on paths ending in /readyz. Reports are encoded as
+// JSON; a down status responds with 503.
+func (g *Governor) HealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var report HealthReport
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/readyz"):
+			report = g.Readiness(r.Context())
+		case strings.HasSuffix(r.URL.Path, "/healthz"):
+			report = g.Liveness()
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		code := http.StatusOK
+		if report.Status == StatusDown {
+			code = http.StatusServiceUnavailable
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		_ = json.NewEncoder(w).Encode(report)
+	})
+}
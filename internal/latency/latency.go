@@ -0,0 +1,73 @@
+// Package latency summarizes the evaluation latencies measured by the bench
+// command and by tools/generate.go.
+package latency
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// percentiles are the latency percentiles reported, in output order.
+var percentiles = []struct {
+	name string
+	q    float64
+}{
+	{"p50", 0.50},
+	{"p90", 0.90},
+	{"p95", 0.95},
+	{"p99", 0.99},
+	{"p999", 0.999},
+}
+
+// Names lists the keys of a summary in output order.
+var Names = func() []string {
+	names := []string{"min", "mean"}
+	for _, p := range percentiles {
+		names = append(names, p.name)
+	}
+	return append(names, "max")
+}()
+
+// Summarize sorts latencies and returns their min, mean, percentiles and max,
+// keyed by Names and converted by unit. The summary of no latencies is empty.
+func Summarize(latencies []time.Duration, unit func(time.Duration) float64) map[string]float64 {
+	summary := map[string]float64{}
+	if len(latencies) == 0 {
+		return summary
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	var total time.Duration
+	for _, l := range latencies {
+		total += l
+	}
+	summary["min"] = unit(latencies[0])
+	summary["mean"] = unit(total / time.Duration(len(latencies)))
+	for _, p := range percentiles {
+		summary[p.name] = unit(Percentile(latencies, p.q))
+	}
+	summary["max"] = unit(latencies[len(latencies)-1])
+	return summary
+}
+
+// Percentile uses the nearest-rank method on sorted latencies.
+func Percentile(sorted []time.Duration, q float64) time.Duration {
+	rank := int(math.Ceil(q*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return sorted[rank]
+}
+
+// Milliseconds returns d in milliseconds, to the microsecond.
+func Milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// Microseconds returns d in microseconds, to the nanosecond.
+func Microseconds(d time.Duration) float64 {
+	return float64(d.Nanoseconds()) / 1000
+}
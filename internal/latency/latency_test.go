@@ -0,0 +1,40 @@
+package latency
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPercentile(t *testing.T) {
+	sorted := []time.Duration{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	tests := []struct {
+		q    float64
+		want time.Duration
+	}{
+		{0, 1},
+		{0.5, 5},
+		{0.9, 9},
+		{0.95, 10},
+		{0.999, 10},
+		{1, 10},
+	}
+	for _, tt := range tests {
+		if got := Percentile(sorted, tt.q); got != tt.want {
+			t.Errorf("p%g: expected %d, got %d", tt.q*100, tt.want, got)
+		}
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	latencies := []time.Duration{3 * time.Millisecond, time.Millisecond, 2 * time.Millisecond}
+	summary := Summarize(latencies, Milliseconds)
+	want := map[string]float64{"min": 1, "mean": 2, "p50": 2, "p90": 3, "p95": 3, "p99": 3, "p999": 3, "max": 3}
+	for _, name := range Names {
+		if summary[name] != want[name] {
+			t.Errorf("%s: expected %g, got %g", name, want[name], summary[name])
+		}
+	}
+	if summary := Summarize(nil, Milliseconds); len(summary) != 0 {
+		t.Fatalf("expected empty summary, got %v", summary)
+	}
+}
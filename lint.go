@@ -0,0 +1,193 @@
+package governor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LintSeverity ranks a LintIssue.
+type LintSeverity string
+
+const (
+	// LintError marks problems that break evaluation or a push.
+	LintError LintSeverity = "error"
+	// LintWarning marks likely mistakes that still evaluate.
+	LintWarning LintSeverity = "warning"
+)
+
+// Lint checks reported in LintIssue.Check.
+const (
+	LintSchema             = "schema"
+	LintMissingID          = "missing-id"
+	LintInvalidRegex       = "invalid-regex"
+	LintInvalidField       = "invalid-field"
+	LintInvalidOperator    = "invalid-operator"
+	LintInvalidCondition   = "invalid-condition"
+	LintInvalidAction      = "invalid-action"
+	LintDuplicateID        = "duplicate-id"
+	LintShadowedRule       = "shadowed-rule"
+	LintMissingDescription = "missing-description"
+)
+
+// LintIssue is a problem found in a rulepack. Rule is the 1-based rule index
+// and zero for rulepack level issues.
+type LintIssue struct {
+	Severity LintSeverity `json:"severity"`
+	Check    string       `json:"check"`
+	Rule     int          `json:"rule,omitempty"`
+	RuleID   string       `json:"rule_id,omitempty"`
+	Message  string       `json:"message"`
+}
+
+func (i LintIssue) String() string {
+	where := ""
+	switch {
+	case i.RuleID != "":
+		where = "rule " + i.RuleID + ": "
+	case i.Rule > 0:
+		where = fmt.Sprintf("rule %d: ", i.Rule)
+	}
+	return fmt.Sprintf("%s: %s%s [%s]", i.Severity, where, i.Message, i.Check)
+}
+
+// rulepackFile is the on-disk layout of a rulepack, which may embed fixtures.
+type rulepackFile struct {
+	Rulepack `yaml:",inline"`
+	Fixtures []RulepackFixture `json:"fixtures" yaml:"fixtures"`
+}
+
+// LintRulepackFile strictly decodes the rulepack at path, reporting unknown
+// fields as schema errors, and lints it. The error is non-nil only when the
+// file cannot be read or parsed at all.
+func LintRulepackFile(path string) ([]LintIssue, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- operator supplied rulepack
+	if err != nil {
+		return nil, err
+	}
+	var file rulepackFile
+	var strictErr error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if strictErr = dec.Decode(&file); strictErr != nil {
+			err = yaml.Unmarshal(data, &file)
+		}
+	default:
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if strictErr = dec.Decode(&file); strictErr != nil {
+			err = json.Unmarshal(data, &file)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("decode %s: %w", path, err)
+	}
+	var issues []LintIssue
+	if strictErr != nil {
+		issues = append(issues, LintIssue{Severity: LintError, Check: LintSchema, Message: strictErr.Error()})
+	}
+	return append(issues, LintRulepack(&file.Rulepack)...), nil
+}
+
+// matchesEverything holds patterns that match any string, which shadow later
+// rules on the same field.
+var matchesEverything = map[string]bool{"": true, ".*": true, "^.*$": true, "^.*": true, ".*$": true, "(?s).*": true}
+
+// LintRulepack checks a decoded rulepack: every rule needs an ID, a pattern
+// that compiles, an operator with a suitable value or valid conditions, a
+// valid field selector if it sets one, a valid action and a description
+// (shown as the decision reason), IDs must be unique, the match strategy must be known, and
+// under first-match no rule may be unreachable because a rule evaluated
+// before it on the same payload field always matches first.
+func LintRulepack(p *Rulepack) []LintIssue {
+	var issues []LintIssue
+	if p.ID == "" {
+		issues = append(issues, LintIssue{Severity: LintError, Check: LintSchema, Message: "rulepack id is required"})
+	}
+	if len(p.Rules) == 0 {
+		outcome := "denies"
+		if p.DefaultDecision == DefaultAllow {
+			outcome = "allows"
+		}
+		issues = append(issues, LintIssue{Severity: LintWarning, Check: LintSchema, Message: "rulepack has no rules and " + outcome + " everything"})
+	}
+	if !p.Strategy.Valid() {
+		issues = append(issues, LintIssue{Severity: LintError, Check: LintSchema, Message: fmt.Sprintf("unknown match strategy %q", p.Strategy)})
+	}
+	if !p.DefaultDecision.Valid() {
+		issues = append(issues, LintIssue{Severity: LintError, Check: LintSchema, Message: fmt.Sprintf("unknown default decision %q", p.DefaultDecision)})
+	}
+	// Rules are checked in evaluation order. Only the first match decides
+	// under MatchFirst, so only then can a rule be shadowed.
+	order := make([]int, len(p.Rules))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return p.Rules[order[a]].Priority > p.Rules[order[b]].Priority })
+	firstMatch := p.Strategy == "" || p.Strategy == MatchFirst
+	// first records, per field, the first rule and whether it matches
+	// everything.
+	type firstRule struct {
+		index    int
+		pattern  string
+		catchAll bool
+	}
+	first := map[string]firstRule{}
+	ids := map[string]int{}
+	for _, i := range order {
+		rule, n := p.Rules[i], i+1
+		if rule.ID == "" {
+			issues = append(issues, LintIssue{Severity: LintError, Check: LintMissingID, Rule: n, Message: "id is required"})
+		}
+		if err := validateRule(rule); err != nil {
+			check := LintInvalidRegex
+			switch {
+			case rule.composite():
+				check = LintInvalidCondition
+			case rule.Operator != "":
+				check = LintInvalidOperator
+			}
+			issues = append(issues, LintIssue{Severity: LintError, Check: check, Rule: n, RuleID: rule.ID, Message: err.Error()})
+		}
+		if err := validateAction(rule); err != nil {
+			issues = append(issues, LintIssue{Severity: LintError, Check: LintInvalidAction, Rule: n, RuleID: rule.ID, Message: err.Error()})
+		}
+		if rule.Field != "" {
+			if _, err := ParseFieldPath(rule.Field); err != nil {
+				issues = append(issues, LintIssue{Severity: LintError, Check: LintInvalidField, Rule: n, RuleID: rule.ID, Message: err.Error()})
+			}
+		}
+		if strings.TrimSpace(rule.Description) == "" {
+			issues = append(issues, LintIssue{Severity: LintWarning, Check: LintMissingDescription, Rule: n, RuleID: rule.ID, Message: "description is empty, so decisions carry no reason"})
+		}
+		if rule.ID == "" {
+			continue
+		}
+		if prev, dup := ids[rule.ID]; dup {
+			issues = append(issues, LintIssue{Severity: LintError, Check: LintDuplicateID, Rule: n, RuleID: rule.ID, Message: fmt.Sprintf("duplicate id, first used by rule %d", prev)})
+		} else {
+			ids[rule.ID] = n
+		}
+		if rule.Operator != "" || rule.composite() || rule.Effect().obligation() {
+			// Only patterns of deciding rules are compared for shadowing.
+			continue
+		}
+		prev, seen := first[rule.Selector()]
+		if !seen {
+			first[rule.Selector()] = firstRule{index: n, pattern: rule.Pattern, catchAll: matchesEverything[rule.Pattern]}
+			continue
+		}
+		if firstMatch && (prev.catchAll || prev.pattern == rule.Pattern) {
+			issues = append(issues, LintIssue{Severity: LintWarning, Check: LintShadowedRule, Rule: n, RuleID: rule.ID, Message: fmt.Sprintf("never matches: rule %d (/%s/) always matches first", prev.index, prev.pattern)})
+		}
+	}
+	return issues
+}
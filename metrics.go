@@ -0,0 +1,257 @@
+package governor
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Phase identifies an evaluation stage measured by the metrics subsystem.
+type Phase string
+
+const (
+	PhaseFetch   Phase = "fetch"
+	PhaseCompile Phase = "compile"
+	PhaseMatch   Phase = "match"
+	PhaseAudit   Phase = "audit"
+	PhaseTotal   Phase = "total"
+)
+
+// Counter names maintained by the Governor.
+const (
+	CounterEvaluations      = "evaluations_total"
+	CounterEvaluationErrors = "evaluation_errors_total"
+	CounterCacheMisses      = "cache_misses_total"
+	CounterAuditFailures    = "audit_failures_total"
+	CounterSlowEvaluations  = "slow_evaluations_total"
+	// Decision cache lookups; see Config.DecisionCacheTTL.
+	CounterDecisionCacheHits   = "decision_cache_hits_total"
+	CounterDecisionCacheMisses = "decision_cache_misses_total"
+	// CounterAuditDropped counts audit records dropped by BackpressureDrop.
+	CounterAuditDropped = "audit_dropped_total"
+)
+
+// DefaultLatencyBuckets are the histogram upper bounds used for latency
+// observations. They cover sub-millisecond rule matching up to slow fetches.
+var DefaultLatencyBuckets = []time.Duration{
+	100 * time.Microsecond,
+	250 * time.Microsecond,
+	500 * time.Microsecond,
+	time.Millisecond,
+	2500 * time.Microsecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+}
+
+// Histogram is a fixed-bucket latency histogram safe for concurrent use.
+type Histogram struct {
+	mu     sync.Mutex
+	bounds []time.Duration
+	counts []uint64
+	sum    time.Duration
+	count  uint64
+}
+
+// NewHistogram creates a histogram with the supplied ascending bucket bounds.
+func NewHistogram(bounds []time.Duration) *Histogram {
+	return &Histogram{bounds: bounds, counts: make([]uint64, len(bounds)+1)}
+}
+
+// Observe records a single duration.
+func (h *Histogram) Observe(d time.Duration) {
+	i := sort.Search(len(h.bounds), func(i int) bool { return d <= h.bounds[i] })
+	h.mu.Lock()
+	h.counts[i]++
+	h.sum += d
+	h.count++
+	h.mu.Unlock()
+}
+
+// Snapshot returns a copy of the histogram with cumulative bucket counts.
+func (h *Histogram) Snapshot() HistogramSnapshot {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	snap := HistogramSnapshot{Buckets: make([]Bucket, len(h.bounds)), Count: h.count, Sum: h.sum}
+	var cumulative uint64
+	for i, bound := range h.bounds {
+		cumulative += h.counts[i]
+		snap.Buckets[i] = Bucket{UpperBound: bound, Count: cumulative}
+	}
+	return snap
+}
+
+// Bucket is a cumulative histogram bucket.
+type Bucket struct {
+	UpperBound time.Duration `json:"le"`
+	Count      uint64        `json:"count"`
+}
+
+// HistogramSnapshot is an immutable view of a Histogram.
+type HistogramSnapshot struct {
+	Buckets []Bucket      `json:"buckets"`
+	Count   uint64        `json:"count"`
+	Sum     time.Duration `json:"sum"`
+}
+
+// Quantile estimates the q-th quantile (0 < q <= 1) from bucket bounds. The
+// estimate is the upper bound of the bucket containing the quantile.
+func (s HistogramSnapshot) Quantile(q float64) time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	rank := uint64(q * float64(s.Count))
+	for _, b := range s.Buckets {
+		if b.Count >= rank {
+			return b.UpperBound
+		}
+	}
+	if len(s.Buckets) == 0 {
+		return 0
+	}
+	return s.Buckets[len(s.Buckets)-1].UpperBound
+}
+
+type latencyKey struct {
+	rulepackID string
+	phase      Phase
+}
+
+// Metrics collects per-rulepack latency histograms and counters. A nil
+// *Metrics discards all observations, which is how disabled metrics behave.
+type Metrics struct {
+	mu       sync.RWMutex
+	latency  map[latencyKey]*Histogram
+	counters map[string]uint64
+}
+
+// NewMetrics creates an empty metrics registry.
+func NewMetrics() *Metrics {
+	return &Metrics{latency: make(map[latencyKey]*Histogram), counters: make(map[string]uint64)}
+}
+
+// ObserveLatency records the duration of phase for rulepackID.
+func (m *Metrics) ObserveLatency(rulepackID string, phase Phase, d time.Duration) {
+	if m == nil {
+		return
+	}
+	key := latencyKey{rulepackID: rulepackID, phase: phase}
+	m.mu.RLock()
+	h, ok := m.latency[key]
+	m.mu.RUnlock()
+	if !ok {
+		m.mu.Lock()
+		if h, ok = m.latency[key]; !ok {
+			h = NewHistogram(DefaultLatencyBuckets)
+			m.latency[key] = h
+		}
+		m.mu.Unlock()
+	}
+	h.Observe(d)
+}
+
+// Inc increments the named counter by one.
+func (m *Metrics) Inc(name string) {
+	m.Add(name, 1)
+}
+
+// Add increments the named counter by n.
+func (m *Metrics) Add(name string, n uint64) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	m.counters[name] += n
+	m.mu.Unlock()
+}
+
+// LatencySnapshot is the histogram for one rulepack and phase.
+type LatencySnapshot struct {
+	RulepackID string            `json:"rulepack_id"`
+	Phase      Phase             `json:"phase"`
+	Histogram  HistogramSnapshot `json:"histogram"`
+}
+
+// MetricsSnapshot is a point-in-time copy of all metrics.
+type MetricsSnapshot struct {
+	Timestamp time.Time         `json:"timestamp"`
+	Latencies []LatencySnapshot `json:"latencies"`
+	Counters  map[string]uint64 `json:"counters"`
+}
+
+// Snapshot copies the current metrics. Latencies are sorted by rulepack and
+// phase so output is stable.
+func (m *Metrics) Snapshot() MetricsSnapshot {
+	snap := MetricsSnapshot{Timestamp: time.Now(), Counters: map[string]uint64{}}
+	if m == nil {
+		return snap
+	}
+	m.mu.RLock()
+	for k, v := range m.counters {
+		snap.Counters[k] = v
+	}
+	for k, h := range m.latency {
+		snap.Latencies = append(snap.Latencies, LatencySnapshot{RulepackID: k.rulepackID, Phase: k.phase, Histogram: h.Snapshot()})
+	}
+	m.mu.RUnlock()
+	sort.Slice(snap.Latencies, func(i, j int) bool {
+		a, b := snap.Latencies[i], snap.Latencies[j]
+		if a.RulepackID != b.RulepackID {
+			return a.RulepackID < b.RulepackID
+		}
+		return a.Phase < b.Phase
+	})
+	return snap
+}
+
+// Latency returns the histogram for rulepackID and phase, if observed.
+func (s MetricsSnapshot) Latency(rulepackID string, phase Phase) (HistogramSnapshot, bool) {
+	for _, l := range s.Latencies {
+		if l.RulepackID == rulepackID && l.Phase == phase {
+			return l.Histogram, true
+		}
+	}
+	return HistogramSnapshot{}, false
+}
+
+// WritePrometheus renders the snapshot in the Prometheus text exposition
+// format.
+func (s MetricsSnapshot) WritePrometheus(w io.Writer) error {
+	var b strings.Builder
+	if len(s.Latencies) > 0 {
+		b.WriteString("# TYPE aisentinel_evaluation_latency_seconds histogram\n")
+	}
+	for _, l := range s.Latencies {
+		labels := fmt.Sprintf("rulepack=%q,phase=%q", l.RulepackID, l.Phase)
+		for _, bucket := range l.Histogram.Buckets {
+			fmt.Fprintf(&b, "aisentinel_evaluation_latency_seconds_bucket{%s,le=%q} %d\n", labels, formatSeconds(bucket.UpperBound), bucket.Count)
+		}
+		fmt.Fprintf(&b, "aisentinel_evaluation_latency_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels, l.Histogram.Count)
+		fmt.Fprintf(&b, "aisentinel_evaluation_latency_seconds_sum{%s} %s\n", labels, formatSeconds(l.Histogram.Sum))
+		fmt.Fprintf(&b, "aisentinel_evaluation_latency_seconds_count{%s} %d\n", labels, l.Histogram.Count)
+	}
+	names := make([]string, 0, len(s.Counters))
+	for name := range s.Counters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(&b, "# TYPE aisentinel_%s counter\naisentinel_%s %d\n", name, name, s.Counters[name])
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'g', -1, 64)
+}
@@ -0,0 +1,102 @@
+package governor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	// maxPendingSnapshots bounds how many unsent snapshots are retained while
+	// the metrics endpoint is unreachable.
+	maxPendingSnapshots = 20
+	// metricsPushAttempts is the number of delivery attempts per interval.
+	metricsPushAttempts = 3
+)
+
+// EventMetricsPushFailure is emitted when a batch of snapshots could not be
+// delivered to Config.MetricsEndpoint.
+const EventMetricsPushFailure EventType = "metrics_push_failure"
+
+// metricsBatch is the JSON document posted to the metrics endpoint.
+type metricsBatch struct {
+	Snapshots []MetricsSnapshot `json:"snapshots"`
+}
+
+// metricsPusher periodically ships metrics snapshots to an HTTP endpoint.
+type metricsPusher struct {
+	gov     *Governor
+	pending []MetricsSnapshot
+}
+
+// run pushes snapshots every Config.MetricsPushInterval until ctx is done.
+func (p *metricsPusher) run(ctx context.Context) {
+	ticker := time.NewTicker(p.gov.cfg.MetricsPushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.pending = append(p.pending, p.gov.Metrics())
+			if dropped := len(p.pending) - maxPendingSnapshots; dropped > 0 {
+				p.pending = p.pending[dropped:]
+			}
+			if err := p.flush(ctx); err != nil {
+				p.gov.events.emit(Event{
+					Type:    EventMetricsPushFailure,
+					Level:   LevelWarn,
+					Message: "metrics push failed",
+					Err:     err,
+					Fields:  map[string]any{"pending": len(p.pending)},
+				})
+			}
+		}
+	}
+}
+
+// flush posts all pending snapshots as a single batch, retrying with backoff.
+func (p *metricsPusher) flush(ctx context.Context) error {
+	body, err := json.Marshal(metricsBatch{Snapshots: p.pending})
+	if err != nil {
+		return err
+	}
+	var lastErr error
+	for attempt := 0; attempt < metricsPushAttempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Duration(attempt) * 500 * time.Millisecond):
+			}
+		}
+		if lastErr = p.post(ctx, body); lastErr == nil {
+			p.pending = p.pending[:0]
+			return nil
+		}
+	}
+	return lastErr
+}
+
+func (p *metricsPusher) post(ctx context.Context, body []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, p.gov.cfg.operationTimeout(p.gov.cfg.TelemetryTimeout))
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.gov.cfg.MetricsEndpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+p.gov.apiKey())
+	resp, err := p.gov.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("metrics push: unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package governor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Operator compares a payload value with a rule's Value instead of matching
+// Pattern. Operators apply to any JSON value, so numbers, booleans and arrays
+// need not be matched as text.
+type Operator string
+
+const (
+	// OpEq matches a value equal to Value; numbers compare numerically.
+	OpEq Operator = "eq"
+	// OpNeq matches a present value not equal to Value.
+	OpNeq Operator = "neq"
+	// OpGt and OpLt match numbers greater or less than Value, a number.
+	OpGt Operator = "gt"
+	OpLt Operator = "lt"
+	// OpIn matches a value equal to an element of Value, an array.
+	OpIn Operator = "in"
+	// OpContains matches a string containing Value, a string, or an array
+	// with an element equal to Value.
+	OpContains Operator = "contains"
+	// OpExists matches any value, null included. It takes no Value.
+	OpExists Operator = "exists"
+)
+
+// compileOperand checks that value suits op and returns it normalized to the
+// types encoding/json decodes payloads into, so YAML and JSON rulepacks
+// compare alike. Operators take no pattern.
+func compileOperand(pattern string, op Operator, value any) (any, error) {
+	if pattern != "" {
+		return nil, fmt.Errorf("operator %s: pattern must be empty", op)
+	}
+	var operand any
+	if value != nil {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("operator %s: value: %w", op, err)
+		}
+		if err := json.Unmarshal(data, &operand); err != nil {
+			return nil, fmt.Errorf("operator %s: value: %w", op, err)
+		}
+	}
+	switch op {
+	case OpEq, OpNeq, OpContains:
+	case OpGt, OpLt:
+		if _, ok := operand.(float64); !ok {
+			return nil, fmt.Errorf("operator %s: value must be a number", op)
+		}
+	case OpIn:
+		if _, ok := operand.([]any); !ok {
+			return nil, fmt.Errorf("operator %s: value must be an array", op)
+		}
+	case OpExists:
+		if operand != nil {
+			return nil, fmt.Errorf("operator %s: takes no value", op)
+		}
+	default:
+		return nil, fmt.Errorf("unknown operator %q", op)
+	}
+	return operand, nil
+}
+
+// compare reports whether field satisfies the rule's operator.
+func (r *Rule) compare(field payloadField) bool {
+	switch r.operator {
+	case OpExists:
+		return true
+	case OpGt, OpLt:
+		raw := bytes.TrimSpace(field.raw)
+		if len(raw) == 0 || (raw[0] != '-' && (raw[0] < '0' || raw[0] > '9')) {
+			return false
+		}
+		n, err := strconv.ParseFloat(string(raw), 64)
+		if err != nil {
+			return false
+		}
+		if r.operator == OpGt {
+			return n > r.operand.(float64)
+		}
+		return n < r.operand.(float64)
+	case OpContains:
+		if s, ok := r.operand.(string); ok && field.isString {
+			return strings.Contains(string(field.value), s)
+		}
+	}
+
+	var value any
+	if err := json.Unmarshal(field.raw, &value); err != nil {
+		return false
+	}
+	switch r.operator {
+	case OpEq:
+		return reflect.DeepEqual(value, r.operand)
+	case OpNeq:
+		return !reflect.DeepEqual(value, r.operand)
+	case OpIn:
+		for _, element := range r.operand.([]any) {
+			if reflect.DeepEqual(value, element) {
+				return true
+			}
+		}
+	case OpContains:
+		elements, _ := value.([]any)
+		for _, element := range elements {
+			if reflect.DeepEqual(element, r.operand) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,237 @@
+package governor
+
+import (
+	"time"
+
+	"github.com/mfifth/aisentinel-go-sdk/storage"
+)
+
+// configOption adapts a Config mutation into an Option. Config options are
+// applied before environment variables and validation, so invalid values are
+// reported by NewGovernor like any other configuration error. They are kept
+// to be applied again on top of a reloaded config file.
+func configOption(fn func(*Config)) Option {
+	return func(g *Governor) error {
+		fn(&g.cfg)
+		g.cfgOptions = append(g.cfgOptions, fn)
+		return nil
+	}
+}
+
+// WithAPIBaseURL sets the control plane base URL.
+func WithAPIBaseURL(baseURL string) Option {
+	return configOption(func(c *Config) { c.APIBaseURL = baseURL })
+}
+
+// WithAPIKey sets the API key used to authenticate with the control plane.
+func WithAPIKey(key string) Option {
+	return configOption(func(c *Config) { c.APIKey = key })
+}
+
+// WithCacheTTL sets how long fetched rulepacks are cached.
+func WithCacheTTL(ttl time.Duration) Option {
+	return configOption(func(c *Config) { c.CacheTTL = ttl })
+}
+
+// WithHTTPTimeout sets the default timeout for control plane operations.
+func WithHTTPTimeout(timeout time.Duration) Option {
+	return configOption(func(c *Config) { c.HTTPTimeout = timeout })
+}
+
+// WithOfflineMode enables or disables offline mode. Unlike Config.Merge, a
+// false value is honoured.
+func WithOfflineMode(enabled bool) Option {
+	return configOption(func(c *Config) { c.OfflineMode = enabled })
+}
+
+// WithOfflineQueueSize sets the capacity of the offline replay queue.
+func WithOfflineQueueSize(size int) Option {
+	return configOption(func(c *Config) { c.OfflineQueueSize = size })
+}
+
+// WithStorageBackend selects a storage backend and its DSN.
+func WithStorageBackend(backend storage.BackendType, dsn string) Option {
+	return configOption(func(c *Config) {
+		c.StorageBackend = string(backend)
+		c.StorageDSN = dsn
+	})
+}
+
+// WithMetricsEnabled enables or disables metrics collection.
+func WithMetricsEnabled(enabled bool) Option {
+	return configOption(func(c *Config) { c.MetricsEnabled = enabled })
+}
+
+// WithMetricsEndpoint pushes metrics snapshots to endpoint every interval.
+func WithMetricsEndpoint(endpoint string, interval time.Duration) Option {
+	return configOption(func(c *Config) {
+		c.MetricsEndpoint = endpoint
+		c.MetricsPushInterval = interval
+	})
+}
+
+// WithEnvironmentPrefix changes the prefix used to read environment variables.
+func WithEnvironmentPrefix(prefix string) Option {
+	return configOption(func(c *Config) { c.EnvironmentPrefix = prefix })
+}
+
+// WithLogLevel sets the minimum level of events forwarded to the logger.
+func WithLogLevel(level EventLevel) Option {
+	return configOption(func(c *Config) { c.LogLevel = level.String() })
+}
+
+// WithFetchRetries sets how often transient rulepack fetch failures are
+// retried. Zero disables retries; in a Config passed to NewGovernor that
+// takes NoFetchRetries.
+func WithFetchRetries(retries int) Option {
+	return configOption(func(c *Config) { c.FetchRetries = retries })
+}
+
+// WithFailurePolicy decides how evaluations behave when a rulepack cannot be
+// loaded.
+func WithFailurePolicy(policy FailurePolicy) Option {
+	return configOption(func(c *Config) { c.FailurePolicy = policy })
+}
+
+// WithDefaultDecision decides payloads no rule matched, in rulepacks that do
+// not set their own default.
+func WithDefaultDecision(decision DefaultDecision) Option {
+	return configOption(func(c *Config) { c.DefaultDecision = decision })
+}
+
+// WithSlowEvaluationThreshold enables slow evaluation warnings above threshold.
+func WithSlowEvaluationThreshold(threshold time.Duration) Option {
+	return configOption(func(c *Config) { c.SlowEvaluationThreshold = threshold })
+}
+
+// WithMaxPayloadBytes sets the largest payload Evaluate accepts; 0 means no
+// limit.
+func WithMaxPayloadBytes(n int64) Option {
+	return configOption(func(c *Config) { c.MaxPayloadBytes = n })
+}
+
+// WithDecisionCacheTTL memoizes identical decisions for ttl; see
+// Config.DecisionCacheTTL.
+func WithDecisionCacheTTL(ttl time.Duration) Option {
+	return configOption(func(c *Config) { c.DecisionCacheTTL = ttl })
+}
+
+// WithDebugEndpoints enables the token-guarded pprof and expvar handlers.
+func WithDebugEndpoints(token string) Option {
+	return configOption(func(c *Config) {
+		c.DebugEndpoints = true
+		c.DebugToken = token
+	})
+}
+
+// WithProxy routes control plane traffic through dedicated proxies instead
+// of the process proxy environment. Either proxy may be empty.
+func WithProxy(httpProxy, httpsProxy, noProxy string) Option {
+	return configOption(func(c *Config) {
+		c.HTTPProxy = httpProxy
+		c.HTTPSProxy = httpsProxy
+		c.NoProxy = noProxy
+	})
+}
+
+// WithTLS configures control plane TLS. Empty values keep the defaults.
+func WithTLS(rootCAsFile, minVersion, serverName string, cipherSuites ...string) Option {
+	return configOption(func(c *Config) {
+		c.TLSRootCAsFile = rootCAsFile
+		c.TLSMinVersion = minVersion
+		c.TLSServerName = serverName
+		c.TLSCipherSuites = cipherSuites
+	})
+}
+
+// WithTimeouts sets the per-operation timeouts. Zero values fall back to
+// HTTPTimeout.
+func WithTimeouts(fetch, telemetry, auditFlush time.Duration) Option {
+	return configOption(func(c *Config) {
+		c.FetchTimeout = fetch
+		c.TelemetryTimeout = telemetry
+		c.AuditFlushTimeout = auditFlush
+	})
+}
+
+// WithRulepackPublicKeys trusts keys, PEM encoded public keys or paths to
+// them, to sign rulepacks and rejects fetched rulepacks they did not sign.
+func WithRulepackPublicKeys(keys ...string) Option {
+	return configOption(func(c *Config) { c.RulepackPublicKeys = keys })
+}
+
+// WithRulepackRefresh re-fetches cached rulepacks in the background every
+// interval; see Config.RulepackRefreshInterval.
+func WithRulepackRefresh(interval time.Duration) Option {
+	return configOption(func(c *Config) { c.RulepackRefreshInterval = interval })
+}
+
+// WithAuditRetention deletes audit records older than retention in the
+// background; see Config.AuditRetention.
+func WithAuditRetention(retention time.Duration) Option {
+	return configOption(func(c *Config) { c.AuditRetention = retention })
+}
+
+// WithAsyncAudit writes audit records in the background in batches of up to
+// batchSize, at least every flushInterval, buffering up to bufferSize and
+// applying policy beyond that; see Config.AuditAsync. Zero values keep the
+// defaults.
+func WithAsyncAudit(batchSize int, flushInterval time.Duration, bufferSize int, policy BackpressurePolicy) Option {
+	return configOption(func(c *Config) {
+		c.AuditAsync = true
+		if batchSize != 0 {
+			c.AuditBatchSize = batchSize
+		}
+		if flushInterval != 0 {
+			c.AuditFlushInterval = flushInterval
+		}
+		if bufferSize != 0 {
+			c.AuditBufferSize = bufferSize
+		}
+		if policy != "" {
+			c.AuditBackpressure = policy
+		}
+	})
+}
+
+// WithRulepackSubscription subscribes to rulepack updates pushed by the
+// control plane; see Config.RulepackSubscribe.
+func WithRulepackSubscription(enabled bool) Option {
+	return configOption(func(c *Config) { c.RulepackSubscribe = enabled })
+}
+
+// WithRulepackPins pins rulepacks to versions given as "id@version"; see
+// Config.RulepackPins.
+func WithRulepackPins(pins ...string) Option {
+	return configOption(func(c *Config) { c.RulepackPins = pins })
+}
+
+// WithDefaultRulepackID sets the rulepack used when a request names none.
+func WithDefaultRulepackID(id string) Option {
+	return configOption(func(c *Config) { c.DefaultRulepackID = id })
+}
+
+// WithStrictMode makes NewGovernor and Reload fail on any ValidationWarning;
+// see Config.StrictMode.
+func WithStrictMode(enabled bool) Option {
+	return configOption(func(c *Config) { c.StrictMode = enabled })
+}
+
+// WithAPIKeyFile reads the API key from the file at path and re-reads it
+// when the file changes, as with a mounted Kubernetes secret. A source given
+// with WithAPIKeySource takes precedence.
+func WithAPIKeyFile(path string) Option {
+	return configOption(func(c *Config) { c.APIKeyFile = path })
+}
+
+// WithCompileCacheDir persists compiled rulepack metadata in dir; see
+// Config.CompileCacheDir.
+func WithCompileCacheDir(dir string) Option {
+	return configOption(func(c *Config) { c.CompileCacheDir = dir })
+}
+
+// WithProfile names the configuration profile in effect, as LoadConfigProfile
+// records it. It does not load the profile's values from a config file.
+func WithProfile(profile string) Option {
+	return configOption(func(c *Config) { c.Profile = profile })
+}
@@ -0,0 +1,324 @@
+package governor
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"unicode/utf8"
+)
+
+// ErrPayloadTooLarge matches every PayloadTooLargeError.
+var ErrPayloadTooLarge = errors.New("governor: payload too large")
+
+// PayloadTooLargeError is returned for payloads over Config.MaxPayloadBytes.
+// Size is the payload length, or Limit+1 for streamed payloads, which are
+// not read past the limit.
+type PayloadTooLargeError struct {
+	Size  int64
+	Limit int64
+}
+
+func (e *PayloadTooLargeError) Error() string {
+	return fmt.Sprintf("governor: payload of %d bytes exceeds the %d byte limit", e.Size, e.Limit)
+}
+
+// Is makes errors.Is(err, ErrPayloadTooLarge) match.
+func (e *PayloadTooLargeError) Is(target error) bool { return target == ErrPayloadTooLarge }
+
+// ErrInvalidPayload matches every PayloadError.
+var ErrInvalidPayload = errors.New("governor: invalid payload")
+
+// PayloadError is returned for payloads that are not JSON, or whose fields
+// read by a rule cannot be decoded. Err is the decoding error.
+type PayloadError struct {
+	Err error
+}
+
+func (e *PayloadError) Error() string { return "governor: invalid payload: " + e.Err.Error() }
+
+// Unwrap returns the decoding error.
+func (e *PayloadError) Unwrap() error { return e.Err }
+
+// Is makes errors.Is(err, ErrInvalidPayload) match.
+func (e *PayloadError) Is(target error) bool { return target == ErrInvalidPayload }
+
+// payloadField is a payload value read by a rule. raw is the JSON value and
+// value the decoded text of strings. Both alias the payload, value only when
+// no unescaping was needed, so they are only valid while the payload is.
+type payloadField struct {
+	raw      []byte
+	value    []byte
+	isString bool
+}
+
+// documentPool recycles the field maps filled by extractFields.
+var documentPool = sync.Pool{New: func() any { return make(map[string]payloadField) }}
+
+// extractFields decodes the top-level fields of payload named in wanted into
+// fields, keeping their raw JSON for nested selectors, and skips everything
+// else, so large payloads cost a validation pass rather than a full decode
+// into map[string]any. It accepts and rejects exactly the
+// payloads json.Unmarshal into a map does, with the same errors, and like it
+// keeps the last of duplicate keys. null decodes to no fields.
+func extractFields(payload []byte, wanted map[string]bool, fields map[string]payloadField) error {
+	if !json.Valid(payload) {
+		var document map[string]any
+		return json.Unmarshal(payload, &document)
+	}
+	i := skipSpace(payload, 0)
+	if payload[i] != '{' {
+		if payload[i] == 'n' {
+			return nil
+		}
+		var document map[string]any
+		return json.Unmarshal(payload, &document)
+	}
+
+	i = skipSpace(payload, i+1)
+	for payload[i] != '}' {
+		end := skipString(payload, i)
+		rawKey := payload[i:end]
+		i = skipSpace(payload, end)
+		i = skipSpace(payload, i+1) // ':'
+		end = skipValue(payload, i)
+
+		var key string
+		want := false
+		if isPlain(rawKey) {
+			// The conversion in the index expression does not allocate.
+			_, want = wanted[string(rawKey[1:len(rawKey)-1])]
+			if want {
+				key = string(rawKey[1 : len(rawKey)-1])
+			}
+		} else {
+			value, err := decodeString(rawKey)
+			if err != nil {
+				return err
+			}
+			key = string(value)
+			_, want = wanted[key]
+		}
+		if want {
+			field := payloadField{raw: payload[i:end], isString: payload[i] == '"'}
+			if field.isString {
+				var err error
+				if field.value, err = decodeString(payload[i:end]); err != nil {
+					return err
+				}
+			}
+			fields[key] = field
+		}
+		i = skipSpace(payload, end)
+		if payload[i] == ',' {
+			i = skipSpace(payload, i+1)
+		}
+	}
+	return nil
+}
+
+// decodeString decodes a JSON string literal, quotes included. Literals
+// without escapes or invalid UTF-8 are returned in place, without copying.
+func decodeString(raw []byte) ([]byte, error) {
+	if isPlain(raw) {
+		return raw[1 : len(raw)-1], nil
+	}
+	var s string
+	err := json.Unmarshal(raw, &s)
+	return []byte(s), err
+}
+
+// isPlain reports whether a string literal decodes to its bytes unchanged.
+func isPlain(raw []byte) bool {
+	inner := raw[1 : len(raw)-1]
+	for _, c := range inner {
+		if c == '\\' {
+			return false
+		}
+	}
+	return utf8.Valid(inner)
+}
+
+// The skip functions walk JSON already checked by json.Valid and return the
+// offset just past the element starting at i.
+
+func skipSpace(data []byte, i int) int {
+	for i < len(data) {
+		switch data[i] {
+		case ' ', '\t', '\n', '\r':
+			i++
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+func skipString(data []byte, i int) int {
+	for i++; i < len(data); i++ {
+		switch data[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return i
+}
+
+func skipValue(data []byte, i int) int {
+	switch data[i] {
+	case '"':
+		return skipString(data, i)
+	case '{', '[':
+		depth := 0
+		for ; i < len(data); i++ {
+			switch data[i] {
+			case '"':
+				i = skipString(data, i) - 1
+			case '{', '[':
+				depth++
+			case '}', ']':
+				depth--
+				if depth == 0 {
+					return i + 1
+				}
+			}
+		}
+		return i
+	default:
+		// Numbers, true, false and null run until a delimiter.
+		for ; i < len(data); i++ {
+			switch data[i] {
+			case ',', '}', ']', ' ', '\t', '\n', '\r':
+				return i
+			}
+		}
+		return i
+	}
+}
+
+// limitedReader fails with a PayloadTooLargeError once more than limit bytes
+// are read; a limit of 0 disables the check.
+type limitedReader struct {
+	r     io.Reader
+	n     int64
+	limit int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.n += int64(n)
+	if l.limit > 0 && l.n > l.limit {
+		return n, &PayloadTooLargeError{Size: l.limit + 1, Limit: l.limit}
+	}
+	return n, err
+}
+
+// readFields streams a JSON object from r and returns a payload holding only
+// its top-level fields named in wanted, so large payloads are never held in
+// memory whole. Wanted fields that are not strings are kept as null, which
+// rules treat like any other non-string value, unless wanted marks them as
+// read by a nested selector; those are kept whole.
+func readFields(r io.Reader, wanted map[string]bool, limit int64) (json.RawMessage, error) {
+	dec := json.NewDecoder(&limitedReader{r: r, limit: limit})
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if tok == nil {
+		return json.RawMessage("null"), nil
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		return nil, fmt.Errorf("payload must be a JSON object, got %v", tok)
+	}
+
+	fields := make(map[string]any, len(wanted))
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key := tok.(string)
+		nested, ok := wanted[key]
+		if !ok {
+			if err := skipTokens(dec); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if nested {
+			var value json.RawMessage
+			if err := dec.Decode(&value); err != nil {
+				return nil, err
+			}
+			fields[key] = value
+			continue
+		}
+		tok, err = dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		if str, ok := tok.(string); ok {
+			fields[key] = str
+			continue
+		}
+		fields[key] = nil
+		if delim, ok := tok.(json.Delim); ok {
+			if err := skipNested(dec, delim); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if _, err := dec.Token(); err != nil { // '}'
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		if err == nil {
+			err = errors.New("invalid data after top-level value")
+		}
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	if err := enc.Encode(fields); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// skipTokens consumes one value token by token, holding at most one token in
+// memory.
+func skipTokens(dec *json.Decoder) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := tok.(json.Delim); ok {
+		return skipNested(dec, delim)
+	}
+	return nil
+}
+
+// skipNested consumes the rest of the array or object opened by delim.
+func skipNested(dec *json.Decoder, delim json.Delim) error {
+	if delim != '{' && delim != '[' {
+		return nil
+	}
+	for depth := 1; depth > 0; {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+	}
+	return nil
+}
@@ -1,6 +1,70 @@
 package pii
 
-import "regexp"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Kind identifies the type of a PII finding.
+type Kind string
+
+const (
+	KindEmail      Kind = "email"
+	KindCreditCard Kind = "credit_card"
+	KindIP         Kind = "ip"
+	KindPhone      Kind = "phone"
+)
+
+// Secret kinds reported by FindSecrets.
+const (
+	KindAWSAccessKey Kind = "aws_access_key"
+	KindGitHubToken  Kind = "github_token"
+	KindSlackToken   Kind = "slack_token"
+	KindPrivateKey   Kind = "private_key"
+	KindJWT          Kind = "jwt"
+	KindAPIKey       Kind = "api_key"
+)
+
+// Finding is a single PII match. Start and End are byte offsets into the
+// scanned input.
+type Finding struct {
+	Kind  Kind   `json:"kind"`
+	Value string `json:"value"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+}
+
+// Strategy selects how Redact replaces findings.
+type Strategy string
+
+const (
+	// StrategyPlaceholder replaces a finding with its kind, e.g. "[EMAIL]".
+	StrategyPlaceholder Strategy = "placeholder"
+	// StrategyMask replaces every character of a finding with '*'.
+	StrategyMask Strategy = "mask"
+	// StrategyHash replaces a finding with a short SHA-256 digest so equal
+	// values stay linkable without being readable.
+	StrategyHash Strategy = "hash"
+	// StrategyRemove deletes findings.
+	StrategyRemove Strategy = "remove"
+)
+
+// Strategies lists the supported redaction strategies.
+var Strategies = []Strategy{StrategyPlaceholder, StrategyMask, StrategyHash, StrategyRemove}
+
+// ParseStrategy validates a strategy name.
+func ParseStrategy(name string) (Strategy, error) {
+	for _, s := range Strategies {
+		if Strategy(name) == s {
+			return s, nil
+		}
+	}
+	return "", fmt.Errorf("pii: unknown strategy %q", name)
+}
 
 // Detector provides simple PII detection helpers built upon Go's regexp
 // package. Patterns are compiled once and re-used to minimise allocations.
@@ -9,6 +73,13 @@ type Detector struct {
 	phone  *regexp.Regexp
 	ip     *regexp.Regexp
 	credit *regexp.Regexp
+
+	secrets []pattern
+}
+
+type pattern struct {
+	kind Kind
+	re   *regexp.Regexp
 }
 
 // New creates a detector with sensible defaults.
@@ -18,6 +89,14 @@ func New() *Detector {
 		phone:  regexp.MustCompile(`\+?[0-9]{1,3}[\s-]?(?:\([0-9]{1,4}\)[\s-]?)?[0-9\s-]{5,}`),
 		ip:     regexp.MustCompile(`\b(?:(?:25[0-5]|2[0-4][0-9]|[01]?[0-9][0-9]?)(?:\.|$)){4}`),
 		credit: regexp.MustCompile(`\b(?:\d[ -]*?){13,16}\b`),
+		secrets: []pattern{
+			{KindPrivateKey, regexp.MustCompile(`-----BEGIN (?:[A-Z]+ )?PRIVATE KEY-----`)},
+			{KindAWSAccessKey, regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+			{KindGitHubToken, regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{50,})\b`)},
+			{KindSlackToken, regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`)},
+			{KindJWT, regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`)},
+			{KindAPIKey, regexp.MustCompile(`(?i)(?:api[_-]?key|secret|token|password)["']?\s*[:=]\s*["']?([A-Za-z0-9_\-./+]{16,})`)},
+		},
 	}
 }
 
@@ -28,3 +107,86 @@ func (d *Detector) ContainsPII(input string) bool {
 		d.ip.MatchString(input) ||
 		d.credit.MatchString(input)
 }
+
+// Find returns the non-overlapping findings in input ordered by offset. When
+// patterns overlap the more specific kind wins: email, then credit card, IP
+// address and phone number.
+func (d *Detector) Find(input string) []Finding {
+	return find(input, []pattern{
+		{KindEmail, d.email},
+		{KindCreditCard, d.credit},
+		{KindIP, d.ip},
+		{KindPhone, d.phone},
+	})
+}
+
+// FindSecrets returns credentials such as cloud access keys, tokens and
+// private keys found in input, ordered by offset. For key/value assignments
+// only the value is reported.
+func (d *Detector) FindSecrets(input string) []Finding {
+	return find(input, d.secrets)
+}
+
+func find(input string, patterns []pattern) []Finding {
+	var findings []Finding
+	for _, p := range patterns {
+		for _, loc := range p.re.FindAllStringSubmatchIndex(input, -1) {
+			start, end := loc[0], loc[1]
+			if len(loc) > 2 && loc[2] >= 0 {
+				start, end = loc[2], loc[3]
+			}
+			// Trim the separators the patterns may swallow at the end.
+			for end > start && strings.ContainsRune(" -.", rune(input[end-1])) {
+				end--
+			}
+			if end == start || overlaps(findings, start, end) {
+				continue
+			}
+			findings = append(findings, Finding{Kind: p.kind, Value: input[start:end], Start: start, End: end})
+		}
+	}
+	sort.Slice(findings, func(i, j int) bool { return findings[i].Start < findings[j].Start })
+	return findings
+}
+
+func overlaps(findings []Finding, start, end int) bool {
+	for _, f := range findings {
+		if start < f.End && f.Start < end {
+			return true
+		}
+	}
+	return false
+}
+
+// Redact replaces the findings in input according to strategy and returns the
+// sanitized text along with what was replaced.
+func (d *Detector) Redact(input string, strategy Strategy) (string, []Finding) {
+	findings := d.Find(input)
+	if len(findings) == 0 {
+		return input, nil
+	}
+	var b strings.Builder
+	b.Grow(len(input))
+	last := 0
+	for _, f := range findings {
+		b.WriteString(input[last:f.Start])
+		b.WriteString(replacement(f, strategy))
+		last = f.End
+	}
+	b.WriteString(input[last:])
+	return b.String(), findings
+}
+
+func replacement(f Finding, strategy Strategy) string {
+	switch strategy {
+	case StrategyMask:
+		return strings.Repeat("*", len(f.Value))
+	case StrategyHash:
+		sum := sha256.Sum256([]byte(f.Value))
+		return "[" + strings.ToUpper(string(f.Kind)) + ":" + hex.EncodeToString(sum[:6]) + "]"
+	case StrategyRemove:
+		return ""
+	default:
+		return "[" + strings.ToUpper(string(f.Kind)) + "]"
+	}
+}
@@ -0,0 +1,56 @@
+package governor
+
+import (
+	"fmt"
+	"strings"
+)
+
+// splitRulepackRef splits a rulepack reference, an ID optionally pinned to a
+// version as in "governance@v12", into its ID and version.
+func splitRulepackRef(ref string) (id, version string) {
+	id, version, _ = strings.Cut(ref, "@")
+	return id, version
+}
+
+// parseRulepackPins parses Config.RulepackPins into versions keyed by
+// rulepack ID.
+func parseRulepackPins(pins []string) (map[string]string, error) {
+	versions := make(map[string]string, len(pins))
+	for _, pin := range pins {
+		id, version := splitRulepackRef(pin)
+		if id == "" || version == "" {
+			return nil, fmt.Errorf("invalid RulepackPins entry %q: want id@version", pin)
+		}
+		if _, dup := versions[id]; dup {
+			return nil, fmt.Errorf("invalid RulepackPins: %s pinned twice", id)
+		}
+		versions[id] = version
+	}
+	return versions, nil
+}
+
+// key identifies the compiled rules of p in an Evaluator: the pinned
+// reference p was fetched as, or else its ID, so a pinned version and the
+// latest one of a rulepack are compiled side by side.
+func (p *Rulepack) key() string {
+	if p.ref != "" {
+		return p.ref
+	}
+	return p.ID
+}
+
+// pinRulepackRef applies Config.RulepackPins to ref unless it names a
+// version itself.
+func (g *Governor) pinRulepackRef(ref string) string {
+	id, version := splitRulepackRef(ref)
+	if version != "" {
+		return ref
+	}
+	g.mu.RLock()
+	version = g.pins[id]
+	g.mu.RUnlock()
+	if version == "" {
+		return ref
+	}
+	return id + "@" + version
+}
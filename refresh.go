@@ -0,0 +1,87 @@
+package governor
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Refresh events emitted by the rulepack refresher.
+const (
+	EventRulepackRefreshed     EventType = "rulepack_refreshed"
+	EventRulepackRefreshFailed EventType = "rulepack_refresh_failed"
+)
+
+// errNotModified reports a conditional fetch answered with 304 Not Modified.
+var errNotModified = errors.New("rulepack not modified")
+
+// fetchedPack is a rulepack fetched from the control plane as id and the
+// ETag it was served with, which may be empty.
+type fetchedPack struct {
+	id   string
+	pack *Rulepack
+	etag string
+}
+
+// remember records a rulepack fetched into the cache, so the refresher keeps
+// it fresh.
+func (g *Governor) remember(id string, pack *Rulepack, etag string) {
+	g.refreshMu.Lock()
+	if g.fetched == nil {
+		g.fetched = map[string]fetchedPack{}
+	}
+	g.fetched[id] = fetchedPack{id: id, pack: pack, etag: etag}
+	g.refreshMu.Unlock()
+}
+
+// refreshRulepacks re-fetches every rulepack fetched so far once per
+// interval until ctx is done. Rulepacks are fetched conditionally on their
+// ETag, and the cache entries of unchanged ones are renewed, so with an
+// interval shorter than Config.CacheTTL evaluations never wait for a fetch.
+func (g *Governor) refreshRulepacks(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			g.refreshMu.Lock()
+			packs := make([]fetchedPack, 0, len(g.fetched))
+			for _, f := range g.fetched {
+				packs = append(packs, f)
+			}
+			g.refreshMu.Unlock()
+			for _, f := range packs {
+				if ctx.Err() != nil {
+					return
+				}
+				_ = g.refreshRulepack(ctx, f)
+			}
+		}
+	}
+}
+
+// refreshRulepack conditionally re-fetches f. Failures are reported,
+// returned and leave the cached copy to expire as usual.
+func (g *Governor) refreshRulepack(ctx context.Context, f fetchedPack) error {
+	id := f.id
+	pack, etag, _, err := g.fetchRulepackOnce(ctx, id, f.etag)
+	if err == nil {
+		err = g.evaluator.Preload(pack.key(), pack.Rules)
+	}
+	switch {
+	case errors.Is(err, errNotModified):
+		g.cache.Set(id, f.pack)
+		return nil
+	case err != nil:
+		g.events.emit(Event{Type: EventRulepackRefreshFailed, Level: LevelWarn, Message: "rulepack refresh failed", RulepackID: id, Err: err})
+		return err
+	}
+	g.cache.Set(id, pack)
+	g.remember(id, pack, etag)
+	if pack.Version != f.pack.Version || !pack.UpdatedAt.Equal(f.pack.UpdatedAt) {
+		g.events.emit(Event{Type: EventRulepackRefreshed, Level: LevelInfo, Message: "rulepack updated", RulepackID: id, Fields: map[string]any{"version": pack.Version}})
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package governor
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"reflect"
+	"syscall"
+	"time"
+)
+
+// Reload events.
+const (
+	EventConfigReloaded     EventType = "config_reloaded"
+	EventConfigReloadFailed EventType = "config_reload_failed"
+)
+
+// configWatchInterval is how often WatchConfig checks the file for changes.
+const configWatchInterval = 2 * time.Second
+
+// liveSettings lists the Config fields Reload applies without a restart.
+var liveSettings = map[string]bool{
+	"CacheTTL":                true,
+	"LogLevel":                true,
+	"FailurePolicy":           true,
+	"DefaultDecision":         true,
+	"FetchRetries":            true,
+	"SlowEvaluationThreshold": true,
+	"MaxPayloadBytes":         true,
+	"DecisionCacheTTL":        true,
+	"DefaultRulepackID":       true,
+	"RulepackPins":            true,
+}
+
+// ReloadReport lists the changed settings of a reload. Applied settings are
+// live immediately; RequiresRestart settings were ignored and only take
+// effect once a new Governor is constructed.
+type ReloadReport struct {
+	Applied         []string
+	RequiresRestart []string
+}
+
+// Reload applies the safe-to-change settings of cfg to the running Governor.
+// cfg must be a complete configuration, as returned by LoadConfig.
+func (g *Governor) Reload(cfg Config) (ReloadReport, error) {
+	if err := cfg.Validate(); err != nil {
+		return ReloadReport{}, err
+	}
+	level, err := ParseEventLevel(cfg.LogLevel)
+	if err != nil {
+		return ReloadReport{}, err
+	}
+	pins, err := parseRulepackPins(cfg.RulepackPins)
+	if err != nil {
+		return ReloadReport{}, err
+	}
+
+	var report ReloadReport
+	g.mu.Lock()
+	// Compare cfg in the form NewGovernor keeps: an API key from APIKeyFile
+	// or a SecretSource is resolved by the Governor, not read from cfg, and
+	// Merge stores NoFetchRetries as 0.
+	if g.keySource != nil {
+		cfg.APIKey = g.cfg.APIKey
+	}
+	if cfg.FetchRetries == NoFetchRetries {
+		cfg.FetchRetries = 0
+	}
+	current := reflect.ValueOf(g.cfg)
+	next := reflect.ValueOf(cfg)
+	for i := 0; i < current.NumField(); i++ {
+		name := current.Type().Field(i).Name
+		if reflect.DeepEqual(current.Field(i).Interface(), next.Field(i).Interface()) {
+			continue
+		}
+		if liveSettings[name] {
+			report.Applied = append(report.Applied, name)
+		} else {
+			report.RequiresRestart = append(report.RequiresRestart, name)
+		}
+	}
+	g.cfg.CacheTTL = cfg.CacheTTL
+	g.cfg.LogLevel = cfg.LogLevel
+	g.cfg.FailurePolicy = cfg.FailurePolicy
+	g.cfg.DefaultDecision = cfg.DefaultDecision
+	g.cfg.FetchRetries = cfg.FetchRetries
+	g.cfg.SlowEvaluationThreshold = cfg.SlowEvaluationThreshold
+	g.cfg.MaxPayloadBytes = cfg.MaxPayloadBytes
+	g.cfg.DecisionCacheTTL = cfg.DecisionCacheTTL
+	g.cfg.DefaultRulepackID = cfg.DefaultRulepackID
+	g.cfg.RulepackPins = cfg.RulepackPins
+	g.pins = pins
+	g.mu.Unlock()
+
+	g.cache.SetTTL(cfg.CacheTTL)
+	g.evaluator.SetDefaultDecision(cfg.DefaultDecision)
+	g.decisions.SetTTL(cfg.DecisionCacheTTL)
+	g.events.setLevel(level)
+	return report, nil
+}
+
+// WatchConfig reloads configuration whenever the process receives SIGHUP or
+// the file at path changes, until ctx is done. With an empty path only the
+// environment is re-read. Options passed to NewGovernor keep overriding the
+// file. Outcomes are reported as events.
+func (g *Governor) WatchConfig(ctx context.Context, path string) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(hup)
+		ticker := time.NewTicker(configWatchInterval)
+		defer ticker.Stop()
+		lastMod := modTime(path)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+			case <-ticker.C:
+				mod := modTime(path)
+				if mod.Equal(lastMod) {
+					continue
+				}
+				lastMod = mod
+			}
+			g.reloadFrom(path)
+		}
+	}()
+}
+
+func (g *Governor) reloadFrom(path string) {
+	var cfg Config
+	var err error
+	if path != "" {
+		if cfg, err = LoadConfig(path); err == nil {
+			// Options override the file but not the environment, as in
+			// NewGovernor.
+			for _, opt := range g.cfgOptions {
+				opt(&cfg)
+			}
+			err = cfg.ApplyEnv()
+		}
+	} else {
+		cfg = g.Config()
+		err = cfg.ApplyEnv()
+	}
+	if err == nil {
+		var report ReloadReport
+		if report, err = g.Reload(cfg); err == nil {
+			g.events.emit(Event{
+				Type:    EventConfigReloaded,
+				Level:   LevelInfo,
+				Message: "configuration reloaded",
+				Fields:  map[string]any{"applied": report.Applied, "requires_restart": report.RequiresRestart},
+			})
+			return
+		}
+	}
+	g.events.emit(Event{Type: EventConfigReloadFailed, Level: LevelError, Message: "configuration reload failed", Err: err})
+}
+
+func modTime(path string) time.Time {
+	if path == "" {
+		return time.Time{}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
@@ -0,0 +1,92 @@
+package governor
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// ReplayDecision is one side of a replayed decision.
+type ReplayDecision struct {
+	Allowed bool   `json:"allowed"`
+	RuleID  string `json:"rule_id,omitempty"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// ReplayChange is a historical decision that a candidate rulepack decides
+// differently.
+type ReplayChange struct {
+	Time       time.Time       `json:"time"`
+	RulepackID string          `json:"rulepack_id"`
+	Payload    json.RawMessage `json:"payload"`
+	Before     ReplayDecision  `json:"before"`
+	After      ReplayDecision  `json:"after"`
+}
+
+// DecisionChanged reports whether the allow/deny outcome flipped, as opposed
+// to only the deciding rule.
+func (c ReplayChange) DecisionChanged() bool {
+	return c.Before.Allowed != c.After.Allowed
+}
+
+// ReplayReport summarises Replay. NewlyAllowed and NewlyDenied count flipped
+// decisions; RuleChanged counts decisions kept but made by a different rule.
+type ReplayReport struct {
+	Total        int            `json:"total"`
+	Unchanged    int            `json:"unchanged"`
+	NewlyAllowed int            `json:"newly_allowed"`
+	NewlyDenied  int            `json:"newly_denied"`
+	RuleChanged  int            `json:"rule_changed"`
+	Errors       int            `json:"errors"`
+	Changes      []ReplayChange `json:"changes"`
+}
+
+// Replay re-evaluates the audit records matching f against a candidate
+// rulepack and reports which decisions would change. The candidate is
+// compiled separately, so the Governor's live rulepacks are untouched.
+// Records written before rule IDs were audited only compare the decision.
+func (g *Governor) Replay(ctx context.Context, candidate *Rulepack, f AuditFilter) (ReplayReport, error) {
+	report := ReplayReport{Changes: []ReplayChange{}}
+	if err := candidate.Validate(); err != nil {
+		return report, err
+	}
+	records, err := g.QueryAudit(ctx, f)
+	if err != nil {
+		return report, err
+	}
+	evaluator := NewEvaluator()
+	if err := evaluator.Preload(candidate.ID, candidate.Rules); err != nil {
+		return report, err
+	}
+	for _, record := range records {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		report.Total++
+		result, err := evaluator.Decide(ctx, candidate, record.Payload)
+		if err != nil {
+			report.Errors++
+			continue
+		}
+		change := ReplayChange{
+			Time:       record.Time,
+			RulepackID: record.RulepackID,
+			Payload:    record.Payload,
+			Before:     ReplayDecision{Allowed: record.Allowed, RuleID: record.RuleID, Reason: record.Reason},
+			After:      ReplayDecision{Allowed: result.Allowed, RuleID: result.RuleID, Reason: result.Reason},
+		}
+		switch {
+		case change.DecisionChanged() && result.Allowed:
+			report.NewlyAllowed++
+		case change.DecisionChanged():
+			report.NewlyDenied++
+		case record.RuleID != "" && record.RuleID != result.RuleID:
+			report.RuleChanged++
+		default:
+			report.Unchanged++
+			continue
+		}
+		report.Changes = append(report.Changes, change)
+	}
+	return report, nil
+}
@@ -0,0 +1,85 @@
+package governor
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/mfifth/aisentinel-go-sdk/storage"
+)
+
+// Retention events emitted by the audit reaper.
+const (
+	EventAuditReaped     EventType = "audit_reaped"
+	EventAuditReapFailed EventType = "audit_reap_failed"
+)
+
+// reapBatch is how many expired records ReapAudit deletes per DeleteBatch.
+const reapBatch = 256
+
+// auditReapInterval is how often records are reaped for retention: a tenth
+// of it, between a minute and an hour.
+func auditReapInterval(retention time.Duration) time.Duration {
+	return min(max(retention/10, time.Minute), time.Hour)
+}
+
+// reapAuditRecords reaps expired audit records at once and then on an
+// interval until ctx is done.
+func (g *Governor) reapAuditRecords(ctx context.Context, retention time.Duration) {
+	ticker := time.NewTicker(auditReapInterval(retention))
+	defer ticker.Stop()
+	for {
+		deleted, err := g.ReapAudit(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			g.events.emit(Event{Type: EventAuditReapFailed, Level: LevelWarn, Message: "audit retention failed", Err: err, Fields: map[string]any{"deleted": deleted}})
+		} else if deleted > 0 {
+			g.events.emit(Event{Type: EventAuditReaped, Level: LevelInfo, Message: "expired audit records deleted", Fields: map[string]any{"deleted": deleted, "retention": retention.String()}})
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ReapAudit deletes the audit records older than Config.AuditRetention, as
+// the background reaper does, and returns how many it deleted. Usage
+// aggregates and records that are not audit records are kept. With no
+// retention it deletes nothing.
+func (g *Governor) ReapAudit(ctx context.Context) (int, error) {
+	g.mu.RLock()
+	retention := g.cfg.AuditRetention
+	g.mu.RUnlock()
+	if g.storage == nil || retention <= 0 {
+		return 0, nil
+	}
+	cutoff := g.now().Add(-retention)
+	// Keys are collected first, since stores may hold a lock while iterating.
+	var expired []string
+	err := g.storage.Iter(ctx, func(rec storage.Record) error {
+		if strings.HasPrefix(rec.Key, usageKeyPrefix) {
+			return nil
+		}
+		if record, err := decodeAuditRecord(rec); err == nil && !record.Time.IsZero() && record.Time.Before(cutoff) {
+			expired = append(expired, rec.Key)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	deleted := 0
+	for len(expired) > 0 {
+		batch := expired[:min(len(expired), reapBatch)]
+		if err := storage.DeleteBatch(ctx, g.storage, batch); err != nil {
+			return deleted, err
+		}
+		deleted += len(batch)
+		expired = expired[len(batch):]
+	}
+	return deleted, nil
+}
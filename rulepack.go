@@ -0,0 +1,210 @@
+package governor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LoadRulepackFile reads a rulepack from path. Files ending in .yaml or .yml
+// are decoded as YAML and everything else as JSON.
+func LoadRulepackFile(path string) (*Rulepack, error) {
+	var pack Rulepack
+	if err := decodeRulepackFile(path, &pack); err != nil {
+		return nil, err
+	}
+	return &pack, nil
+}
+
+func decodeRulepackFile(path string, v any) error {
+	data, err := os.ReadFile(path) // #nosec G304 -- operator supplied rulepack
+	if err != nil {
+		return err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, v)
+	default:
+		err = json.Unmarshal(data, v)
+	}
+	if err != nil {
+		return fmt.Errorf("decode %s: %w", path, err)
+	}
+	return nil
+}
+
+// Validate checks that the rulepack has an ID and that every rule has a
+// unique ID, a pattern that compiles, an operator with a suitable value or
+// valid conditions, a valid field selector, if any, and a known action that
+// agrees with Allow and Params, and that the match strategy is known. All
+// problems are reported.
+func (p *Rulepack) Validate() error {
+	var errs []error
+	if p.ID == "" {
+		errs = append(errs, errors.New("rulepack id is required"))
+	}
+	if !p.Strategy.Valid() {
+		errs = append(errs, fmt.Errorf("unknown match strategy %q", p.Strategy))
+	}
+	if !p.DefaultDecision.Valid() {
+		errs = append(errs, fmt.Errorf("unknown default decision %q", p.DefaultDecision))
+	}
+	seen := make(map[string]bool, len(p.Rules))
+	for i, rule := range p.Rules {
+		if rule.ID == "" {
+			errs = append(errs, fmt.Errorf("rule %d: id is required", i))
+		} else if seen[rule.ID] {
+			errs = append(errs, fmt.Errorf("rule %s: duplicate id", rule.ID))
+		}
+		seen[rule.ID] = true
+		if err := validateRule(rule); err != nil {
+			errs = append(errs, fmt.Errorf("rule %s: %w", rule.ID, err))
+		}
+		if err := validateAction(rule); err != nil {
+			errs = append(errs, fmt.Errorf("rule %s: %w", rule.ID, err))
+		}
+		if rule.Field != "" {
+			if _, err := ParseFieldPath(rule.Field); err != nil {
+				errs = append(errs, fmt.Errorf("rule %s: %w", rule.ID, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ChangeKind classifies a RuleChange.
+type ChangeKind string
+
+const (
+	ChangeAdded    ChangeKind = "added"
+	ChangeRemoved  ChangeKind = "removed"
+	ChangeModified ChangeKind = "modified"
+)
+
+// RuleChange describes how a rule differs between two rulepacks. Before is nil
+// for added rules and After is nil for removed ones.
+type RuleChange struct {
+	RuleID string
+	Kind   ChangeKind
+	Before *RuleDefinition
+	After  *RuleDefinition
+}
+
+// DiffRulepacks lists the rule changes from a to b. Removed and modified
+// rules are reported in the order of a, followed by added rules in the order
+// of b.
+func DiffRulepacks(a, b *Rulepack) []RuleChange {
+	after := make(map[string]*RuleDefinition, len(b.Rules))
+	for i := range b.Rules {
+		after[b.Rules[i].ID] = &b.Rules[i]
+	}
+	var changes []RuleChange
+	before := make(map[string]bool, len(a.Rules))
+	for i := range a.Rules {
+		rule := &a.Rules[i]
+		before[rule.ID] = true
+		switch other, ok := after[rule.ID]; {
+		case !ok:
+			changes = append(changes, RuleChange{RuleID: rule.ID, Kind: ChangeRemoved, Before: rule})
+		case !reflect.DeepEqual(*other, *rule):
+			changes = append(changes, RuleChange{RuleID: rule.ID, Kind: ChangeModified, Before: rule, After: other})
+		}
+	}
+	for i := range b.Rules {
+		if rule := &b.Rules[i]; !before[rule.ID] {
+			changes = append(changes, RuleChange{RuleID: rule.ID, Kind: ChangeAdded, After: rule})
+		}
+	}
+	return changes
+}
+
+// FetchRulepack downloads a rulepack from the control plane, bypassing the
+// cache. id may pin a version, as in "governance@v12".
+func (g *Governor) FetchRulepack(ctx context.Context, id string) (*Rulepack, error) {
+	if g.offline {
+		return nil, fmt.Errorf("%w: rulepack %s unavailable", ErrOffline, id)
+	}
+	pack, _, err := g.fetchRulepack(ctx, id)
+	return pack, err
+}
+
+// PushRulepack validates pack and uploads it to the control plane. The cached
+// copy, if any, is invalidated so the next evaluation fetches the new version.
+func (g *Governor) PushRulepack(ctx context.Context, pack *Rulepack) error {
+	if err := pack.Validate(); err != nil {
+		return err
+	}
+	if g.offline {
+		return fmt.Errorf("%w: cannot push rulepack %s", ErrOffline, pack.ID)
+	}
+	body, err := json.Marshal(pack)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, g.cfg.operationTimeout(g.cfg.FetchTimeout))
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, fmt.Sprintf("%s/rulepacks/%s", g.cfg.APIBaseURL, pack.ID), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+g.apiKey())
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("push rulepack: unexpected status %d", resp.StatusCode)
+	}
+	g.cache.Invalidate(pack.ID)
+	return nil
+}
+
+// RulepackSummary describes a rulepack available on the control plane.
+type RulepackSummary struct {
+	ID        string    `json:"id"`
+	Version   string    `json:"version,omitempty"`
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+}
+
+// ListRulepacks returns the rulepacks available to the API key, sorted by ID.
+func (g *Governor) ListRulepacks(ctx context.Context) ([]RulepackSummary, error) {
+	if g.offline {
+		return nil, fmt.Errorf("%w: cannot list rulepacks", ErrOffline)
+	}
+	ctx, cancel := context.WithTimeout(ctx, g.cfg.operationTimeout(g.cfg.FetchTimeout))
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, g.cfg.APIBaseURL+"/rulepacks", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+g.apiKey())
+
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("list rulepacks: unexpected status %d", resp.StatusCode)
+	}
+	var packs []RulepackSummary
+	if err := json.NewDecoder(resp.Body).Decode(&packs); err != nil {
+		return nil, fmt.Errorf("list rulepacks: %w", err)
+	}
+	sort.Slice(packs, func(i, j int) bool { return packs[i].ID < packs[j].ID })
+	return packs, nil
+}
@@ -0,0 +1,183 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AWSSecretsManager reads a secret from AWS Secrets Manager using the
+// GetSecretValue API signed with Signature Version 4. Without static
+// credentials it signs with role credentials, so no keys need to be placed in
+// the environment; see Credentials.
+type AWSSecretsManager struct {
+	// Region defaults to AWS_REGION.
+	Region string
+	// SecretID is the secret name or ARN.
+	SecretID string
+	// VersionStage defaults to AWSCURRENT when empty.
+	VersionStage string
+	// Field optionally selects a key when the secret holds a JSON object.
+	Field string
+	// Static credentials. When empty, credentials are resolved like the AWS
+	// SDKs' default chain: AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and
+	// AWS_SESSION_TOKEN; a web identity token from
+	// AWS_WEB_IDENTITY_TOKEN_FILE for AWS_ROLE_ARN, as set up by IRSA on
+	// EKS; the ECS or EKS Pod Identity container credentials endpoint; and
+	// the EC2 instance metadata service (IMDSv2).
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	// Credentials, when set, replaces the static fields and the default
+	// chain, for example to assume a role with the AWS SDK.
+	Credentials func(ctx context.Context) (AWSCredentials, error)
+	// Endpoint overrides the regional endpoint, e.g. for VPC endpoints or tests.
+	Endpoint   string
+	HTTPClient *http.Client
+
+	now func() time.Time
+}
+
+// FetchSecret retrieves the SecretString of the configured secret.
+func (a AWSSecretsManager) FetchSecret(ctx context.Context) (string, error) {
+	region := firstNonEmpty(a.Region, os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION"))
+	if region == "" || a.SecretID == "" {
+		return "", fmt.Errorf("aws secrets manager: region and secret id are required")
+	}
+	credentials := a.Credentials
+	if credentials == nil {
+		credentials = func(ctx context.Context) (AWSCredentials, error) { return a.defaultCredentials(ctx, region) }
+	}
+	creds, err := credentials(ctx)
+	if err != nil {
+		return "", fmt.Errorf("aws secrets manager: credentials: %w", err)
+	}
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return "", fmt.Errorf("aws secrets manager: credentials are required")
+	}
+	endpoint := a.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://secretsmanager.%s.amazonaws.com", region)
+	}
+
+	input := map[string]string{"SecretId": a.SecretID}
+	if a.VersionStage != "" {
+		input["VersionStage"] = a.VersionStage
+	}
+	payload, err := json.Marshal(input)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(endpoint, "/")+"/", bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+	now := time.Now
+	if a.now != nil {
+		now = a.now
+	}
+	signV4(req, payload, creds.AccessKeyID, creds.SecretAccessKey, region, "secretsmanager", now().UTC())
+
+	var body struct {
+		SecretString string `json:"SecretString"`
+	}
+	if err := doJSON(a.HTTPClient, req, &body); err != nil {
+		return "", fmt.Errorf("aws secrets manager: %w", err)
+	}
+	return extractField(body.SecretString, a.Field)
+}
+
+// signV4 adds AWS Signature Version 4 headers to req.
+func signV4(req *http.Request, payload []byte, accessKey, secretKey, region, service string, t time.Time) {
+	amzDate := t.Format("20060102T150405Z")
+	date := t.Format("20060102")
+	payloadHash := sha256Hex(payload)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("Host", req.URL.Host)
+
+	headers := make([]string, 0, len(req.Header))
+	for name := range req.Header {
+		headers = append(headers, strings.ToLower(name))
+	}
+	sort.Strings(headers)
+	var canonicalHeaders strings.Builder
+	for _, name := range headers {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(req.Header.Get(name)) + "\n")
+	}
+	signedHeaders := strings.Join(headers, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKey, scope, signedHeaders, signature))
+}
+
+func canonicalQuery(values url.Values) string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		for _, v := range values[k] {
+			parts = append(parts, url.QueryEscape(k)+"="+url.QueryEscape(v))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
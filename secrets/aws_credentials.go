@@ -0,0 +1,198 @@
+package secrets
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Endpoints of the AWS credential providers, variables so tests can point
+// them at local servers.
+var (
+	awsSTSEndpoint   = "https://sts.%s.amazonaws.com"
+	awsContainerHost = "http://169.254.170.2"
+	awsIMDSEndpoint  = "http://169.254.169.254"
+)
+
+// AWSCredentials sign AWS requests. SessionToken is set for temporary
+// credentials, such as those of an assumed role.
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// defaultCredentials resolves credentials like the AWS SDKs' default chain,
+// minus shared config files: static fields, then AWS_ACCESS_KEY_ID and
+// AWS_SECRET_ACCESS_KEY, then a web identity token (IRSA on EKS), then the
+// ECS or EKS Pod Identity container endpoint, then the EC2 instance metadata
+// service. Role credentials are fetched on every call; FetchSecret runs once
+// per refresh interval, well within their lifetime.
+func (a AWSSecretsManager) defaultCredentials(ctx context.Context, region string) (AWSCredentials, error) {
+	if a.AccessKeyID != "" || a.SecretAccessKey != "" {
+		return AWSCredentials{AccessKeyID: a.AccessKeyID, SecretAccessKey: a.SecretAccessKey, SessionToken: a.SessionToken}, nil
+	}
+	if key := os.Getenv("AWS_ACCESS_KEY_ID"); key != "" {
+		return AWSCredentials{AccessKeyID: key, SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"), SessionToken: os.Getenv("AWS_SESSION_TOKEN")}, nil
+	}
+	if tokenFile := os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE"); tokenFile != "" {
+		return a.webIdentityCredentials(ctx, region, tokenFile)
+	}
+	if os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI") != "" || os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI") != "" {
+		return a.containerCredentials(ctx)
+	}
+	if strings.EqualFold(os.Getenv("AWS_EC2_METADATA_DISABLED"), "true") {
+		return AWSCredentials{}, fmt.Errorf("no credentials found")
+	}
+	creds, err := a.instanceCredentials(ctx)
+	if err != nil {
+		return AWSCredentials{}, fmt.Errorf("no credentials found; instance metadata: %w", err)
+	}
+	return creds, nil
+}
+
+// webIdentityCredentials assumes AWS_ROLE_ARN with the token in tokenFile
+// through STS AssumeRoleWithWebIdentity, which needs no signature.
+func (a AWSSecretsManager) webIdentityCredentials(ctx context.Context, region, tokenFile string) (AWSCredentials, error) {
+	token, err := File{Path: tokenFile}.FetchSecret(ctx)
+	if err != nil {
+		return AWSCredentials{}, fmt.Errorf("web identity: %w", err)
+	}
+	roleARN := os.Getenv("AWS_ROLE_ARN")
+	if roleARN == "" {
+		return AWSCredentials{}, fmt.Errorf("web identity: AWS_ROLE_ARN is required with AWS_WEB_IDENTITY_TOKEN_FILE")
+	}
+	form := url.Values{
+		"Action":           {"AssumeRoleWithWebIdentity"},
+		"Version":          {"2011-06-15"},
+		"RoleArn":          {roleARN},
+		"RoleSessionName":  {firstNonEmpty(os.Getenv("AWS_ROLE_SESSION_NAME"), "aisentinel-go-sdk")},
+		"WebIdentityToken": {token},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf(awsSTSEndpoint, region)+"/", strings.NewReader(form.Encode()))
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := httpClient(a.HTTPClient).Do(req)
+	if err != nil {
+		return AWSCredentials{}, fmt.Errorf("web identity: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return AWSCredentials{}, fmt.Errorf("web identity: unexpected status %d: %s", resp.StatusCode, body)
+	}
+	var body struct {
+		Credentials struct {
+			AccessKeyID     string `xml:"AccessKeyId"`
+			SecretAccessKey string `xml:"SecretAccessKey"`
+			SessionToken    string `xml:"SessionToken"`
+		} `xml:"AssumeRoleWithWebIdentityResult>Credentials"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return AWSCredentials{}, fmt.Errorf("web identity: %w", err)
+	}
+	creds := AWSCredentials(body.Credentials)
+	if creds.AccessKeyID == "" {
+		return AWSCredentials{}, fmt.Errorf("web identity: no credentials in response")
+	}
+	return creds, nil
+}
+
+// containerCredentials reads the credentials of an ECS task role, or of an
+// EKS Pod Identity association, from the container credentials endpoint.
+func (a AWSSecretsManager) containerCredentials(ctx context.Context) (AWSCredentials, error) {
+	endpoint := os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI")
+	if uri := os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI"); uri != "" {
+		endpoint = awsContainerHost + uri
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+	token := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN")
+	if tokenFile := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN_FILE"); tokenFile != "" {
+		if token, err = (File{Path: tokenFile}).FetchSecret(ctx); err != nil {
+			return AWSCredentials{}, fmt.Errorf("container credentials: %w", err)
+		}
+	}
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+	return a.roleCredentials(req, "container credentials")
+}
+
+// instanceCredentials reads the credentials of the EC2 instance profile from
+// the instance metadata service, using an IMDSv2 session token.
+func (a AWSSecretsManager) instanceCredentials(ctx context.Context) (AWSCredentials, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, awsIMDSEndpoint+"/latest/api/token", nil)
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+	req.Header.Set("X-Aws-Ec2-Metadata-Token-Ttl-Seconds", "21600")
+	token, err := readText(a.HTTPClient, req)
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+	const credentialsPath = "/latest/meta-data/iam/security-credentials/"
+	req, err = http.NewRequestWithContext(ctx, http.MethodGet, awsIMDSEndpoint+credentialsPath, nil)
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+	req.Header.Set("X-Aws-Ec2-Metadata-Token", token)
+	roles, err := readText(a.HTTPClient, req)
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+	role, _, _ := strings.Cut(roles, "\n")
+	if role == "" {
+		return AWSCredentials{}, fmt.Errorf("no instance profile role")
+	}
+	req, err = http.NewRequestWithContext(ctx, http.MethodGet, awsIMDSEndpoint+credentialsPath+url.PathEscape(role), nil)
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+	req.Header.Set("X-Aws-Ec2-Metadata-Token", token)
+	return a.roleCredentials(req, "instance metadata")
+}
+
+// roleCredentials decodes the JSON credentials served by the container and
+// instance metadata endpoints.
+func (a AWSSecretsManager) roleCredentials(req *http.Request, provider string) (AWSCredentials, error) {
+	var body struct {
+		AccessKeyID     string `json:"AccessKeyId"`
+		SecretAccessKey string `json:"SecretAccessKey"`
+		Token           string `json:"Token"`
+	}
+	if err := doJSON(a.HTTPClient, req, &body); err != nil {
+		return AWSCredentials{}, fmt.Errorf("%s: %w", provider, err)
+	}
+	if body.AccessKeyID == "" || body.SecretAccessKey == "" {
+		return AWSCredentials{}, fmt.Errorf("%s: no credentials in response", provider)
+	}
+	return AWSCredentials{AccessKeyID: body.AccessKeyID, SecretAccessKey: body.SecretAccessKey, SessionToken: body.Token}, nil
+}
+
+// readText executes req and returns the trimmed body of a successful
+// response.
+func readText(client *http.Client, req *http.Request) (string, error) {
+	resp, err := httpClient(client).Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %d: %s", resp.StatusCode, body)
+	}
+	return strings.TrimSpace(string(body)), nil
+}
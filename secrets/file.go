@@ -0,0 +1,28 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// File reads a secret from a file such as a mounted Kubernetes secret.
+// Surrounding whitespace, including the trailing newline most tools write, is
+// trimmed. The file is re-read on every fetch so rotations are picked up.
+type File struct {
+	Path string
+}
+
+// FetchSecret returns the trimmed file contents.
+func (f File) FetchSecret(_ context.Context) (string, error) {
+	data, err := os.ReadFile(f.Path) // #nosec G304 -- the path is operator supplied configuration
+	if err != nil {
+		return "", fmt.Errorf("read secret file: %w", err)
+	}
+	secret := strings.TrimSpace(string(data))
+	if secret == "" {
+		return "", fmt.Errorf("secret file %s is empty", f.Path)
+	}
+	return secret, nil
+}
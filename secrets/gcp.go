@@ -0,0 +1,90 @@
+package secrets
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+const (
+	gcpSecretManagerURL = "https://secretmanager.googleapis.com"
+	gcpMetadataTokenURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+)
+
+// GCPSecretManager reads a secret version from Google Cloud Secret Manager.
+type GCPSecretManager struct {
+	Project string
+	Secret  string
+	// Version defaults to "latest".
+	Version string
+	// Field optionally selects a key when the secret holds a JSON object.
+	Field string
+	// TokenSource returns an OAuth2 access token. When nil the token of the
+	// default service account is read from the GCE metadata server.
+	TokenSource func(ctx context.Context) (string, error)
+	// Endpoint overrides the Secret Manager API endpoint, mainly for tests.
+	Endpoint   string
+	HTTPClient *http.Client
+}
+
+// FetchSecret accesses the configured secret version.
+func (g GCPSecretManager) FetchSecret(ctx context.Context) (string, error) {
+	if g.Project == "" || g.Secret == "" {
+		return "", fmt.Errorf("gcp secret manager: project and secret are required")
+	}
+	version := g.Version
+	if version == "" {
+		version = "latest"
+	}
+	endpoint := g.Endpoint
+	if endpoint == "" {
+		endpoint = gcpSecretManagerURL
+	}
+	tokenSource := g.TokenSource
+	if tokenSource == nil {
+		tokenSource = g.metadataToken
+	}
+	token, err := tokenSource(ctx)
+	if err != nil {
+		return "", fmt.Errorf("gcp secret manager: token: %w", err)
+	}
+
+	u := fmt.Sprintf("%s/v1/projects/%s/secrets/%s/versions/%s:access", endpoint,
+		url.PathEscape(g.Project), url.PathEscape(g.Secret), url.PathEscape(version))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	var body struct {
+		Payload struct {
+			Data string `json:"data"`
+		} `json:"payload"`
+	}
+	if err := doJSON(g.HTTPClient, req, &body); err != nil {
+		return "", fmt.Errorf("gcp secret manager: %w", err)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(body.Payload.Data)
+	if err != nil {
+		return "", fmt.Errorf("gcp secret manager: decode payload: %w", err)
+	}
+	return extractField(string(decoded), g.Field)
+}
+
+func (g GCPSecretManager) metadataToken(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, gcpMetadataTokenURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	var body struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := doJSON(g.HTTPClient, req, &body); err != nil {
+		return "", err
+	}
+	return body.AccessToken, nil
+}
@@ -0,0 +1,48 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrKeyringNotFound is returned by Keyring when no secret is stored for the
+// service and account.
+var ErrKeyringNotFound = errors.New("secrets: no keyring entry")
+
+// ErrKeyringUnsupported is returned on platforms without a supported keyring.
+var ErrKeyringUnsupported = errors.New("secrets: keyring not supported on this platform")
+
+// Keyring reads a secret from the operating system keyring: the macOS
+// Keychain, the Windows Credential Manager or the freedesktop Secret Service
+// (GNOME Keyring, KWallet) through secret-tool.
+type Keyring struct {
+	Service string
+	Account string
+}
+
+// FetchSecret returns the stored secret.
+func (k Keyring) FetchSecret(ctx context.Context) (string, error) {
+	secret, err := keyringGet(ctx, k.Service, k.Account)
+	if err != nil {
+		return "", err
+	}
+	if secret == "" {
+		return "", ErrKeyringNotFound
+	}
+	return secret, nil
+}
+
+// Set stores secret, replacing any existing entry.
+func (k Keyring) Set(ctx context.Context, secret string) error {
+	if secret == "" {
+		return fmt.Errorf("secrets: refusing to store an empty secret")
+	}
+	return keyringSet(ctx, k.Service, k.Account, secret)
+}
+
+// Delete removes the entry. Deleting a missing entry returns
+// ErrKeyringNotFound.
+func (k Keyring) Delete(ctx context.Context) error {
+	return keyringDelete(ctx, k.Service, k.Account)
+}
@@ -0,0 +1,58 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// The Keychain is driven through security(1). Commands that carry the secret
+// are written to "security -i" on stdin so the secret never appears in the
+// process list.
+
+// errSecItemNotFound is the exit status of security(1) for missing items.
+const errSecItemNotFound = 44
+
+func keyringGet(ctx context.Context, service, account string) (string, error) {
+	out, err := exec.CommandContext(ctx, "security", "find-generic-password", "-s", service, "-a", account, "-w").Output()
+	if err != nil {
+		return "", keychainError(err)
+	}
+	return strings.TrimRight(string(out), "\n"), nil
+}
+
+func keyringSet(ctx context.Context, service, account, secret string) error {
+	cmd := exec.CommandContext(ctx, "security", "-i")
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s %s -a %s -w %s\n", shellQuote(service), shellQuote(account), shellQuote(secret)))
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("security add-generic-password: %w: %s", err, msg)
+		}
+		return fmt.Errorf("security add-generic-password: %w", err)
+	}
+	return nil
+}
+
+func keyringDelete(ctx context.Context, service, account string) error {
+	if err := exec.CommandContext(ctx, "security", "delete-generic-password", "-s", service, "-a", account).Run(); err != nil {
+		return keychainError(err)
+	}
+	return nil
+}
+
+func keychainError(err error) error {
+	var exit *exec.ExitError
+	if errors.As(err, &exit) && exit.ExitCode() == errSecItemNotFound {
+		return ErrKeyringNotFound
+	}
+	return fmt.Errorf("security: %w", err)
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,17 @@
+//go:build !darwin && !windows && !linux && !freebsd && !openbsd && !netbsd && !dragonfly
+
+package secrets
+
+import "context"
+
+func keyringGet(context.Context, string, string) (string, error) {
+	return "", ErrKeyringUnsupported
+}
+
+func keyringSet(context.Context, string, string, string) error {
+	return ErrKeyringUnsupported
+}
+
+func keyringDelete(context.Context, string, string) error {
+	return ErrKeyringUnsupported
+}
@@ -0,0 +1,60 @@
+//go:build linux || freebsd || openbsd || netbsd || dragonfly
+
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// The Secret Service is driven through secret-tool(1) from libsecret, which
+// reads the secret to store from stdin.
+
+func keyringGet(ctx context.Context, service, account string) (string, error) {
+	out, err := exec.CommandContext(ctx, "secret-tool", "lookup", "service", service, "account", account).Output()
+	if err != nil {
+		return "", secretToolError(err)
+	}
+	return strings.TrimRight(string(out), "\n"), nil
+}
+
+func keyringSet(ctx context.Context, service, account, secret string) error {
+	cmd := exec.CommandContext(ctx, "secret-tool", "store", "--label", service+" ("+account+")", "service", service, "account", account)
+	cmd.Stdin = strings.NewReader(secret)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("secret-tool store: %w: %s", secretToolError(err), msg)
+		}
+		return fmt.Errorf("secret-tool store: %w", secretToolError(err))
+	}
+	return nil
+}
+
+func keyringDelete(ctx context.Context, service, account string) error {
+	if _, err := keyringGet(ctx, service, account); err != nil {
+		return err
+	}
+	if err := exec.CommandContext(ctx, "secret-tool", "clear", "service", service, "account", account).Run(); err != nil {
+		return secretToolError(err)
+	}
+	return nil
+}
+
+// secretToolError maps a missing binary to ErrKeyringUnsupported and the
+// lookup exit status 1 to ErrKeyringNotFound.
+func secretToolError(err error) error {
+	if errors.Is(err, exec.ErrNotFound) {
+		return fmt.Errorf("%w: secret-tool not installed", ErrKeyringUnsupported)
+	}
+	var exit *exec.ExitError
+	if errors.As(err, &exit) && exit.ExitCode() == 1 {
+		return ErrKeyringNotFound
+	}
+	return fmt.Errorf("secret-tool: %w", err)
+}
@@ -0,0 +1,53 @@
+// Package secrets provides SecretSource implementations backed by common
+// secret managers. Each source speaks the provider's REST API directly so the
+// SDK does not pull in vendor client libraries.
+package secrets
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// defaultClient is used when a source does not configure its own client.
+var defaultClient = &http.Client{Timeout: 10 * time.Second}
+
+func httpClient(c *http.Client) *http.Client {
+	if c != nil {
+		return c
+	}
+	return defaultClient
+}
+
+// doJSON executes req and decodes a successful JSON response into out.
+func doJSON(client *http.Client, req *http.Request, out any) error {
+	resp, err := httpClient(client).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, body)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// extractField returns field from a JSON object encoded in value. An empty
+// field returns value unchanged.
+func extractField(value, field string) (string, error) {
+	if field == "" {
+		return value, nil
+	}
+	var doc map[string]any
+	if err := json.Unmarshal([]byte(value), &doc); err != nil {
+		return "", fmt.Errorf("secret is not a JSON object: %w", err)
+	}
+	v, ok := doc[field].(string)
+	if !ok {
+		return "", fmt.Errorf("secret field %q missing or not a string", field)
+	}
+	return v, nil
+}
@@ -0,0 +1,178 @@
+package secrets
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVaultKVv2(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root" || r.URL.Path != "/v1/secret/data/aisentinel" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"data": map[string]any{"api_key": "vault-key"}}})
+	}))
+	t.Cleanup(srv.Close)
+
+	key, err := Vault{Address: srv.URL, Token: "root", Path: "secret/data/aisentinel"}.FetchSecret(context.Background())
+	if err != nil || key != "vault-key" {
+		t.Fatalf("unexpected vault result: %q %v", key, err)
+	}
+}
+
+func TestGCPSecretManager(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tok" || !strings.HasSuffix(r.URL.Path, "/secrets/api/versions/latest:access") {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		data := base64.StdEncoding.EncodeToString([]byte(`{"api_key":"gcp-key"}`))
+		_ = json.NewEncoder(w).Encode(map[string]any{"payload": map[string]string{"data": data}})
+	}))
+	t.Cleanup(srv.Close)
+
+	source := GCPSecretManager{
+		Project:     "proj",
+		Secret:      "api",
+		Field:       "api_key",
+		Endpoint:    srv.URL,
+		TokenSource: func(context.Context) (string, error) { return "tok", nil },
+	}
+	key, err := source.FetchSecret(context.Background())
+	if err != nil || key != "gcp-key" {
+		t.Fatalf("unexpected gcp result: %q %v", key, err)
+	}
+}
+
+// TestSignV4 checks the signer against the get-vanilla case of the AWS
+// Signature Version 4 test suite.
+func TestSignV4(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signV4(req, nil, "AKIDEXAMPLE", "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "us-east-1", "service", time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, " +
+		"Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Fatalf("expected %s, got %s", want, got)
+	}
+}
+
+func TestAWSSecretsManagerSignsRequest(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/") || !strings.Contains(auth, "/us-east-1/secretsmanager/aws4_request") {
+			http.Error(w, "bad signature", http.StatusForbidden)
+			return
+		}
+		if r.Header.Get("X-Amz-Target") != "secretsmanager.GetSecretValue" {
+			http.Error(w, "bad target", http.StatusBadRequest)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"SecretString": "aws-key"})
+	}))
+	t.Cleanup(srv.Close)
+
+	source := AWSSecretsManager{Region: "us-east-1", SecretID: "aisentinel", AccessKeyID: "AKID", SecretAccessKey: "secret", Endpoint: srv.URL}
+	key, err := source.FetchSecret(context.Background())
+	if err != nil || key != "aws-key" {
+		t.Fatalf("unexpected aws result: %q %v", key, err)
+	}
+}
+
+func TestAWSSecretsManagerRoleCredentials(t *testing.T) {
+	role := func(w http.ResponseWriter) {
+		_ = json.NewEncoder(w).Encode(map[string]string{"AccessKeyId": "ROLEKEY", "SecretAccessKey": "role-secret", "Token": "role-token"})
+	}
+	var imdsToken string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sts/us-east-1/":
+			_ = r.ParseForm()
+			if r.PostForm.Get("Action") != "AssumeRoleWithWebIdentity" || r.PostForm.Get("WebIdentityToken") != "jwt" || r.PostForm.Get("RoleArn") != "arn:aws:iam::1:role/app" {
+				http.Error(w, "bad request", http.StatusBadRequest)
+				return
+			}
+			_, _ = w.Write([]byte(`<AssumeRoleWithWebIdentityResponse><AssumeRoleWithWebIdentityResult><Credentials>` +
+				`<AccessKeyId>ROLEKEY</AccessKeyId><SecretAccessKey>role-secret</SecretAccessKey><SessionToken>role-token</SessionToken>` +
+				`</Credentials></AssumeRoleWithWebIdentityResult></AssumeRoleWithWebIdentityResponse>`))
+		case "/ecs/creds":
+			if r.Header.Get("Authorization") != "ecs-auth" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			role(w)
+		case "/latest/api/token":
+			if r.Method != http.MethodPut || r.Header.Get("X-Aws-Ec2-Metadata-Token-Ttl-Seconds") == "" {
+				http.Error(w, "IMDSv2 only", http.StatusForbidden)
+				return
+			}
+			imdsToken = "imds-token"
+			_, _ = w.Write([]byte(imdsToken))
+		case "/latest/meta-data/iam/security-credentials/", "/latest/meta-data/iam/security-credentials/app":
+			if imdsToken == "" || r.Header.Get("X-Aws-Ec2-Metadata-Token") != imdsToken {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			if strings.HasSuffix(r.URL.Path, "/") {
+				_, _ = w.Write([]byte("app\n"))
+				return
+			}
+			role(w)
+		case "/":
+			auth := r.Header.Get("Authorization")
+			if !strings.Contains(auth, "Credential=ROLEKEY/") || !strings.Contains(auth, "x-amz-security-token") || r.Header.Get("X-Amz-Security-Token") != "role-token" {
+				http.Error(w, "bad signature", http.StatusForbidden)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]string{"SecretString": "aws-key"})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	oldSTS, oldIMDS := awsSTSEndpoint, awsIMDSEndpoint
+	awsSTSEndpoint, awsIMDSEndpoint = srv.URL+"/sts/%s", srv.URL
+	t.Cleanup(func() { awsSTSEndpoint, awsIMDSEndpoint = oldSTS, oldIMDS })
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("jwt\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for name, env := range map[string]map[string]string{
+		"web identity": {"AWS_WEB_IDENTITY_TOKEN_FILE": tokenFile, "AWS_ROLE_ARN": "arn:aws:iam::1:role/app"},
+		"container":    {"AWS_CONTAINER_CREDENTIALS_FULL_URI": srv.URL + "/ecs/creds", "AWS_CONTAINER_AUTHORIZATION_TOKEN": "ecs-auth"},
+		"instance":     {},
+	} {
+		t.Run(name, func(t *testing.T) {
+			for _, key := range []string{
+				"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN", "AWS_WEB_IDENTITY_TOKEN_FILE", "AWS_ROLE_ARN",
+				"AWS_CONTAINER_CREDENTIALS_RELATIVE_URI", "AWS_CONTAINER_CREDENTIALS_FULL_URI", "AWS_CONTAINER_AUTHORIZATION_TOKEN",
+				"AWS_CONTAINER_AUTHORIZATION_TOKEN_FILE", "AWS_EC2_METADATA_DISABLED",
+			} {
+				t.Setenv(key, env[key])
+			}
+			source := AWSSecretsManager{Region: "us-east-1", SecretID: "aisentinel", Endpoint: srv.URL}
+			key, err := source.FetchSecret(context.Background())
+			if err != nil || key != "aws-key" {
+				t.Fatalf("unexpected aws result: %q %v", key, err)
+			}
+		})
+	}
+
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+	t.Setenv("AWS_CONTAINER_CREDENTIALS_FULL_URI", "")
+	if _, err := (AWSSecretsManager{Region: "us-east-1", SecretID: "aisentinel", Endpoint: srv.URL}).FetchSecret(context.Background()); err == nil || !strings.Contains(err.Error(), "no credentials found") {
+		t.Fatalf("expected missing credentials reported, got %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Vault reads a secret from HashiCorp Vault's KV engine (v1 or v2).
+type Vault struct {
+	// Address of the Vault server; defaults to VAULT_ADDR.
+	Address string
+	// Token used for authentication; defaults to VAULT_TOKEN.
+	Token string
+	// Namespace is sent as X-Vault-Namespace when set (Vault Enterprise).
+	Namespace string
+	// Path is the secret path including the mount, e.g. "secret/data/aisentinel".
+	Path string
+	// Field selects the key inside the secret; defaults to "api_key".
+	Field      string
+	HTTPClient *http.Client
+}
+
+// FetchSecret reads the configured field from Vault.
+func (v Vault) FetchSecret(ctx context.Context) (string, error) {
+	address := v.Address
+	if address == "" {
+		address = os.Getenv("VAULT_ADDR")
+	}
+	token := v.Token
+	if token == "" {
+		token = os.Getenv("VAULT_TOKEN")
+	}
+	if address == "" || v.Path == "" {
+		return "", fmt.Errorf("vault: address and path are required")
+	}
+	field := v.Field
+	if field == "" {
+		field = "api_key"
+	}
+
+	url := strings.TrimSuffix(address, "/") + "/v1/" + strings.TrimPrefix(v.Path, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if v.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.Namespace)
+	}
+
+	var body struct {
+		Data map[string]any `json:"data"`
+	}
+	if err := doJSON(v.HTTPClient, req, &body); err != nil {
+		return "", fmt.Errorf("vault: %w", err)
+	}
+	data := body.Data
+	// KV v2 nests the secret under data.data.
+	if nested, ok := data["data"].(map[string]any); ok {
+		data = nested
+	}
+	value, ok := data[field].(string)
+	if !ok {
+		return "", fmt.Errorf("vault: field %q missing or not a string", field)
+	}
+	return value, nil
+}
@@ -0,0 +1,260 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	governor "github.com/mfifth/aisentinel-go-sdk"
+)
+
+// AuthZEN Authorization API paths.
+const (
+	authzenEvaluationPath  = "/access/v1/evaluation"
+	authzenEvaluationsPath = "/access/v1/evaluations"
+	authzenMetadataPath    = "/.well-known/authzen-configuration"
+)
+
+// Semantics of a batch of AuthZEN evaluations.
+const (
+	executeAll          = "execute_all"
+	denyOnFirstDeny     = "deny_on_first_deny"
+	permitOnFirstPermit = "permit_on_first_permit"
+)
+
+// authzenEntity is an AuthZEN subject or resource.
+type authzenEntity struct {
+	Type       string         `json:"type"`
+	ID         string         `json:"id"`
+	Properties map[string]any `json:"properties,omitempty"`
+}
+
+// authzenAction is an AuthZEN action.
+type authzenAction struct {
+	Name       string         `json:"name"`
+	Properties map[string]any `json:"properties,omitempty"`
+}
+
+// authzenRequest is the wire format of an access evaluation. In a batch every
+// field is optional and falls back to the top-level request.
+type authzenRequest struct {
+	Subject  *authzenEntity `json:"subject,omitempty"`
+	Action   *authzenAction `json:"action,omitempty"`
+	Resource *authzenEntity `json:"resource,omitempty"`
+	Context  map[string]any `json:"context,omitempty"`
+}
+
+type authzenBatchRequest struct {
+	authzenRequest
+	Evaluations []authzenRequest `json:"evaluations"`
+	Options     struct {
+		Semantic string `json:"evaluations_semantic"`
+	} `json:"options"`
+}
+
+type authzenResponse struct {
+	Decision bool           `json:"decision"`
+	Context  map[string]any `json:"context,omitempty"`
+}
+
+// handleAuthZENEvaluation serves the OpenID AuthZEN access evaluation API.
+// The subject, action and resource are payload objects under their names, for
+// selectors such as subject.properties.department, and are also flattened to
+// string fields for rules named after them: "subject.type", "subject.id",
+// "action.name", "resource.type", "resource.id", and one
+// "<entity>.properties.<name>" per string property. Top-level context fields
+// are added as they are, so prompt rules apply unchanged, except the names
+// subject, action and resource and names in their namespaces, which would let
+// callers forge those fields. The subject ID is the usage Subject and the
+// rulepack is Options.AuthZENRulepackID.
+func (s *Server) handleAuthZENEvaluation(w http.ResponseWriter, r *http.Request) {
+	if !s.authzenPreamble(w, r) {
+		return
+	}
+	defer func() { <-s.sem }()
+
+	var req authzenRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, s.opts.MaxBodyBytes)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request: "+err.Error())
+		return
+	}
+	resp, err := s.authzenEvaluate(r, req)
+	if err != nil {
+		writeAuthZENError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleAuthZENEvaluations serves the batch access evaluations API.
+func (s *Server) handleAuthZENEvaluations(w http.ResponseWriter, r *http.Request) {
+	if !s.authzenPreamble(w, r) {
+		return
+	}
+	defer func() { <-s.sem }()
+
+	var req authzenBatchRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, s.opts.MaxBodyBytes)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request: "+err.Error())
+		return
+	}
+	semantic := req.Options.Semantic
+	switch semantic {
+	case "":
+		semantic = executeAll
+	case executeAll, denyOnFirstDeny, permitOnFirstPermit:
+	default:
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("unknown evaluations_semantic %q", semantic))
+		return
+	}
+	if len(req.Evaluations) == 0 {
+		resp, err := s.authzenEvaluate(r, req.authzenRequest)
+		if err != nil {
+			writeAuthZENError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, resp)
+		return
+	}
+
+	results := make([]authzenResponse, 0, len(req.Evaluations))
+	for _, eval := range req.Evaluations {
+		resp, err := s.authzenEvaluate(r, eval.withDefaults(req.authzenRequest))
+		if err != nil {
+			writeAuthZENError(w, err)
+			return
+		}
+		results = append(results, resp)
+		if (semantic == denyOnFirstDeny && !resp.Decision) || (semantic == permitOnFirstPermit && resp.Decision) {
+			break
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string][]authzenResponse{"evaluations": results})
+}
+
+// handleAuthZENMetadata serves the PDP metadata document.
+func (s *Server) handleAuthZENMetadata(w http.ResponseWriter, r *http.Request) {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	base := scheme + "://" + r.Host
+	writeJSON(w, http.StatusOK, map[string]string{
+		"policy_decision_point":       base,
+		"access_evaluation_endpoint":  base + authzenEvaluationPath,
+		"access_evaluations_endpoint": base + authzenEvaluationsPath,
+	})
+}
+
+// authzenPreamble checks the method, echoes X-Request-ID and takes an
+// evaluation slot. It reports false when it has already responded; otherwise
+// the caller must release the slot.
+func (s *Server) authzenPreamble(w http.ResponseWriter, r *http.Request) bool {
+	if id := r.Header.Get("X-Request-ID"); id != "" {
+		w.Header().Set("X-Request-ID", id)
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return false
+	}
+	select {
+	case s.sem <- struct{}{}:
+		return true
+	default:
+		writeError(w, http.StatusTooManyRequests, "too many concurrent evaluations")
+		return false
+	}
+}
+
+// errIncompleteAuthZEN reports an evaluation without subject, action or
+// resource.
+var errIncompleteAuthZEN = errors.New("subject, action and resource are required")
+
+func (s *Server) authzenEvaluate(r *http.Request, req authzenRequest) (authzenResponse, error) {
+	if req.Subject == nil || req.Action == nil || req.Resource == nil {
+		return authzenResponse{}, errIncompleteAuthZEN
+	}
+	payload, err := json.Marshal(req.payload())
+	if err != nil {
+		return authzenResponse{}, err
+	}
+	result, err := s.gov.Evaluate(r.Context(), governor.DecisionRequest{
+		RulepackID: s.opts.AuthZENRulepackID,
+		Payload:    payload,
+		Subject:    req.Subject.ID,
+	})
+	if err != nil {
+		return authzenResponse{}, err
+	}
+	resp := authzenResponse{Decision: result.Allowed, Context: map[string]any{"reason": result.Reason}}
+	if result.RuleID != "" {
+		resp.Context["rule_id"] = result.RuleID
+	}
+	return resp, nil
+}
+
+// withDefaults fills the fields missing from a batch entry from the
+// top-level request.
+func (req authzenRequest) withDefaults(defaults authzenRequest) authzenRequest {
+	if req.Subject == nil {
+		req.Subject = defaults.Subject
+	}
+	if req.Action == nil {
+		req.Action = defaults.Action
+	}
+	if req.Resource == nil {
+		req.Resource = defaults.Resource
+	}
+	if req.Context == nil {
+		req.Context = defaults.Context
+	}
+	return req
+}
+
+// payload turns the request into payload fields.
+func (req authzenRequest) payload() map[string]any {
+	fields := make(map[string]any, len(req.Context)+9)
+	for k, v := range req.Context {
+		if !reservedAuthZENField(k) {
+			fields[k] = v
+		}
+	}
+	set := func(prefix string, properties map[string]any) {
+		for k, v := range properties {
+			if s, ok := v.(string); ok {
+				fields[prefix+".properties."+k] = s
+			}
+		}
+	}
+	fields["subject"], fields["action"], fields["resource"] = req.Subject, req.Action, req.Resource
+	fields["subject.type"], fields["subject.id"] = req.Subject.Type, req.Subject.ID
+	set("subject", req.Subject.Properties)
+	fields["action.name"] = req.Action.Name
+	set("action", req.Action.Properties)
+	fields["resource.type"], fields["resource.id"] = req.Resource.Type, req.Resource.ID
+	set("resource", req.Resource.Properties)
+	return fields
+}
+
+// reservedAuthZENField reports whether a context field name would shadow the
+// subject, action or resource, nested or flattened.
+func reservedAuthZENField(name string) bool {
+	for _, entity := range []string{"subject", "action", "resource"} {
+		if name == entity || strings.HasPrefix(name, entity+".") {
+			return true
+		}
+	}
+	return false
+}
+
+func writeAuthZENError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errIncompleteAuthZEN), errors.Is(err, governor.ErrNoRulepackID):
+		writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+	}
+}
@@ -0,0 +1,129 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	governor "github.com/mfifth/aisentinel-go-sdk"
+)
+
+func TestAuthZENEvaluation(t *testing.T) {
+	pack := governor.Rulepack{ID: "authz", Rules: []governor.RuleDefinition{
+		{ID: "prompt", Pattern: "@", Allow: false},
+		{ID: "subject.properties.department", Pattern: "^support$", Allow: true},
+	}}
+	control := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(pack)
+	}))
+	t.Cleanup(control.Close)
+
+	gov, err := governor.NewGovernor(context.Background(), governor.Config{APIKey: "test", APIBaseURL: control.URL})
+	if err != nil {
+		t.Fatalf("expected governor: %v", err)
+	}
+	t.Cleanup(func() { _ = gov.Close() })
+	handler, err := New(gov, Options{AuthZENRulepackID: "authz"}).Handler()
+	if err != nil {
+		t.Fatalf("expected handler: %v", err)
+	}
+
+	post := func(target, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+		req.Header.Set("X-Request-ID", "req-1")
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	const (
+		alice   = `"subject":{"type":"user","id":"alice","properties":{"department":"support"}}`
+		bob     = `"subject":{"type":"user","id":"bob","properties":{"department":"sales"}}`
+		request = `"action":{"name":"chat"},"resource":{"type":"model","id":"gpt-4o"}`
+	)
+
+	rec := post("/access/v1/evaluation", `{`+alice+`,`+request+`,"context":{"prompt":"hi"}}`)
+	var resp authzenResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || !resp.Decision || resp.Context["rule_id"] != "subject.properties.department" {
+		t.Fatalf("expected a permit, got %d %+v %v", rec.Code, resp, err)
+	}
+	if rec.Header().Get("X-Request-ID") != "req-1" {
+		t.Fatal("expected X-Request-ID to be echoed")
+	}
+
+	for _, forged := range []string{
+		`{"subject.properties.department":"support"}`,
+		`{"subject":{"properties":{"department":"support"}}}`,
+	} {
+		rec = post("/access/v1/evaluation", `{`+bob+`,`+request+`,"context":`+forged+`}`)
+		if resp = (authzenResponse{}); json.NewDecoder(rec.Body).Decode(&resp) != nil || resp.Decision {
+			t.Fatalf("expected context %s not to forge subject properties, got %+v", forged, resp)
+		}
+	}
+
+	if rec := post("/access/v1/evaluation", `{`+alice+`}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 without action and resource, got %d", rec.Code)
+	}
+
+	rec = post("/access/v1/evaluations", `{`+alice+`,`+request+`,"evaluations":[{"context":{"prompt":"hi"}},{"context":{"prompt":"mail a@b.c"}},{`+bob+`}],"options":{"evaluations_semantic":"deny_on_first_deny"}}`)
+	var batch struct {
+		Evaluations []authzenResponse `json:"evaluations"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&batch); err != nil || len(batch.Evaluations) != 2 || !batch.Evaluations[0].Decision || batch.Evaluations[1].Decision {
+		t.Fatalf("expected the batch to stop at the first deny, got %d %+v %v", rec.Code, batch, err)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://pdp.local/.well-known/authzen-configuration", nil))
+	if !strings.Contains(rec.Body.String(), `"access_evaluation_endpoint":"http://pdp.local/access/v1/evaluation"`) {
+		t.Fatalf("expected PDP metadata, got %s", rec.Body)
+	}
+}
+
+func TestAuthZENNestedFields(t *testing.T) {
+	pack := governor.Rulepack{ID: "authz", Rules: []governor.RuleDefinition{
+		{ID: "department", Field: "subject.properties.department", Pattern: "^support$", Allow: true},
+		{ID: "model", Field: "resource.id", Pattern: "^gpt-", Allow: true},
+	}}
+	control := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(pack)
+	}))
+	t.Cleanup(control.Close)
+	gov, err := governor.NewGovernor(context.Background(), governor.Config{APIKey: "test", APIBaseURL: control.URL})
+	if err != nil {
+		t.Fatalf("expected governor: %v", err)
+	}
+	t.Cleanup(func() { _ = gov.Close() })
+	handler, err := New(gov, Options{AuthZENRulepackID: "authz"}).Handler()
+	if err != nil {
+		t.Fatalf("expected handler: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		body    string
+		allowed bool
+		ruleID  string
+	}{
+		{"nested subject property", `{"subject":{"type":"user","id":"alice","properties":{"department":"support"}},"action":{"name":"chat"},"resource":{"type":"model","id":"claude"}}`, true, "department"},
+		{"nested resource id", `{"subject":{"type":"user","id":"bob"},"action":{"name":"chat"},"resource":{"type":"model","id":"gpt-4o"}}`, true, "model"},
+		{"forged nested subject", `{"subject":{"type":"user","id":"bob"},"action":{"name":"chat"},"resource":{"type":"model","id":"claude"},"context":{"subject":{"properties":{"department":"support"}}}}`, false, ""},
+		{"forged nested resource", `{"subject":{"type":"user","id":"bob"},"action":{"name":"chat"},"resource":{"type":"model","id":"claude"},"context":{"resource":{"id":"gpt-4o"}}}`, false, ""},
+		{"forged flattened subject", `{"subject":{"type":"user","id":"bob"},"action":{"name":"chat"},"resource":{"type":"model","id":"claude"},"context":{"subject.properties.department":"support"}}`, false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/access/v1/evaluation", strings.NewReader(tt.body)))
+			var resp authzenResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("expected a decision, got %d %v", rec.Code, err)
+			}
+			if resp.Decision != tt.allowed || (tt.ruleID != "" && resp.Context["rule_id"] != tt.ruleID) {
+				t.Fatalf("expected decision %v by %q, got %+v", tt.allowed, tt.ruleID, resp)
+			}
+		})
+	}
+}
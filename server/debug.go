@@ -0,0 +1,160 @@
+// Package server contains the HTTP building blocks used when the SDK runs as
+// a standalone decision sidecar.
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	governor "github.com/mfifth/aisentinel-go-sdk"
+)
+
+// ErrDebugDisabled is returned when debug endpoints are requested but not
+// enabled in the Governor configuration.
+var ErrDebugDisabled = errors.New("server: debug endpoints disabled")
+
+// DebugHandler returns a handler serving /debug/pprof/* and /debug/vars for
+// the supplied Governor. Every request must carry the configured DebugToken
+// either as a bearer token or in the X-Debug-Token header.
+//
+// The endpoints follow net/http/pprof and expvar, which are not imported, as
+// both register unguarded handlers on http.DefaultServeMux. /debug/pprof/
+// lists the runtime profiles and serves each by name, with ?debug=N for
+// text; /debug/pprof/profile and /debug/pprof/trace record for ?seconds=N.
+// /debug/vars reports cmdline, memstats and, under "aisentinel", the stats
+// and metrics of this Governor, so several Governors can each be mounted.
+func DebugHandler(gov *governor.Governor) (http.Handler, error) {
+	cfg := gov.Config()
+	if !cfg.DebugEndpoints {
+		return nil, ErrDebugDisabled
+	}
+	if cfg.DebugToken == "" {
+		return nil, errors.New("server: debug token is required")
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprofIndex)
+	mux.HandleFunc("/debug/pprof/cmdline", pprofCmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprofProfile)
+	mux.HandleFunc("/debug/pprof/trace", pprofTrace)
+	mux.HandleFunc("/debug/vars", func(w http.ResponseWriter, _ *http.Request) {
+		var mem runtime.MemStats
+		runtime.ReadMemStats(&mem)
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"cmdline":    os.Args,
+			"memstats":   mem,
+			"aisentinel": map[string]any{"stats": gov.Stats(), "metrics": gov.Metrics()},
+		})
+	})
+
+	return requireToken(cfg.DebugToken, mux), nil
+}
+
+// pprofIndex serves the profile named by the path, or lists the profiles.
+func pprofIndex(w http.ResponseWriter, r *http.Request) {
+	if name := strings.TrimPrefix(r.URL.Path, "/debug/pprof/"); name != "" {
+		profile := pprof.Lookup(name)
+		if profile == nil {
+			http.Error(w, "unknown profile", http.StatusNotFound)
+			return
+		}
+		debug, _ := strconv.Atoi(r.URL.Query().Get("debug"))
+		if debug > 0 {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		} else {
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+		}
+		_ = profile.WriteTo(w, debug)
+		return
+	}
+	profiles := pprof.Profiles()
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i].Name() < profiles[j].Name() })
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	for _, profile := range profiles {
+		fmt.Fprintf(w, "%d\t%s\n", profile.Count(), profile.Name())
+	}
+	fmt.Fprintln(w, "-\tprofile")
+	fmt.Fprintln(w, "-\ttrace")
+}
+
+// pprofCmdline serves the command line, its arguments separated by NUL bytes.
+func pprofCmdline(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprint(w, strings.Join(os.Args, "\x00"))
+}
+
+// pprofProfile serves a CPU profile of ?seconds=N, 30 by default.
+func pprofProfile(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", `attachment; filename="profile"`)
+	if err := pprof.StartCPUProfile(w); err != nil {
+		debugError(w, err)
+		return
+	}
+	record(r, 30*time.Second)
+	pprof.StopCPUProfile()
+}
+
+// pprofTrace serves an execution trace of ?seconds=N, 1 by default.
+func pprofTrace(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", `attachment; filename="trace"`)
+	if err := trace.Start(w); err != nil {
+		debugError(w, err)
+		return
+	}
+	record(r, time.Second)
+	trace.Stop()
+}
+
+// record waits for the ?seconds=N of r, or fallback, unless the client goes
+// away first.
+func record(r *http.Request, fallback time.Duration) {
+	d := fallback
+	if seconds, err := strconv.ParseFloat(r.URL.Query().Get("seconds"), 64); err == nil && seconds > 0 {
+		d = time.Duration(seconds * float64(time.Second))
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-r.Context().Done():
+	}
+}
+
+// debugError reports a profile that could not be started, typically because
+// another one is running.
+func debugError(w http.ResponseWriter, err error) {
+	w.Header().Del("Content-Disposition")
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	http.Error(w, "could not start: "+err.Error(), http.StatusInternalServerError)
+}
+
+// requireToken rejects requests that do not present the expected token.
+func requireToken(token string, next http.Handler) http.Handler {
+	expected := []byte(token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		presented := r.Header.Get("X-Debug-Token")
+		if presented == "" {
+			presented = strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		}
+		if subtle.ConstantTimeCompare([]byte(presented), expected) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
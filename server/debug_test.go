@@ -0,0 +1,103 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	governor "github.com/mfifth/aisentinel-go-sdk"
+)
+
+func TestDebugHandlerRequiresToken(t *testing.T) {
+	ctx := context.Background()
+	gov, err := governor.NewGovernor(ctx, governor.Config{APIKey: "test", OfflineMode: true, DebugEndpoints: true, DebugToken: "secret"})
+	if err != nil {
+		t.Fatalf("expected governor: %v", err)
+	}
+	t.Cleanup(func() { _ = gov.Close() })
+
+	handler, err := DebugHandler(gov)
+	if err != nil {
+		t.Fatalf("expected debug handler: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/vars", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without token, got %d", rec.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/debug/vars", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 with token, got %d", rec.Code)
+	}
+}
+
+func TestDebugHandlerDisabled(t *testing.T) {
+	gov, err := governor.NewGovernor(context.Background(), governor.Config{APIKey: "test", OfflineMode: true})
+	if err != nil {
+		t.Fatalf("expected governor: %v", err)
+	}
+	t.Cleanup(func() { _ = gov.Close() })
+
+	if _, err := DebugHandler(gov); err != ErrDebugDisabled {
+		t.Fatalf("expected ErrDebugDisabled, got %v", err)
+	}
+}
+
+func TestDebugHandlerEndpoints(t *testing.T) {
+	get := func(handler http.Handler, target string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set("X-Debug-Token", "secret")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	handlers := make([]http.Handler, 2)
+	for i, size := range []int{8, 16} {
+		gov, err := governor.NewGovernor(context.Background(), governor.Config{APIKey: "test", OfflineMode: true, OfflineQueueSize: size, DebugEndpoints: true, DebugToken: "secret"})
+		if err != nil {
+			t.Fatalf("expected governor: %v", err)
+		}
+		t.Cleanup(func() { _ = gov.Close() })
+		if handlers[i], err = DebugHandler(gov); err != nil {
+			t.Fatalf("expected debug handler: %v", err)
+		}
+	}
+
+	for i, want := range []int{8, 16} {
+		var vars struct {
+			Cmdline    []string
+			Aisentinel struct{ Stats governor.Stats }
+		}
+		rec := get(handlers[i], "/debug/vars")
+		if err := json.Unmarshal(rec.Body.Bytes(), &vars); err != nil || len(vars.Cmdline) == 0 || vars.Aisentinel.Stats.QueueCapacity != want {
+			t.Fatalf("expected the vars of Governor %d, got %s %v", i, rec.Body, err)
+		}
+	}
+	for target, want := range map[string]string{
+		"/debug/pprof/":                     "goroutine",
+		"/debug/pprof/goroutine?debug=1":    "goroutine profile",
+		"/debug/pprof/cmdline":              "",
+		"/debug/pprof/profile?seconds=0.01": "",
+	} {
+		if rec := get(handlers[0], target); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), want) {
+			t.Fatalf("expected %s to serve %q, got %d %.100s", target, want, rec.Code, rec.Body)
+		}
+	}
+	if rec := get(handlers[0], "/debug/pprof/nope"); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown profile, got %d", rec.Code)
+	}
+	for _, target := range []string{"/debug/pprof/", "/debug/vars"} {
+		if _, pattern := http.DefaultServeMux.Handler(httptest.NewRequest(http.MethodGet, target, nil)); pattern != "" {
+			t.Fatalf("expected nothing registered on the default mux, got %s for %s", pattern, target)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	governor "github.com/mfifth/aisentinel-go-sdk"
+)
+
+// opaDataPrefix is the path of OPA's Data API.
+const opaDataPrefix = "/v1/data"
+
+// opaRequest is the wire format of POST /v1/data/<path>.
+type opaRequest struct {
+	Input json.RawMessage `json:"input"`
+}
+
+// opaDecision is the document returned for a package path.
+type opaDecision struct {
+	Allow  bool   `json:"allow"`
+	Reason string `json:"reason,omitempty"`
+	RuleID string `json:"rule_id,omitempty"`
+}
+
+// opaError is OPA's error body.
+type opaError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// handleOPAData serves OPA's Data API so services integrated with OPA can use
+// the sidecar as their PDP without client changes. The input document is the
+// payload and the path names the rulepack, with slashes read as dots as in
+// OPA package names: POST /v1/data/httpapi/authz evaluates rulepack
+// "httpapi.authz" and answers {"result": {"allow": ...}}. A trailing /allow
+// answers with the bare boolean, {"result": true}. An empty path evaluates
+// the default rulepack.
+func (s *Server) handleOPAData(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeJSON(w, http.StatusMethodNotAllowed, opaError{Code: "invalid_parameter", Message: "method not allowed"})
+		return
+	}
+	select {
+	case s.sem <- struct{}{}:
+		defer func() { <-s.sem }()
+	default:
+		writeJSON(w, http.StatusTooManyRequests, opaError{Code: "resource_conflict", Message: "too many concurrent evaluations"})
+		return
+	}
+
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, opaDataPrefix), "/")
+	segments := strings.Split(path, "/")
+	bare := segments[len(segments)-1] == "allow"
+	if bare {
+		segments = segments[:len(segments)-1]
+	}
+	rulepack := strings.Join(segments, ".")
+
+	var req opaRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, s.opts.MaxBodyBytes)).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeJSON(w, http.StatusBadRequest, opaError{Code: "invalid_parameter", Message: "invalid request: " + err.Error()})
+		return
+	}
+	if len(req.Input) > 0 && !bytes.HasPrefix(bytes.TrimSpace(req.Input), []byte("{")) {
+		writeJSON(w, http.StatusBadRequest, opaError{Code: "invalid_parameter", Message: "input must be an object"})
+		return
+	}
+
+	result, err := s.gov.Evaluate(r.Context(), governor.DecisionRequest{RulepackID: rulepack, Payload: req.Input})
+	switch {
+	case errors.Is(err, governor.ErrNoRulepackID):
+		writeJSON(w, http.StatusBadRequest, opaError{Code: "invalid_parameter", Message: err.Error()})
+		return
+	case err != nil:
+		writeJSON(w, http.StatusInternalServerError, opaError{Code: "internal_error", Message: err.Error()})
+		return
+	}
+	if bare {
+		writeJSON(w, http.StatusOK, map[string]bool{"result": result.Allowed})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]opaDecision{"result": {Allow: result.Allowed, Reason: result.Reason, RuleID: result.RuleID}})
+}
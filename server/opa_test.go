@@ -0,0 +1,64 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+
+	governor "github.com/mfifth/aisentinel-go-sdk"
+)
+
+func TestOPADataAPI(t *testing.T) {
+	pack := governor.Rulepack{ID: "httpapi.authz", Rules: []governor.RuleDefinition{{ID: "prompt", Pattern: "^ok", Allow: true}}}
+	control := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if path.Base(r.URL.Path) != pack.ID {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(pack)
+	}))
+	t.Cleanup(control.Close)
+
+	gov, err := governor.NewGovernor(context.Background(), governor.Config{APIKey: "test", APIBaseURL: control.URL})
+	if err != nil {
+		t.Fatalf("expected governor: %v", err)
+	}
+	t.Cleanup(func() { _ = gov.Close() })
+	handler, err := New(gov, Options{}).Handler()
+	if err != nil {
+		t.Fatalf("expected handler: %v", err)
+	}
+
+	post := func(target, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, target, strings.NewReader(body)))
+		return rec
+	}
+
+	rec := post("/v1/data/httpapi/authz", `{"input":{"prompt":"ok then"}}`)
+	var doc struct {
+		Result opaDecision `json:"result"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&doc); err != nil || rec.Code != http.StatusOK || !doc.Result.Allow || doc.Result.RuleID != "prompt" {
+		t.Fatalf("expected result.allow, got %d %+v %v", rec.Code, doc, err)
+	}
+
+	rec = post("/v1/data/httpapi/authz/allow", `{"input":{"prompt":"leak"}}`)
+	if rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != `{"result":false}` {
+		t.Fatalf("expected a bare boolean result, got %d %s", rec.Code, rec.Body)
+	}
+
+	if rec := post("/v1/data/httpapi/authz", `{"input":["ok"]}`); rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), `"code":"invalid_parameter"`) {
+		t.Fatalf("expected an OPA error for a non-object input, got %d %s", rec.Code, rec.Body)
+	}
+	if rec := post("/v1/data", `{"input":{}}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 without a path or default rulepack, got %d", rec.Code)
+	}
+	if rec := post("/v1/data/missing", `{"input":{}}`); rec.Code != http.StatusInternalServerError || !strings.Contains(rec.Body.String(), `"code":"internal_error"`) {
+		t.Fatalf("expected an OPA internal error, got %d %s", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,228 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	governor "github.com/mfifth/aisentinel-go-sdk"
+)
+
+// Defaults applied by New for zero Options fields.
+const (
+	DefaultAddr            = "127.0.0.1:8080"
+	DefaultMaxConcurrent   = 64
+	DefaultShutdownTimeout = 15 * time.Second
+	DefaultMaxBodyBytes    = 1 << 20 // 1 MiB
+)
+
+// Options configures a sidecar Server.
+type Options struct {
+	// Addr is the listen address.
+	Addr string
+	// MaxConcurrent bounds in-flight evaluations. Requests beyond the limit
+	// are rejected with 429 rather than queued.
+	MaxConcurrent int
+	// ShutdownTimeout bounds how long in-flight requests may drain once the
+	// serve context is cancelled.
+	ShutdownTimeout time.Duration
+	// MaxBodyBytes limits the size of evaluation requests.
+	MaxBodyBytes int64
+	// AuthZENRulepackID is evaluated for AuthZEN access evaluations; empty
+	// selects Config.DefaultRulepackID.
+	AuthZENRulepackID string
+}
+
+// Server exposes a Governor over HTTP so non-Go services can use it as a
+// local policy decision point. It serves:
+//
+//	POST /v1/evaluate              evaluate a decision request
+//	POST /v1/data/...              OPA-compatible Data API
+//	POST /access/v1/evaluation[s]  OpenID AuthZEN access evaluation API
+//	GET  /healthz                  liveness
+//	GET  /readyz                   readiness
+//	GET  /metrics                  Prometheus metrics
+//
+// The debug handlers are mounted under /debug/ when enabled in the Governor
+// configuration.
+type Server struct {
+	gov  *governor.Governor
+	opts Options
+	sem  chan struct{}
+}
+
+// New returns a Server for gov.
+func New(gov *governor.Governor, opts Options) *Server {
+	if opts.Addr == "" {
+		opts.Addr = DefaultAddr
+	}
+	if opts.MaxConcurrent <= 0 {
+		opts.MaxConcurrent = DefaultMaxConcurrent
+	}
+	if opts.ShutdownTimeout <= 0 {
+		opts.ShutdownTimeout = DefaultShutdownTimeout
+	}
+	if opts.MaxBodyBytes <= 0 {
+		opts.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+	return &Server{gov: gov, opts: opts, sem: make(chan struct{}, opts.MaxConcurrent)}
+}
+
+// Handler returns the sidecar routes.
+func (s *Server) Handler() (http.Handler, error) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/evaluate", s.handleEvaluate)
+	mux.HandleFunc(opaDataPrefix, s.handleOPAData)
+	mux.HandleFunc(opaDataPrefix+"/", s.handleOPAData)
+	mux.HandleFunc(authzenEvaluationPath, s.handleAuthZENEvaluation)
+	mux.HandleFunc(authzenEvaluationsPath, s.handleAuthZENEvaluations)
+	mux.HandleFunc(authzenMetadataPath, s.handleAuthZENMetadata)
+	health := s.gov.HealthHandler()
+	mux.Handle("/healthz", health)
+	mux.Handle("/readyz", health)
+	mux.HandleFunc("/metrics", s.handleMetrics)
+
+	debug, err := DebugHandler(s.gov)
+	switch {
+	case err == nil:
+		mux.Handle("/debug/", debug)
+	case !errors.Is(err, ErrDebugDisabled):
+		return nil, err
+	}
+	return mux, nil
+}
+
+// ListenAndServe serves until ctx is cancelled, then shuts down gracefully,
+// waiting up to ShutdownTimeout for in-flight requests.
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	ln, err := net.Listen("tcp", s.opts.Addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx, ln)
+}
+
+// Serve is like ListenAndServe but accepts connections on ln.
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	handler, err := s.Handler()
+	if err != nil {
+		ln.Close()
+		return err
+	}
+	srv := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.Serve(ln) }()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.opts.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// evaluateRequest is the wire format of POST /v1/evaluate.
+type evaluateRequest struct {
+	RulepackID       string          `json:"rulepack_id"`
+	Payload          json.RawMessage `json:"payload"`
+	Subject          string          `json:"subject,omitempty"`
+	Model            string          `json:"model,omitempty"`
+	PromptTokens     int             `json:"prompt_tokens,omitempty"`
+	CompletionTokens int             `json:"completion_tokens,omitempty"`
+	EstimatedCost    float64         `json:"estimated_cost,omitempty"`
+	NoCache          bool            `json:"no_cache,omitempty"`
+	Explain          bool            `json:"explain,omitempty"`
+}
+
+type evaluateResponse struct {
+	Allowed         bool                  `json:"allowed"`
+	Reason          string                `json:"reason"`
+	Code            governor.ReasonCode   `json:"code,omitempty"`
+	RuleID          string                `json:"rule_id,omitempty"`
+	MatchedRuleIDs  []string              `json:"matched_rule_ids,omitempty"`
+	RulepackVersion string                `json:"rulepack_version,omitempty"`
+	Obligations     []governor.Obligation `json:"obligations,omitempty"`
+	LatencyMS       int64                 `json:"latency_ms"`
+	Explanation     *governor.Explanation `json:"explanation,omitempty"`
+}
+
+func (s *Server) handleEvaluate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	select {
+	case s.sem <- struct{}{}:
+		defer func() { <-s.sem }()
+	default:
+		writeError(w, http.StatusTooManyRequests, "too many concurrent evaluations")
+		return
+	}
+
+	var req evaluateRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, s.opts.MaxBodyBytes)).Decode(&req); err != nil {
+		code := http.StatusBadRequest
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			code = http.StatusRequestEntityTooLarge
+		}
+		writeError(w, code, "invalid request: "+err.Error())
+		return
+	}
+	result, err := s.gov.Evaluate(r.Context(), governor.DecisionRequest{
+		RulepackID:       req.RulepackID,
+		Payload:          req.Payload,
+		Subject:          req.Subject,
+		Model:            req.Model,
+		PromptTokens:     req.PromptTokens,
+		CompletionTokens: req.CompletionTokens,
+		EstimatedCost:    req.EstimatedCost,
+		NoCache:          req.NoCache,
+		Explain:          req.Explain,
+	})
+	// Requests the client must change are 4xx; only a rulepack that cannot
+	// be fetched or evaluated is 503.
+	switch {
+	case errors.Is(err, governor.ErrNoRulepackID), errors.Is(err, governor.ErrInvalidPayload):
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	case errors.Is(err, governor.ErrPayloadTooLarge):
+		writeError(w, http.StatusRequestEntityTooLarge, err.Error())
+		return
+	case err != nil:
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, evaluateResponse{Allowed: result.Allowed, Reason: result.Reason, Code: result.Code, RuleID: result.RuleID, MatchedRuleIDs: result.MatchedRuleIDs, RulepackVersion: result.RulepackVersion, Obligations: result.Obligations, LatencyMS: result.Latency.Milliseconds(), Explanation: result.Explanation})
+}
+
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	_ = s.gov.Metrics().WritePrometheus(w)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, map[string]string{"error": msg})
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	governor "github.com/mfifth/aisentinel-go-sdk"
+)
+
+func TestSidecarEvaluate(t *testing.T) {
+	pack := governor.Rulepack{ID: "remote", Rules: []governor.RuleDefinition{{ID: "prompt", Pattern: "ok", Allow: true}}}
+	control := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(pack)
+	}))
+	t.Cleanup(control.Close)
+
+	ctx := context.Background()
+	gov, err := governor.NewGovernor(ctx, governor.Config{APIKey: "test", APIBaseURL: control.URL, MetricsEnabled: true})
+	if err != nil {
+		t.Fatalf("expected governor: %v", err)
+	}
+	t.Cleanup(func() { _ = gov.Close() })
+
+	handler, err := New(gov, Options{}).Handler()
+	if err != nil {
+		t.Fatalf("expected handler: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	body := strings.NewReader(`{"rulepack_id":"remote","payload":{"prompt":"ok"}}`)
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/evaluate", body))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var resp evaluateResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || !resp.Allowed {
+		t.Fatalf("expected allowed decision, got %+v, %v", resp, err)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/evaluate", strings.NewReader(`{"payload":{}}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 without rulepack, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if !strings.Contains(rec.Body.String(), "aisentinel_evaluation_latency_seconds") {
+		t.Fatalf("expected latency metrics, got %s", rec.Body)
+	}
+}
+
+func TestSidecarEvaluateStatus(t *testing.T) {
+	pack := governor.Rulepack{ID: "remote", Rules: []governor.RuleDefinition{{ID: "prompt", Pattern: "ok", Allow: true}}}
+	control := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/remote") {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(pack)
+	}))
+	t.Cleanup(control.Close)
+
+	gov, err := governor.NewGovernor(context.Background(), governor.Config{APIKey: "test", APIBaseURL: control.URL, MaxPayloadBytes: 64}, governor.WithFetchRetries(governor.NoFetchRetries))
+	if err != nil {
+		t.Fatalf("expected governor: %v", err)
+	}
+	t.Cleanup(func() { _ = gov.Close() })
+
+	handler, err := New(gov, Options{MaxBodyBytes: 256}).Handler()
+	if err != nil {
+		t.Fatalf("expected handler: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		body string
+		code int
+	}{
+		{name: "allowed", body: `{"rulepack_id":"remote","payload":{"prompt":"ok"}}`, code: http.StatusOK},
+		{name: "malformed request", body: `{"rulepack_id":`, code: http.StatusBadRequest},
+		{name: "payload a string", body: `{"rulepack_id":"remote","payload":"ok"}`, code: http.StatusBadRequest},
+		{name: "payload an array", body: `{"rulepack_id":"remote","payload":[1]}`, code: http.StatusBadRequest},
+		{name: "payload over MaxPayloadBytes", body: `{"rulepack_id":"remote","payload":{"prompt":"` + strings.Repeat("x", 64) + `"}}`, code: http.StatusRequestEntityTooLarge},
+		{name: "body over MaxBodyBytes", body: `{"rulepack_id":"remote","payload":{"prompt":"` + strings.Repeat("x", 256) + `"}}`, code: http.StatusRequestEntityTooLarge},
+		{name: "rulepack unavailable", body: `{"rulepack_id":"missing","payload":{"prompt":"ok"}}`, code: http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/evaluate", strings.NewReader(tt.body)))
+			if rec.Code != tt.code {
+				t.Fatalf("expected %d, got %d: %s", tt.code, rec.Code, rec.Body)
+			}
+		})
+	}
+}
+
+func TestSidecarConcurrencyLimit(t *testing.T) {
+	gov, err := governor.NewGovernor(context.Background(), governor.Config{APIKey: "test", OfflineMode: true})
+	if err != nil {
+		t.Fatalf("expected governor: %v", err)
+	}
+	t.Cleanup(func() { _ = gov.Close() })
+
+	srv := New(gov, Options{MaxConcurrent: 1})
+	handler, err := srv.Handler()
+	if err != nil {
+		t.Fatalf("expected handler: %v", err)
+	}
+	srv.sem <- struct{}{} // occupy the only slot
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/evaluate", strings.NewReader(`{}`)))
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,127 @@
+package governor
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// RulepackSignatureHeader carries the detached signature of a fetched
+// rulepack: the base64 encoded Ed25519 or ASN.1 ECDSA signature of the
+// response body.
+const RulepackSignatureHeader = "X-Rulepack-Signature"
+
+// ErrRulepackSignature indicates a fetched rulepack whose signature is
+// missing or verifies against none of Config.RulepackPublicKeys.
+var ErrRulepackSignature = errors.New("governor: rulepack signature invalid")
+
+// loadPublicKeys parses Config.RulepackPublicKeys. Each entry is a PEM
+// encoded PKIX public key, or the path of a file holding one.
+func loadPublicKeys(entries []string) ([]crypto.PublicKey, error) {
+	keys := make([]crypto.PublicKey, 0, len(entries))
+	for _, entry := range entries {
+		data := []byte(entry)
+		if !strings.HasPrefix(strings.TrimSpace(entry), "-----BEGIN") {
+			var err error
+			if data, err = os.ReadFile(entry); err != nil { // #nosec G304 -- operator supplied key
+				return nil, fmt.Errorf("read RulepackPublicKeys: %w", err)
+			}
+		}
+		block, _ := pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("RulepackPublicKeys: no PEM block in %s", keyName(entry))
+		}
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("RulepackPublicKeys: %s: %w", keyName(entry), err)
+		}
+		switch key.(type) {
+		case ed25519.PublicKey, *ecdsa.PublicKey:
+		default:
+			return nil, fmt.Errorf("RulepackPublicKeys: %s: unsupported key type %T", keyName(entry), key)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// keyName names an entry of Config.RulepackPublicKeys in errors without
+// repeating inline keys.
+func keyName(entry string) string {
+	if strings.HasPrefix(strings.TrimSpace(entry), "-----BEGIN") {
+		return "inline key"
+	}
+	return entry
+}
+
+// verifyRulepack checks signature, a RulepackSignatureHeader value, against
+// body. Any trusted key may have signed it, so keys can be rotated.
+func verifyRulepack(keys []crypto.PublicKey, body []byte, signature string) error {
+	if signature == "" {
+		return fmt.Errorf("%w: no %s header", ErrRulepackSignature, RulepackSignatureHeader)
+	}
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrRulepackSignature, err)
+	}
+	for _, key := range keys {
+		switch key := key.(type) {
+		case ed25519.PublicKey:
+			if ed25519.Verify(key, body, sig) {
+				return nil
+			}
+		case *ecdsa.PublicKey:
+			if ecdsa.VerifyASN1(key, ecdsaDigest(key.Curve.Params().BitSize, body), sig) {
+				return nil
+			}
+		}
+	}
+	return ErrRulepackSignature
+}
+
+// ecdsaDigest hashes body with the hash matching the curve size.
+func ecdsaDigest(bits int, body []byte) []byte {
+	switch {
+	case bits > 384:
+		sum := sha512.Sum512(body)
+		return sum[:]
+	case bits > 256:
+		sum := sha512.Sum384(body)
+		return sum[:]
+	default:
+		sum := sha256.Sum256(body)
+		return sum[:]
+	}
+}
+
+// SignRulepack signs data, the exact rulepack bytes a control plane serves,
+// with an ed25519.PrivateKey or *ecdsa.PrivateKey and returns the
+// RulepackSignatureHeader value.
+func SignRulepack(key crypto.Signer, data []byte) (string, error) {
+	var (
+		sig []byte
+		err error
+	)
+	switch key := key.(type) {
+	case ed25519.PrivateKey:
+		sig = ed25519.Sign(key, data)
+	case *ecdsa.PrivateKey:
+		sig, err = ecdsa.SignASN1(rand.Reader, key, ecdsaDigest(key.Curve.Params().BitSize, data))
+	default:
+		return "", fmt.Errorf("unsupported signing key type %T", key)
+	}
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(sig), nil
+}
@@ -0,0 +1,109 @@
+package governor
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func publicKeyPEM(t *testing.T, key crypto.PublicKey) string {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+func TestSignedRulepacks(t *testing.T) {
+	edPublic, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecPrivate, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, untrusted, _ := ed25519.GenerateKey(rand.Reader)
+	keyFile := filepath.Join(t.TempDir(), "rulepacks.pem")
+	if err := os.WriteFile(keyFile, []byte(publicKeyPEM(t, &ecPrivate.PublicKey)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each rulepack is served as its ID names: signed by that signer, unsigned,
+	// tampered with after signing, or validly signed but for another rulepack.
+	pack := func(id string) []byte {
+		body, _ := json.Marshal(Rulepack{ID: id, Rules: []RuleDefinition{{ID: "prompt", Pattern: "ok", Allow: true}}})
+		return body
+	}
+	signers := map[string]crypto.Signer{"ed25519": edPrivate, "ecdsa": ecPrivate, "untrusted": untrusted}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := path.Base(r.URL.Path)
+		served, signer := pack(id), signers[id]
+		switch id {
+		case "unsigned":
+			signer = nil
+		case "tampered":
+			sig, _ := SignRulepack(edPrivate, served)
+			w.Header().Set(RulepackSignatureHeader, sig)
+			signer, served = nil, []byte(`{"id":"tampered","rules":[{"ID":"prompt","Pattern":"","Allow":true}]}`)
+		case "mismatched":
+			served, signer = pack("permissive"), edPrivate
+		}
+		if signer != nil {
+			sig, err := SignRulepack(signer, served)
+			if err != nil {
+				t.Error(err)
+			}
+			w.Header().Set(RulepackSignatureHeader, sig)
+		}
+		_, _ = w.Write(served)
+	}))
+	t.Cleanup(srv.Close)
+
+	ctx := context.Background()
+	gov, err := NewGovernor(ctx, Config{APIKey: "test", APIBaseURL: srv.URL}, WithRulepackPublicKeys(publicKeyPEM(t, edPublic), keyFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = gov.Close() })
+	for id, wantErr := range map[string]bool{"ed25519": false, "ecdsa": false, "unsigned": true, "untrusted": true, "tampered": true} {
+		_, _, err := gov.fetchRulepack(ctx, id)
+		if wantErr != errors.Is(err, ErrRulepackSignature) || (!wantErr && err != nil) {
+			t.Errorf("rulepack %q: expected signature error %t, got %v", id, wantErr, err)
+		}
+	}
+	if _, _, err := gov.fetchRulepack(ctx, "mismatched"); err == nil || !strings.Contains(err.Error(), `got rulepack "permissive"`) {
+		t.Fatalf("expected a rulepack signed for another ID rejected, got %v", err)
+	}
+	if _, ok := gov.cache.Get("mismatched"); ok {
+		t.Fatal("expected the mismatched rulepack not cached")
+	}
+
+	unsigned, err := NewGovernor(ctx, Config{APIKey: "test", APIBaseURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = unsigned.Close() })
+	if _, _, err := unsigned.fetchRulepack(ctx, "unsigned"); err != nil {
+		t.Fatalf("expected unsigned rulepacks accepted without trusted keys, got %v", err)
+	}
+
+	if _, err := NewGovernor(ctx, Config{APIKey: "test"}, WithRulepackPublicKeys("-----BEGIN PUBLIC KEY-----\nnope\n-----END PUBLIC KEY-----\n")); err == nil {
+		t.Fatal("expected an invalid public key to fail construction")
+	}
+}
@@ -4,11 +4,16 @@ import (
 	"context"
 	"errors"
 	"sync"
+	"time"
 )
 
 // BadgerStore acts as an in-memory approximation of a BadgerDB store. The
 // simplified implementation keeps the Go module dependency free for CI
 // environments without network access while remaining API compatible.
+// Records do not survive a restart. Importing
+// github.com/mfifth/aisentinel-go-sdk/contrib/badgerstore registers a
+// BadgerDB store, which Governors configured with BackendBadger then use
+// instead.
 type BadgerStore struct {
 	mu   sync.RWMutex
 	data map[string][]byte
@@ -50,6 +55,33 @@ func (s *BadgerStore) Iter(_ context.Context, fn func(Record) error) error {
 	return nil
 }
 
+// IterRange iterates in key order over the records in a key range.
+func (s *BadgerStore) IterRange(_ context.Context, start, end string, fn func(Record) error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return iterMapRange(s.data, start, end, fn)
+}
+
+// PutBatch stores records under one lock.
+func (s *BadgerStore) PutBatch(_ context.Context, records []Record) error {
+	s.mu.Lock()
+	for _, record := range records {
+		s.data[record.Key] = append([]byte(nil), record.Value...)
+	}
+	s.mu.Unlock()
+	return nil
+}
+
+// DeleteBatch removes records by key under one lock.
+func (s *BadgerStore) DeleteBatch(_ context.Context, keys []string) error {
+	s.mu.Lock()
+	for _, key := range keys {
+		delete(s.data, key)
+	}
+	s.mu.Unlock()
+	return nil
+}
+
 // Delete removes a record.
 func (s *BadgerStore) Delete(_ context.Context, key string) error {
 	s.mu.Lock()
@@ -69,5 +101,24 @@ func (s *BadgerStore) Close() error {
 	return nil
 }
 
-// DefaultBadgerOptions returns a nil placeholder for API compatibility.
-func DefaultBadgerOptions() any { return nil }
+// BadgerOptions tune the BadgerDB store of contrib/badgerstore.
+type BadgerOptions struct {
+	// SyncWrites fsyncs every write, so committed records survive a crash.
+	SyncWrites bool
+	// GCInterval is how often the value log is garbage collected, reclaiming
+	// the space of deleted and overwritten records. Zero disables it.
+	GCInterval time.Duration
+	// GCDiscardRatio is the fraction of a value log file that must be
+	// garbage for GC to rewrite it.
+	GCDiscardRatio float64
+	// ValueLogFileSize caps each value log file in bytes; zero keeps
+	// BadgerDB's default.
+	ValueLogFileSize int64
+}
+
+// DefaultBadgerOptions returns the options the registered badger backend
+// uses: synced writes and value log GC every five minutes at a 0.5 discard
+// ratio.
+func DefaultBadgerOptions() BadgerOptions {
+	return BadgerOptions{SyncWrites: true, GCInterval: 5 * time.Minute, GCDiscardRatio: 0.5}
+}
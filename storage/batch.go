@@ -0,0 +1,52 @@
+package storage
+
+import "context"
+
+// BatchWriter is implemented by Stores that write several records more
+// cheaply together than one at a time, for example in one transaction or
+// statement. Callers use PutBatch and DeleteBatch, which fall back to Put
+// and Delete for other Stores.
+type BatchWriter interface {
+	// PutBatch stores records as Put does. Later records win over earlier
+	// ones with the same key. Like Put, it must not retain the values
+	// after it returns. A failed batch may be partially written.
+	PutBatch(ctx context.Context, records []Record) error
+	// DeleteBatch removes the records with keys. Missing keys are not an
+	// error. A failed batch may be partially deleted.
+	DeleteBatch(ctx context.Context, keys []string) error
+}
+
+// PutBatch stores records in store, in one call when it is a BatchWriter
+// and otherwise with a Put per record, stopping at the first error.
+func PutBatch(ctx context.Context, store Store, records []Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+	if b, ok := store.(BatchWriter); ok {
+		return b.PutBatch(ctx, records)
+	}
+	for _, record := range records {
+		if err := store.Put(ctx, record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteBatch removes the records with keys from store, in one call when it
+// is a BatchWriter and otherwise with a Delete per key, stopping at the
+// first error.
+func DeleteBatch(ctx context.Context, store Store, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	if b, ok := store.(BatchWriter); ok {
+		return b.DeleteBatch(ctx, keys)
+	}
+	for _, key := range keys {
+		if err := store.Delete(ctx, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -6,9 +6,11 @@ import (
 	"sync"
 )
 
-// BoltStore simulates a BoltDB backed storage. In constrained environments the
-// SDK falls back to an in-memory map while preserving the public API expected by
-// the higher level components.
+// BoltStore simulates a BoltDB backed storage with an in-memory map, keeping
+// the SDK free of third-party dependencies. Records do not survive a restart.
+// Importing github.com/mfifth/aisentinel-go-sdk/contrib/boltstore registers
+// a bbolt file store, which Governors configured with BackendBolt then use
+// instead.
 type BoltStore struct {
 	mu     sync.RWMutex
 	bucket map[string][]byte
@@ -50,6 +52,33 @@ func (s *BoltStore) Iter(_ context.Context, fn func(Record) error) error {
 	return nil
 }
 
+// IterRange iterates in key order over the records in a key range.
+func (s *BoltStore) IterRange(_ context.Context, start, end string, fn func(Record) error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return iterMapRange(s.bucket, start, end, fn)
+}
+
+// PutBatch stores records under one lock.
+func (s *BoltStore) PutBatch(_ context.Context, records []Record) error {
+	s.mu.Lock()
+	for _, record := range records {
+		s.bucket[record.Key] = append([]byte(nil), record.Value...)
+	}
+	s.mu.Unlock()
+	return nil
+}
+
+// DeleteBatch removes records by key under one lock.
+func (s *BoltStore) DeleteBatch(_ context.Context, keys []string) error {
+	s.mu.Lock()
+	for _, key := range keys {
+		delete(s.bucket, key)
+	}
+	s.mu.Unlock()
+	return nil
+}
+
 // Delete removes a record by key.
 func (s *BoltStore) Delete(_ context.Context, key string) error {
 	s.mu.Lock()
@@ -0,0 +1,230 @@
+package storage
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// encryptedVersion prefixes values written by EncryptedStore, followed by
+// the key ID length, the key ID, the nonce and the sealed value.
+const encryptedVersion byte = 1
+
+// ErrUnknownKey is returned when a KeyProvider has no key with the ID a
+// record was encrypted with.
+var ErrUnknownKey = errors.New("storage: unknown encryption key")
+
+// KeyProvider supplies the AES keys, 16, 24 or 32 bytes long, of an
+// EncryptedStore. Records remember the ID of the key that encrypted them,
+// so keys can be rotated without re-encrypting older records as long as
+// Key still returns the old ones.
+type KeyProvider interface {
+	// CurrentKey returns the key new records are encrypted with. It is
+	// called for every Put, so remote providers should cache it.
+	CurrentKey(ctx context.Context) (id string, key []byte, err error)
+	// Key returns the key with id, or ErrUnknownKey. An ID must always name
+	// the same key.
+	Key(ctx context.Context, id string) ([]byte, error)
+}
+
+// StaticKeys is a KeyProvider over fixed keys by ID; Current names the key
+// new records are encrypted with.
+type StaticKeys struct {
+	Current string
+	Keys    map[string][]byte
+}
+
+// StaticKey returns a StaticKeys holding only key, with its fingerprint as
+// the ID.
+func StaticKey(key []byte) StaticKeys {
+	sum := sha256.Sum256(key)
+	id := hex.EncodeToString(sum[:4])
+	return StaticKeys{Current: id, Keys: map[string][]byte{id: key}}
+}
+
+// CurrentKey returns the key named by Current.
+func (k StaticKeys) CurrentKey(ctx context.Context) (string, []byte, error) {
+	key, err := k.Key(ctx, k.Current)
+	return k.Current, key, err
+}
+
+// Key returns the key with id.
+func (k StaticKeys) Key(_ context.Context, id string) ([]byte, error) {
+	key, ok := k.Keys[id]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownKey, id)
+	}
+	return key, nil
+}
+
+// EncryptedStore encrypts record values with AES-GCM before delegating to
+// another Store. Keys stay in plaintext, so they must not hold secrets.
+// Each value is bound to its key, so a value copied to another key fails
+// to decrypt. Records written without encryption cannot be read back, so
+// enable it on an empty store.
+type EncryptedStore struct {
+	store Store
+	keys  KeyProvider
+
+	mu    sync.Mutex
+	aeads map[string]cipher.AEAD // by key ID
+}
+
+var _ Store = (*EncryptedStore)(nil)
+
+// NewEncryptedStore returns a Store encrypting values with the keys from
+// keys before writing them to store.
+func NewEncryptedStore(store Store, keys KeyProvider) *EncryptedStore {
+	return &EncryptedStore{store: store, keys: keys, aeads: map[string]cipher.AEAD{}}
+}
+
+// aead returns the cipher for the key with id, creating it from key, or
+// from the provider when key is nil.
+func (s *EncryptedStore) aead(ctx context.Context, id string, key []byte) (cipher.AEAD, error) {
+	s.mu.Lock()
+	aead, ok := s.aeads[id]
+	s.mu.Unlock()
+	if ok {
+		return aead, nil
+	}
+	if key == nil {
+		var err error
+		if key, err = s.keys.Key(ctx, id); err != nil {
+			return nil, err
+		}
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("storage: encryption key %q: %w", id, err)
+	}
+	if aead, err = cipher.NewGCM(block); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.aeads[id] = aead
+	s.mu.Unlock()
+	return aead, nil
+}
+
+// Put encrypts the record's value with the current key and stores it.
+func (s *EncryptedStore) Put(ctx context.Context, record Record) error {
+	record, err := s.seal(ctx, record)
+	if err != nil {
+		return err
+	}
+	return s.store.Put(ctx, record)
+}
+
+// PutBatch encrypts the records' values with the current key and stores
+// them as PutBatch does on the underlying store.
+func (s *EncryptedStore) PutBatch(ctx context.Context, records []Record) error {
+	sealed := make([]Record, len(records))
+	for i, record := range records {
+		var err error
+		if sealed[i], err = s.seal(ctx, record); err != nil {
+			return err
+		}
+	}
+	return PutBatch(ctx, s.store, sealed)
+}
+
+// seal returns record with its value encrypted with the current key.
+func (s *EncryptedStore) seal(ctx context.Context, record Record) (Record, error) {
+	id, key, err := s.keys.CurrentKey(ctx)
+	if err != nil {
+		return Record{}, fmt.Errorf("storage: encryption key: %w", err)
+	}
+	if len(id) > 255 {
+		return Record{}, fmt.Errorf("storage: encryption key ID %q longer than 255 bytes", id)
+	}
+	aead, err := s.aead(ctx, id, key)
+	if err != nil {
+		return Record{}, err
+	}
+	header := 2 + len(id) + aead.NonceSize()
+	sealed := make([]byte, header, header+len(record.Value)+aead.Overhead())
+	sealed[0], sealed[1] = encryptedVersion, byte(len(id))
+	copy(sealed[2:], id)
+	nonce := sealed[2+len(id) : header]
+	if _, err := rand.Read(nonce); err != nil {
+		return Record{}, err
+	}
+	record.Value = aead.Seal(sealed, nonce, record.Value, []byte(record.Key))
+	return record, nil
+}
+
+// open decrypts a value read from the underlying store.
+func (s *EncryptedStore) open(ctx context.Context, record Record) (Record, error) {
+	value := record.Value
+	if len(value) < 2 || value[0] != encryptedVersion || len(value) < 2+int(value[1]) {
+		return Record{}, fmt.Errorf("storage: record %s is not encrypted", record.Key)
+	}
+	id := string(value[2 : 2+value[1]])
+	aead, err := s.aead(ctx, id, nil)
+	if err != nil {
+		return Record{}, fmt.Errorf("storage: decrypt record %s: %w", record.Key, err)
+	}
+	value = value[2+len(id):]
+	if len(value) < aead.NonceSize() {
+		return Record{}, fmt.Errorf("storage: record %s is truncated", record.Key)
+	}
+	plain, err := aead.Open(nil, value[:aead.NonceSize()], value[aead.NonceSize():], []byte(record.Key))
+	if err != nil {
+		return Record{}, fmt.Errorf("storage: decrypt record %s: %w", record.Key, err)
+	}
+	return Record{Key: record.Key, Value: plain}, nil
+}
+
+// Get retrieves and decrypts a record.
+func (s *EncryptedStore) Get(ctx context.Context, key string) (Record, error) {
+	record, err := s.store.Get(ctx, key)
+	if err != nil {
+		return Record{}, err
+	}
+	return s.open(ctx, record)
+}
+
+// Iter iterates over the decrypted records, stopping at the first that
+// fails to decrypt.
+func (s *EncryptedStore) Iter(ctx context.Context, fn func(Record) error) error {
+	return s.store.Iter(ctx, func(record Record) error {
+		record, err := s.open(ctx, record)
+		if err != nil {
+			return err
+		}
+		return fn(record)
+	})
+}
+
+// IterRange iterates over the decrypted records in a key range, as
+// IterRange does on the underlying store.
+func (s *EncryptedStore) IterRange(ctx context.Context, start, end string, fn func(Record) error) error {
+	return IterRange(ctx, s.store, start, end, func(record Record) error {
+		record, err := s.open(ctx, record)
+		if err != nil {
+			return err
+		}
+		return fn(record)
+	})
+}
+
+// Delete removes a record.
+func (s *EncryptedStore) Delete(ctx context.Context, key string) error {
+	return s.store.Delete(ctx, key)
+}
+
+// DeleteBatch removes records as DeleteBatch does on the underlying store.
+func (s *EncryptedStore) DeleteBatch(ctx context.Context, keys []string) error {
+	return DeleteBatch(ctx, s.store, keys)
+}
+
+// Close closes the underlying store.
+func (s *EncryptedStore) Close() error {
+	return s.store.Close()
+}
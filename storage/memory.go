@@ -51,6 +51,33 @@ func (s *MemoryStore) Iter(_ context.Context, fn func(Record) error) error {
 	return nil
 }
 
+// IterRange iterates in key order over the records in a key range.
+func (s *MemoryStore) IterRange(_ context.Context, start, end string, fn func(Record) error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return iterMapRange(s.buffer, start, end, fn)
+}
+
+// PutBatch stores records under one lock.
+func (s *MemoryStore) PutBatch(_ context.Context, records []Record) error {
+	s.mu.Lock()
+	for _, record := range records {
+		s.buffer[record.Key] = append([]byte(nil), record.Value...)
+	}
+	s.mu.Unlock()
+	return nil
+}
+
+// DeleteBatch removes records by key under one lock.
+func (s *MemoryStore) DeleteBatch(_ context.Context, keys []string) error {
+	s.mu.Lock()
+	for _, key := range keys {
+		delete(s.buffer, key)
+	}
+	s.mu.Unlock()
+	return nil
+}
+
 // Delete removes a record by key.
 func (s *MemoryStore) Delete(_ context.Context, key string) error {
 	s.mu.Lock()
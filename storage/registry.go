@@ -0,0 +1,39 @@
+package storage
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Opener opens a Store at dsn, the Governor's Config.StorageDSN.
+type Opener func(dsn string) (Store, error)
+
+var (
+	openersMu sync.RWMutex
+	openers   = map[BackendType]Opener{}
+)
+
+// Register makes a Store implementation available as backend, so Governors
+// configured with that StorageBackend open it with open. Implementations
+// with third-party dependencies live in their own modules and register
+// themselves from init, like database/sql drivers. It panics when open is
+// nil or backend is already registered.
+func Register(backend BackendType, open Opener) {
+	openersMu.Lock()
+	defer openersMu.Unlock()
+	if open == nil {
+		panic("storage: Register opener is nil")
+	}
+	if _, dup := openers[backend]; dup {
+		panic(fmt.Sprintf("storage: Register called twice for backend %s", backend))
+	}
+	openers[backend] = open
+}
+
+// Lookup returns the Opener registered for backend.
+func Lookup(backend BackendType) (Opener, bool) {
+	openersMu.RLock()
+	defer openersMu.RUnlock()
+	open, ok := openers[backend]
+	return open, ok
+}
@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"context"
+	"sort"
+)
+
+// RangeIterator is implemented by Stores that can iterate over a range of
+// keys without scanning every record, such as those keeping records in key
+// order. Callers use IterRange and IterPrefix, which fall back to Iter for
+// other Stores.
+type RangeIterator interface {
+	// IterRange iterates over the records with keys from start,
+	// inclusive, to end, exclusive. An empty end is unbounded. Stores
+	// keeping records in key order iterate in key order.
+	IterRange(ctx context.Context, start, end string, fn func(Record) error) error
+}
+
+// IterRange iterates over the records of store with keys from start,
+// inclusive, to end, exclusive; an empty end is unbounded. Stores that are
+// not a RangeIterator are scanned in full with Iter and their records
+// filtered, in no particular order.
+func IterRange(ctx context.Context, store Store, start, end string, fn func(Record) error) error {
+	if r, ok := store.(RangeIterator); ok {
+		return r.IterRange(ctx, start, end, fn)
+	}
+	return store.Iter(ctx, func(record Record) error {
+		if !inRange(record.Key, start, end) {
+			return nil
+		}
+		return fn(record)
+	})
+}
+
+// IterPrefix iterates over the records of store whose keys start with
+// prefix, as IterRange does.
+func IterPrefix(ctx context.Context, store Store, prefix string, fn func(Record) error) error {
+	return IterRange(ctx, store, prefix, PrefixEnd(prefix), fn)
+}
+
+// PrefixEnd returns the smallest key greater than every key starting with
+// prefix, for use as a range end, or "" when there is none.
+func PrefixEnd(prefix string) string {
+	end := []byte(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return string(end[:i+1])
+		}
+	}
+	return ""
+}
+
+func inRange(key, start, end string) bool {
+	return key >= start && (end == "" || key < end)
+}
+
+// iterMapRange calls fn in key order with copies of the records of m in the
+// range. The caller holds the lock guarding m.
+func iterMapRange(m map[string][]byte, start, end string, fn func(Record) error) error {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		if inRange(k, start, end) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := fn(Record{Key: k, Value: append([]byte(nil), m[k]...)}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -9,6 +9,14 @@ const (
 	BackendMemory BackendType = "memory"
 	BackendBolt   BackendType = "bolt"
 	BackendBadger BackendType = "badger"
+	// BackendSQLite has no built-in store; it is available once
+	// github.com/mfifth/aisentinel-go-sdk/contrib/sqlitestore is imported,
+	// and NewGovernor fails without it.
+	BackendSQLite BackendType = "sqlite"
+	// BackendPostgres has no built-in store either; import
+	// github.com/mfifth/aisentinel-go-sdk/contrib/pgstore, without which
+	// NewGovernor fails, and set the connection string as StorageDSN.
+	BackendPostgres BackendType = "postgres"
 )
 
 // Record represents an audit log entry saved to embedded storage.
@@ -0,0 +1,208 @@
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/mfifth/aisentinel-go-sdk/storage"
+)
+
+// conformanceRecords is how many records Run iterates over, more than the
+// batches persistent backends read per transaction or query.
+const conformanceRecords = 600
+
+// Run checks that the stores returned by newStore keep the storage.Store
+// contract, including storage.PutBatch, storage.DeleteBatch and
+// storage.IterRange, whichever of the optional interfaces the store
+// implements. newStore is called once per subtest and must return an empty
+// store, which Run closes. Backends call it from their own tests and test
+// what only they promise, such as persistence across a reopen, separately:
+//
+//	func TestStore(t *testing.T) {
+//		storagetest.Run(t, func(t *testing.T) storage.Store {
+//			store, err := Open(filepath.Join(t.TempDir(), "audit.db"))
+//			if err != nil {
+//				t.Fatal(err)
+//			}
+//			return store
+//		})
+//	}
+func Run(t *testing.T, newStore func(t *testing.T) storage.Store) {
+	open := func(t *testing.T) storage.Store {
+		t.Helper()
+		store := newStore(t)
+		t.Cleanup(func() { _ = store.Close() })
+		return store
+	}
+	ctx := context.Background()
+
+	t.Run("PutGet", func(t *testing.T) {
+		store := open(t)
+		for _, value := range []string{"1", "2"} {
+			if err := store.Put(ctx, storage.Record{Key: "k", Value: []byte(value)}); err != nil {
+				t.Fatalf("put: %v", err)
+			}
+			if record, err := store.Get(ctx, "k"); err != nil || record.Key != "k" || string(record.Value) != value {
+				t.Fatalf("expected k=%s, got %+v %v", value, record, err)
+			}
+		}
+		if err := store.Put(ctx, storage.Record{Key: "empty"}); err != nil {
+			t.Fatalf("put: %v", err)
+		}
+		if record, err := store.Get(ctx, "empty"); err != nil || len(record.Value) != 0 {
+			t.Fatalf("expected an empty record, got %+v %v", record, err)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		store := open(t)
+		if _, err := store.Get(ctx, "missing"); !errors.Is(err, storage.ErrNotFound()) {
+			t.Fatalf("expected storage.ErrNotFound, got %v", err)
+		}
+		if err := store.Put(ctx, storage.Record{Key: "k", Value: []byte("v")}); err != nil {
+			t.Fatalf("put: %v", err)
+		}
+		if err := store.Delete(ctx, "k"); err != nil {
+			t.Fatalf("delete: %v", err)
+		}
+		if _, err := store.Get(ctx, "k"); !errors.Is(err, storage.ErrNotFound()) {
+			t.Fatalf("expected not found after delete, got %v", err)
+		}
+		if err := store.Delete(ctx, "missing"); err != nil {
+			t.Fatalf("expected deleting a missing key to succeed, got %v", err)
+		}
+	})
+
+	t.Run("Batch", func(t *testing.T) {
+		store := open(t)
+		if err := storage.PutBatch(ctx, store, []storage.Record{{Key: "b", Value: []byte("1")}, {Key: "b", Value: []byte("2")}, {Key: "c"}}); err != nil {
+			t.Fatalf("put batch: %v", err)
+		}
+		if record, err := store.Get(ctx, "b"); err != nil || string(record.Value) != "2" {
+			t.Fatalf("expected the last record of a key to win, got %+v %v", record, err)
+		}
+		if err := storage.DeleteBatch(ctx, store, []string{"b", "c", "missing"}); err != nil {
+			t.Fatalf("delete batch: %v", err)
+		}
+		for _, key := range []string{"b", "c"} {
+			if _, err := store.Get(ctx, key); !errors.Is(err, storage.ErrNotFound()) {
+				t.Fatalf("expected %s not found after delete batch, got %v", key, err)
+			}
+		}
+	})
+
+	t.Run("Iter", func(t *testing.T) {
+		store := open(t)
+		want := putRecords(t, store)
+		var keys []string
+		err := store.Iter(ctx, func(record storage.Record) error {
+			if string(record.Value) != "v"+record.Key {
+				return fmt.Errorf("record %s has value %q", record.Key, record.Value)
+			}
+			keys = append(keys, record.Key)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("iter: %v", err)
+		}
+		slices.Sort(keys)
+		if !slices.Equal(keys, want) {
+			t.Fatalf("expected every record once, got %d of %d", len(keys), len(want))
+		}
+
+		stop := errors.New("stop")
+		calls := 0
+		err = store.Iter(ctx, func(storage.Record) error {
+			calls++
+			return stop
+		})
+		if !errors.Is(err, stop) || calls != 1 {
+			t.Fatalf("expected the error of fn to end Iter, got %v after %d calls", err, calls)
+		}
+	})
+
+	t.Run("IterRange", func(t *testing.T) {
+		store := open(t)
+		putRecords(t, store)
+		_, ordered := store.(storage.RangeIterator)
+		var keys []string
+		err := storage.IterPrefix(ctx, store, "k01", func(record storage.Record) error {
+			keys = append(keys, record.Key)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("iter prefix: %v", err)
+		}
+		if ordered && !slices.IsSorted(keys) {
+			t.Fatalf("expected a RangeIterator to iterate in key order, got %v", keys)
+		}
+		slices.Sort(keys)
+		if len(keys) != 100 || keys[0] != "k0100" || keys[99] != "k0199" {
+			t.Fatalf("expected the 100 keys of prefix k01, got %d", len(keys))
+		}
+
+		keys = keys[:0]
+		err = storage.IterRange(ctx, store, "k0590", "", func(record storage.Record) error {
+			keys = append(keys, record.Key)
+			return nil
+		})
+		slices.Sort(keys)
+		if err != nil || len(keys) != 10 || keys[0] != "k0590" {
+			t.Fatalf("expected an unbounded range to run to the last key, got %v %v", keys, err)
+		}
+	})
+
+	t.Run("Concurrent", func(t *testing.T) {
+		store := open(t)
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func(key string) {
+				defer wg.Done()
+				if err := store.Put(ctx, storage.Record{Key: key, Value: []byte(key)}); err != nil {
+					t.Errorf("put: %v", err)
+				}
+				if _, err := store.Get(ctx, key); err != nil {
+					t.Errorf("get: %v", err)
+				}
+			}(fmt.Sprintf("k%02d", i))
+		}
+		wg.Wait()
+		n := 0
+		if err := store.Iter(ctx, func(storage.Record) error { n++; return nil }); err != nil || n != 50 {
+			t.Fatalf("expected 50 records, got %d %v", n, err)
+		}
+	})
+}
+
+// putRecords stores conformanceRecords records, keyed k0000 onwards with
+// values "v" plus the key, in random order, and returns their keys in order.
+func putRecords(t *testing.T, store storage.Store) []string {
+	t.Helper()
+	keys := make([]string, conformanceRecords)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("k%04d", i)
+	}
+	shuffled := slices.Clone(keys)
+	rand.New(rand.NewSource(1)).Shuffle(len(shuffled), func(i, j int) { // #nosec G404 -- test data order
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+	records := make([]storage.Record, len(shuffled))
+	for i, key := range shuffled {
+		records[i] = storage.Record{Key: key, Value: []byte("v" + key)}
+	}
+	if err := storage.PutBatch(context.Background(), store, records[:len(records)/2]); err != nil {
+		t.Fatalf("put batch: %v", err)
+	}
+	for _, record := range records[len(records)/2:] {
+		if err := store.Put(context.Background(), record); err != nil {
+			t.Fatalf("put: %v", err)
+		}
+	}
+	return keys
+}
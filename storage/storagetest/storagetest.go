@@ -0,0 +1,271 @@
+// Package storagetest provides a storage.Store wrapper that injects faults,
+// for testing how code using a Governor copes with a slow, flaky or full
+// audit store, and Run, a conformance suite for Store implementations.
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/mfifth/aisentinel-go-sdk/storage"
+)
+
+// ErrInjected is returned by operations failed by Faults.ErrorRate or
+// FailNext.
+var ErrInjected = errors.New("storagetest: injected fault")
+
+// ErrDiskFull is returned by Put once Faults.Capacity is used up.
+var ErrDiskFull = errors.New("storagetest: no space left on device")
+
+// Op names a Store operation.
+type Op string
+
+const (
+	OpPut    Op = "put"
+	OpGet    Op = "get"
+	OpIter   Op = "iter"
+	OpDelete Op = "delete"
+)
+
+// Faults configures the faults a FaultyStore injects. The zero value injects
+// none.
+type Faults struct {
+	// Latency delays every operation. Jitter adds up to that much more at
+	// random. A delay is cut short when the context is done.
+	Latency time.Duration
+	Jitter  time.Duration
+	// ErrorRate is the probability, from 0 to 1, that an operation fails
+	// with ErrInjected.
+	ErrorRate float64
+	// Ops restricts Latency and ErrorRate to these operations; empty
+	// affects all of them.
+	Ops []Op
+	// Capacity is the number of value bytes Put may hold before failing with
+	// ErrDiskFull; 0 is unlimited. Deleting records frees space.
+	Capacity int
+	// Seed seeds the random source, so a failing sequence can be replayed.
+	Seed int64
+}
+
+// FaultyStore wraps a Store and injects the configured faults before
+// delegating to it. It is safe for concurrent use.
+type FaultyStore struct {
+	store storage.Store
+
+	mu       sync.Mutex
+	faults   Faults
+	rng      *rand.Rand
+	failNext int
+	sizes    map[string]int
+	used     int
+	calls    map[Op]int
+	injected int
+}
+
+// NewFaultyStore wraps store, which defaults to a storage.MemoryStore, with
+// faults.
+func NewFaultyStore(store storage.Store, faults Faults) *FaultyStore {
+	if store == nil {
+		store = storage.NewMemory()
+	}
+	s := &FaultyStore{store: store, sizes: make(map[string]int), calls: make(map[Op]int)}
+	s.SetFaults(faults)
+	return s
+}
+
+// SetFaults replaces the injected faults, for example to simulate an outage
+// starting or ending mid-test. Space already used counts against the new
+// Capacity.
+func (s *FaultyStore) SetFaults(faults Faults) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = faults
+	s.rng = rand.New(rand.NewSource(faults.Seed)) // #nosec G404 -- test fault injection
+}
+
+// FailNext makes the next n operations fail with ErrInjected regardless of
+// Faults.
+func (s *FaultyStore) FailNext(n int) {
+	s.mu.Lock()
+	s.failNext = n
+	s.mu.Unlock()
+}
+
+// Calls reports how many times op was called, including failed calls.
+func (s *FaultyStore) Calls(op Op) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[op]
+}
+
+// Injected reports how many operations failed because of an injected fault.
+func (s *FaultyStore) Injected() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.injected
+}
+
+// Put stores record unless a fault is injected or it does not fit.
+func (s *FaultyStore) Put(ctx context.Context, record storage.Record) error {
+	if err := s.inject(ctx, OpPut); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	grow := len(record.Value) - s.sizes[record.Key]
+	if s.faults.Capacity > 0 && s.used+grow > s.faults.Capacity {
+		s.injected++
+		s.mu.Unlock()
+		return ErrDiskFull
+	}
+	s.mu.Unlock()
+	if err := s.store.Put(ctx, record); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.used += len(record.Value) - s.sizes[record.Key]
+	s.sizes[record.Key] = len(record.Value)
+	s.mu.Unlock()
+	return nil
+}
+
+// PutBatch stores records unless a fault is injected or they do not all
+// fit. It is one OpPut.
+func (s *FaultyStore) PutBatch(ctx context.Context, records []storage.Record) error {
+	if err := s.inject(ctx, OpPut); err != nil {
+		return err
+	}
+	sizes := make(map[string]int, len(records))
+	for _, record := range records {
+		sizes[record.Key] = len(record.Value)
+	}
+	s.mu.Lock()
+	grow := 0
+	for key, size := range sizes {
+		grow += size - s.sizes[key]
+	}
+	if s.faults.Capacity > 0 && s.used+grow > s.faults.Capacity {
+		s.injected++
+		s.mu.Unlock()
+		return ErrDiskFull
+	}
+	s.mu.Unlock()
+	if err := storage.PutBatch(ctx, s.store, records); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	for key, size := range sizes {
+		s.used += size - s.sizes[key]
+		s.sizes[key] = size
+	}
+	s.mu.Unlock()
+	return nil
+}
+
+// Get retrieves a record unless a fault is injected.
+func (s *FaultyStore) Get(ctx context.Context, key string) (storage.Record, error) {
+	if err := s.inject(ctx, OpGet); err != nil {
+		return storage.Record{}, err
+	}
+	return s.store.Get(ctx, key)
+}
+
+// Iter iterates over all records unless a fault is injected.
+func (s *FaultyStore) Iter(ctx context.Context, fn func(storage.Record) error) error {
+	if err := s.inject(ctx, OpIter); err != nil {
+		return err
+	}
+	return s.store.Iter(ctx, fn)
+}
+
+// IterRange iterates over a key range unless a fault is injected. It is an
+// OpIter.
+func (s *FaultyStore) IterRange(ctx context.Context, start, end string, fn func(storage.Record) error) error {
+	if err := s.inject(ctx, OpIter); err != nil {
+		return err
+	}
+	return storage.IterRange(ctx, s.store, start, end, fn)
+}
+
+// Delete removes a record unless a fault is injected, freeing its space.
+func (s *FaultyStore) Delete(ctx context.Context, key string) error {
+	if err := s.inject(ctx, OpDelete); err != nil {
+		return err
+	}
+	if err := s.store.Delete(ctx, key); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.used -= s.sizes[key]
+	delete(s.sizes, key)
+	s.mu.Unlock()
+	return nil
+}
+
+// DeleteBatch removes records unless a fault is injected, freeing their
+// space. It is one OpDelete.
+func (s *FaultyStore) DeleteBatch(ctx context.Context, keys []string) error {
+	if err := s.inject(ctx, OpDelete); err != nil {
+		return err
+	}
+	if err := storage.DeleteBatch(ctx, s.store, keys); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	for _, key := range keys {
+		s.used -= s.sizes[key]
+		delete(s.sizes, key)
+	}
+	s.mu.Unlock()
+	return nil
+}
+
+// Close closes the wrapped store. Faults are not injected.
+func (s *FaultyStore) Close() error {
+	return s.store.Close()
+}
+
+// inject counts the call, waits out the configured latency and decides
+// whether op fails.
+func (s *FaultyStore) inject(ctx context.Context, op Op) error {
+	s.mu.Lock()
+	s.calls[op]++
+	affected := len(s.faults.Ops) == 0
+	for _, o := range s.faults.Ops {
+		affected = affected || o == op
+	}
+	var delay time.Duration
+	fail := false
+	switch {
+	case s.failNext > 0:
+		s.failNext--
+		fail = true
+	case affected:
+		delay = s.faults.Latency
+		if s.faults.Jitter > 0 {
+			delay += time.Duration(s.rng.Int63n(int64(s.faults.Jitter)))
+		}
+		fail = s.faults.ErrorRate > 0 && s.rng.Float64() < s.faults.ErrorRate
+	}
+	if fail {
+		s.injected++
+	}
+	s.mu.Unlock()
+
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if fail {
+		return fmt.Errorf("%s: %w", op, ErrInjected)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	governor "github.com/mfifth/aisentinel-go-sdk"
+	"github.com/mfifth/aisentinel-go-sdk/storage"
+)
+
+func TestRun(t *testing.T) {
+	Run(t, func(*testing.T) storage.Store { return storage.NewMemory() })
+	t.Run("FaultyStore", func(t *testing.T) {
+		Run(t, func(*testing.T) storage.Store { return NewFaultyStore(nil, Faults{}) })
+	})
+}
+
+func TestFaultyStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewFaultyStore(nil, Faults{Capacity: 8})
+
+	if err := store.Put(ctx, storage.Record{Key: "a", Value: []byte("12345")}); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	if err := store.Put(ctx, storage.Record{Key: "b", Value: []byte("12345")}); !errors.Is(err, ErrDiskFull) {
+		t.Fatalf("expected the disk to be full, got %v", err)
+	}
+	if err := store.Delete(ctx, "a"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if err := store.Put(ctx, storage.Record{Key: "b", Value: []byte("12345")}); err != nil {
+		t.Fatalf("expected deleting to free space: %v", err)
+	}
+
+	store.FailNext(1)
+	if _, err := store.Get(ctx, "b"); !errors.Is(err, ErrInjected) {
+		t.Fatalf("expected an injected failure, got %v", err)
+	}
+	if rec, err := store.Get(ctx, "b"); err != nil || string(rec.Value) != "12345" {
+		t.Fatalf("expected the store to recover, got %+v %v", rec, err)
+	}
+
+	store.SetFaults(Faults{Latency: time.Hour, Ops: []Op{OpGet}})
+	timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := store.Get(timeout, "b"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the delay to honour the context, got %v", err)
+	}
+	if err := store.Delete(ctx, "b"); err != nil {
+		t.Fatalf("expected other operations to be unaffected: %v", err)
+	}
+	if store.Calls(OpGet) != 3 || store.Injected() != 2 {
+		t.Fatalf("unexpected counters: %d gets, %d injected", store.Calls(OpGet), store.Injected())
+	}
+}
+
+func TestFaultyStoreAuditFailures(t *testing.T) {
+	store := NewFaultyStore(nil, Faults{ErrorRate: 1, Ops: []Op{OpPut}})
+	var failures atomic.Int32
+	pack := &governor.Rulepack{ID: "default", Rules: []governor.RuleDefinition{{ID: "prompt", Pattern: "^ok", Allow: true}}}
+	gov, err := governor.NewGovernor(context.Background(), governor.Config{APIKey: "test", OfflineMode: true},
+		governor.WithRulepacks(pack), governor.WithStorage(store),
+		governor.WithEventListener(func(e governor.Event) {
+			if e.Type == governor.EventAuditFailure {
+				failures.Add(1)
+			}
+		}))
+	if err != nil {
+		t.Fatalf("expected governor: %v", err)
+	}
+	defer gov.Close()
+
+	result, err := gov.Evaluate(context.Background(), governor.DecisionRequest{RulepackID: "default", Payload: []byte(`{"prompt":"ok"}`)})
+	if err != nil || !result.Allowed {
+		t.Fatalf("expected the decision despite the audit failure, got %+v %v", result, err)
+	}
+	if failures.Load() != 1 || store.Injected() != 1 {
+		t.Fatalf("expected one audit failure, got %d events and %d injected", failures.Load(), store.Injected())
+	}
+}
@@ -0,0 +1,76 @@
+package governor
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+)
+
+// StreamEvaluator governs text that arrives in pieces, such as a streamed
+// completion. Every delta is appended to the text so far and the whole text is
+// evaluated, so patterns spanning deltas still match. Only the first denial
+// and the decision of Finish are audited and metered, so a long stream is
+// not stored once per delta. Once a delta is denied the stream stays denied.
+// A StreamEvaluator is not safe for concurrent use.
+type StreamEvaluator struct {
+	gov      *Governor
+	rulepack string
+	field    string
+	fields   map[string]string
+	text     strings.Builder
+	denied   *DecisionResult
+}
+
+// NewStreamEvaluator returns a StreamEvaluator for rulepackID. The text is
+// evaluated under field, alongside the fixed string fields such as the model
+// name.
+func (g *Governor) NewStreamEvaluator(rulepackID, field string, fields map[string]string) *StreamEvaluator {
+	payload := make(map[string]string, len(fields)+1)
+	for k, v := range fields {
+		payload[k] = v
+	}
+	return &StreamEvaluator{gov: g, rulepack: rulepackID, field: field, fields: payload}
+}
+
+// Write appends delta and evaluates the text so far. A denial is evaluated
+// again to be audited. After a denial Write returns that decision again
+// without evaluating.
+func (s *StreamEvaluator) Write(ctx context.Context, delta string) (DecisionResult, error) {
+	if s.denied != nil {
+		return *s.denied, nil
+	}
+	s.text.WriteString(delta)
+	result, err := s.evaluate(ctx, false)
+	if err != nil || result.Allowed {
+		return result, err
+	}
+	return s.evaluate(ctx, true)
+}
+
+// Finish evaluates and audits the complete text once the stream has ended,
+// and must be called once. After a denial, which is audited already, it
+// returns that decision again.
+func (s *StreamEvaluator) Finish(ctx context.Context) (DecisionResult, error) {
+	if s.denied != nil {
+		return *s.denied, nil
+	}
+	return s.evaluate(ctx, true)
+}
+
+// evaluate evaluates the text so far. Unrecorded decisions are not memoized
+// either, since the text only grows.
+func (s *StreamEvaluator) evaluate(ctx context.Context, record bool) (DecisionResult, error) {
+	s.fields[s.field] = s.text.String()
+	payload, err := json.Marshal(s.fields)
+	if err != nil {
+		return DecisionResult{}, err
+	}
+	result, err := s.gov.Evaluate(ctx, DecisionRequest{RulepackID: s.rulepack, Payload: payload, NoCache: !record, unrecorded: !record})
+	if err == nil && record && !result.Allowed {
+		s.denied = &result
+	}
+	return result, err
+}
+
+// Text returns the text written so far.
+func (s *StreamEvaluator) Text() string { return s.text.String() }
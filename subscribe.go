@@ -0,0 +1,145 @@
+package governor
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Subscription events emitted while Config.RulepackSubscribe is set.
+const (
+	EventRulepackSubscribed         EventType = "rulepack_subscribed"
+	EventRulepackSubscriptionFailed EventType = "rulepack_subscription_failed"
+)
+
+// RulepackEventsPath is the control plane endpoint streaming rulepack
+// changes as server-sent "rulepack" events whose data is a RulepackChange.
+const RulepackEventsPath = "/rulepacks/-/events"
+
+// RulepackChange is the data of a rulepack event: Type is "updated" or
+// "removed".
+type RulepackChange struct {
+	Type    string `json:"type"`
+	ID      string `json:"id"`
+	Version string `json:"version,omitempty"`
+	ETag    string `json:"etag,omitempty"`
+}
+
+// subscribeRulepacks keeps the subscription to rulepack updates open until
+// ctx is done, reconnecting with exponential backoff. Updates missed while
+// disconnected are caught up by refreshing every cached rulepack once
+// reconnected.
+func (g *Governor) subscribeRulepacks(ctx context.Context) {
+	const maxBackoff = 30 * time.Second
+	backoff, reconnect := time.Second, false
+	for {
+		connected, err := g.streamRulepackUpdates(ctx, reconnect)
+		if ctx.Err() != nil {
+			return
+		}
+		if connected {
+			backoff, reconnect = time.Second, true
+		}
+		g.events.emit(Event{Type: EventRulepackSubscriptionFailed, Level: LevelWarn, Message: "rulepack subscription lost", Err: err, Fields: map[string]any{"backoff": backoff.String()}})
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, maxBackoff)
+	}
+}
+
+// streamRulepackUpdates reads rulepack changes from RulepackEventsPath until
+// the stream ends, and reports whether it connected at all. With catchUp
+// every cached rulepack is refreshed once connected.
+func (g *Governor) streamRulepackUpdates(ctx context.Context, catchUp bool) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, g.cfg.APIBaseURL+RulepackEventsPath, nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Authorization", "Bearer "+g.apiKey())
+	req.Header.Set("Accept", "text/event-stream")
+	client := *g.httpClient
+	client.Timeout = 0 // the stream stays open
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("subscribe: unexpected status %d", resp.StatusCode)
+	}
+	g.events.emit(Event{Type: EventRulepackSubscribed, Level: LevelInfo, Message: "subscribed to rulepack updates"})
+	if catchUp {
+		g.applyRulepackChange(ctx, RulepackChange{Type: "updated"})
+	}
+
+	var event, data string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			var change RulepackChange
+			if event == "rulepack" && json.Unmarshal([]byte(data), &change) == nil && change.ID != "" {
+				g.applyRulepackChange(ctx, change)
+			}
+			event, data = "", ""
+		case strings.HasPrefix(line, ":"):
+			// A comment, sent to keep the connection alive.
+		default:
+			field, value, _ := strings.Cut(line, ":")
+			value = strings.TrimPrefix(value, " ")
+			switch field {
+			case "event":
+				event = value
+			case "data":
+				if data != "" {
+					data += "\n"
+				}
+				data += value
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return true, err
+	}
+	return true, errors.New("subscribe: stream closed")
+}
+
+// applyRulepackChange re-fetches the cached latest version of an updated
+// rulepack, or of every rulepack when the change has no ID, and evicts every
+// cached version of a removed one. Pinned versions never change, so updates
+// leave them alone, as they do copies already at the announced ETag. A
+// rulepack that fails to refresh is evicted too, so the next evaluation
+// fetches it rather than keep using the outdated copy.
+func (g *Governor) applyRulepackChange(ctx context.Context, change RulepackChange) {
+	removed := change.Type == "removed"
+	g.refreshMu.Lock()
+	var packs []fetchedPack
+	for ref, f := range g.fetched {
+		id, version := splitRulepackRef(ref)
+		if change.ID != "" && id != change.ID {
+			continue
+		}
+		switch {
+		case removed:
+			delete(g.fetched, ref)
+			g.cache.Invalidate(ref)
+		case version == "" && (change.ETag == "" || change.ETag != f.etag):
+			packs = append(packs, f)
+		}
+	}
+	g.refreshMu.Unlock()
+	for _, f := range packs {
+		if err := g.refreshRulepack(ctx, f); err != nil {
+			g.cache.Invalidate(f.id)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package governor
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// newHTTPClient builds the default HTTP client from configuration. The client
+// has no overall timeout; each operation applies its own deadline so a long
+// bundle download does not dictate the decision path budget.
+func newHTTPClient(cfg Config) (*http.Client, error) {
+	proxy, err := proxyFunc(cfg)
+	if err != nil {
+		return nil, err
+	}
+	tlsConf, err := tlsConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy:               proxy,
+			DialContext:         (&net.Dialer{Timeout: 5 * time.Second}).DialContext,
+			TLSClientConfig:     tlsConf,
+			IdleConnTimeout:     90 * time.Second,
+			MaxIdleConns:        100,
+			MaxIdleConnsPerHost: 10,
+		},
+	}, nil
+}
+
+// tlsVersions maps accepted TLSMinVersion values to protocol versions.
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// tlsConfig builds the client TLS configuration. TLS 1.2 is the default
+// minimum version.
+func tlsConfig(cfg Config) (*tls.Config, error) {
+	conf := &tls.Config{MinVersion: tls.VersionTLS12, ServerName: cfg.TLSServerName}
+
+	if cfg.TLSMinVersion != "" {
+		version, ok := tlsVersions[cfg.TLSMinVersion]
+		if !ok {
+			return nil, fmt.Errorf("invalid TLSMinVersion %q", cfg.TLSMinVersion)
+		}
+		conf.MinVersion = version
+	}
+
+	if cfg.TLSRootCAsFile != "" {
+		pem, err := os.ReadFile(cfg.TLSRootCAsFile) // #nosec G304 -- operator supplied CA bundle
+		if err != nil {
+			return nil, fmt.Errorf("read TLSRootCAsFile: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("TLSRootCAsFile %s contains no certificates", cfg.TLSRootCAsFile)
+		}
+		conf.RootCAs = pool
+	}
+
+	if len(cfg.TLSCipherSuites) > 0 {
+		available := map[string]uint16{}
+		for _, suite := range tls.CipherSuites() {
+			available[suite.Name] = suite.ID
+		}
+		for _, name := range cfg.TLSCipherSuites {
+			id, ok := available[name]
+			if !ok {
+				return nil, fmt.Errorf("unsupported TLS cipher suite %q", name)
+			}
+			conf.CipherSuites = append(conf.CipherSuites, id)
+		}
+	}
+	return conf, nil
+}
+
+// proxyFunc returns the proxy selector for the transport. When none of the
+// proxy fields are configured the process environment is used; otherwise
+// only the Config values apply, so the SDK can use a different egress proxy
+// than the rest of the application. socks5:// URLs are supported.
+func proxyFunc(cfg Config) (func(*http.Request) (*url.URL, error), error) {
+	if cfg.HTTPProxy == "" && cfg.HTTPSProxy == "" && cfg.NoProxy == "" {
+		return http.ProxyFromEnvironment, nil
+	}
+	httpProxy, err := parseProxyURL(cfg.HTTPProxy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid HTTPProxy: %w", err)
+	}
+	httpsProxy, err := parseProxyURL(cfg.HTTPSProxy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid HTTPSProxy: %w", err)
+	}
+	bypass := parseNoProxy(cfg.NoProxy)
+
+	return func(req *http.Request) (*url.URL, error) {
+		if bypass.matches(req.URL) {
+			return nil, nil
+		}
+		if req.URL.Scheme == "https" {
+			return httpsProxy, nil
+		}
+		return httpProxy, nil
+	}, nil
+}
+
+func parseProxyURL(raw string) (*url.URL, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("proxy URL %q has no host", raw)
+	}
+	return u, nil
+}
+
+// noProxy holds parsed NO_PROXY entries.
+type noProxy struct {
+	all      bool
+	networks []*net.IPNet
+	ips      []net.IP
+	domains  []string
+}
+
+// parseNoProxy parses a comma separated list of hosts, domain suffixes
+// (optionally starting with a dot), IP addresses and CIDR ranges. A single
+// "*" bypasses the proxy for every request.
+func parseNoProxy(list string) noProxy {
+	var np noProxy
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		switch {
+		case entry == "":
+		case entry == "*":
+			np.all = true
+		default:
+			if _, network, err := net.ParseCIDR(entry); err == nil {
+				np.networks = append(np.networks, network)
+				continue
+			}
+			if host, _, err := net.SplitHostPort(entry); err == nil {
+				entry = host
+			}
+			if ip := net.ParseIP(entry); ip != nil {
+				np.ips = append(np.ips, ip)
+				continue
+			}
+			np.domains = append(np.domains, strings.TrimPrefix(entry, "."))
+		}
+	}
+	return np
+}
+
+func (np noProxy) matches(u *url.URL) bool {
+	if np.all {
+		return true
+	}
+	host := strings.ToLower(u.Hostname())
+	if ip := net.ParseIP(host); ip != nil {
+		for _, candidate := range np.ips {
+			if candidate.Equal(ip) {
+				return true
+			}
+		}
+		for _, network := range np.networks {
+			if network.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, domain := range np.domains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,24 @@
+package governor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// TypedPayload is a payload struct bound to the rulepack it is written for.
+// `aisentinel-go-sdk rulepack gen` emits such structs, one field per rule.
+type TypedPayload interface {
+	RulepackID() string
+}
+
+// Evaluate evaluates a typed payload against the rulepack it is bound to. The
+// payload is marshaled as JSON, so unset omitempty fields are missing from the
+// payload exactly as in a hand-built map.
+func Evaluate[T TypedPayload](ctx context.Context, g *Governor, payload T) (DecisionResult, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return DecisionResult{}, fmt.Errorf("marshal payload: %w", err)
+	}
+	return g.Evaluate(ctx, DecisionRequest{RulepackID: payload.RulepackID(), Payload: data})
+}
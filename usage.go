@@ -0,0 +1,111 @@
+package governor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/mfifth/aisentinel-go-sdk/storage"
+)
+
+// EventUsageFailure is emitted when usage counters could not be updated.
+const EventUsageFailure EventType = "usage_failure"
+
+// usageKeyPrefix namespaces usage aggregates within the audit store.
+const usageKeyPrefix = "usage/"
+
+// UsageRecord aggregates metered usage for a subject and model pair.
+type UsageRecord struct {
+	Subject          string    `json:"subject"`
+	Model            string    `json:"model"`
+	Requests         int64     `json:"requests"`
+	PromptTokens     int64     `json:"prompt_tokens"`
+	CompletionTokens int64     `json:"completion_tokens"`
+	EstimatedCost    float64   `json:"estimated_cost"`
+	FirstSeen        time.Time `json:"first_seen"`
+	LastSeen         time.Time `json:"last_seen"`
+}
+
+// metered reports whether the request carries any usage information.
+func (r DecisionRequest) metered() bool {
+	return r.Subject != "" || r.Model != "" || r.PromptTokens != 0 || r.CompletionTokens != 0 || r.EstimatedCost != 0
+}
+
+func usageKey(subject, model string) string {
+	return usageKeyPrefix + url.PathEscape(subject) + "/" + url.PathEscape(model)
+}
+
+// recordUsage folds the request's usage into the stored aggregate.
+func (g *Governor) recordUsage(ctx context.Context, req DecisionRequest) error {
+	if g.storage == nil || !req.metered() {
+		return nil
+	}
+	key := usageKey(req.Subject, req.Model)
+	now := g.now()
+
+	g.usageMu.Lock()
+	defer g.usageMu.Unlock()
+
+	record := UsageRecord{Subject: req.Subject, Model: req.Model, FirstSeen: now}
+	existing, err := g.storage.Get(ctx, key)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(existing.Value, &record); err != nil {
+			return err
+		}
+	case !errors.Is(err, storage.ErrNotFound()):
+		return err
+	}
+
+	record.Requests++
+	record.PromptTokens += int64(req.PromptTokens)
+	record.CompletionTokens += int64(req.CompletionTokens)
+	record.EstimatedCost += req.EstimatedCost
+	record.LastSeen = now
+	return g.storage.Put(ctx, storage.Record{Key: key, Value: mustJSON(record)})
+}
+
+// Usage returns all usage aggregates sorted by subject then model.
+func (g *Governor) Usage(ctx context.Context) ([]UsageRecord, error) {
+	if g.storage == nil {
+		return nil, nil
+	}
+	var records []UsageRecord
+	err := storage.IterPrefix(ctx, g.storage, usageKeyPrefix, func(rec storage.Record) error {
+		var usage UsageRecord
+		if err := json.Unmarshal(rec.Value, &usage); err != nil {
+			return err
+		}
+		records = append(records, usage)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Subject != records[j].Subject {
+			return records[i].Subject < records[j].Subject
+		}
+		return records[i].Model < records[j].Model
+	})
+	return records, nil
+}
+
+// ExportUsage writes every usage aggregate to w as newline-delimited JSON.
+func (g *Governor) ExportUsage(ctx context.Context, w io.Writer) error {
+	records, err := g.Usage(ctx)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	for _, record := range records {
+		if err := enc.Encode(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}